@@ -226,12 +226,16 @@ func (c *Connection) URL() string {
 			url = g.F("%s://%s:%s", c.Type.String(), c.Data["host"], cast.ToString(c.Data["port"]))
 		case dbio.TypeFileFtp:
 			url = g.F("%s://%s:%s", c.Type.String(), c.Data["host"], cast.ToString(c.Data["port"]))
+		case dbio.TypeFileHDFS, dbio.TypeFileWebDAV:
+			url = g.F("%s://%s:%s", c.Type.String(), c.Data["host"], cast.ToString(c.Data["port"]))
 		case dbio.TypeFileS3:
 			url = g.F("%s://%s", c.Type.String(), c.Data["bucket"])
 		case dbio.TypeFileGoogle:
 			url = g.F("%s://%s", c.Type.String(), c.Data["bucket"])
 		case dbio.TypeFileAzure:
 			url = g.F("https://%s.blob.core.windows.net/%s", c.Data["account"], c.Data["container"])
+		case dbio.TypeFileGSheets:
+			url = g.F("%s://%s", c.Type.String(), c.Data["spreadsheet_id"])
 		}
 	}
 
@@ -370,7 +374,7 @@ func (c *Connection) setURL() (err error) {
 				setIfMissing(k, v)
 			}
 		}
-		if g.In(c.Type, dbio.TypeFileSftp, dbio.TypeFileFtp) {
+		if g.In(c.Type, dbio.TypeFileSftp, dbio.TypeFileFtp, dbio.TypeFileWebDAV) {
 			setIfMissing("user", U.Username())
 			setIfMissing("host", U.Hostname())
 			setIfMissing("password", U.Password())
@@ -383,6 +387,14 @@ func (c *Connection) setURL() (err error) {
 			setIfMissing("account", strings.ReplaceAll(U.U.Host, ".blob.core.windows.net", ""))
 			setIfMissing("container", strings.ReplaceAll(U.U.Path, "/", ""))
 		}
+		if c.Type == dbio.TypeFileHDFS {
+			setIfMissing("user", U.Username())
+			setIfMissing("host", U.Hostname())
+			setIfMissing("port", U.Port(c.Info().Type.DefPort()))
+		}
+		if c.Type == dbio.TypeFileGSheets {
+			setIfMissing("spreadsheet_id", U.U.Host)
+		}
 	}
 
 	template := ""
@@ -615,8 +627,11 @@ func (c *Connection) setURL() (err error) {
 		setIfMissing("password", "")
 		setIfMissing("port", c.Type.DefPort())
 		template = c.Type.String() + "://{user}:{password}@{host}:{port}/"
+	case dbio.TypeFileHDFS, dbio.TypeFileWebDAV:
+		setIfMissing("port", c.Type.DefPort())
+		template = c.Type.String() + "://{host}:{port}/"
 	case dbio.TypeFileS3, dbio.TypeFileGoogle, dbio.TypeFileAzure,
-		dbio.TypeFileLocal:
+		dbio.TypeFileLocal, dbio.TypeFileGSheets:
 		return nil
 	default:
 		if c.Type.IsUnknown() {
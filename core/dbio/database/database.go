@@ -203,6 +203,52 @@ func init() {
 	}
 }
 
+// DatabaseFactory returns a new connection for the provided URL.
+// The returned connection must embed BaseConn.
+type DatabaseFactory func(URL string) Connection
+
+var (
+	registeredDatabases   = map[dbio.Type]DatabaseFactory{}
+	registeredDatabasesMu sync.RWMutex
+)
+
+// RegisterDatabase registers a custom database connection type.
+// URLs with the scheme of the type (e.g. `mydb://...`) will be
+// opened with the provided factory. A template (`TypeRegistration.Template`)
+// can be registered beforehand with dbio.RegisterType to customize
+// the SQL generated for the database.
+func RegisterDatabase(t dbio.Type, factory DatabaseFactory) (err error) {
+	t = dbio.Type(strings.ToLower(t.String()))
+	if factory == nil {
+		return g.Error("database factory is nil for type '%s'", t)
+	}
+
+	if t.Kind() == dbio.KindUnknown {
+		err = dbio.RegisterType(t, dbio.TypeRegistration{Kind: dbio.KindDatabase})
+		if err != nil {
+			return g.Error(err, "could not register database type '%s'", t)
+		}
+	} else if !t.IsDb() {
+		return g.Error("type '%s' is not a database type", t)
+	} else if !t.IsRegistered() {
+		return g.Error("type '%s' conflicts with a built-in type", t)
+	}
+
+	registeredDatabasesMu.Lock()
+	registeredDatabases[t] = factory
+	registeredDatabasesMu.Unlock()
+
+	return nil
+}
+
+// registeredDatabase returns the factory of a registered database type
+func registeredDatabase(t dbio.Type) (factory DatabaseFactory, ok bool) {
+	registeredDatabasesMu.RLock()
+	factory, ok = registeredDatabases[t]
+	registeredDatabasesMu.RUnlock()
+	return
+}
+
 // NewConn return the most proper connection for a given database
 func NewConn(URL string, props ...string) (Connection, error) {
 	return NewConnContext(context.Background(), URL, props...)
@@ -248,7 +294,24 @@ func NewConnContext(ctx context.Context, URL string, props ...string) (Connectio
 	}
 
 	concurrency := 10
-	if strings.HasPrefix(URL, "postgres") {
+	if factory, ok := registeredDatabase(dbio.Type(strings.ToLower(u.U.Scheme))); ok {
+		conn = factory(URL)
+		if conn == nil || conn.Base() == nil {
+			return nil, g.Error("database factory returned a nil connection for %s", u.U.Scheme)
+		}
+
+		base := conn.Base()
+		if base.URL == "" {
+			base.URL = URL
+		}
+		if base.Type == "" {
+			base.Type = dbio.Type(strings.ToLower(u.U.Scheme))
+		}
+		if base.instance == nil {
+			instance := conn
+			base.instance = &instance
+		}
+	} else if strings.HasPrefix(URL, "postgres") {
 		if strings.Contains(URL, "redshift.amazonaws.com") {
 			conn = &RedshiftConn{URL: URL}
 		} else {
@@ -16,6 +16,7 @@ import (
 
 	"github.com/dustin/go-humanize"
 	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 	"github.com/slingdata-io/sling-cli/core/env"
 	"github.com/spf13/cast"
@@ -1388,6 +1389,71 @@ func TestParseURL(t *testing.T) {
 	g.Info(g.Marshal(u))
 }
 
+// testRegisteredConn is a custom database connection, opening a sqlite database
+type testRegisteredConn struct {
+	SQLiteConn
+	initialized bool
+}
+
+func (conn *testRegisteredConn) Init() error {
+	conn.initialized = true
+	if err := conn.SQLiteConn.Init(); err != nil {
+		return err
+	}
+
+	// the sqlite init sets itself as the instance
+	var instance Connection = conn
+	conn.BaseConn.instance = &instance
+	return nil
+}
+
+func TestRegisterDatabase(t *testing.T) {
+	assert.Error(t, RegisterDatabase(dbio.TypeDbPostgres, func(URL string) Connection { return &testRegisteredConn{} }))
+	assert.Error(t, RegisterDatabase(dbio.TypeFileS3, func(URL string) Connection { return &testRegisteredConn{} }))
+	assert.Error(t, RegisterDatabase("testdb", nil))
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	var factoryURL string
+	err := RegisterDatabase("TestDB", func(URL string) Connection {
+		factoryURL = URL
+		return &testRegisteredConn{SQLiteConn: SQLiteConn{URL: "sqlite://" + dbPath}}
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, dbio.Type("testdb").IsDb())
+
+	// the scheme of the url resolves the factory
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, err := NewConnContext(ctx, "testdb://"+dbPath, "custom_prop=value")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "testdb://"+dbPath, factoryURL)
+
+	tConn, ok := conn.(*testRegisteredConn)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.True(t, tConn.initialized)
+	assert.Same(t, tConn, conn.Self())
+	assert.Equal(t, "value", conn.GetProp("custom_prop"))
+
+	// the connection works
+	if assert.NoError(t, conn.Connect()) {
+		defer conn.Close()
+		data, err := conn.Query("select 1 as one")
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, 1, cast.ToInt(data.Rows[0][0]))
+		}
+	}
+
+	// the connection uses the provided context
+	cancel()
+	assert.Error(t, conn.Context().Ctx.Err())
+}
+
 func TestInteractiveDuckDb(t *testing.T) {
 	var err error
 
@@ -2,6 +2,7 @@ package dbio
 
 import (
 	"errors"
+	"hash/fnv"
 	"sort"
 	"strings"
 	"time"
@@ -21,6 +22,7 @@ type FileNode struct {
 	Created  int64       `json:"created,omitempty"`
 	Updated  int64       `json:"updated,omitempty"`
 	Owner    string      `json:"owner,omitempty"`
+	ETag     string      `json:"etag,omitempty"`
 	Columns  iop.Columns `json:"columns,omitempty"`
 	Children FileNodes   `json:"children,omitempty"`
 
@@ -78,6 +80,14 @@ func (fn *FileNode) Path() string {
 	return fn.path
 }
 
+// Fingerprint returns a hash of the size, modification time and etag,
+// to detect whether a file has changed
+func (fn *FileNode) Fingerprint() int64 {
+	h := fnv.New64a()
+	h.Write([]byte(g.F("%d|%d|%s", fn.Size, fn.Updated, strings.Trim(fn.ETag, `"`))))
+	return int64(h.Sum64())
+}
+
 // FileNodes represent file nodes
 type FileNodes []FileNode
 
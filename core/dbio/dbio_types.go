@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"embed"
 	"strings"
+	"sync"
 
 	"github.com/flarco/g"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
@@ -38,14 +39,16 @@ type Type string
 const (
 	TypeUnknown Type = ""
 
-	TypeFileLocal  Type = "file"
-	TypeFileHDFS   Type = "hdfs"
-	TypeFileS3     Type = "s3"
-	TypeFileAzure  Type = "azure"
-	TypeFileGoogle Type = "gs"
-	TypeFileFtp    Type = "ftp"
-	TypeFileSftp   Type = "sftp"
-	TypeFileHTTP   Type = "http"
+	TypeFileLocal   Type = "file"
+	TypeFileHDFS    Type = "hdfs"
+	TypeFileS3      Type = "s3"
+	TypeFileAzure   Type = "azure"
+	TypeFileGoogle  Type = "gs"
+	TypeFileFtp     Type = "ftp"
+	TypeFileSftp    Type = "sftp"
+	TypeFileHTTP    Type = "http"
+	TypeFileGSheets Type = "gsheets"
+	TypeFileWebDAV  Type = "webdav"
 
 	TypeDbPostgres   Type = "postgres"
 	TypeDbRedshift   Type = "redshift"
@@ -82,6 +85,8 @@ var AllType = []struct {
 	{TypeFileFtp, "TypeFileFtp"},
 	{TypeFileSftp, "TypeFileSftp"},
 	{TypeFileHTTP, "TypeFileHTTP"},
+	{TypeFileGSheets, "TypeFileGSheets"},
+	{TypeFileWebDAV, "TypeFileWebDAV"},
 	{TypeDbPostgres, "TypeDbPostgres"},
 	{TypeDbRedshift, "TypeDbRedshift"},
 	{TypeDbStarRocks, "TypeDbStarRocks"},
@@ -118,9 +123,13 @@ func ValidateType(tStr string) (Type, bool) {
 		t = tMatched
 	}
 
+	if _, ok := registeredType(t); ok {
+		return t, true
+	}
+
 	switch t {
 	case
-		TypeFileLocal, TypeFileS3, TypeFileAzure, TypeFileGoogle, TypeFileSftp, TypeFileFtp,
+		TypeFileLocal, TypeFileHDFS, TypeFileS3, TypeFileAzure, TypeFileGoogle, TypeFileSftp, TypeFileFtp, TypeFileGSheets, TypeFileWebDAV,
 		TypeDbPostgres, TypeDbRedshift, TypeDbStarRocks, TypeDbMySQL, TypeDbMariaDB, TypeDbOracle, TypeDbBigQuery, TypeDbSnowflake, TypeDbSQLite, TypeDbSQLServer, TypeDbAzure, TypeDbAzureDWH, TypeDbDuckDb, TypeDbMotherDuck, TypeDbClickhouse, TypeDbTrino, TypeDbMongoDB, TypeDbPrometheus:
 		return t, true
 	}
@@ -128,6 +137,64 @@ func ValidateType(tStr string) (Type, bool) {
 	return t, false
 }
 
+// TypeRegistration describes a connection type registered at runtime,
+// such as a proprietary database or file system
+type TypeRegistration struct {
+	Kind     Kind   // KindDatabase or KindFile
+	Name     string // display name, e.g. "MyDB"
+	DefPort  int    // default port, if any
+	Template string // YAML template merged over the base template (for databases)
+}
+
+var (
+	registeredTypes   = map[Type]TypeRegistration{}
+	registeredTypesMu sync.RWMutex
+)
+
+// RegisterType registers a custom connection type, so that it is
+// recognized when parsing URLs and connection configurations
+func RegisterType(t Type, reg TypeRegistration) error {
+	t = Type(strings.ToLower(string(t)))
+	if t == TypeUnknown {
+		return g.Error("connection type is blank")
+	} else if reg.Kind != KindDatabase && reg.Kind != KindFile {
+		return g.Error("invalid kind for connection type '%s': %s", t, reg.Kind)
+	} else if !t.IsRegistered() && t.Kind() != KindUnknown {
+		return g.Error("connection type '%s' conflicts with a built-in type", t)
+	}
+
+	if reg.Template != "" {
+		if err := yaml.Unmarshal([]byte(reg.Template), &Template{}); err != nil {
+			return g.Error(err, "invalid template for connection type '%s'", t)
+		}
+	}
+
+	if reg.Name == "" {
+		reg.Name = t.String()
+	}
+
+	registeredTypesMu.Lock()
+	registeredTypes[t] = reg
+	delete(typeTemplate, t)
+	registeredTypesMu.Unlock()
+
+	return nil
+}
+
+// registeredType returns the registration of a custom connection type
+func registeredType(t Type) (reg TypeRegistration, ok bool) {
+	registeredTypesMu.RLock()
+	reg, ok = registeredTypes[t]
+	registeredTypesMu.RUnlock()
+	return
+}
+
+// IsRegistered returns true if the type was registered with RegisterType
+func (t Type) IsRegistered() bool {
+	_, ok := registeredType(t)
+	return ok
+}
+
 // String returns string instance
 func (t Type) String() string {
 	return string(t)
@@ -151,6 +218,11 @@ func (t Type) DefPort() int {
 		TypeDbProton:     8463,
 		TypeFileFtp:      21,
 		TypeFileSftp:     22,
+		TypeFileHDFS:     9870, // WebHDFS (namenode http)
+		TypeFileWebDAV:   443,
+	}
+	if reg, ok := registeredType(t); ok {
+		return reg.DefPort
 	}
 	return connTypesDefPort[t]
 }
@@ -166,9 +238,12 @@ func (t Type) Kind() Kind {
 	case TypeDbPostgres, TypeDbRedshift, TypeDbStarRocks, TypeDbMySQL, TypeDbMariaDB, TypeDbOracle, TypeDbBigQuery, TypeDbBigTable,
 		TypeDbSnowflake, TypeDbSQLite, TypeDbSQLServer, TypeDbAzure, TypeDbClickhouse, TypeDbTrino, TypeDbDuckDb, TypeDbMotherDuck, TypeDbMongoDB, TypeDbPrometheus, TypeDbProton:
 		return KindDatabase
-	case TypeFileLocal, TypeFileHDFS, TypeFileS3, TypeFileAzure, TypeFileGoogle, TypeFileSftp, TypeFileFtp, TypeFileHTTP, Type("https"):
+	case TypeFileLocal, TypeFileHDFS, TypeFileS3, TypeFileAzure, TypeFileGoogle, TypeFileSftp, TypeFileFtp, TypeFileHTTP, Type("https"), TypeFileGSheets, TypeFileWebDAV:
 		return KindFile
 	}
+	if reg, ok := registeredType(t); ok {
+		return reg.Kind
+	}
 	return KindUnknown
 }
 
@@ -204,6 +279,8 @@ func (t Type) NameLong() string {
 		TypeFileFtp:      "FileSys - Ftp",
 		TypeFileHTTP:     "FileSys - HTTP",
 		Type("https"):    "FileSys - HTTP",
+		TypeFileGSheets:  "FileSys - Google Sheets",
+		TypeFileWebDAV:   "FileSys - WebDAV",
 		TypeDbPostgres:   "DB - PostgreSQL",
 		TypeDbRedshift:   "DB - Redshift",
 		TypeDbStarRocks:  "DB - StarRocks",
@@ -225,6 +302,13 @@ func (t Type) NameLong() string {
 		TypeDbProton:     "DB - Proton",
 	}
 
+	if reg, ok := registeredType(t); ok {
+		if reg.Kind == KindFile {
+			return "FileSys - " + reg.Name
+		}
+		return "DB - " + reg.Name
+	}
+
 	return mapping[t]
 }
 
@@ -240,6 +324,8 @@ func (t Type) Name() string {
 		TypeFileFtp:      "Ftp",
 		TypeFileHTTP:     "HTTP",
 		Type("https"):    "HTTP",
+		TypeFileGSheets:  "Google Sheets",
+		TypeFileWebDAV:   "WebDAV",
 		TypeDbPostgres:   "PostgreSQL",
 		TypeDbRedshift:   "Redshift",
 		TypeDbStarRocks:  "StarRocks",
@@ -261,6 +347,10 @@ func (t Type) Name() string {
 		TypeDbProton:     "Proton",
 	}
 
+	if reg, ok := registeredType(t); ok {
+		return reg.Name
+	}
+
 	return mapping[t]
 }
 
@@ -346,9 +436,16 @@ func (t Type) Template() (template Template, err error) {
 		return template, g.Error(err, "yaml.Unmarshal")
 	}
 
-	templateBytes, err := templatesFolder.ReadFile("templates/" + t.String() + ".yaml")
-	if err != nil {
-		return template, g.Error(err, "io.ReadAll(templateFile) for "+t.String())
+	reg, registered := registeredType(t)
+
+	var templateBytes []byte
+	if registered {
+		templateBytes = []byte(reg.Template)
+	} else {
+		templateBytes, err = templatesFolder.ReadFile("templates/" + t.String() + ".yaml")
+		if err != nil {
+			return template, g.Error(err, "io.ReadAll(templateFile) for "+t.String())
+		}
 	}
 
 	err = yaml.Unmarshal([]byte(templateBytes), &connTemplate)
@@ -419,6 +516,19 @@ func (t Type) Template() (template Template, err error) {
 		template.GeneralTypeMap[gt] = cast.ToString(rec[t.String()])
 	}
 
+	// registered types declare their type mappings in their template
+	if registered {
+		for key, val := range connTemplate.GeneralTypeMap {
+			template.GeneralTypeMap[key] = val
+		}
+		for key, val := range connTemplate.NativeTypeMap {
+			template.NativeTypeMap[key] = val
+		}
+		for key, val := range connTemplate.NativeStatsMap {
+			template.NativeStatsMap[key] = val
+		}
+	}
+
 	// cache
 	typeTemplate[t] = template
 
@@ -10,6 +10,8 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gobwas/glob"
@@ -53,6 +55,7 @@ type FileSysClient interface {
 	GetPath(uri string) (path string, err error)
 
 	delete(path string) (err error)
+	move(srcURI, tgtURI string) (err error)
 	setDf(df *iop.Dataflow)
 }
 
@@ -84,17 +87,35 @@ func NewFileSysClientContext(ctx context.Context, fst dbio.Type, props ...string
 		fsClient = &FtpFileSysClient{}
 	case dbio.TypeFileSftp:
 		fsClient = &SftpFileSysClient{}
-	// case HDFSFileSys:
-	// 	fsClient = fsClient
+	case dbio.TypeFileHDFS:
+		fsClient = &HDFSFileSysClient{}
+	case dbio.TypeFileWebDAV:
+		fsClient = &WebDAVFileSysClient{}
 	case dbio.TypeFileAzure:
 		fsClient = &AzureFileSysClient{}
 	case dbio.TypeFileGoogle:
 		fsClient = &GoogleFileSysClient{}
 	case dbio.TypeFileHTTP:
 		fsClient = &HTTPFileSysClient{}
+	case dbio.TypeFileGSheets:
+		fsClient = &GoogleSheetFileSysClient{}
 	default:
-		err = g.Error("Unrecognized File System")
-		return
+		factory, ok := registeredFileSystem(fst)
+		if !ok {
+			err = g.Error("Unrecognized File System")
+			return
+		}
+
+		fsClient = factory()
+		if fsClient == nil || fsClient.Client() == nil {
+			err = g.Error("file system factory returned a nil client for %s", fst)
+			return
+		}
+
+		if fsClient.Client().instance == nil {
+			instance := fsClient
+			fsClient.Client().instance = &instance
+		}
 	}
 
 	fsClient.Client().fsType = fst
@@ -125,6 +146,51 @@ func NewFileSysClientContext(ctx context.Context, fst dbio.Type, props ...string
 	return
 }
 
+// FileSystemFactory returns a new file system client.
+// The returned client must embed BaseFileSysClient, and can implement
+// `Delete(path string) error` to support deleting paths.
+type FileSystemFactory func() FileSysClient
+
+var (
+	registeredFileSystems   = map[dbio.Type]FileSystemFactory{}
+	registeredFileSystemsMu sync.RWMutex
+)
+
+// RegisterFileSystem registers a custom file system type. URLs with the
+// scheme of the type (e.g. `myfs://bucket/path`) will be read and written
+// with a client created by the provided factory.
+func RegisterFileSystem(t dbio.Type, factory FileSystemFactory) (err error) {
+	t = dbio.Type(strings.ToLower(t.String()))
+	if factory == nil {
+		return g.Error("file system factory is nil for type '%s'", t)
+	}
+
+	if t.Kind() == dbio.KindUnknown {
+		err = dbio.RegisterType(t, dbio.TypeRegistration{Kind: dbio.KindFile})
+		if err != nil {
+			return g.Error(err, "could not register file system type '%s'", t)
+		}
+	} else if !t.IsFile() {
+		return g.Error("type '%s' is not a file system type", t)
+	} else if !t.IsRegistered() {
+		return g.Error("type '%s' conflicts with a built-in type", t)
+	}
+
+	registeredFileSystemsMu.Lock()
+	registeredFileSystems[t] = factory
+	registeredFileSystemsMu.Unlock()
+
+	return nil
+}
+
+// registeredFileSystem returns the factory of a registered file system type
+func registeredFileSystem(t dbio.Type) (factory FileSystemFactory, ok bool) {
+	registeredFileSystemsMu.RLock()
+	factory, ok = registeredFileSystems[t]
+	registeredFileSystemsMu.RUnlock()
+	return
+}
+
 // NewFileSysClientFromURL returns the proper fs client for the given path
 // props are provided as `"Prop1=Value1", "Prop2=Value2", ...`
 func NewFileSysClientFromURL(url string, props ...string) (fsClient FileSysClient, err error) {
@@ -144,9 +210,18 @@ func NewFileSysClientFromURLContext(ctx context.Context, url string, props ...st
 	case strings.HasPrefix(url, "sftp://"):
 		props = append(props, "URL="+url)
 		return NewFileSysClientContext(ctx, dbio.TypeFileSftp, props...)
+	case strings.HasPrefix(url, "hdfs://"):
+		props = append(props, "URL="+url)
+		return NewFileSysClientContext(ctx, dbio.TypeFileHDFS, props...)
+	case strings.HasPrefix(url, "webdav://"):
+		props = append(props, "URL="+url)
+		return NewFileSysClientContext(ctx, dbio.TypeFileWebDAV, props...)
 	case strings.HasPrefix(url, "gs://"):
 		props = append(props, "URL="+url)
 		return NewFileSysClientContext(ctx, dbio.TypeFileGoogle, props...)
+	case strings.HasPrefix(url, "gsheets://"):
+		props = append(props, "URL="+url)
+		return NewFileSysClientContext(ctx, dbio.TypeFileGSheets, props...)
 	case strings.Contains(url, ".core.windows.net") || strings.HasPrefix(url, "azure://"):
 		return NewFileSysClientContext(ctx, dbio.TypeFileAzure, props...)
 	case strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://"):
@@ -156,6 +231,11 @@ func NewFileSysClientFromURLContext(ctx context.Context, url string, props ...st
 		props = append(props, g.F("concurrencyLimit=%d", 20))
 		return NewFileSysClientContext(ctx, dbio.TypeFileLocal, props...)
 	case strings.Contains(url, "://"):
+		scheme := dbio.Type(strings.ToLower(strings.Split(url, "://")[0]))
+		if _, ok := registeredFileSystem(scheme); ok {
+			props = append(props, "URL="+url)
+			return NewFileSysClientContext(ctx, scheme, props...)
+		}
 		err = g.Error("Unable to determine FileSysClient for " + url)
 		return
 	default:
@@ -167,15 +247,20 @@ func NewFileSysClientFromURLContext(ctx context.Context, url string, props ...st
 type FileType string
 
 const (
-	FileTypeNone      FileType = ""
-	FileTypeCsv       FileType = "csv"
-	FileTypeXml       FileType = "xml"
-	FileTypeExcel     FileType = "xlsx"
-	FileTypeJson      FileType = "json"
-	FileTypeParquet   FileType = "parquet"
-	FileTypeAvro      FileType = "avro"
-	FileTypeSAS       FileType = "sas7bdat"
-	FileTypeJsonLines FileType = "jsonlines"
+	FileTypeNone       FileType = ""
+	FileTypeCsv        FileType = "csv"
+	FileTypeXml        FileType = "xml"
+	FileTypeExcel      FileType = "xlsx"
+	FileTypeJson       FileType = "json"
+	FileTypeParquet    FileType = "parquet"
+	FileTypeAvro       FileType = "avro"
+	FileTypeOrc        FileType = "orc"
+	FileTypeArrow      FileType = "arrow"
+	FileTypeFixedWidth FileType = "fixed_width"
+	FileTypeSAS        FileType = "sas7bdat"
+	FileTypeJsonLines  FileType = "jsonlines"
+	FileTypeDelta      FileType = "delta"
+	FileTypeIceberg    FileType = "iceberg"
 )
 
 var AllFileType = []struct {
@@ -189,14 +274,21 @@ var AllFileType = []struct {
 	{FileTypeJson, "FileTypeJson"},
 	{FileTypeParquet, "FileTypeParquet"},
 	{FileTypeAvro, "FileTypeAvro"},
+	{FileTypeOrc, "FileTypeOrc"},
+	{FileTypeArrow, "FileTypeArrow"},
+	{FileTypeFixedWidth, "FileTypeFixedWidth"},
 	{FileTypeSAS, "FileTypeSAS"},
 	{FileTypeJsonLines, "FileTypeJsonLines"},
+	{FileTypeDelta, "FileTypeDelta"},
+	{FileTypeIceberg, "FileTypeIceberg"},
 }
 
 func (ft FileType) Ext() string {
 	switch ft {
 	case FileTypeJsonLines:
 		return ".jsonl"
+	case FileTypeFixedWidth:
+		return ".txt"
 	default:
 		return "." + string(ft)
 	}
@@ -261,7 +353,7 @@ func NormalizeURI(fs FileSysClient, uri string) string {
 			}
 		}
 		return fs.Prefix("/") + path
-	case dbio.TypeFileFtp:
+	case dbio.TypeFileFtp, dbio.TypeFileHDFS, dbio.TypeFileWebDAV:
 		path := strings.TrimPrefix(uri, fs.FsType().String()+"://")
 		u, err := net.NewURL(uri)
 		if err == nil {
@@ -332,6 +424,10 @@ type BaseFileSysClient struct {
 	context    g.Context
 	fsType     dbio.Type
 	df         *iop.Dataflow
+	readNodes  dbio.FileNodes
+
+	archives    map[string]*archive
+	archivesMux sync.Mutex
 }
 
 // Context provides a pointer to context
@@ -344,11 +440,47 @@ func (fs *BaseFileSysClient) Client() *BaseFileSysClient {
 	return fs
 }
 
+// delete deletes the provided path, if the client
+// implements a `Delete(path string) error` method
+func (fs *BaseFileSysClient) delete(path string) (err error) {
+	if deleter, ok := fs.Self().(interface{ Delete(string) error }); ok {
+		return deleter.Delete(path)
+	}
+	return g.Error("delete is not implemented for %s", fs.fsType)
+}
+
 // setDf sets the dataflow
 func (fs *BaseFileSysClient) setDf(df *iop.Dataflow) {
 	fs.df = df
 }
 
+// move writes the file to the new location and then deletes the original
+func (fs *BaseFileSysClient) move(srcURI, tgtURI string) (err error) {
+	reader, err := fs.Self().GetReader(srcURI)
+	if err != nil {
+		return g.Error(err, "could not read %s", srcURI)
+	}
+
+	_, err = fs.Self().Write(tgtURI, reader)
+	if closer, ok := reader.(io.Closer); ok {
+		closer.Close()
+	}
+	if err != nil {
+		return g.Error(err, "could not write %s", tgtURI)
+	}
+
+	if err = fs.Self().delete(srcURI); err != nil {
+		return g.Error(err, "could not delete %s", srcURI)
+	}
+
+	return nil
+}
+
+// ReadNodes returns the file nodes consumed by the last dataflow read
+func (fs *BaseFileSysClient) ReadNodes() dbio.FileNodes {
+	return fs.readNodes
+}
+
 // Instance returns the respective connection Instance
 // This is useful to refer back to a subclass method
 // from the superclass level. (Aka overloading)
@@ -417,6 +549,14 @@ func (fs *BaseFileSysClient) GetRefTs() time.Time {
 	return ts
 }
 
+// partitionColumns returns the hive partition columns of the files being read
+func (fs *BaseFileSysClient) partitionColumns() (columns iop.Columns) {
+	if val := fs.GetProp("partitionColumns"); val != "" {
+		g.Unmarshal(val, &columns)
+	}
+	return columns
+}
+
 // GetDatastream return a datastream for the given path
 func (fs *BaseFileSysClient) GetDatastream(urlStr string) (ds *iop.Datastream, err error) {
 
@@ -424,6 +564,7 @@ func (fs *BaseFileSysClient) GetDatastream(urlStr string) (ds *iop.Datastream, e
 	ds.SafeInference = true
 	ds.SetMetadata(fs.GetProp("METADATA"))
 	ds.Metadata.StreamURL.Value = urlStr
+	ds.Partitions = fs.partitionColumns()
 	ds.SetConfig(fs.Props())
 
 	fileFormat := FileType(cast.ToString(fs.GetProp("FORMAT")))
@@ -431,6 +572,13 @@ func (fs *BaseFileSysClient) GetDatastream(urlStr string) (ds *iop.Datastream, e
 		fileFormat = InferFileFormat(urlStr)
 	}
 
+	// set selectFields for pruning at source
+	if fileFormat == FileTypeParquet {
+		selectFields := []string{}
+		g.Unmarshal(fs.GetProp("selectFields"), &selectFields)
+		ds.Columns = iop.NewColumnsFromFields(selectFields...)
+	}
+
 	go func() {
 		// recover from panic
 		defer func() {
@@ -445,7 +593,18 @@ func (fs *BaseFileSysClient) GetDatastream(urlStr string) (ds *iop.Datastream, e
 		fs.Context().Wg.Read.Add()
 
 		g.Debug("reading datastream from %s [format=%s]", urlStr, fileFormat)
-		reader, err := fs.Self().GetReader(urlStr)
+
+		// read only the needed parts of parquet files, when possible
+		if fileFormat == FileTypeParquet {
+			if ok, err := consumeParquetRange(fs.Self(), ds, urlStr); ok {
+				if err != nil {
+					ds.Context.CaptureErr(g.Error(err, "Error consuming reader for %s", urlStr))
+				}
+				return
+			}
+		}
+
+		reader, err := getReader(fs.Self(), urlStr)
 		if err != nil {
 			ds.Context.CaptureErr(g.Error(err, "error getting reader"))
 			return
@@ -474,6 +633,12 @@ func (fs *BaseFileSysClient) GetDatastream(urlStr string) (ds *iop.Datastream, e
 			err = ds.ConsumeParquetReader(reader)
 		case FileTypeAvro:
 			err = ds.ConsumeAvroReader(reader)
+		case FileTypeOrc:
+			err = ds.ConsumeOrcReader(reader)
+		case FileTypeArrow:
+			err = ds.ConsumeArrowReader(reader)
+		case FileTypeFixedWidth:
+			err = ds.ConsumeFixedWidthReader(reader)
 		case FileTypeSAS:
 			err = ds.ConsumeSASReader(reader)
 		case FileTypeExcel:
@@ -503,42 +668,55 @@ func (fs *BaseFileSysClient) ReadDataflow(url string, cfg ...FileStreamConfig) (
 
 	fs.SetProp("url", url)
 
-	if strings.HasSuffix(strings.ToLower(url), ".zip") {
-		localFs, err := NewFileSysClient(dbio.TypeFileLocal)
+	switch FileType(strings.ToLower(fs.GetProp("FORMAT"))) {
+	case FileTypeDelta:
+		df, err = readDeltaDataflow(fs.Self(), url, Cfg)
 		if err != nil {
-			return df, g.Error(err, "could not initialize localFs")
+			return df, g.Error(err, "error getting delta dataflow")
 		}
-
-		reader, err := fs.Self().GetReader(url)
+		df.FsURL = url
+		return df, nil
+	case FileTypeIceberg:
+		df, err = readIcebergDataflow(fs.Self(), url, Cfg)
 		if err != nil {
-			return df, g.Error(err, "could not get zip reader")
+			return df, g.Error(err, "error getting iceberg dataflow")
 		}
+		df.FsURL = url
+		return df, nil
+	}
 
-		folderPath := path.Join(env.GetTempFolder(), "dbio_temp_")
-
-		zipPath := folderPath + ".zip"
-		_, err = localFs.Write(zipPath, reader)
+	// archives are read as virtual folders
+	if archiveURI, memberPath, ok := SplitArchiveURI(url); ok {
+		g.Trace("listing archive: %s", archiveURI)
+		archiveNodes, err := fs.Self().ListRecursive(archiveURI)
 		if err != nil {
-			return df, g.Error(err, "could not write to "+zipPath)
+			return df, g.Error(err, "Error getting paths")
+		}
+		if val := fs.GetProp("SLING_FILE_STATE"); val != "" {
+			state := map[string]int64{}
+			if err = g.Unmarshal(val, &state); err != nil {
+				return df, g.Error(err, "could not parse file state")
+			}
+			archiveNodes = FilterFileState(archiveNodes, state, cast.ToBool(fs.GetProp("REPROCESS_CHANGED")))
 		}
 
-		nodeMaps, err := iop.Unzip(zipPath, folderPath)
-		if err != nil {
-			return df, g.Error(err, "Error unzipping")
+		nodes := dbio.FileNodes{}
+		if len(archiveNodes) > 0 {
+			nodes, err = listArchive(fs.Self(), archiveURI, memberPath)
+			if err != nil {
+				return df, g.Error(err, "Error listing archive")
+			}
 		}
-		// delete zip file
-		Delete(localFs, zipPath)
 
-		// TODO: handle multiple files, yielding multiple schemas
-		nodes := dbio.NewFileNodes(nodeMaps)
-		df, err = GetDataflow(localFs.Self(), nodes, Cfg)
+		df, err = GetDataflow(fs.Self(), nodes, Cfg)
 		if err != nil {
-			return df, g.Error(err, "Error making dataflow")
+			fs.closeArchives()
+			return df, g.Error(err, "error getting dataflow")
 		}
+		fs.readNodes = archiveNodes // the archive is the processed file
 
-		// delete unzipped folder when done
-		df.Defer(func() { Delete(localFs, folderPath) })
-
+		df.Defer(fs.closeArchives)
+		df.FsURL = url
 		return df, nil
 	}
 
@@ -548,6 +726,15 @@ func (fs *BaseFileSysClient) ReadDataflow(url string, cfg ...FileStreamConfig) (
 		err = g.Error(err, "Error getting paths")
 		return
 	}
+
+	// only read the files which are new (or changed), compared to the file state
+	if val := fs.GetProp("SLING_FILE_STATE"); val != "" {
+		state := map[string]int64{}
+		if err = g.Unmarshal(val, &state); err != nil {
+			return df, g.Error(err, "could not parse file state")
+		}
+		nodes = FilterFileState(nodes, state, cast.ToBool(fs.GetProp("REPROCESS_CHANGED")))
+	}
 	df, err = GetDataflow(fs.Self(), nodes, Cfg)
 	if err != nil {
 		err = g.Error(err, "error getting dataflow")
@@ -584,6 +771,17 @@ func WriteDataflow(fs FileSysClient, df *iop.Dataflow, url string) (bw int64, er
 		}
 	}
 
+	switch FileType(strings.ToLower(fs.GetProp("FORMAT"))) {
+	case FileTypeDelta:
+		return writeDeltaDataflow(fs, df, url)
+	case FileTypeIceberg:
+		return 0, g.Error("writing iceberg tables is not supported")
+	}
+
+	if cast.ToBool(fs.GetProp("ATOMIC")) && fs.FsType() != dbio.TypeFileGSheets {
+		return writeDataflowAtomic(fs, df, url)
+	}
+
 	fileReadyChn := make(chan FileReady, 10000)
 
 	g.Trace("writing dataflow to %s", url)
@@ -596,6 +794,120 @@ func WriteDataflow(fs FileSysClient, df *iop.Dataflow, url string) (bw int64, er
 	return fs.Self().WriteDataflowReady(df, url, fileReadyChn, nil)
 }
 
+// getReader returns a reader of the uri, reading from the archive if
+// the uri points to an archive member. Brotli files have no magic bytes,
+// so they are decompressed here from the suffix.
+func getReader(fs FileSysClient, uri string) (reader io.Reader, err error) {
+	if archiveURI, member, ok := SplitArchiveURI(uri); ok && member != "" {
+		a, err := getArchive(fs, archiveURI)
+		if err != nil {
+			return nil, err
+		}
+		reader, err = a.GetReader(member)
+	} else {
+		reader, err = fs.Self().GetReader(uri)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decryptor, err := getEncryptor(fs, "DECRYPT")
+	if err != nil {
+		return nil, err
+	} else if decryptor != nil {
+		if reader, err = decryptor.Decrypt(reader); err != nil {
+			return nil, g.Error(err, "could not decrypt %s", uri)
+		}
+	}
+
+	if iop.CompressorTypeFromSuffix(iop.TrimEncryptionSuffix(uri)) == iop.BrotliCompressorType {
+		return iop.NewCompressor(iop.BrotliCompressorType).Decompress(reader)
+	}
+	return reader, nil
+}
+
+// egressReader adds the bytes read to the dataflow egress bytes
+type egressReader struct {
+	io.Reader
+	df *iop.Dataflow
+}
+
+func (r *egressReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	if n > 0 {
+		r.df.AddEgressBytes(uint64(n))
+	}
+	return
+}
+
+// countingReader counts the bytes read
+type countingReader struct {
+	io.Reader
+	bytes int64
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	atomic.AddInt64(&r.bytes, int64(n))
+	return
+}
+
+// Count returns the bytes read
+func (r *countingReader) Count() int64 {
+	return atomic.LoadInt64(&r.bytes)
+}
+
+// uploadConfig is the configuration of the multipart uploads of the
+// cloud storage clients. Zero values use the client defaults.
+type uploadConfig struct {
+	PartSize    int64 // the size of each part (block or chunk), in bytes
+	Concurrency int   // the number of parts uploaded in parallel
+	Retries     int   // the number of retries of each part
+}
+
+// getUploadConfig returns the upload config from the
+// `UPLOAD_PART_SIZE`, `UPLOAD_PARALLEL` and `UPLOAD_RETRIES` props
+func getUploadConfig(fs FileSysClient) uploadConfig {
+	return uploadConfig{
+		PartSize:    cast.ToInt64(fs.GetProp("UPLOAD_PART_SIZE")),
+		Concurrency: cast.ToInt(fs.GetProp("UPLOAD_PARALLEL")),
+		Retries:     cast.ToInt(fs.GetProp("UPLOAD_RETRIES")),
+	}
+}
+
+// getEncryptor returns the encryptor of the `ENCRYPT` or `DECRYPT` prop, if set.
+// The prop is the encryption type, or the JSON of the encryption config.
+// Missing keys are taken from the props (connection or env vars).
+func getEncryptor(fs FileSysClient, key string) (encryptor iop.Encryptor, err error) {
+	val := strings.TrimSpace(fs.GetProp(key))
+	if val == "" || strings.EqualFold(val, "false") {
+		return nil, nil
+	}
+
+	config := iop.EncryptConfig{}
+	if strings.HasPrefix(val, "{") {
+		if err = g.Unmarshal(val, &config); err != nil {
+			return nil, g.Error(err, "invalid %s config", strings.ToLower(key))
+		}
+	} else if !strings.EqualFold(val, "true") {
+		config.Type = iop.EncryptorType(strings.ToLower(val))
+	}
+
+	switch key {
+	case "ENCRYPT":
+		if config.PublicKey = lo.Ternary(config.PublicKey == "", fs.GetProp("PGP_PUBLIC_KEY"), config.PublicKey); config.PublicKey == "" {
+			return nil, g.Error("encryption requires a public key (PGP_PUBLIC_KEY)")
+		}
+	case "DECRYPT":
+		if config.PrivateKey = lo.Ternary(config.PrivateKey == "", fs.GetProp("PGP_PRIVATE_KEY"), config.PrivateKey); config.PrivateKey == "" {
+			return nil, g.Error("decryption requires a private key (PGP_PRIVATE_KEY)")
+		}
+		config.Passphrase = lo.Ternary(config.Passphrase == "", fs.GetProp("PGP_PASSPHRASE"), config.Passphrase)
+	}
+
+	return iop.NewEncryptor(config)
+}
+
 // GetReaders returns one or more readers from specified paths in specified FileSysClient
 func (fs *BaseFileSysClient) GetReaders(paths ...string) (readers []io.Reader, err error) {
 	if len(paths) == 0 {
@@ -618,6 +930,7 @@ type FileReady struct {
 	Columns iop.Columns
 	Node    dbio.FileNode
 	BytesW  int64
+	Rows    int64 // -1 if unknown
 	BatchID string
 }
 
@@ -632,6 +945,20 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 	fileRowLimit := cast.ToInt(fs.GetProp("FILE_MAX_ROWS"))
 	fileBytesLimit := cast.ToInt64(fs.GetProp("FILE_MAX_BYTES")) // uncompressed file size
 	fileExt := cast.ToString(fs.GetProp("FILE_EXTENSION"))
+	stripeSize := cast.ToInt64(fs.GetProp("STRIPE_SIZE"))
+	partitionMaxOpen := cast.ToInt(fs.GetProp("PARTITION_MAX_OPEN"))
+
+	partitionBy, err := ParsePartitionBy(fs.GetProp("PARTITION_BY"))
+	if err != nil {
+		return 0, g.Error(err, "invalid partition_by")
+	}
+
+	fixedWidthColumns, err := iop.ParseFixedWidthColumns(fs.GetProp("FIXED_WIDTH_COLUMNS"))
+	if err != nil {
+		return 0, g.Error(err, "invalid fixed-width columns")
+	} else if fileFormat == FileTypeFixedWidth && len(fixedWidthColumns) == 0 {
+		return 0, g.Error("fixed-width format requires the column positions (name, start, length)")
+	}
 
 	// set default concurrency
 	// let's set 7 as a safe limit
@@ -650,7 +977,7 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 
 	url = strings.TrimSuffix(NormalizeURI(fs, url), "/")
 
-	singleFile := fileRowLimit == 0 && fileBytesLimit == 0 && len(df.Streams) == 1
+	singleFile := fileRowLimit == 0 && fileBytesLimit == 0 && len(df.Streams) == 1 && len(partitionBy) == 0
 
 	// parse file partitioning notation (*), determine single-file vs folder mode
 	parts := strings.Split(url, "/")
@@ -666,7 +993,8 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 	}
 
 	// adjust fileBytesLimit due to compression
-	if g.In(compression, iop.GzipCompressorType, iop.ZStandardCompressorType, iop.SnappyCompressorType) {
+	if g.In(compression, iop.GzipCompressorType, iop.ZStandardCompressorType, iop.SnappyCompressorType,
+		iop.Lz4CompressorType, iop.BrotliCompressorType, iop.XzCompressorType) {
 		fileBytesLimit = fileBytesLimit * 6 // compressed, multiply
 	}
 
@@ -674,17 +1002,36 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 		df.SetConfig(sc)
 	}
 
-	processStream := func(ds *iop.Datastream, partURL string) {
-		defer df.Context.Wg.Read.Done()
+	// several streams, partitions or splits go into the sheets of one workbook
+	if fileFormat == FileTypeExcel && !singleFile && strings.HasSuffix(strings.ToLower(url), FileTypeExcel.Ext()) {
+		return writeExcelWorkbook(fs, df, url, fileReadyChn, partitionBy, partitionMaxOpen, fileRowLimit)
+	}
+
+	encryptor, err := getEncryptor(fsClient, "ENCRYPT")
+	if err != nil {
+		return 0, g.Error(err, "invalid encrypt option")
+	}
+
+	xmlConfig := iop.XmlConfig{Root: fs.GetProp("XML_ROOT"), Row: fs.GetProp("XML_ROW")}
+	if attrs := fs.GetProp("XML_ATTRIBUTES"); attrs != "" {
+		if err := g.Unmarshal(attrs, &xmlConfig.Attributes); err != nil {
+			// comma separated
+			xmlConfig.Attributes = lo.Map(strings.Split(attrs, ","), func(a string, i int) string { return strings.TrimSpace(a) })
+		}
+	}
+
+	processStream := func(ds *iop.Datastream, partURL string, nextFileURL func(suffix string) string) {
 		localCtx := g.NewContext(ds.Context.Ctx, concurrency)
 
 		writePart := func(reader io.Reader, batchR *iop.BatchReader, partURL string) {
 			defer localCtx.Wg.Read.Done()
 
-			bw0, err := fsClient.Write(partURL, reader)
+			// report the egress bytes as they are uploaded
+			bw0, err := fsClient.Write(partURL, &egressReader{Reader: reader, df: df})
 			bID := lo.Ternary(batchR.Batch != nil, batchR.Batch.ID(), "")
 			node := dbio.FileNode{URI: partURL, Size: cast.ToUint64(bw0)}
-			fileReadyChn <- FileReady{batchR.Columns, node, bw0, bID}
+			rows := cast.ToInt64(lo.Ternary(batchR.Counter < 0, -1, batchR.Counter))
+			fileReadyChn <- FileReady{Columns: batchR.Columns, Node: node, BytesW: bw0, Rows: rows, BatchID: bID}
 
 			if err != nil {
 				g.LogError(err)
@@ -694,42 +1041,50 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 			}
 			g.Trace("wrote %s [%d rows] to %s", humanize.Bytes(cast.ToUint64(bw0)), batchR.Counter, partURL)
 			bw += bw0
-			df.AddEgressBytes(uint64(bw0))
 		}
 
 		// pre-add to WG to not hold next reader in memory while waiting
 		localCtx.Wg.Read.Add()
-		fileCount := 0
 
 		processReader := func(batchR *iop.BatchReader) error {
-			fileCount++
 			fileSuffix := lo.Ternary(fileExt == "", fileFormat.Ext(), fileExt)
-			subPartURL := fmt.Sprintf("%s.%04d%s", partURL, fileCount, fileSuffix)
+			subPartURL := nextFileURL(fileSuffix)
+			encryptSuffix := ""
+			if encryptor != nil {
+				encryptSuffix = encryptor.Suffix()
+			}
 			if singleFile {
 				subPartURL = partURL
-				for _, comp := range []iop.CompressorType{
-					iop.GzipCompressorType,
-					iop.SnappyCompressorType,
-					iop.ZStandardCompressorType,
-				} {
-					compressor := iop.NewCompressor(comp)
-					if strings.HasSuffix(subPartURL, compressor.Suffix()) {
-						compression = comp
-						subPartURL = strings.TrimSuffix(subPartURL, compressor.Suffix())
-						break
-					}
+				if encryptor != nil {
+					subPartURL = iop.TrimEncryptionSuffix(partURL)
+					encryptSuffix = partURL[len(subPartURL):]
+				}
+				if comp := iop.CompressorTypeFromSuffix(subPartURL); comp != iop.NoneCompressorType {
+					compression = comp
+					subPartURL = strings.TrimSuffix(subPartURL, iop.NewCompressor(comp).Suffix())
 				}
 			}
 
 			compressor := iop.NewCompressor(compression)
-			if fileFormat == FileTypeParquet {
+			if g.In(fileFormat, FileTypeParquet, FileTypeAvro, FileTypeOrc, FileTypeArrow) {
 				compressor = iop.NewCompressor("none") // compression is done internally
 			} else {
 				subPartURL = subPartURL + compressor.Suffix()
 			}
 
+			reader := compressor.Compress(batchR.Reader)
+			if encryptor != nil {
+				subPartURL = subPartURL + encryptSuffix
+				reader, err = encryptor.Encrypt(reader)
+				if err != nil {
+					io.Copy(io.Discard, batchR.Reader) // flush it out so it can close
+					df.Context.CaptureErr(g.Error(err, "could not encrypt %s", subPartURL))
+					return df.Err()
+				}
+			}
+
 			g.Trace("writing stream to " + subPartURL)
-			go writePart(compressor.Compress(batchR.Reader), batchR, subPartURL)
+			go writePart(reader, batchR, subPartURL)
 			localCtx.Wg.Read.Add()
 			// localCtx.MemBasedLimit(98) // wait until memory is lower than 90%
 
@@ -758,6 +1113,46 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 					break
 				}
 			}
+		case FileTypeXml:
+			for reader := range ds.NewXmlReaderChnl(fileRowLimit, fileBytesLimit, xmlConfig) {
+				err := processReader(reader)
+				if err != nil {
+					break
+				}
+			}
+		case FileTypeAvro:
+			for reader := range ds.NewAvroReaderChnl(fileRowLimit, fileBytesLimit, compression) {
+				err := processReader(reader)
+				if err != nil {
+					break
+				}
+			}
+		case FileTypeOrc:
+			for reader := range ds.NewOrcReaderChnl(fileRowLimit, fileBytesLimit, compression, stripeSize) {
+				err := processReader(reader)
+				if err != nil {
+					break
+				}
+			}
+		case FileTypeArrow:
+			// the `.arrows` extension is for the streaming format
+			arrowFormat := iop.ArrowFormatFile
+			if strings.HasSuffix(strings.ToLower(lo.Ternary(singleFile, url, fileExt)), ".arrows") {
+				arrowFormat = iop.ArrowFormatStream
+			}
+			for reader := range ds.NewArrowReaderChnl(fileRowLimit, fileBytesLimit, compression, arrowFormat) {
+				err := processReader(reader)
+				if err != nil {
+					break
+				}
+			}
+		case FileTypeFixedWidth:
+			for reader := range ds.NewFixedWidthReaderChnl(fileRowLimit, fileBytesLimit, fixedWidthColumns) {
+				err := processReader(reader)
+				if err != nil {
+					break
+				}
+			}
 		case FileTypeExcel:
 			for reader := range ds.NewExcelReaderChnl(fileRowLimit, fileBytesLimit, fs.GetProp("sheet")) {
 				err := processReader(reader)
@@ -801,7 +1196,7 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 		return
 	}
 
-	if !singleFile && g.In(fsClient.FsType(), dbio.TypeFileLocal, dbio.TypeFileSftp, dbio.TypeFileFtp) {
+	if !singleFile && g.In(fsClient.FsType(), dbio.TypeFileLocal, dbio.TypeFileSftp, dbio.TypeFileFtp, dbio.TypeFileHDFS, dbio.TypeFileWebDAV) {
 		err = fsClient.MkdirAll(url)
 		if err != nil {
 			err = g.Error(err, "could not create directory")
@@ -809,6 +1204,34 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 		}
 	}
 
+	// partitionStream writes the rows of the stream into Hive-style partition folders
+	partitionStream := func(ds *iop.Datastream) {
+		defer df.Context.Wg.Read.Done()
+
+		// partitions are not bound by the concurrency limit, since
+		// up to `partition_max_open` of them are written at once
+		var partWg sync.WaitGroup
+		startPartition := func(pds *iop.Datastream, nextFileURL func(suffix string) string) {
+			partWg.Add(1)
+			go func() {
+				if err := pds.Start(); err != nil {
+					df.Context.CaptureErr(g.Error(err, "could not start partition stream"))
+				}
+			}()
+			go func() {
+				defer partWg.Done()
+				processStream(pds, url, nextFileURL)
+			}()
+		}
+
+		router := newPartitionRouter(url, partitionBy, partitionMaxOpen, fs.Props(), startPartition)
+		if err := router.route(ds); err != nil {
+			df.Context.CaptureErr(g.Error(err, "could not partition stream"))
+			ds.Context.Cancel()
+		}
+		partWg.Wait()
+	}
+
 	partCnt := 1
 	// for ds := range df.MakeStreamCh(true) {
 	for ds := range df.StreamCh {
@@ -818,11 +1241,28 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 			partURL = url
 		}
 
-		g.DebugLow("writing to %s [fileRowLimit=%d fileBytesLimit=%d compression=%s concurrency=%d useBufferedStream=%v fileFormat=%v]", partURL, fileRowLimit, fileBytesLimit, compression, concurrency, useBufferedStream, fileFormat)
-
 		df.Context.Wg.Read.Add()
 		ds.SetConfig(fs.Props()) // pass options
-		go processStream(ds, partURL)
+
+		if len(partitionBy) > 0 {
+			g.DebugLow("writing to %s [partitionBy=%s fileRowLimit=%d fileBytesLimit=%d compression=%s fileFormat=%v]", url, g.Marshal(partitionBy), fileRowLimit, fileBytesLimit, compression, fileFormat)
+			go partitionStream(ds)
+			partCnt++
+			continue
+		}
+
+		g.DebugLow("writing to %s [fileRowLimit=%d fileBytesLimit=%d compression=%s concurrency=%d useBufferedStream=%v fileFormat=%v]", partURL, fileRowLimit, fileBytesLimit, compression, concurrency, useBufferedStream, fileFormat)
+
+		fileCount := 0
+		nextFileURL := func(suffix string) string {
+			fileCount++
+			return fmt.Sprintf("%s.%04d%s", partURL, fileCount, suffix)
+		}
+
+		go func(ds *iop.Datastream) {
+			defer df.Context.Wg.Read.Done()
+			processStream(ds, partURL, nextFileURL)
+		}(ds)
 		partCnt++
 	}
 
@@ -834,9 +1274,44 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 	return
 }
 
+// FilterFileState returns the file nodes which are not in the state
+// (uri to fingerprint). Changed files are returned if reprocessChanged is true.
+func FilterFileState(nodes dbio.FileNodes, state map[string]int64, reprocessChanged bool) (filtered dbio.FileNodes) {
+	for _, node := range nodes {
+		fingerprint, processed := state[node.URI]
+		if !processed || node.IsDir {
+			filtered = append(filtered, node)
+		} else if fingerprint != node.Fingerprint() {
+			if reprocessChanged {
+				g.Debug("reprocessing changed file %s", node.URI)
+				filtered = append(filtered, node)
+			} else {
+				g.Warn("skipping changed file %s since it was already processed (set reprocess_changed to load again)", node.URI)
+			}
+		}
+	}
+	return
+}
+
+// Move moves a file within the file system. Clients which support it
+// rename or copy server-side, otherwise the file is streamed.
+func Move(fs FileSysClient, srcURI, tgtURI string) (err error) {
+	return fs.Self().move(NormalizeURI(fs, srcURI), NormalizeURI(fs, tgtURI))
+}
+
 // Delete deletes the provided path
 // with some safeguards so to not accidentally delete some root path
 func Delete(fs FileSysClient, uri string) (err error) {
+	return deletePath(fs, uri, false)
+}
+
+// DeleteStrict deletes the provided path with the safeguards of Delete,
+// and returns the error if the deletion fails
+func DeleteStrict(fs FileSysClient, uri string) (err error) {
+	return deletePath(fs, uri, true)
+}
+
+func deletePath(fs FileSysClient, uri string, strict bool) (err error) {
 	uri = NormalizeURI(fs, uri)
 
 	host, path, err := ParseURL(uri)
@@ -869,7 +1344,7 @@ func Delete(fs FileSysClient, uri string) (err error) {
 		if len(p) == 0 {
 			return g.Error("will not delete root level %s", uri)
 		}
-	case dbio.TypeFileFtp:
+	case dbio.TypeFileFtp, dbio.TypeFileHDFS, dbio.TypeFileWebDAV:
 		if len(p) == 0 {
 			return g.Error("will not delete root level %s", uri)
 		}
@@ -877,7 +1352,9 @@ func Delete(fs FileSysClient, uri string) (err error) {
 
 	err = fs.delete(uri)
 	if err != nil {
-		if g.IsDebugLow() {
+		if strict {
+			return g.Error(err, "could not delete %s", uri)
+		} else if g.IsDebugLow() {
 			g.Warn("could not delete path %s\n%s", uri, err.Error())
 		}
 		err = nil
@@ -899,10 +1376,41 @@ func GetDataflow(fs FileSysClient, nodes dbio.FileNodes, cfg FileStreamConfig) (
 		return
 	}
 
+	// prune files with the partition filter. The hive partitions are only
+	// added as columns with HIVE_PARTITIONING (off by default)
+	var partitionColumns iop.Columns
+	hivePartitioning := cast.ToBool(fs.GetProp("HIVE_PARTITIONING"))
+	filter := fs.GetProp("PARTITION_FILTER")
+	if hivePartitioning || filter != "" {
+		partitionColumns = PartitionColumns(nodes, fs.GetProp("url"))
+	}
+	if filter != "" {
+		nodes, err = FilterPartitions(nodes, partitionColumns, filter)
+		if err != nil {
+			return nil, g.Error(err, "could not apply partition filter")
+		} else if len(lo.Filter(nodes, func(n dbio.FileNode, i int) bool { return !n.IsDir })) == 0 {
+			return nil, g.Error("no files match partition_filter: %s", filter)
+		}
+	}
+
+	// filter the rows with the where expression, parquet row groups are skipped with their statistics
+	var wf *whereFilter
+	selectFields, parquetFilters := cfg.Select, ""
+	if where := fs.GetProp("WHERE"); where != "" {
+		if wf, err = newWhereFilter(where); err != nil {
+			return nil, err
+		}
+		selectFields = wf.selectFields(cfg.Select)
+		parquetFilters = g.Marshal(wf.parquetFilters())
+	}
+
 	df = iop.NewDataflowContext(fs.Context().Ctx, cfg.Limit)
 	dsCh := make(chan *iop.Datastream)
 	fs.setDf(df)
-	fs.SetProp("selectFields", g.Marshal(cfg.Select))
+	fs.Client().readNodes = lo.Filter(nodes, func(n dbio.FileNode, i int) bool { return !n.IsDir })
+	fs.SetProp("selectFields", g.Marshal(selectFields))
+	fs.SetProp("parquet_filters", parquetFilters)
+	fs.SetProp("partitionColumns", lo.Ternary(hivePartitioning && len(partitionColumns) > 0, g.Marshal(partitionColumns), ""))
 
 	go func() {
 		defer close(dsCh)
@@ -910,9 +1418,13 @@ func GetDataflow(fs FileSysClient, nodes dbio.FileNodes, cfg FileStreamConfig) (
 		allowMerging := strings.ToLower(os.Getenv("SLING_MERGE_READERS")) != "false"
 
 		pushDatastream := func(ds *iop.Datastream) {
-			// use selected fields only when not parquet
-			skipSelect := g.In(fs.GetProp("FORMAT"), string(FileTypeParquet))
-			if len(cfg.Select) > 1 && !skipSelect {
+			if wf != nil {
+				ds = wf.Apply(df, ds)
+			}
+
+			// use selected fields only when not parquet, or when fields of the where expression were added
+			skipSelect := g.In(fs.GetProp("FORMAT"), string(FileTypeParquet)) && len(selectFields) == len(cfg.Select)
+			if (len(cfg.Select) > 1 || len(selectFields) > len(cfg.Select)) && !skipSelect {
 				cols := iop.NewColumnsFromFields(cfg.Select...)
 				fm := ds.Columns.FieldMap(true)
 				ds.Columns.DbTypes()
@@ -1122,6 +1634,7 @@ func MergeReaders(fs FileSysClient, fileType FileType, nodes dbio.FileNodes, lim
 	ds.SafeInference = true
 	ds.SetMetadata(fs.GetProp("METADATA"))
 	ds.Metadata.StreamURL.Value = url
+	ds.Partitions = fs.Client().partitionColumns()
 	ds.SetConfig(fs.Client().Props())
 	g.Debug("reading datastream from %s [format=%s]", url, fileType)
 
@@ -1166,7 +1679,7 @@ func MergeReaders(fs FileSysClient, fileType FileType, nodes dbio.FileNodes, lim
 				defer ds.Context.Wg.Read.Done()
 				g.Debug("processing reader from %s", path)
 
-				reader, err := fs.Self().GetReader(path)
+				reader, err := getReader(fs, path)
 				if err != nil {
 					setError(g.Error(err, "Error getting reader"))
 					return
@@ -1220,6 +1733,12 @@ func MergeReaders(fs FileSysClient, fileType FileType, nodes dbio.FileNodes, lim
 			err = ds.ConsumeParquetReader(pipeR)
 		case FileTypeAvro:
 			err = ds.ConsumeAvroReader(pipeR)
+		case FileTypeOrc:
+			err = ds.ConsumeOrcReader(pipeR)
+		case FileTypeArrow:
+			err = ds.ConsumeArrowReader(pipeR)
+		case FileTypeFixedWidth:
+			err = ds.ConsumeFixedWidthReader(pipeR)
 		case FileTypeSAS:
 			err = ds.ConsumeSASReader(pipeR)
 		case FileTypeExcel:
@@ -1283,13 +1802,20 @@ func ProcessStreamViaTempFile(ds *iop.Datastream) (nDs *iop.Datastream, err erro
 func InferFileFormat(path string) FileType {
 	path = strings.TrimSpace(strings.ToLower(path))
 
-	for _, fileType := range []FileType{FileTypeJsonLines, FileTypeJson, FileTypeXml, FileTypeParquet, FileTypeAvro, FileTypeSAS, FileTypeExcel} {
+	for _, fileType := range []FileType{FileTypeJsonLines, FileTypeJson, FileTypeXml, FileTypeParquet, FileTypeAvro, FileTypeOrc, FileTypeArrow, FileTypeSAS, FileTypeExcel} {
 		ext := fileType.Ext()
 		if strings.HasSuffix(path, ext) || strings.Contains(path, ext+".") {
 			return fileType
 		}
 	}
 
+	// other arrow IPC extensions
+	for _, ext := range []string{".arrows", ".feather"} {
+		if strings.HasSuffix(path, ext) {
+			return FileTypeArrow
+		}
+	}
+
 	// default is csv
 	return FileTypeCsv
 }
@@ -0,0 +1,294 @@
+package filesys
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/gobwas/glob"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/env"
+)
+
+// ArchiveType is the type of an archive file, which is read as a virtual folder:
+// `s3://bucket/export.zip/folder/*.csv`
+type ArchiveType string
+
+const (
+	ArchiveTypeNone  ArchiveType = ""
+	ArchiveTypeZip   ArchiveType = "zip"
+	ArchiveTypeTar   ArchiveType = "tar"
+	ArchiveTypeTarGz ArchiveType = "tar.gz"
+)
+
+// GetArchiveType returns the archive type from the file name
+func GetArchiveType(name string) ArchiveType {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveTypeZip
+	case strings.HasSuffix(name, ".tar"):
+		return ArchiveTypeTar
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTypeTarGz
+	}
+	return ArchiveTypeNone
+}
+
+// SplitArchiveURI splits a uri pointing to (or into) an archive, into the
+// archive uri and the member path (which can be a glob pattern):
+// `s3://bucket/export.zip/folder/*.csv` => `s3://bucket/export.zip`, `folder/*.csv`
+func SplitArchiveURI(uri string) (archiveURI, memberPath string, ok bool) {
+	scheme, rest := "", uri
+	if i := strings.Index(uri, "://"); i > -1 {
+		scheme, rest = uri[:i+3], uri[i+3:]
+	}
+
+	parts := strings.Split(rest, "/")
+	for i, part := range parts {
+		if strings.Contains(part, "*") {
+			break
+		} else if GetArchiveType(part) != ArchiveTypeNone {
+			archiveURI = scheme + strings.Join(parts[:i+1], "/")
+			memberPath = strings.Join(parts[i+1:], "/")
+			return archiveURI, memberPath, true
+		}
+	}
+	return uri, "", false
+}
+
+// archive reads the members of an archive file. Archives are read with
+// random access: remote archives are first copied to a temp file, and
+// tar.gz archives are decompressed to a temp file. The offsets of the tar
+// members are indexed once, so each member is read without a new pass.
+type archive struct {
+	fs         FileSysClient
+	uri        string
+	Type       ArchiveType
+	zipReader  *zip.Reader
+	file       *os.File
+	tempPath   string
+	tarMembers []tarMember
+}
+
+// tarMember is the header of a tar member, with the offset of its data
+type tarMember struct {
+	name    string
+	isDir   bool
+	offset  int64
+	size    int64
+	updated int64
+}
+
+// getArchive returns the opened archive, caching it in the client
+func getArchive(fs FileSysClient, archiveURI string) (a *archive, err error) {
+	client := fs.Client()
+	client.archivesMux.Lock()
+	defer client.archivesMux.Unlock()
+
+	if a, ok := client.archives[archiveURI]; ok {
+		return a, nil
+	}
+
+	a = &archive{fs: fs.Self(), uri: archiveURI, Type: GetArchiveType(archiveURI)}
+	if err = a.open(); err != nil {
+		a.close()
+		return nil, g.Error(err, "could not open archive %s", archiveURI)
+	}
+
+	if client.archives == nil {
+		client.archives = map[string]*archive{}
+	}
+	client.archives[archiveURI] = a
+	return a, nil
+}
+
+// closeArchives closes the archives opened by the client
+func (fs *BaseFileSysClient) closeArchives() {
+	fs.archivesMux.Lock()
+	defer fs.archivesMux.Unlock()
+
+	for uri, a := range fs.archives {
+		a.close()
+		delete(fs.archives, uri)
+	}
+}
+
+func (a *archive) open() (err error) {
+	filePath := ""
+	if a.fs.FsType() == dbio.TypeFileLocal && a.Type != ArchiveTypeTarGz {
+		if filePath, err = a.fs.GetPath(a.uri); err != nil {
+			return g.Error(err, "could not get path of %s", a.uri)
+		}
+	} else if filePath, err = a.copyToTemp(); err != nil {
+		return err
+	}
+
+	a.file, err = os.Open(filePath)
+	if err != nil {
+		return g.Error(err, "could not open %s", filePath)
+	}
+
+	stat, err := a.file.Stat()
+	if err != nil {
+		return g.Error(err, "could not stat %s", filePath)
+	}
+
+	if a.Type == ArchiveTypeZip {
+		a.zipReader, err = zip.NewReader(a.file, stat.Size())
+		if err != nil {
+			return g.Error(err, "could not read zip %s", a.uri)
+		}
+		return nil
+	}
+
+	return a.indexTar(stat.Size())
+}
+
+// copyToTemp copies the archive to a temp file, decompressing tar.gz
+func (a *archive) copyToTemp() (filePath string, err error) {
+	reader, err := a.fs.GetReader(a.uri)
+	if err != nil {
+		return "", g.Error(err, "could not get archive reader")
+	}
+	if rc, ok := reader.(io.ReadCloser); ok {
+		defer rc.Close()
+	}
+
+	if a.Type == ArchiveTypeTarGz {
+		gzReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return "", g.Error(err, "could not read gzip of %s", a.uri)
+		}
+		reader = gzReader
+	}
+
+	file, err := os.CreateTemp(env.GetTempFolder(), "sling-archive-*")
+	if err != nil {
+		return "", g.Error(err, "could not create temp file")
+	}
+	a.tempPath = file.Name()
+
+	_, err = io.Copy(file, reader)
+	file.Close()
+	if err != nil {
+		return "", g.Error(err, "could not copy %s to %s", a.uri, a.tempPath)
+	}
+
+	return a.tempPath, nil
+}
+
+// indexTar reads the tar headers once, keeping the offsets of the members.
+// The tar reader reads whole blocks without buffering, so the position
+// after a header is the offset of the member data.
+func (a *archive) indexTar(size int64) (err error) {
+	cr := &countingReader{Reader: io.NewSectionReader(a.file, 0, size)}
+	tr := tar.NewReader(cr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return g.Error(err, "could not read tar %s", a.uri)
+		}
+
+		a.tarMembers = append(a.tarMembers, tarMember{
+			name:    header.Name,
+			isDir:   header.Typeflag != tar.TypeReg,
+			offset:  cr.Count(),
+			size:    header.Size,
+			updated: header.ModTime.Unix(),
+		})
+	}
+	return nil
+}
+
+func (a *archive) close() {
+	if a.file != nil {
+		a.file.Close()
+	}
+	if a.tempPath != "" {
+		os.Remove(a.tempPath)
+	}
+}
+
+// List lists the file members of the archive matching the member path,
+// which can be a folder or a glob pattern
+func (a *archive) List(memberPath string) (nodes dbio.FileNodes, err error) {
+	memberPath = strings.Trim(memberPath, "/")
+
+	var pattern glob.Glob
+	if strings.Contains(memberPath, "*") {
+		if pattern, err = glob.Compile(memberPath); err != nil {
+			return nil, g.Error(err, "invalid member pattern: %s", memberPath)
+		}
+	}
+
+	addNode := func(name string, isDir bool, size uint64, updated int64) {
+		name = strings.Trim(path.Clean("/"+name), "/") // no leading slash or dot segments
+		switch {
+		case name == "" || isDir:
+			return
+		case pattern != nil && !pattern.Match(name):
+			return
+		case pattern == nil && memberPath != "" && name != memberPath && !strings.HasPrefix(name, memberPath+"/"):
+			return
+		}
+		nodes.Add(dbio.FileNode{URI: a.uri + "/" + name, Size: size, Updated: updated})
+	}
+
+	switch a.Type {
+	case ArchiveTypeZip:
+		for _, f := range a.zipReader.File {
+			addNode(f.Name, f.FileInfo().IsDir(), f.UncompressedSize64, f.Modified.Unix())
+		}
+	case ArchiveTypeTar, ArchiveTypeTarGz:
+		for _, m := range a.tarMembers {
+			addNode(m.name, m.isDir, uint64(m.size), m.updated)
+		}
+	}
+
+	return nodes, nil
+}
+
+// GetReader returns a reader of the archive member
+func (a *archive) GetReader(member string) (reader io.Reader, err error) {
+	member = strings.Trim(path.Clean("/"+member), "/")
+
+	switch a.Type {
+	case ArchiveTypeZip:
+		for _, f := range a.zipReader.File {
+			if strings.Trim(path.Clean("/"+f.Name), "/") == member {
+				return f.Open()
+			}
+		}
+	case ArchiveTypeTar, ArchiveTypeTarGz:
+		for _, m := range a.tarMembers {
+			if !m.isDir && strings.Trim(path.Clean("/"+m.name), "/") == member {
+				return io.NewSectionReader(a.file, m.offset, m.size), nil
+			}
+		}
+	}
+
+	return nil, g.Error("member %s not found in archive %s", member, a.uri)
+}
+
+// listArchive lists the archive members matching the member path
+func listArchive(fs FileSysClient, archiveURI, memberPath string) (nodes dbio.FileNodes, err error) {
+	a, err := getArchive(fs, archiveURI)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err = a.List(memberPath)
+	if err != nil {
+		return nil, g.Error(err, "could not list archive %s", archiveURI)
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,188 @@
+package filesys
+
+import (
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/google/uuid"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// stagingFolder is the folder where atomic writes are staged,
+// next to the target file or folder
+const stagingFolder = "_sling_staging"
+
+// Manifest lists the files written in a target folder
+type Manifest struct {
+	ExecID    string         `json:"exec_id,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	Files     []ManifestFile `json:"files"`
+	Rows      uint64         `json:"rows"`
+	Bytes     int64          `json:"bytes"`
+}
+
+// ManifestFile is a file of the manifest. Rows is -1 if unknown.
+type ManifestFile struct {
+	URI   string `json:"uri"`
+	Rows  int64  `json:"rows"`
+	Bytes int64  `json:"bytes"`
+}
+
+// writeDataflowAtomic writes the dataflow to `<parent>/_sling_staging/<exec_id>/`,
+// then moves the files into place once all were written successfully.
+// A failed run leaves the target untouched.
+func writeDataflowAtomic(fs FileSysClient, df *iop.Dataflow, url string) (bw int64, err error) {
+	url = strings.TrimSuffix(NormalizeURI(fs, url), "/")
+
+	// keep the file partitioning notation (*) for the staged url
+	globPart := ""
+	if parts := strings.Split(url, "/"); strings.HasPrefix(parts[len(parts)-1], "*") {
+		globPart = "/" + parts[len(parts)-1]
+		url = strings.TrimSuffix(url, globPart)
+	}
+
+	urlPath := strings.TrimPrefix(url, fs.Prefix())
+	parentPath, name := "", urlPath
+	if i := strings.LastIndex(urlPath, "/"); i > -1 {
+		parentPath, name = urlPath[:i+1], urlPath[i+1:]
+	}
+	if name == "" {
+		return 0, g.Error("cannot write atomically to %s, need a file or folder path", url)
+	}
+
+	execID := fs.GetProp("EXEC_ID")
+	if execID == "" {
+		execID = uuid.NewString()
+	}
+
+	parentURL := fs.Prefix() + parentPath
+	stagingRoot := parentURL + stagingFolder + "/" + execID
+	stagingURL := stagingRoot + "/" + name
+
+	cleanUp := func() {
+		Delete(fs, stagingRoot)
+		if nodes, _ := fs.List(parentURL + stagingFolder + "/"); len(nodes) == 0 {
+			Delete(fs, parentURL+stagingFolder)
+		}
+	}
+
+	files := []FileReady{}
+	fileReadyChn := make(chan FileReady, 10000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for fileReady := range fileReadyChn {
+			files = append(files, fileReady)
+		}
+	}()
+
+	g.Debug("writing dataflow to staging %s", stagingURL)
+	bw, err = fs.Self().WriteDataflowReady(df, stagingURL+globPart, fileReadyChn, nil)
+	<-done
+	if err == nil {
+		err = df.Err()
+	}
+	if err != nil {
+		cleanUp()
+		return bw, g.Error(err, "could not write to staging %s", stagingURL)
+	}
+
+	// move the previous output aside, to be restored if a move fails
+	previousURL := stagingRoot + "/_previous/" + name
+	previous, err := listTarget(fs, parentURL, url)
+	if err != nil {
+		cleanUp()
+		return bw, g.Error(err, "could not list %s", url)
+	}
+
+	moved := [][2]string{} // source and target of the moves, to undo
+	undo := func() (err error) {
+		for i := len(moved) - 1; i >= 0; i-- {
+			if err = Move(fs, moved[i][1], moved[i][0]); err != nil {
+				return g.Error(err, "could not restore %s, previous output is in %s", moved[i][0], previousURL)
+			}
+		}
+		return nil
+	}
+	move := func(srcURI, tgtURI string) (err error) {
+		if err = Move(fs, srcURI, tgtURI); err != nil {
+			err = g.Error(err, "could not move %s to %s", srcURI, tgtURI)
+			if undoErr := undo(); undoErr != nil {
+				return g.Error(undoErr, err.Error()) // keep the staging folder
+			}
+			cleanUp()
+			return err
+		}
+		moved = append(moved, [2]string{srcURI, tgtURI})
+		return nil
+	}
+
+	for _, node := range previous {
+		if err = move(node.URI, previousURL+strings.TrimPrefix(node.URI, url)); err != nil {
+			return bw, g.Error(err, "could not move previous output aside")
+		}
+	}
+
+	// move the staged files into place
+	manifest := Manifest{ExecID: execID, CreatedAt: time.Now().UTC(), Rows: df.Count(), Bytes: bw}
+	for _, file := range files {
+		tgtURI := url + strings.TrimPrefix(file.Node.URI, stagingURL)
+		if err = move(file.Node.URI, tgtURI); err != nil {
+			return bw, g.Error(err, "could not move staged files into place")
+		}
+		manifest.Files = append(manifest.Files, ManifestFile{URI: tgtURI, Rows: file.Rows, Bytes: file.BytesW})
+	}
+	cleanUp()
+
+	// the marker and manifest are only written in a target folder
+	singleFile := len(files) == 1 && files[0].Node.URI == stagingURL
+	if singleFile {
+		return bw, nil
+	}
+
+	if cast.ToBool(fs.GetProp("MANIFEST")) {
+		_, err = fs.Self().Write(url+"/_manifest.json", strings.NewReader(g.Pretty(manifest)))
+		if err != nil {
+			return bw, g.Error(err, "could not write manifest")
+		}
+	}
+
+	if cast.ToBool(fs.GetProp("SUCCESS_FILE")) {
+		_, err = fs.Self().Write(url+"/_SUCCESS", strings.NewReader(""))
+		if err != nil {
+			return bw, g.Error(err, "could not write _SUCCESS file")
+		}
+	}
+
+	return bw, nil
+}
+
+// listTarget lists the files of the target, which is either a file or a folder
+func listTarget(fs FileSysClient, parentURL, url string) (nodes dbio.FileNodes, err error) {
+	siblings, err := fs.Self().List(parentURL)
+	if err != nil {
+		return nil, g.Error(err, "could not list %s", parentURL)
+	}
+
+	for _, node := range siblings {
+		if strings.TrimSuffix(node.URI, "/") != url {
+			continue
+		} else if !node.IsDir {
+			return dbio.FileNodes{node}, nil
+		}
+
+		children, err := fs.Self().ListRecursive(url + "/")
+		if err != nil {
+			return nil, g.Error(err, "could not list %s", url)
+		}
+		for _, child := range children {
+			if !child.IsDir && strings.HasPrefix(child.URI, url+"/") {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	return nodes, nil
+}
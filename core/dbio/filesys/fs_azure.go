@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
@@ -76,12 +77,16 @@ func (fs *AzureFileSysClient) GetPath(uri string) (path string, err error) {
 func (fs *AzureFileSysClient) Connect() (err error) {
 
 	serviceURL := g.F("https://%s.blob.core.windows.net/", fs.account)
+	clientOptions := &azblob.ClientOptions{}
+	if retries := getUploadConfig(fs).Retries; retries > 0 {
+		clientOptions.Retry.MaxRetries = int32(retries)
+	}
 	if cs := fs.GetProp("CONN_STR"); cs != "" {
 		connProps := g.KVArrToMap(strings.Split(cs, ";")...)
 		fs.account = connProps["AccountName"]
 		fs.key = connProps["AccountKey"]
 
-		fs.client, err = azblob.NewClientFromConnectionString(cs, &azblob.ClientOptions{})
+		fs.client, err = azblob.NewClientFromConnectionString(cs, clientOptions)
 		if err != nil {
 			err = g.Error(err, "Could not connect to Azure using provided CONN_STR")
 			return
@@ -93,7 +98,7 @@ func (fs *AzureFileSysClient) Connect() (err error) {
 			return
 		}
 
-		fs.client, err = azblob.NewClientWithNoCredential(cs, &azblob.ClientOptions{})
+		fs.client, err = azblob.NewClientWithNoCredential(cs, clientOptions)
 		if err != nil {
 			err = g.Error(err, "Could not connect to Azure using provided SAS_SVC_URL")
 			return
@@ -104,7 +109,7 @@ func (fs *AzureFileSysClient) Connect() (err error) {
 			return g.Error(err, "Could not process shared key / account key")
 		}
 
-		fs.client, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, &azblob.ClientOptions{})
+		fs.client, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, clientOptions)
 		if err != nil {
 			return g.Error(err, "Could not connect to Azure using shared key credentials")
 		}
@@ -114,7 +119,7 @@ func (fs *AzureFileSysClient) Connect() (err error) {
 			return g.Error(err, "No Azure credentials provided")
 		}
 
-		fs.client, err = azblob.NewClient(serviceURL, cred, &azblob.ClientOptions{})
+		fs.client, err = azblob.NewClient(serviceURL, cred, clientOptions)
 		if err != nil {
 			return g.Error(err, "Could not connect to Azure using default credentials")
 		}
@@ -198,6 +203,9 @@ func (fs *AzureFileSysClient) List(uri string) (nodes dbio.FileNodes, err error)
 					node.Size = cast.ToUint64(blob.Properties.ContentLength)
 					node.Created = blob.Properties.CreationTime.Unix()
 					node.Updated = blob.Properties.LastModified.Unix()
+					if blob.Properties.ETag != nil {
+						node.ETag = string(*blob.Properties.ETag)
+					}
 					node.IsDir = strings.HasSuffix(blobName, "/")
 				}
 				nodes.Add(node)
@@ -250,6 +258,9 @@ func (fs *AzureFileSysClient) ListRecursive(uri string) (nodes dbio.FileNodes, e
 				Updated: lastModified.Unix(),
 				Size:    cast.ToUint64(blob.Properties.ContentLength),
 			}
+			if blob.Properties.ETag != nil {
+				file.ETag = string(*blob.Properties.ETag)
+			}
 			nodes.AddWhere(pattern, ts, file)
 		}
 	}
@@ -276,22 +287,107 @@ func (fs *AzureFileSysClient) delete(uri string) (err error) {
 	return
 }
 
+// move copies the blob server-side, then deletes the source
+func (fs *AzureFileSysClient) move(srcURI, tgtURI string) (err error) {
+	srcPath, err := fs.GetPath(srcURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+srcURI)
+	}
+	tgtPath, err := fs.GetPath(tgtURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+tgtURI)
+	}
+
+	ctx := fs.Context().Ctx
+	containerClient := fs.client.ServiceClient().NewContainerClient(fs.container)
+	srcBlob := containerClient.NewBlobClient(srcPath)
+	tgtBlob := containerClient.NewBlobClient(tgtPath)
+
+	resp, err := tgtBlob.StartCopyFromURL(ctx, srcBlob.URL(), nil)
+	if err != nil {
+		return g.Error(err, "could not copy %s to %s", srcURI, tgtURI)
+	}
+
+	// copies within the same account are usually synchronous
+	status := resp.CopyStatus
+	for status != nil && *status == blob.CopyStatusTypePending {
+		time.Sleep(500 * time.Millisecond)
+		props, err := tgtBlob.GetProperties(ctx, nil)
+		if err != nil {
+			return g.Error(err, "could not get copy status of "+tgtURI)
+		}
+		status = props.CopyStatus
+	}
+	if status != nil && *status != blob.CopyStatusTypeSuccess {
+		return g.Error("could not copy %s to %s, status: %s", srcURI, tgtURI, *status)
+	}
+
+	_, err = srcBlob.Delete(ctx, nil)
+	if err != nil {
+		return g.Error(err, "could not delete "+srcURI)
+	}
+
+	return
+}
+
 func (fs *AzureFileSysClient) Write(uri string, reader io.Reader) (bw int64, err error) {
 	path, err := fs.GetPath(uri)
 	if err != nil {
 		return
 	}
 
-	resp, err := fs.client.UploadStream(fs.Context().Ctx, fs.container, path, reader, &blockblob.UploadStreamOptions{})
+	// blocks are uploaded in parallel, and retried individually
+	upload := getUploadConfig(fs)
+	options := &blockblob.UploadStreamOptions{
+		BlockSize:   upload.PartSize,
+		Concurrency: lo.Ternary(upload.Concurrency > 0, upload.Concurrency, fs.Context().Wg.Limit),
+	}
+
+	counter := &countingReader{Reader: reader}
+	_, err = fs.client.UploadStream(fs.Context().Ctx, fs.container, path, counter, options)
+	bw = counter.Count()
 	if err != nil {
 		err = g.Error(err, "Error UploadStream: "+uri)
 		return
 	}
-	_ = resp
 
 	return
 }
 
+// objectSize returns the size of the blob
+func (fs *AzureFileSysClient) objectSize(uri string) (size int64, err error) {
+	key, err := fs.GetPath(uri)
+	if err != nil {
+		return
+	}
+
+	blobClient := fs.client.ServiceClient().NewContainerClient(fs.container).NewBlobClient(key)
+	props, err := blobClient.GetProperties(fs.Context().Ctx, nil)
+	if err != nil {
+		return 0, g.Error(err, "could not get blob properties of "+uri)
+	} else if props.ContentLength == nil {
+		return 0, g.Error("unknown blob size of " + uri)
+	}
+
+	return *props.ContentLength, nil
+}
+
+// readRange returns a reader of the byte range of the blob
+func (fs *AzureFileSysClient) readRange(uri string, offset, length int64) (reader io.ReadCloser, err error) {
+	key, err := fs.GetPath(uri)
+	if err != nil {
+		return
+	}
+
+	options := &blob.DownloadStreamOptions{Range: blob.HTTPRange{Offset: offset, Count: length}}
+	resp, err := fs.client.DownloadStream(fs.Context().Ctx, fs.container, key, options)
+	if err != nil {
+		return nil, g.Error(err, "Error DownloadStream: "+uri)
+	}
+
+	return resp.Body, nil
+}
+
 // GetReader returns an Azure FS reader
 func (fs *AzureFileSysClient) GetReader(uri string) (reader io.Reader, err error) {
 	key, err := fs.GetPath(uri)
@@ -0,0 +1,502 @@
+package filesys
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// deltaLogFileRegex matches the JSON commit files of the `_delta_log` folder
+var deltaLogFileRegex = regexp.MustCompile(`(^|/)(\d{20})\.json$`)
+
+// DeltaAction is an action of a Delta Lake commit.
+// See https://github.com/delta-io/delta/blob/master/PROTOCOL.md
+type DeltaAction struct {
+	Add        *DeltaAdd      `json:"add,omitempty"`
+	Remove     *DeltaRemove   `json:"remove,omitempty"`
+	MetaData   *DeltaMetaData `json:"metaData,omitempty"`
+	Protocol   *DeltaProtocol `json:"protocol,omitempty"`
+	CommitInfo map[string]any `json:"commitInfo,omitempty"`
+}
+
+// DeltaAdd adds a data file to the table
+type DeltaAdd struct {
+	Path             string             `json:"path"`
+	PartitionValues  map[string]*string `json:"partitionValues"`
+	Size             int64              `json:"size"`
+	ModificationTime int64              `json:"modificationTime"`
+	DataChange       bool               `json:"dataChange"`
+	DeletionVector   map[string]any     `json:"deletionVector,omitempty"`
+}
+
+// DeltaRemove removes a data file from the table
+type DeltaRemove struct {
+	Path              string `json:"path"`
+	DeletionTimestamp int64  `json:"deletionTimestamp"`
+	DataChange        bool   `json:"dataChange"`
+}
+
+// DeltaMetaData is the metadata of the table
+type DeltaMetaData struct {
+	ID               string            `json:"id"`
+	Format           DeltaFormat       `json:"format"`
+	SchemaString     string            `json:"schemaString"`
+	PartitionColumns []string          `json:"partitionColumns"`
+	Configuration    map[string]string `json:"configuration"`
+	CreatedTime      int64             `json:"createdTime,omitempty"`
+}
+
+// DeltaFormat is the format of the data files
+type DeltaFormat struct {
+	Provider string            `json:"provider"`
+	Options  map[string]string `json:"options"`
+}
+
+// DeltaProtocol is the protocol version of the table
+type DeltaProtocol struct {
+	MinReaderVersion int      `json:"minReaderVersion"`
+	MinWriterVersion int      `json:"minWriterVersion"`
+	ReaderFeatures   []string `json:"readerFeatures,omitempty"`
+	WriterFeatures   []string `json:"writerFeatures,omitempty"`
+}
+
+// deltaReaderFeatures are the supported reader features, which do not
+// change how the data files are read
+var deltaReaderFeatures = []string{"timestampNtz", "vacuumProtocolCheck"}
+
+// validate returns an error if reading the table requires
+// protocol features which are not supported, such as
+// deletion vectors or column mapping
+func (p *DeltaProtocol) validate() error {
+	switch {
+	case p.MinReaderVersion <= 1:
+		return nil
+	case p.MinReaderVersion == 2:
+		return g.Error("delta reader version 2 (column mapping) is not supported")
+	case p.MinReaderVersion == 3:
+		if unsupported := lo.Without(p.ReaderFeatures, deltaReaderFeatures...); len(unsupported) > 0 {
+			return g.Error("delta reader features are not supported: %s", strings.Join(unsupported, ", "))
+		}
+		return nil
+	}
+	return g.Error("delta reader version %d is not supported", p.MinReaderVersion)
+}
+
+// DeltaSnapshot is the state of a Delta Lake table at a version
+type DeltaSnapshot struct {
+	URL      string
+	Version  int64 // -1 when the table does not exist
+	MetaData *DeltaMetaData
+	Protocol *DeltaProtocol
+	Files    map[string]DeltaAdd // active files by path
+}
+
+// DeltaSchema is the schema of a Delta Lake table
+type DeltaSchema struct {
+	Type   string             `json:"type"`
+	Fields []DeltaSchemaField `json:"fields"`
+}
+
+// DeltaSchemaField is a field of a Delta Lake schema
+type DeltaSchemaField struct {
+	Name     string         `json:"name"`
+	Type     any            `json:"type"`
+	Nullable bool           `json:"nullable"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+// isNotFoundError returns true when the error of a listing means that the
+// path does not exist. Most clients return no nodes in that case, but the
+// local client returns the error of the file system.
+func isNotFoundError(err error) bool {
+	return errors.Is(err, os.ErrNotExist) ||
+		strings.Contains(err.Error(), "no such file or directory") ||
+		strings.Contains(err.Error(), "cannot find the")
+}
+
+// ReadDeltaSnapshot replays the JSON commits of the `_delta_log` folder of
+// the table to resolve the latest metadata and the active data files
+func ReadDeltaSnapshot(fs FileSysClient, tableURL string) (snapshot *DeltaSnapshot, err error) {
+	tableURL = strings.TrimSuffix(NormalizeURI(fs, tableURL), "/")
+	snapshot = &DeltaSnapshot{URL: tableURL, Version: -1, Files: map[string]DeltaAdd{}}
+
+	nodes, err := fs.Self().List(tableURL + "/_delta_log/")
+	if err != nil {
+		if isNotFoundError(err) {
+			return snapshot, nil // table does not exist
+		}
+		return snapshot, g.Error(err, "could not list delta log of %s", tableURL)
+	}
+
+	type logFile struct {
+		uri     string
+		version int64
+	}
+
+	logFiles := []logFile{}
+	hasCheckpoint := false
+	for _, uri := range nodes.URIs() {
+		if matches := deltaLogFileRegex.FindStringSubmatch(uri); len(matches) == 3 {
+			logFiles = append(logFiles, logFile{uri: uri, version: cast.ToInt64(strings.TrimLeft(matches[2], "0"))})
+		} else if strings.HasSuffix(uri, "_last_checkpoint") {
+			hasCheckpoint = true
+		}
+	}
+
+	if len(logFiles) == 0 {
+		return snapshot, nil
+	}
+
+	sort.Slice(logFiles, func(i, j int) bool { return logFiles[i].version < logFiles[j].version })
+	if logFiles[0].version != 0 {
+		if hasCheckpoint {
+			return snapshot, g.Error("delta tables with cleaned up logs (checkpoint only) are not supported: %s", tableURL)
+		}
+		return snapshot, g.Error("delta log of %s does not start at version 0", tableURL)
+	}
+
+	for i, logFile := range logFiles {
+		if logFile.version != int64(i) {
+			return snapshot, g.Error("delta log of %s is missing version %d", tableURL, i)
+		}
+
+		reader, err := fs.Self().GetReader(logFile.uri)
+		if err != nil {
+			return snapshot, g.Error(err, "could not read delta log %s", logFile.uri)
+		}
+
+		if err = snapshot.apply(reader); err != nil {
+			return snapshot, g.Error(err, "could not apply delta log %s", logFile.uri)
+		}
+		snapshot.Version = logFile.version
+	}
+
+	if snapshot.MetaData == nil {
+		return snapshot, g.Error("delta log of %s has no metadata", tableURL)
+	} else if snapshot.MetaData.Format.Provider != "" && snapshot.MetaData.Format.Provider != "parquet" {
+		return snapshot, g.Error("unsupported delta data file format: %s", snapshot.MetaData.Format.Provider)
+	} else if snapshot.Protocol != nil {
+		if err = snapshot.Protocol.validate(); err != nil {
+			return snapshot, g.Error(err, "cannot read delta table %s", tableURL)
+		}
+	}
+
+	for path, add := range snapshot.Files {
+		if add.DeletionVector != nil {
+			return snapshot, g.Error("cannot read delta table %s, deletion vectors are not supported (%s)", tableURL, path)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// apply applies the actions of a commit file
+func (s *DeltaSnapshot) apply(reader io.Reader) (err error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 100*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		action := DeltaAction{}
+		if err = g.Unmarshal(line, &action); err != nil {
+			return g.Error(err, "could not parse delta action: %s", line)
+		}
+
+		switch {
+		case action.Add != nil:
+			s.Files[action.Add.Path] = *action.Add
+		case action.Remove != nil:
+			delete(s.Files, action.Remove.Path)
+		case action.MetaData != nil:
+			s.MetaData = action.MetaData
+		case action.Protocol != nil:
+			s.Protocol = action.Protocol
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Nodes returns the active data files
+func (s *DeltaSnapshot) Nodes() (nodes dbio.FileNodes) {
+	for _, path := range lo.Keys(s.Files) {
+		add := s.Files[path]
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			path = unescaped
+		}
+		if !strings.Contains(path, "://") {
+			path = s.URL + "/" + strings.TrimPrefix(path, "/")
+		}
+		nodes = append(nodes, dbio.FileNode{URI: path, Size: cast.ToUint64(add.Size), Updated: add.ModificationTime / 1000})
+	}
+	nodes.Sort()
+	return nodes
+}
+
+// Columns returns the columns of the table schema
+func (s *DeltaSnapshot) Columns() (columns iop.Columns, err error) {
+	if s.MetaData == nil {
+		return nil, nil
+	}
+
+	schema := DeltaSchema{}
+	if err = g.Unmarshal(s.MetaData.SchemaString, &schema); err != nil {
+		return nil, g.Error(err, "could not parse delta schema")
+	}
+
+	for i, field := range schema.Fields {
+		col := iop.Column{Name: field.Name, Position: i + 1, Type: iop.StringType}
+		typ, _ := field.Type.(string)
+		switch {
+		case typ == "long":
+			col.Type = iop.BigIntType
+		case g.In(typ, "integer", "short", "byte"):
+			col.Type = lo.Ternary(typ == "integer", iop.IntegerType, iop.SmallIntType)
+		case g.In(typ, "double", "float"):
+			col.Type = iop.FloatType
+		case strings.HasPrefix(typ, "decimal"):
+			col.Type = iop.DecimalType
+			parts := strings.Split(strings.Trim(strings.TrimPrefix(typ, "decimal"), "()"), ",")
+			if len(parts) == 2 {
+				col.DbPrecision = cast.ToInt(strings.TrimSpace(parts[0]))
+				col.DbScale = cast.ToInt(strings.TrimSpace(parts[1]))
+			}
+		case typ == "boolean":
+			col.Type = iop.BoolType
+		case typ == "date":
+			col.Type = iop.DateType
+		case g.In(typ, "timestamp", "timestamp_ntz"):
+			col.Type = iop.TimestampType
+		case typ == "binary":
+			col.Type = iop.BinaryType
+		case typ == "": // struct, array or map
+			col.Type = iop.JsonType
+		}
+		columns = append(columns, col)
+	}
+
+	return columns, nil
+}
+
+// deltaSchemaString returns the Delta schema of the columns
+func deltaSchemaString(columns iop.Columns) string {
+	schema := DeltaSchema{Type: "struct", Fields: []DeltaSchemaField{}}
+
+	for _, col := range columns {
+		field := DeltaSchemaField{Name: col.Name, Type: "string", Nullable: true, Metadata: map[string]any{}}
+		switch {
+		case col.Type == iop.BigIntType:
+			field.Type = "long"
+		case col.Type == iop.IntegerType:
+			field.Type = "integer"
+		case col.Type == iop.SmallIntType:
+			field.Type = "short"
+		case col.Type == iop.FloatType:
+			field.Type = "double"
+		case col.Type == iop.DecimalType:
+			precision := lo.Ternary(col.DbPrecision > 0, col.DbPrecision, 38)
+			scale := lo.Ternary(col.DbPrecision > 0, col.DbScale, 9)
+			field.Type = g.F("decimal(%d,%d)", precision, scale)
+		case col.Type == iop.BoolType:
+			field.Type = "boolean"
+		case col.Type == iop.DateType:
+			field.Type = "date"
+		case col.Type.IsDatetime():
+			field.Type = "timestamp"
+		case col.Type == iop.BinaryType:
+			field.Type = "binary"
+		}
+		schema.Fields = append(schema.Fields, field)
+	}
+
+	return g.Marshal(schema)
+}
+
+// readDeltaDataflow reads the active parquet files of a Delta Lake table
+func readDeltaDataflow(fs FileSysClient, tableURL string, cfg FileStreamConfig) (df *iop.Dataflow, err error) {
+	snapshot, err := ReadDeltaSnapshot(fs, tableURL)
+	if err != nil {
+		return nil, g.Error(err, "could not read delta table")
+	} else if snapshot.Version == -1 {
+		return nil, g.Error("no delta log found at %s", tableURL)
+	}
+
+	nodes := snapshot.Nodes()
+	if len(nodes) == 0 {
+		return nil, g.Error("delta table %s has no data files (version %d)", tableURL, snapshot.Version)
+	}
+	g.Debug("reading delta table %s [version=%d files=%d]", tableURL, snapshot.Version, len(nodes))
+
+	// data files are parquet, and are read after this returns, so the props
+	// are not reset. Partition values are not in the data files, they are
+	// parsed from the hive-style folders, which is how Delta writers lay out partitions
+	fs.SetProp("FORMAT", string(FileTypeParquet))
+	fs.SetProp("HIVE_PARTITIONING", "true")
+
+	return GetDataflow(fs.Self(), nodes, cfg)
+}
+
+// writeDeltaDataflow writes the dataflow as parquet files in the table folder,
+// then commits them in a new JSON entry of the `_delta_log`. The mode is
+// `overwrite` (previous files are removed in the commit) unless SLING_MODE
+// is an appending mode (incremental, snapshot, backfill) or `append`.
+func writeDeltaDataflow(fs FileSysClient, df *iop.Dataflow, tableURL string) (bw int64, err error) {
+	tableURL = strings.TrimSuffix(NormalizeURI(fs, tableURL), "/")
+
+	snapshot, err := ReadDeltaSnapshot(fs, tableURL)
+	if err != nil {
+		return 0, g.Error(err, "could not read delta table")
+	}
+
+	mode := strings.ToLower(fs.GetProp("SLING_MODE"))
+	appendMode := g.In(mode, "append", "incremental", "snapshot", "backfill")
+
+	partitionBy, err := ParsePartitionBy(fs.GetProp("PARTITION_BY"))
+	if err != nil {
+		return 0, g.Error(err, "invalid partition_by")
+	}
+	partitionCols := []string{}
+	for _, field := range partitionBy {
+		if field.Date != "" {
+			return 0, g.Error("date partitions are not supported for delta tables, use a column: %s", field.Date)
+		}
+		partitionCols = append(partitionCols, field.Column)
+	}
+
+	if appendMode && snapshot.MetaData != nil {
+		if len(partitionBy) > 0 && !lo.Every(snapshot.MetaData.PartitionColumns, partitionCols) {
+			return 0, g.Error("partition_by %v does not match the delta table partitions %v", partitionCols, snapshot.MetaData.PartitionColumns)
+		}
+		partitionCols = snapshot.MetaData.PartitionColumns
+		partitionBy = lo.Map(partitionCols, func(c string, i int) PartitionField { return PartitionField{Column: c} })
+	}
+
+	// write the parquet files in a new folder of the table
+	dataFolder := g.F("sling-%s-%s", time.Now().UTC().Format("20060102T150405"), uuid.NewString()[:8])
+	props := map[string]string{
+		"FORMAT":       string(FileTypeParquet),
+		"PARTITION_BY": lo.Ternary(len(partitionBy) > 0, g.Marshal(partitionBy), ""),
+	}
+	previous := fs.Client().Props()
+	for k, v := range props {
+		fs.SetProp(k, v)
+	}
+	defer func() {
+		for k := range props {
+			fs.SetProp(k, previous[strings.ToLower(k)])
+		}
+	}()
+
+	adds := []DeltaAdd{}
+	fileReadyChn := make(chan FileReady, 10000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for fileReady := range fileReadyChn {
+			path := strings.TrimPrefix(fileReady.Node.URI, tableURL+"/")
+			_, values := iop.HivePartitions(path)
+			partitionValues := map[string]*string{}
+			for _, col := range partitionCols {
+				partitionValues[col] = values[col]
+			}
+			adds = append(adds, DeltaAdd{
+				Path:             (&url.URL{Path: path}).EscapedPath(),
+				PartitionValues:  partitionValues,
+				Size:             fileReady.BytesW,
+				ModificationTime: time.Now().UnixMilli(),
+				DataChange:       true,
+			})
+		}
+	}()
+
+	bw, err = fs.Self().WriteDataflowReady(df, tableURL+"/"+dataFolder+"/*.parquet", fileReadyChn, nil)
+	<-done
+	if err != nil {
+		return bw, g.Error(err, "could not write delta data files")
+	}
+
+	// commit
+	now := time.Now().UnixMilli()
+	version := snapshot.Version + 1
+	actions := []DeltaAction{{CommitInfo: map[string]any{
+		"timestamp": now,
+		"operation": "WRITE",
+		"operationParameters": map[string]any{
+			"mode":        lo.Ternary(appendMode, "Append", "Overwrite"),
+			"partitionBy": g.Marshal(partitionCols),
+		},
+		"engineInfo": "sling-cli",
+	}}}
+
+	if snapshot.Protocol == nil {
+		actions = append(actions, DeltaAction{Protocol: &DeltaProtocol{MinReaderVersion: 1, MinWriterVersion: 2}})
+	}
+
+	if snapshot.MetaData == nil || !appendMode {
+		metaData := &DeltaMetaData{
+			ID:               uuid.NewString(),
+			Format:           DeltaFormat{Provider: "parquet", Options: map[string]string{}},
+			SchemaString:     deltaSchemaString(df.Columns),
+			PartitionColumns: partitionCols,
+			Configuration:    map[string]string{},
+			CreatedTime:      now,
+		}
+		if snapshot.MetaData != nil {
+			metaData.ID = snapshot.MetaData.ID
+			metaData.CreatedTime = snapshot.MetaData.CreatedTime
+			metaData.Configuration = snapshot.MetaData.Configuration
+		}
+		actions = append(actions, DeltaAction{MetaData: metaData})
+	} else if columns, _ := snapshot.Columns(); len(df.Columns) > len(columns) {
+		// new columns are added to the schema
+		metaData := *snapshot.MetaData
+		merged, _, _ := columns.Merge(df.Columns, false)
+		metaData.SchemaString = deltaSchemaString(merged)
+		actions = append(actions, DeltaAction{MetaData: &metaData})
+	}
+
+	if !appendMode {
+		for _, path := range lo.Keys(snapshot.Files) {
+			actions = append(actions, DeltaAction{Remove: &DeltaRemove{Path: path, DeletionTimestamp: now, DataChange: true}})
+		}
+	}
+
+	for i := range adds {
+		actions = append(actions, DeltaAction{Add: &adds[i]})
+	}
+
+	lines := lo.Map(actions, func(a DeltaAction, i int) string { return g.Marshal(a) })
+	logURL := g.F("%s/_delta_log/%020d.json", tableURL, version)
+
+	// another writer may have committed the version in the meantime
+	if nodes, err := fs.Self().List(logURL); err != nil && !isNotFoundError(err) {
+		return bw, g.Error(err, "could not check if delta version %d exists", version)
+	} else if len(nodes) > 0 {
+		return bw, g.Error("could not commit delta version %d, since it already exists", version)
+	}
+
+	if _, err = fs.Self().Write(logURL, strings.NewReader(strings.Join(lines, "\n")+"\n")); err != nil {
+		return bw, g.Error(err, "could not write delta log %s", logURL)
+	}
+
+	g.Debug("committed delta version %d with %d files to %s", version, len(adds), tableURL)
+
+	return bw, nil
+}
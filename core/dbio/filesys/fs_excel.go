@@ -0,0 +1,173 @@
+package filesys
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// excelMaxRows is the maximum number of data rows of a sheet (without header)
+const excelMaxRows = 1048575
+
+var excelSheetNameRegex = regexp.MustCompile(`[:\\/?*\[\]]`)
+
+// excelSheetName cleans the sheet name: some characters are
+// not allowed, and the length is up to 31 characters
+func excelSheetName(name string) string {
+	name = strings.TrimSpace(excelSheetNameRegex.ReplaceAllString(name, "_"))
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	return name
+}
+
+// excelSheet is the data of a sheet to write
+type excelSheet struct {
+	name string
+	data iop.Dataset
+}
+
+// writeExcelWorkbook writes the streams, partitions or row splits
+// (file_max_rows) of the dataflow into separate sheets of one workbook
+func writeExcelWorkbook(fs *BaseFileSysClient, df *iop.Dataflow, url string, fileReadyChn chan FileReady, partitionBy []PartitionField, partitionMaxOpen, fileRowLimit int) (bw int64, err error) {
+	baseName := fs.GetProp("SHEET")
+	multiStream := len(df.Streams) > 1
+
+	var mux sync.Mutex
+	sheets := []*excelSheet{}
+	sheetMap := map[string]*excelSheet{}
+	addSheetData := func(name string, data iop.Dataset) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		if sheet, ok := sheetMap[name]; ok {
+			// partition was re-opened
+			sheet.data.Rows = append(sheet.data.Rows, data.Rows...)
+			return
+		}
+		sheet := &excelSheet{name: name, data: data}
+		sheetMap[name] = sheet
+		sheets = append(sheets, sheet)
+	}
+
+	streamNum := 0
+	for ds := range df.StreamCh {
+		streamNum++
+		ds.SetConfig(fs.Props()) // pass options
+
+		streamName := baseName
+		if multiStream {
+			streamName = g.F("%s%d", lo.Ternary(baseName == "", "Sheet", baseName+"_"), streamNum)
+		} else if streamName == "" {
+			streamName = "Sheet1"
+		}
+
+		if len(partitionBy) == 0 {
+			data, err := ds.Collect(0)
+			if err != nil {
+				return bw, g.Error(err, "could not read stream for sheet %s", streamName)
+			}
+			addSheetData(streamName, data)
+			continue
+		}
+
+		// one sheet per partition
+		var partWg sync.WaitGroup
+		startPartition := func(pds *iop.Datastream, nextFileURL func(suffix string) string) {
+			partURL := nextFileURL("")
+			partPath := strings.TrimPrefix(partURL[:strings.LastIndex(partURL, "/")], url+"/")
+			sheetName := strings.ReplaceAll(partPath, "/", "_")
+			if multiStream {
+				sheetName = streamName + "_" + sheetName
+			}
+
+			partWg.Add(1)
+			go func() {
+				if err := pds.Start(); err != nil {
+					df.Context.CaptureErr(g.Error(err, "could not start partition stream"))
+				}
+			}()
+			go func() {
+				defer partWg.Done()
+				data, err := pds.Collect(0)
+				if err != nil {
+					df.Context.CaptureErr(g.Error(err, "could not read partition %s", partPath))
+					return
+				}
+				addSheetData(sheetName, data)
+			}()
+		}
+
+		router := newPartitionRouter(url, partitionBy, partitionMaxOpen, fs.Props(), startPartition)
+		if err = router.route(ds); err != nil {
+			ds.Context.Cancel()
+			return bw, g.Error(err, "could not partition stream")
+		}
+		partWg.Wait()
+
+		if err = df.Err(); err != nil {
+			return bw, g.Error(err)
+		}
+	}
+
+	// split the sheets with the row limit
+	rowLimit := excelMaxRows
+	if fileRowLimit > 0 && fileRowLimit < rowLimit {
+		rowLimit = fileRowLimit
+	}
+
+	xls := iop.NewExcel()
+	sheetCnt := 0
+	for _, sheet := range sheets {
+		for i := 0; i == 0 || i*rowLimit < len(sheet.data.Rows); i++ {
+			sheetName := excelSheetName(sheet.name)
+			if i > 0 {
+				sheetName = excelSheetName(g.F("%s_%d", sheet.name, i+1))
+			}
+
+			data := iop.NewDataset(sheet.data.Columns)
+			data.Rows = sheet.data.Rows[i*rowLimit : min((i+1)*rowLimit, len(sheet.data.Rows))]
+
+			mode := "new"
+			if sheetCnt == 0 {
+				// replace the default sheet
+				xls.File.SetSheetName("Sheet1", sheetName)
+				xls.RefreshSheets()
+				mode = "overwrite"
+			}
+
+			if err = xls.WriteSheet(sheetName, data.Stream(), mode); err != nil {
+				return bw, g.Error(err, "could not write sheet %s", sheetName)
+			}
+			sheetCnt++
+		}
+	}
+
+	pipeR, pipeW := io.Pipe()
+	go func() {
+		if err := xls.WriteToWriter(pipeW); err != nil {
+			pipeW.CloseWithError(err)
+			return
+		}
+		pipeW.Close()
+	}()
+
+	bw, err = fs.Self().Write(url, pipeR)
+	if err != nil {
+		return bw, g.Error(err, "could not write workbook to %s", url)
+	}
+	df.AddEgressBytes(uint64(bw))
+
+	node := dbio.FileNode{URI: url, Size: cast.ToUint64(bw)}
+	fileReadyChn <- FileReady{Columns: df.Columns, Node: node, BytesW: bw}
+	g.Debug("wrote %d sheets to %s", sheetCnt, url)
+
+	return
+}
@@ -120,8 +120,18 @@ func (fs *GoogleFileSysClient) Write(path string, reader io.Reader) (bw int64, e
 		return
 	}
 
-	obj := fs.client.Bucket(fs.bucket).Object(key)
+	// the upload is a resumable session, each chunk is retried on failure
+	upload := getUploadConfig(fs)
+	retryOpts := []gcstorage.RetryOption{gcstorage.WithPolicy(gcstorage.RetryAlways)}
+	if upload.Retries > 0 {
+		retryOpts = append(retryOpts, gcstorage.WithMaxAttempts(upload.Retries+1))
+	}
+
+	obj := fs.client.Bucket(fs.bucket).Object(key).Retryer(retryOpts...)
 	wc := obj.NewWriter(fs.Context().Ctx)
+	if upload.PartSize > 0 {
+		wc.ChunkSize = int(upload.PartSize) // rounded up to a multiple of 256KiB
+	}
 	bw, err = io.Copy(wc, reader)
 	if err != nil {
 		err = g.Error(err, "Error Copying")
@@ -149,6 +159,36 @@ func (fs *GoogleFileSysClient) GetReader(path string) (reader io.Reader, err err
 	return
 }
 
+// objectSize returns the size of the object
+func (fs *GoogleFileSysClient) objectSize(uri string) (size int64, err error) {
+	key, err := fs.GetPath(uri)
+	if err != nil {
+		return
+	}
+
+	attrs, err := fs.client.Bucket(fs.bucket).Object(key).Attrs(fs.Context().Ctx)
+	if err != nil {
+		return 0, g.Error(err, "could not get object attributes of "+key)
+	}
+
+	return attrs.Size, nil
+}
+
+// readRange returns a reader of the byte range of the object
+func (fs *GoogleFileSysClient) readRange(uri string, offset, length int64) (reader io.ReadCloser, err error) {
+	key, err := fs.GetPath(uri)
+	if err != nil {
+		return
+	}
+
+	reader, err = fs.client.Bucket(fs.bucket).Object(key).NewRangeReader(fs.Context().Ctx, offset, length)
+	if err != nil {
+		return nil, g.Error(err, "could not get range reader of "+key)
+	}
+
+	return reader, nil
+}
+
 // Buckets returns the buckets found in the project
 func (fs *GoogleFileSysClient) Buckets() (paths []string, err error) {
 	// Create S3 service client
@@ -219,6 +259,7 @@ func (fs *GoogleFileSysClient) List(uri string) (nodes dbio.FileNodes, err error
 				node.Created = attrs.Created.Unix()
 				node.Updated = attrs.Updated.Unix()
 				node.Owner = attrs.Owner
+				node.ETag = attrs.Etag
 				node.IsDir = strings.HasSuffix(attrs.Name, "/")
 			}
 			nodes.Add(node)
@@ -265,6 +306,7 @@ func (fs *GoogleFileSysClient) ListRecursive(uri string) (nodes dbio.FileNodes,
 			Created: attrs.Created.Unix(),
 			Updated: attrs.Updated.Unix(),
 			Owner:   attrs.Owner,
+			ETag:    attrs.Etag,
 		}
 		nodes.AddWhere(pattern, ts, node)
 	}
@@ -309,3 +351,26 @@ func (fs *GoogleFileSysClient) delete(urlStr string) (err error) {
 	}
 	return
 }
+
+// move copies the object server-side, then deletes the source
+func (fs *GoogleFileSysClient) move(srcURI, tgtURI string) (err error) {
+	srcKey, err := fs.GetPath(srcURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+srcURI)
+	}
+	tgtKey, err := fs.GetPath(tgtURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+tgtURI)
+	}
+
+	bucket := fs.client.Bucket(fs.bucket)
+	src := bucket.Object(srcKey)
+	_, err = bucket.Object(tgtKey).CopierFrom(src).Run(fs.Context().Ctx)
+	if err != nil {
+		return g.Error(err, "could not copy %s to %s", srcURI, tgtURI)
+	} else if err = src.Delete(fs.Context().Ctx); err != nil {
+		return g.Error(err, "could not delete "+srcURI)
+	}
+
+	return
+}
@@ -0,0 +1,262 @@
+package filesys
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// GoogleSheetFileSysClient is a file system client for Google Sheets.
+// The spreadsheet is the bucket, and each sheet is a file:
+// `gsheets://<spreadsheet_id>/<sheet>`
+type GoogleSheetFileSysClient struct {
+	BaseFileSysClient
+	spreadsheetID string
+}
+
+// Init initializes the fs client
+func (fs *GoogleSheetFileSysClient) Init(ctx context.Context) (err error) {
+	instance := FileSysClient(fs)
+	fs.BaseFileSysClient.instance = &instance
+	fs.BaseFileSysClient.context = g.NewContext(ctx)
+
+	for _, key := range g.ArrStr("KEY_FILE", "KEY_BODY") {
+		if fs.GetProp(key) == "" {
+			fs.SetProp(key, fs.GetProp("GC_"+key))
+		}
+	}
+	if fs.GetProp("KEY_FILE") == "" {
+		fs.SetProp("KEY_FILE", fs.GetProp("KEYFILE")) // dbt style
+	}
+	if fs.GetProp("KEY_BODY") == "" {
+		fs.SetProp("KEY_BODY", fs.GetProp("GSHEET_CLIENT_JSON_BODY"))
+	}
+
+	if val := fs.GetProp("KEY_FILE"); val != "" {
+		b, err := os.ReadFile(val)
+		if err != nil {
+			return g.Error(err, "could not read google cloud key file")
+		}
+		fs.SetProp("KEY_BODY", string(b))
+	}
+
+	return fs.Connect()
+}
+
+// Connect sets the spreadsheet id
+func (fs *GoogleSheetFileSysClient) Connect() (err error) {
+	fs.spreadsheetID = fs.GetProp("SPREADSHEET_ID")
+	if fs.spreadsheetID == "" && fs.GetProp("url") != "" {
+		fs.spreadsheetID, _, err = ParseURL(fs.GetProp("url"))
+		if err != nil {
+			return g.Error(err, "could not parse google sheets url")
+		}
+	}
+	return
+}
+
+// Prefix returns the url prefix
+func (fs *GoogleSheetFileSysClient) Prefix(suffix ...string) string {
+	return g.F("%s://%s", fs.FsType().String(), fs.spreadsheetID) + strings.Join(suffix, "")
+}
+
+// GetPath returns the sheet name of url
+func (fs *GoogleSheetFileSysClient) GetPath(uri string) (path string, err error) {
+	// normalize, in case url is provided without prefix
+	uri = NormalizeURI(fs, uri)
+
+	host, path, err := ParseURL(uri)
+	if err != nil {
+		return
+	}
+
+	if fs.spreadsheetID != "" && fs.spreadsheetID != host {
+		err = g.Error("URL spreadsheet differs from connection spreadsheet. %s != %s", host, fs.spreadsheetID)
+	}
+
+	return path, err
+}
+
+// openSheet loads the spreadsheet and returns the sheet name to use.
+// The `sheet` property has precedence over the sheet of the url.
+func (fs *GoogleSheetFileSysClient) openSheet(uri string) (ggs *iop.GoogleSheet, shtName string, err error) {
+	shtName, err = fs.GetPath(uri)
+	if err != nil {
+		return nil, "", g.Error(err, "invalid google sheets url: %s", uri)
+	} else if val := fs.GetProp("SHEET", "GSHEET_SHEET_NAME"); val != "" {
+		shtName = val
+	}
+
+	props := map[string]string{
+		"KEY_BODY": fs.GetProp("KEY_BODY"),
+		"ENDPOINT": fs.GetProp("ENDPOINT"),
+	}
+	ggs, err = iop.NewGoogleSheet(g.MapToKVArr(props)...)
+	if err != nil {
+		return nil, "", g.Error(err, "could not initialize google sheets")
+	}
+
+	for k, v := range fs.Client().Props() {
+		ggs.Props[k] = v
+	}
+	if _, ok := ggs.Props["header"]; !ok {
+		ggs.Props["header"] = "true" // first row has the column names
+	}
+
+	if ggs.SpreadsheetID = fs.spreadsheetID; ggs.SpreadsheetID != "" {
+		if err = ggs.RefreshSheets(); err != nil {
+			return nil, "", g.Error(err, "could not load spreadsheet %s", ggs.SpreadsheetID)
+		}
+	}
+
+	return ggs, shtName, nil
+}
+
+// sheetExists returns true if the sheet (without range) is in the spreadsheet
+func sheetExists(ggs *iop.GoogleSheet, shtName string) bool {
+	return lo.Contains(ggs.Sheets, strings.Split(shtName, "!")[0])
+}
+
+// writeMode returns the write mode: `new`, `append` or `overwrite`.
+// If not provided, the sling mode determines it.
+func (fs *GoogleSheetFileSysClient) writeMode() (mode string, err error) {
+	mode = strings.ToLower(fs.GetProp("MODE", "GSHEET_MODE"))
+	switch mode {
+	case "new", "append", "overwrite":
+		return mode, nil
+	case "":
+		if g.In(fs.GetProp("SLING_MODE"), "incremental", "backfill", "snapshot") {
+			return "append", nil
+		}
+		return "overwrite", nil
+	}
+	return "", g.Error("invalid google sheets mode: %s (must be new, append or overwrite)", mode)
+}
+
+// List lists the sheets of the spreadsheet
+func (fs *GoogleSheetFileSysClient) List(uri string) (nodes dbio.FileNodes, err error) {
+	ggs, shtName, err := fs.openSheet(uri)
+	if err != nil {
+		return nodes, g.Error(err, "could not list sheets")
+	}
+
+	if shtName != "" {
+		if sheetExists(ggs, shtName) {
+			nodes.Add(dbio.FileNode{URI: fs.Prefix("/", shtName)})
+		}
+		return
+	}
+
+	for _, sheet := range ggs.Sheets {
+		nodes.Add(dbio.FileNode{URI: fs.Prefix("/", sheet)})
+	}
+	return
+}
+
+// ListRecursive lists the sheets of the spreadsheet
+func (fs *GoogleSheetFileSysClient) ListRecursive(uri string) (nodes dbio.FileNodes, err error) {
+	return fs.List(uri)
+}
+
+// GetReader return a CSV reader of the sheet data
+func (fs *GoogleSheetFileSysClient) GetReader(uri string) (reader io.Reader, err error) {
+	ggs, shtName, err := fs.openSheet(uri)
+	if err != nil {
+		return nil, g.Error(err, "could not open google sheets")
+	}
+
+	data, err := ggs.GetDataset(shtName)
+	if err != nil {
+		return nil, g.Error(err, "could not read sheet: "+shtName)
+	}
+
+	pipeR, pipeW := io.Pipe()
+	go func() {
+		_, err := data.WriteCsv(pipeW)
+		pipeW.CloseWithError(err)
+	}()
+
+	return pipeR, nil
+}
+
+// Write writes the CSV data of the reader into the sheet
+func (fs *GoogleSheetFileSysClient) Write(uri string, reader io.Reader) (bw int64, err error) {
+	csv := iop.CSV{Reader: reader}
+	ds, err := csv.ReadStream()
+	if err != nil {
+		return 0, g.Error(err, "could not parse csv stream")
+	}
+
+	return fs.writeSheet(uri, ds)
+}
+
+// WriteDataflowReady writes the dataflow streams into one sheet
+func (fs *GoogleSheetFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fileReadyChn chan FileReady, sc *iop.StreamConfig) (bw int64, err error) {
+	defer close(fileReadyChn)
+
+	if sc != nil {
+		df.SetConfig(sc)
+	}
+
+	ds := iop.MergeDataflow(df)
+	ds.SetConfig(fs.Props())
+
+	bw, err = fs.writeSheet(url, ds)
+	if err != nil {
+		return bw, g.Error(err)
+	}
+	df.AddEgressBytes(uint64(bw))
+
+	node := dbio.FileNode{URI: NormalizeURI(fs, url), Size: cast.ToUint64(bw)}
+	fileReadyChn <- FileReady{Columns: df.Columns, Node: node, BytesW: bw}
+
+	return
+}
+
+func (fs *GoogleSheetFileSysClient) writeSheet(uri string, ds *iop.Datastream) (bw int64, err error) {
+	mode, err := fs.writeMode()
+	if err != nil {
+		return 0, err
+	}
+
+	ggs, shtName, err := fs.openSheet(uri)
+	if err != nil {
+		return 0, g.Error(err, "could not open google sheets")
+	}
+
+	shtName = lo.Ternary(shtName == "", "Sheet1", shtName)
+	if rng := fs.GetProp("RANGE"); rng != "" && !strings.Contains(shtName, "!") {
+		shtName = shtName + "!" + rng // start cell
+	}
+
+	g.Debug("writing to google sheet %s [mode=%s]", shtName, mode)
+	err = ggs.WriteSheet(shtName, ds, mode)
+	if err != nil {
+		return 0, g.Error(err, "could not write to sheet: "+shtName)
+	}
+
+	return cast.ToInt64(ds.Bytes.Load()), nil
+}
+
+// delete deletes the sheet
+func (fs *GoogleSheetFileSysClient) delete(uri string) (err error) {
+	ggs, shtName, err := fs.openSheet(uri)
+	if err != nil {
+		return g.Error(err, "could not open google sheets")
+	} else if shtName == "" {
+		return g.Error("cannot delete a spreadsheet, only sheets")
+	}
+
+	if !sheetExists(ggs, shtName) {
+		return nil
+	}
+
+	return ggs.DeleteSheet(strings.Split(shtName, "!")[0])
+}
@@ -0,0 +1,472 @@
+package filesys
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/spf13/cast"
+)
+
+// HDFSFileSysClient is for HDFS files, with the WebHDFS REST API
+// https://hadoop.apache.org/docs/stable/hadoop-project-dist/hadoop-hdfs/WebHDFS.html
+type HDFSFileSysClient struct {
+	BaseFileSysClient
+	client    *http.Client
+	krbClient *client.Client
+	baseURL   string
+}
+
+// hdfsFileStatus is the status of a file or directory
+type hdfsFileStatus struct {
+	PathSuffix       string `json:"pathSuffix"`
+	Type             string `json:"type"` // FILE or DIRECTORY
+	Length           int64  `json:"length"`
+	ModificationTime int64  `json:"modificationTime"` // milliseconds
+	Owner            string `json:"owner"`
+}
+
+// hdfsRemoteException is the error payload of WebHDFS
+type hdfsRemoteException struct {
+	RemoteException struct {
+		Exception string `json:"exception"`
+		Message   string `json:"message"`
+	} `json:"RemoteException"`
+}
+
+// Init initializes the fs client
+func (fs *HDFSFileSysClient) Init(ctx context.Context) (err error) {
+	var instance FileSysClient
+	instance = fs
+	fs.BaseFileSysClient.instance = &instance
+	fs.BaseFileSysClient.context = g.NewContext(ctx)
+	return fs.Connect()
+}
+
+// Prefix returns the url prefix
+func (fs *HDFSFileSysClient) Prefix(suffix ...string) string {
+	return g.F("%s://%s:%s", fs.FsType().String(), fs.GetProp("host"), fs.GetProp("port")) + strings.Join(suffix, "")
+}
+
+// GetPath returns the path of url
+func (fs *HDFSFileSysClient) GetPath(uri string) (path string, err error) {
+	// normalize, in case url is provided without prefix
+	uri = NormalizeURI(fs, uri)
+
+	_, path, err = ParseURL(uri)
+	if err != nil {
+		return
+	}
+
+	return path, err
+}
+
+// Connect sets up the WebHDFS http client, with optional kerberos (SPNEGO) auth
+func (fs *HDFSFileSysClient) Connect() (err error) {
+	if fs.GetProp("URL") != "" {
+		u, err := url.Parse(fs.GetProp("URL"))
+		if err != nil {
+			return g.Error(err, "could not parse HDFS URL")
+		}
+
+		if user := u.User.Username(); user != "" {
+			fs.SetProp("USER", user)
+		}
+		if host := u.Hostname(); host != "" {
+			fs.SetProp("HOST", host)
+		}
+		if port := u.Port(); port != "" {
+			fs.SetProp("PORT", port)
+		}
+	}
+
+	if fs.GetProp("HOST") == "" {
+		return g.Error("HDFS host (namenode) is required")
+	} else if fs.GetProp("PORT") == "" {
+		fs.SetProp("PORT", cast.ToString(dbio.TypeFileHDFS.DefPort()))
+	}
+
+	scheme := lo.Ternary(cast.ToBool(fs.GetProp("HTTPS")), "https", "http")
+	fs.baseURL = g.F("%s://%s:%s/webhdfs/v1", scheme, fs.GetProp("HOST"), fs.GetProp("PORT"))
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cast.ToBool(fs.GetProp("INSECURE")) {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	fs.client = &http.Client{
+		Transport: transport,
+		// redirects to the datanodes are followed manually, to send the body
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	if cast.ToBool(fs.GetProp("KERBEROS_ENABLED")) || fs.GetProp("KERBEROS_PRINCIPAL") != "" {
+		fs.krbClient, err = fs.kerberosClient()
+		if err != nil {
+			return g.Error(err, "could not initialize kerberos client")
+		}
+	}
+
+	return nil
+}
+
+// kerberosClient logs in with the keytab, or from the credentials cache
+func (fs *HDFSFileSysClient) kerberosClient() (krbClient *client.Client, err error) {
+	confPath := fs.GetProp("KERBEROS_CONFIG_PATH")
+	if confPath == "" {
+		confPath = lo.Ternary(os.Getenv("KRB5_CONFIG") != "", os.Getenv("KRB5_CONFIG"), "/etc/krb5.conf")
+	}
+
+	krbConf, err := config.Load(confPath)
+	if err != nil {
+		return nil, g.Error(err, "could not load kerberos config: %s", confPath)
+	}
+
+	if ktPath := fs.GetProp("KERBEROS_KEYTAB_PATH"); ktPath != "" {
+		kt, err := keytab.Load(ktPath)
+		if err != nil {
+			return nil, g.Error(err, "could not load keytab: %s", ktPath)
+		}
+
+		// principal can be provided as user@REALM
+		username, realm, _ := strings.Cut(fs.GetProp("KERBEROS_PRINCIPAL"), "@")
+		if val := fs.GetProp("KERBEROS_REALM"); val != "" {
+			realm = val
+		} else if realm == "" {
+			realm = krbConf.LibDefaults.DefaultRealm
+		}
+
+		krbClient = client.NewWithKeytab(username, realm, kt, krbConf, client.DisablePAFXFAST(true))
+		if err = krbClient.Login(); err != nil {
+			return nil, g.Error(err, "could not login with keytab as %s@%s", username, realm)
+		}
+		return krbClient, nil
+	}
+
+	ccachePath := fs.GetProp("KERBEROS_CCACHE_PATH")
+	if ccachePath == "" {
+		ccachePath = strings.TrimPrefix(os.Getenv("KRB5CCNAME"), "FILE:")
+	}
+	if ccachePath == "" {
+		return nil, g.Error("kerberos requires a keytab (kerberos_keytab_path) or a credentials cache (kerberos_ccache_path)")
+	}
+
+	ccache, err := credentials.LoadCCache(ccachePath)
+	if err != nil {
+		return nil, g.Error(err, "could not load kerberos credentials cache: %s", ccachePath)
+	}
+
+	krbClient, err = client.NewFromCCache(ccache, krbConf, client.DisablePAFXFAST(true))
+	if err != nil {
+		return nil, g.Error(err, "could not initialize kerberos client from credentials cache")
+	}
+	return krbClient, nil
+}
+
+// request sends a WebHDFS operation to the namenode. When redirected
+// to a datanode (OPEN, CREATE), the request is sent there with the body.
+func (fs *HDFSFileSysClient) request(method, path, op string, params map[string]string, body io.Reader) (resp *http.Response, err error) {
+	values := url.Values{"op": {op}}
+	if user := fs.GetProp("USER"); user != "" && fs.krbClient == nil {
+		values.Set("user.name", user)
+	}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+
+	reqURL := fs.baseURL + (&url.URL{Path: "/" + strings.TrimPrefix(path, "/")}).EscapedPath() + "?" + values.Encode()
+	req, err := http.NewRequestWithContext(fs.Context().Ctx, method, reqURL, nil)
+	if err != nil {
+		return nil, g.Error(err, "could not construct request")
+	}
+
+	if fs.krbClient != nil {
+		err = spnego.SetSPNEGOHeader(fs.krbClient, req, fs.GetProp("KERBEROS_SPN"))
+		if err != nil {
+			return nil, g.Error(err, "could not set kerberos auth header")
+		}
+	}
+
+	g.Trace("%s %s", method, reqURL)
+	resp, err = fs.client.Do(req)
+	if err != nil {
+		return nil, g.Error(err, "could not %s %s", op, path)
+	}
+
+	if location := resp.Header.Get("Location"); location != "" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		resp.Body.Close()
+
+		req, err = http.NewRequestWithContext(fs.Context().Ctx, method, location, body)
+		if err != nil {
+			return nil, g.Error(err, "could not construct datanode request")
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/octet-stream")
+		}
+
+		g.Trace("%s %s", method, location)
+		resp, err = fs.client.Do(req)
+		if err != nil {
+			return nil, g.Error(err, "could not %s %s", op, path)
+		}
+	} else if body != nil && resp.StatusCode < 300 {
+		resp.Body.Close()
+		return nil, g.Error("could not %s %s: not redirected to a datanode, the data was not sent", op, path)
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBytes, _ := io.ReadAll(resp.Body)
+
+		remoteErr := hdfsRemoteException{}
+		if g.Unmarshal(string(respBytes), &remoteErr) == nil && remoteErr.RemoteException.Exception != "" {
+			return resp, g.Error("%s: %s", remoteErr.RemoteException.Exception, remoteErr.RemoteException.Message)
+		}
+		return resp, g.Error("could not %s %s (status %d): %s", op, path, resp.StatusCode, string(respBytes))
+	}
+
+	return resp, nil
+}
+
+// requestJSON sends a WebHDFS operation and parses the JSON response
+func (fs *HDFSFileSysClient) requestJSON(method, path, op string, params map[string]string, result any) (err error) {
+	resp, err := fs.request(method, path, op, params, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return g.Error(err, "could not read response of %s", op)
+	}
+
+	return g.Unmarshal(string(respBytes), result)
+}
+
+func (fs *HDFSFileSysClient) fileStatus(path string) (status hdfsFileStatus, err error) {
+	result := struct {
+		FileStatus hdfsFileStatus `json:"FileStatus"`
+	}{}
+	err = fs.requestJSON(http.MethodGet, path, "GETFILESTATUS", nil, &result)
+	return result.FileStatus, err
+}
+
+func (fs *HDFSFileSysClient) listStatus(path string) (statuses []hdfsFileStatus, err error) {
+	result := struct {
+		FileStatuses struct {
+			FileStatus []hdfsFileStatus `json:"FileStatus"`
+		} `json:"FileStatuses"`
+	}{}
+	err = fs.requestJSON(http.MethodGet, path, "LISTSTATUS", nil, &result)
+	return result.FileStatuses.FileStatus, err
+}
+
+func (fs *HDFSFileSysClient) makeNode(path string, status hdfsFileStatus) dbio.FileNode {
+	return dbio.FileNode{
+		URI:     g.F("%s%s", fs.Prefix("/"), strings.TrimPrefix(path, "/")),
+		Updated: status.ModificationTime / 1000,
+		Size:    cast.ToUint64(status.Length),
+		Owner:   status.Owner,
+		IsDir:   status.Type == "DIRECTORY",
+	}
+}
+
+// List list objects in path
+func (fs *HDFSFileSysClient) List(url string) (nodes dbio.FileNodes, err error) {
+	path, err := fs.GetPath(url)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+url)
+		return
+	}
+
+	status, err := fs.fileStatus(strings.TrimSuffix(path, "/"))
+	if err != nil {
+		if strings.Contains(err.Error(), "FileNotFoundException") {
+			return nodes, nil
+		}
+		return nodes, g.Error(err, "error getting status of path: %#v", path)
+	} else if status.Type != "DIRECTORY" || !strings.HasSuffix(path, "/") {
+		nodes.Add(fs.makeNode(strings.TrimSuffix(path, "/"), status))
+		return
+	}
+
+	path = strings.TrimSuffix(path, "/")
+	statuses, err := fs.listStatus(path)
+	if err != nil {
+		return nodes, g.Error(err, "error listing path: %#v", path)
+	}
+
+	for _, status := range statuses {
+		nodes.Add(fs.makeNode(path+"/"+status.PathSuffix, status))
+	}
+
+	return
+}
+
+// ListRecursive list objects in path recursively
+func (fs *HDFSFileSysClient) ListRecursive(uri string) (nodes dbio.FileNodes, err error) {
+	path, err := fs.GetPath(uri)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+uri)
+		return
+	}
+
+	pattern, err := makeGlob(NormalizeURI(fs, uri))
+	if err != nil {
+		err = g.Error(err, "Error Parsing url pattern: "+uri)
+		return
+	}
+
+	ts := fs.GetRefTs().Unix()
+
+	path = strings.TrimSuffix(path, "/")
+	if pattern != nil {
+		// list from the folder of the glob
+		path = strings.TrimSuffix(strings.Split(path, "*")[0], "/")
+		if i := strings.LastIndex(path, "/"); i > -1 {
+			path = path[:i]
+		}
+	}
+
+	status, err := fs.fileStatus(path)
+	if err != nil {
+		if strings.Contains(err.Error(), "FileNotFoundException") {
+			return nodes, nil
+		}
+		return nodes, g.Error(err, "error getting status of path: %#v", path)
+	} else if status.Type != "DIRECTORY" {
+		nodes.AddWhere(pattern, ts, fs.makeNode(path, status))
+		return
+	}
+
+	statuses, err := fs.listStatus(path)
+	if err != nil {
+		return nodes, g.Error(err, "error listing path: %#v", path)
+	}
+
+	for _, status := range statuses {
+		node := fs.makeNode(path+"/"+status.PathSuffix, status)
+		if node.IsDir {
+			subNodes, err := fs.ListRecursive(node.URI + "/")
+			if err != nil {
+				return nil, g.Error(err, "error listing sub path")
+			}
+			nodes.AddWhere(pattern, ts, subNodes...)
+		} else {
+			nodes.AddWhere(pattern, ts, node)
+		}
+	}
+
+	return
+}
+
+// delete deletes the path, recursively
+func (fs *HDFSFileSysClient) delete(urlStr string) (err error) {
+	path, err := fs.GetPath(urlStr)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+urlStr)
+		return
+	}
+
+	result := struct {
+		Boolean bool `json:"boolean"`
+	}{}
+	params := map[string]string{"recursive": "true"}
+	err = fs.requestJSON(http.MethodDelete, strings.TrimSuffix(path, "/"), "DELETE", params, &result)
+	if err != nil {
+		return g.Error(err, "error deleting path")
+	}
+	return nil
+}
+
+// MkdirAll creates child directories
+func (fs *HDFSFileSysClient) MkdirAll(uri string) (err error) {
+	path, err := fs.GetPath(uri)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+uri)
+		return
+	}
+
+	result := struct {
+		Boolean bool `json:"boolean"`
+	}{}
+	err = fs.requestJSON(http.MethodPut, path, "MKDIRS", nil, &result)
+	if err != nil {
+		return g.Error(err, "could not create directory %s", path)
+	}
+	return nil
+}
+
+// Write uploads a file, replacing an existing one.
+// Parent directories are created as needed.
+func (fs *HDFSFileSysClient) Write(urlStr string, reader io.Reader) (bw int64, err error) {
+	path, err := fs.GetPath(urlStr)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+urlStr)
+		return
+	}
+
+	// manage concurrency
+	defer fs.Context().Wg.Write.Done()
+	fs.Context().Wg.Write.Add()
+
+	type copyResult struct {
+		n   int64
+		err error
+	}
+
+	pr, pw := io.Pipe()
+	copied := make(chan copyResult, 1)
+	go func() {
+		n, err := io.Copy(pw, reader)
+		pw.CloseWithError(err)
+		copied <- copyResult{n, err}
+	}()
+
+	params := map[string]string{"overwrite": "true"}
+	resp, err := fs.request(http.MethodPut, path, "CREATE", params, pr)
+	pr.CloseWithError(err) // stops the copy if the body was not fully sent
+	result := <-copied
+	bw = result.n
+	if err != nil {
+		return bw, g.Error(err, "could not write to %s", path)
+	}
+	resp.Body.Close()
+
+	if result.err != nil {
+		return bw, g.Error(result.err, "could not write to %s", path)
+	}
+
+	return bw, nil
+}
+
+// GetReader return a reader for the given path
+func (fs *HDFSFileSysClient) GetReader(urlStr string) (reader io.Reader, err error) {
+	path, err := fs.GetPath(urlStr)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+urlStr)
+		return
+	}
+
+	resp, err := fs.request(http.MethodGet, path, "OPEN", nil, nil)
+	if err != nil {
+		return nil, g.Error(err, "could not open %s", path)
+	}
+
+	return resp.Body, nil
+}
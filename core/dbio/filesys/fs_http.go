@@ -83,8 +83,12 @@ func (fs *HTTPFileSysClient) GetPath(uri string) (path string, err error) {
 }
 
 func (fs *HTTPFileSysClient) doGet(url string) (resp *http.Response, err error) {
+	return fs.doRequest("GET", url, nil)
+}
+
+func (fs *HTTPFileSysClient) doRequest(method, url string, headers map[string]string) (resp *http.Response, err error) {
 	// Request the HTML page.
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
 		return nil, g.Error(err, "could not construct request")
 	}
@@ -96,6 +100,9 @@ func (fs *HTTPFileSysClient) doGet(url string) (resp *http.Response, err error)
 	req.Header.Set("DNT", "1")
 	req.Header.Set("Upgrade-Insecure-Requests", "1")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.36")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 
 	// req.Header.Write(os.Stdout)
 	g.Trace(url)
@@ -136,6 +143,7 @@ func (fs *HTTPFileSysClient) List(url string) (nodes dbio.FileNodes, err error)
 	if !strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
 		// We have no easy way of determining if the url is a page with link
 		// or if the url is a body of data. Fow now, return url if not "text/html"
+		resp.Body.Close()
 		nodes.Add(dbio.FileNode{URI: url})
 		return
 	}
@@ -202,6 +210,35 @@ func (fs *HTTPFileSysClient) GetReader(url string) (reader io.Reader, err error)
 	return resp.Body, nil
 }
 
+// objectSize returns the size of the file, if the server accepts range requests
+func (fs *HTTPFileSysClient) objectSize(url string) (size int64, err error) {
+	resp, err := fs.doRequest("HEAD", url, nil)
+	if err != nil {
+		return 0, g.Error(err, "could not get HTTP file info")
+	}
+	resp.Body.Close()
+
+	if resp.Header.Get("Accept-Ranges") != "bytes" || resp.ContentLength < 0 {
+		return 0, g.Error("server does not accept range requests for %s", url)
+	}
+
+	return resp.ContentLength, nil
+}
+
+// readRange returns a reader of the byte range of the file
+func (fs *HTTPFileSysClient) readRange(url string, offset, length int64) (reader io.ReadCloser, err error) {
+	header := map[string]string{"Range": g.F("bytes=%d-%d", offset, offset+length-1)}
+	resp, err := fs.doRequest("GET", url, header)
+	if err != nil {
+		return nil, g.Error(err, "could not get HTTP file range")
+	} else if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, g.Error("server did not return the range for %s", url)
+	}
+
+	return resp.Body, nil
+}
+
 // Write uploads an HTTP file
 func (fs *HTTPFileSysClient) Write(urlStr string, reader io.Reader) (bw int64, err error) {
 	if strings.HasPrefix(urlStr, "https://docs.google.com/spreadsheets/d") {
@@ -0,0 +1,424 @@
+package filesys
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// icebergMetadataFileRegex matches metadata files such as
+// `v3.metadata.json` or `00003-<uuid>.metadata.json`
+var icebergMetadataFileRegex = regexp.MustCompile(`(?:^|/)v?(\d+)(?:-[^/]*)?\.metadata\.json$`)
+
+// IcebergMetadata is the table metadata of an Iceberg table.
+// See https://iceberg.apache.org/spec/#table-metadata
+type IcebergMetadata struct {
+	FormatVersion     int                     `json:"format-version"`
+	TableUUID         string                  `json:"table-uuid"`
+	Location          string                  `json:"location"`
+	CurrentSnapshotID *int64                  `json:"current-snapshot-id"`
+	Snapshots         []IcebergSnapshot       `json:"snapshots"`
+	DefaultSpecID     int                     `json:"default-spec-id"`
+	PartitionSpecs    []IcebergPartitionSpec  `json:"partition-specs"`
+	PartitionSpec     []IcebergPartitionField `json:"partition-spec"` // v1
+}
+
+// IcebergSnapshot is a snapshot of an Iceberg table
+type IcebergSnapshot struct {
+	SnapshotID   int64             `json:"snapshot-id"`
+	TimestampMs  int64             `json:"timestamp-ms"`
+	ManifestList string            `json:"manifest-list"`
+	Manifests    []string          `json:"manifests"` // v1, instead of a manifest list
+	Summary      map[string]string `json:"summary"`
+}
+
+// IcebergPartitionSpec is a partition spec of an Iceberg table
+type IcebergPartitionSpec struct {
+	SpecID int                     `json:"spec-id"`
+	Fields []IcebergPartitionField `json:"fields"`
+}
+
+// IcebergPartitionField is a partition field of an Iceberg table
+type IcebergPartitionField struct {
+	Name      string `json:"name"`
+	Transform string `json:"transform"`
+	SourceID  int    `json:"source-id"`
+	FieldID   int    `json:"field-id"`
+}
+
+// IcebergDataFile is a data file of an Iceberg snapshot
+type IcebergDataFile struct {
+	Path            string
+	Format          string
+	RecordCount     int64
+	Size            int64
+	SpecID          int
+	PartitionValues map[string]*string
+}
+
+// IcebergTable reads the metadata of an Iceberg table
+type IcebergTable struct {
+	URL      string // table url, on the file system
+	Metadata IcebergMetadata
+	fs       FileSysClient
+}
+
+// NewIcebergTable reads the table metadata of the Iceberg table at the url,
+// which is either the table folder or a metadata json file
+func NewIcebergTable(fs FileSysClient, tableURL string) (t *IcebergTable, err error) {
+	tableURL = strings.TrimSuffix(NormalizeURI(fs, tableURL), "/")
+	t = &IcebergTable{URL: tableURL, fs: fs}
+
+	metadataURL := tableURL
+	if strings.HasSuffix(tableURL, ".metadata.json") {
+		// the table folder is the parent of the metadata folder
+		i := strings.LastIndex(tableURL, "/metadata/")
+		if i == -1 {
+			return nil, g.Error("iceberg metadata file is not in a metadata folder: %s", tableURL)
+		}
+		t.URL = tableURL[:i]
+	} else if metadataURL, err = t.latestMetadataURL(); err != nil {
+		return nil, g.Error(err, "could not find iceberg metadata at %s", tableURL)
+	}
+
+	data, err := t.read(metadataURL)
+	if err != nil {
+		return nil, g.Error(err, "could not read iceberg metadata %s", metadataURL)
+	} else if err = g.Unmarshal(string(data), &t.Metadata); err != nil {
+		return nil, g.Error(err, "could not parse iceberg metadata %s", metadataURL)
+	}
+
+	// v1 tables may have a single partition spec
+	if len(t.Metadata.PartitionSpecs) == 0 && len(t.Metadata.PartitionSpec) > 0 {
+		t.Metadata.PartitionSpecs = []IcebergPartitionSpec{{SpecID: 0, Fields: t.Metadata.PartitionSpec}}
+	}
+
+	g.Debug("read iceberg metadata %s [format-version=%d snapshots=%d]", metadataURL, t.Metadata.FormatVersion, len(t.Metadata.Snapshots))
+
+	return t, nil
+}
+
+// latestMetadataURL returns the url of the latest metadata json file,
+// using `version-hint.text` if present
+func (t *IcebergTable) latestMetadataURL() (string, error) {
+	nodes, err := t.fs.Self().List(t.URL + "/metadata/")
+	if err != nil {
+		return "", g.Error(err, "could not list metadata folder")
+	}
+
+	latestURL := ""
+	latestVersion := int64(-1)
+	for _, uri := range nodes.URIs() {
+		if strings.HasSuffix(uri, "/version-hint.text") {
+			if data, err := t.read(uri); err == nil {
+				hintURL := g.F("%s/metadata/v%s.metadata.json", t.URL, strings.TrimSpace(string(data)))
+				if lo.Contains(nodes.URIs(), hintURL) {
+					return hintURL, nil
+				}
+			}
+		} else if matches := icebergMetadataFileRegex.FindStringSubmatch(uri); len(matches) == 2 {
+			if version := cast.ToInt64(strings.TrimLeft(matches[1], "0")); version > latestVersion {
+				latestURL, latestVersion = uri, version
+			}
+		}
+	}
+
+	if latestURL == "" {
+		return "", g.Error("no metadata json file found")
+	}
+
+	return latestURL, nil
+}
+
+// Snapshot returns the snapshot with the id, the latest snapshot as of the
+// timestamp, or the current snapshot when both are empty
+func (t *IcebergTable) Snapshot(snapshotID int64, asOf time.Time) (snapshot *IcebergSnapshot, err error) {
+	switch {
+	case snapshotID != 0:
+		for i, s := range t.Metadata.Snapshots {
+			if s.SnapshotID == snapshotID {
+				return &t.Metadata.Snapshots[i], nil
+			}
+		}
+		return nil, g.Error("iceberg snapshot not found: %d", snapshotID)
+	case !asOf.IsZero():
+		for i, s := range t.Metadata.Snapshots {
+			if s.TimestampMs <= asOf.UnixMilli() && (snapshot == nil || s.TimestampMs > snapshot.TimestampMs) {
+				snapshot = &t.Metadata.Snapshots[i]
+			}
+		}
+		if snapshot == nil {
+			return nil, g.Error("no iceberg snapshot as of %s", asOf.Format(time.RFC3339))
+		}
+		return snapshot, nil
+	case t.Metadata.CurrentSnapshotID != nil && *t.Metadata.CurrentSnapshotID != -1:
+		return t.Snapshot(*t.Metadata.CurrentSnapshotID, time.Time{})
+	}
+
+	return nil, nil // empty table
+}
+
+// DataFiles returns the live data files of the snapshot
+func (t *IcebergTable) DataFiles(snapshot *IcebergSnapshot) (dataFiles []IcebergDataFile, err error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+
+	type manifest struct {
+		path    string
+		specID  int
+		content int // 0 for data, 1 for deletes
+	}
+
+	manifests := []manifest{}
+	if snapshot.ManifestList != "" {
+		records, err := t.readAvro(snapshot.ManifestList)
+		if err != nil {
+			return nil, g.Error(err, "could not read manifest list %s", snapshot.ManifestList)
+		}
+
+		for _, record := range records {
+			manifests = append(manifests, manifest{
+				path:    cast.ToString(icebergValue(record["manifest_path"])),
+				specID:  cast.ToInt(icebergValue(record["partition_spec_id"])),
+				content: cast.ToInt(icebergValue(record["content"])),
+			})
+		}
+	} else {
+		for _, path := range snapshot.Manifests {
+			manifests = append(manifests, manifest{path: path, specID: t.Metadata.DefaultSpecID})
+		}
+	}
+
+	for _, m := range manifests {
+		records, err := t.readAvro(m.path)
+		if err != nil {
+			return nil, g.Error(err, "could not read manifest %s", m.path)
+		}
+
+		for _, record := range records {
+			if status := cast.ToInt(icebergValue(record["status"])); status == 2 {
+				continue // deleted
+			}
+
+			dataFile, ok := record["data_file"].(map[string]any)
+			if !ok {
+				return nil, g.Error("invalid manifest entry in %s", m.path)
+			}
+
+			// row-level deletes are not applied, the deleted rows would be returned
+			if content := cast.ToInt(icebergValue(dataFile["content"])); content != 0 || m.content != 0 {
+				return nil, g.Error("iceberg snapshot %d has row-level delete files, which are not supported (%s)", snapshot.SnapshotID, cast.ToString(icebergValue(dataFile["file_path"])))
+			}
+
+			df := IcebergDataFile{
+				Path:            cast.ToString(icebergValue(dataFile["file_path"])),
+				Format:          strings.ToLower(cast.ToString(icebergValue(dataFile["file_format"]))),
+				RecordCount:     cast.ToInt64(icebergValue(dataFile["record_count"])),
+				Size:            cast.ToInt64(icebergValue(dataFile["file_size_in_bytes"])),
+				SpecID:          m.specID,
+				PartitionValues: map[string]*string{},
+			}
+
+			partition, _ := dataFile["partition"].(map[string]any)
+			for _, field := range t.partitionFields(m.specID) {
+				df.PartitionValues[field.Name] = icebergPartitionValue(field, icebergValue(partition[field.Name]))
+			}
+
+			dataFiles = append(dataFiles, df)
+		}
+	}
+
+	return dataFiles, nil
+}
+
+func (t *IcebergTable) partitionFields(specID int) []IcebergPartitionField {
+	for _, spec := range t.Metadata.PartitionSpecs {
+		if spec.SpecID == specID {
+			return spec.Fields
+		}
+	}
+	return nil
+}
+
+// resolve returns the file system url of a path of the metadata. Paths
+// under the table location are resolved from the table url, so that
+// relocated tables can be read
+func (t *IcebergTable) resolve(uri string) string {
+	if location := strings.TrimSuffix(t.Metadata.Location, "/"); location != "" && strings.HasPrefix(uri, location+"/") {
+		return t.URL + strings.TrimPrefix(uri, location)
+	} else if strings.HasPrefix(uri, "file:/") && !strings.HasPrefix(uri, "file://") {
+		return "file://" + strings.TrimPrefix(uri, "file:")
+	}
+	return uri
+}
+
+func (t *IcebergTable) read(uri string) (data []byte, err error) {
+	reader, err := t.fs.Self().GetReader(t.resolve(uri))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+// readAvro reads the records of an avro file
+func (t *IcebergTable) readAvro(uri string) (records []map[string]any, err error) {
+	data, err := t.read(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := iop.NewAvroStream(bytes.NewReader(data), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for a.Reader.Scan() {
+		datum, err := a.Reader.Read()
+		if err != nil {
+			return nil, g.Error(err, "could not read avro record")
+		}
+		if record, ok := datum.(map[string]any); ok {
+			records = append(records, record)
+		}
+	}
+
+	return records, a.Reader.Err()
+}
+
+// icebergValue unwraps an avro union value, such as `{"long": 1}`
+func icebergValue(val any) any {
+	if m, ok := val.(map[string]any); ok && len(m) == 1 {
+		for _, v := range m {
+			return v
+		}
+	}
+	return val
+}
+
+// icebergPartitionValue returns the partition value as a string, with
+// date transforms as readable values (e.g. `2024-01` for month)
+func icebergPartitionValue(field IcebergPartitionField, val any) *string {
+	if val == nil {
+		return nil
+	}
+
+	epoch := time.Unix(0, 0).UTC()
+	str := cast.ToString(val)
+	switch v := val.(type) {
+	case time.Time:
+		str = v.UTC().Format("2006-01-02 15:04:05.000000")
+		if field.Transform == "day" || v.Equal(v.Truncate(24*time.Hour)) {
+			str = v.UTC().Format("2006-01-02") // date
+		}
+	case []byte:
+		str = string(v)
+	default:
+		switch field.Transform {
+		case "year":
+			str = cast.ToString(1970 + cast.ToInt(val))
+		case "month":
+			str = epoch.AddDate(0, cast.ToInt(val), 0).Format("2006-01")
+		case "day":
+			str = epoch.AddDate(0, 0, cast.ToInt(val)).Format("2006-01-02")
+		case "hour":
+			str = epoch.Add(time.Duration(cast.ToInt64(val)) * time.Hour).Format("2006-01-02-15")
+		}
+	}
+
+	return &str
+}
+
+// readIcebergDataflow reads the data files of an Iceberg table snapshot.
+// The snapshot is chosen with the `snapshot_id` or `snapshot_timestamp`
+// properties, and the data files are pruned with `partition_filter`,
+// which applies to the partition field names (e.g. `created_at_day`)
+func readIcebergDataflow(fs FileSysClient, tableURL string, cfg FileStreamConfig) (df *iop.Dataflow, err error) {
+	table, err := NewIcebergTable(fs, tableURL)
+	if err != nil {
+		return nil, g.Error(err, "could not read iceberg table")
+	}
+
+	var asOf time.Time
+	if val := fs.GetProp("SNAPSHOT_TIMESTAMP"); val != "" {
+		if ms, err := cast.ToInt64E(val); err == nil {
+			asOf = time.UnixMilli(ms)
+		} else if asOf, err = iop.NewStreamProcessor().ParseTime(val); err != nil {
+			return nil, g.Error(err, "invalid snapshot_timestamp: %s", val)
+		}
+	}
+
+	snapshot, err := table.Snapshot(cast.ToInt64(fs.GetProp("SNAPSHOT_ID")), asOf)
+	if err != nil {
+		return nil, g.Error(err, "could not resolve iceberg snapshot")
+	} else if snapshot == nil {
+		return nil, g.Error("iceberg table %s has no snapshot", tableURL)
+	}
+
+	dataFiles, err := table.DataFiles(snapshot)
+	if err != nil {
+		return nil, g.Error(err, "could not read iceberg data files")
+	}
+	total := len(dataFiles)
+
+	// partition pruning
+	if filter := fs.GetProp("PARTITION_FILTER"); filter != "" && len(dataFiles) > 0 {
+		names := []string{}
+		for _, spec := range table.Metadata.PartitionSpecs {
+			for _, field := range spec.Fields {
+				if !g.In(field.Name, names...) {
+					names = append(names, field.Name)
+				}
+			}
+		}
+
+		partitions := lo.Map(dataFiles, func(df IcebergDataFile, i int) map[string]*string { return df.PartitionValues })
+		pf, err := newPartitionFilter(filter, inferPartitionColumns(names, partitions))
+		if err != nil {
+			return nil, err
+		}
+
+		pruned := []IcebergDataFile{}
+		for _, dataFile := range dataFiles {
+			if match, err := pf.Match(dataFile.PartitionValues); err != nil {
+				return nil, err
+			} else if match {
+				pruned = append(pruned, dataFile)
+			}
+		}
+		dataFiles = pruned
+	}
+
+	g.Debug("reading iceberg table %s [snapshot=%d files=%d/%d]", tableURL, snapshot.SnapshotID, len(dataFiles), total)
+	if len(dataFiles) == 0 {
+		return nil, g.Error("no iceberg data files to read for snapshot %d", snapshot.SnapshotID)
+	}
+
+	formats := lo.Uniq(lo.Map(dataFiles, func(df IcebergDataFile, i int) string { return df.Format }))
+	if len(formats) > 1 || !g.In(formats[0], "parquet", "avro", "orc") {
+		return nil, g.Error("unsupported iceberg data file format: %s", strings.Join(formats, ", "))
+	}
+
+	nodes := dbio.FileNodes{}
+	for _, dataFile := range dataFiles {
+		nodes = append(nodes, dbio.FileNode{URI: table.resolve(dataFile.Path), Size: cast.ToUint64(dataFile.Size)})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].URI < nodes[j].URI })
+
+	// data files are read after this returns, so the props are not reset.
+	// Partitions are resolved from the manifests, not the folder names
+	fs.SetProp("FORMAT", formats[0])
+	fs.SetProp("HIVE_PARTITIONING", "false")
+	fs.SetProp("PARTITION_FILTER", "")
+
+	return GetDataflow(fs.Self(), nodes, cfg)
+}
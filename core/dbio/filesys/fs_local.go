@@ -114,6 +114,7 @@ func (fs *LocalFileSysClient) GetDatastream(uri string) (ds *iop.Datastream, err
 	ds.SafeInference = true
 	ds.SetMetadata(fs.GetProp("METADATA"))
 	ds.Metadata.StreamURL.Value = path
+	ds.Partitions = fs.partitionColumns()
 	ds.SetConfig(fs.Props())
 
 	// set selectFields for pruning at source
@@ -150,6 +151,12 @@ func (fs *LocalFileSysClient) GetDatastream(uri string) (ds *iop.Datastream, err
 			err = ds.ConsumeParquetReaderSeeker(file)
 		case FileTypeAvro:
 			err = ds.ConsumeAvroReaderSeeker(file)
+		case FileTypeOrc:
+			err = ds.ConsumeOrcReaderSeeker(file)
+		case FileTypeArrow:
+			err = ds.ConsumeArrowReaderSeeker(file)
+		case FileTypeFixedWidth:
+			err = ds.ConsumeFixedWidthReader(bufio.NewReader(file))
 		case FileTypeSAS:
 			err = ds.ConsumeSASReaderSeeker(file)
 		case FileTypeExcel:
@@ -187,6 +194,25 @@ func (fs *LocalFileSysClient) GetWriter(uri string) (writer io.Writer, err error
 	return
 }
 
+// move renames the file
+func (fs *LocalFileSysClient) move(srcURI, tgtURI string) (err error) {
+	srcPath, err := fs.GetPath(srcURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+srcURI)
+	}
+	tgtPath, err := fs.GetPath(tgtURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+tgtURI)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(tgtPath), 0777); err != nil {
+		return g.Error(err, "could not create directory of %s", tgtPath)
+	} else if err = os.Rename(srcPath, tgtPath); err != nil {
+		return g.Error(err, "could not move %s to %s", srcPath, tgtPath)
+	}
+	return nil
+}
+
 // MkdirAll creates child directories
 func (fs *LocalFileSysClient) MkdirAll(uri string) (err error) {
 	path, err := fs.GetPath(uri)
@@ -0,0 +1,827 @@
+package filesys
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// PartitionGranularity is the granularity of date partitions
+type PartitionGranularity string
+
+const (
+	PartitionGranularityYear  PartitionGranularity = "year"
+	PartitionGranularityMonth PartitionGranularity = "month"
+	PartitionGranularityDay   PartitionGranularity = "day"
+	PartitionGranularityHour  PartitionGranularity = "hour"
+)
+
+// PartitionField is a Hive-style partition field. It is either a column,
+// whose values are used as is, or a date column from which the date parts
+// are derived, down to the granularity (e.g. `year=2024/month=05/day=01`)
+type PartitionField struct {
+	Column      string               `json:"column,omitempty" yaml:"column,omitempty"`
+	Date        string               `json:"date,omitempty" yaml:"date,omitempty"`
+	Granularity PartitionGranularity `json:"granularity,omitempty" yaml:"granularity,omitempty"`
+}
+
+// ParsePartitionBy parses the `partition_by` option, which is either a list of
+// columns and date derivations (`[region, {date: created_at, granularity: day}]`),
+// a single date derivation, or a comma separated list of columns
+func ParsePartitionBy(val string) (fields []PartitionField, err error) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return nil, nil
+	}
+
+	var items []any
+	switch {
+	case strings.HasPrefix(val, "["):
+		if err = g.Unmarshal(val, &items); err != nil {
+			return nil, g.Error(err, "could not parse partition_by: %s", val)
+		}
+	case strings.HasPrefix(val, "{"):
+		item := g.M()
+		if err = g.Unmarshal(val, &item); err != nil {
+			return nil, g.Error(err, "could not parse partition_by: %s", val)
+		}
+		items = append(items, item)
+	default:
+		for _, col := range strings.Split(val, ",") {
+			items = append(items, strings.TrimSpace(col))
+		}
+	}
+
+	for _, item := range items {
+		field := PartitionField{}
+		switch v := item.(type) {
+		case string:
+			field.Column = v
+		case map[string]any:
+			if err = g.Unmarshal(g.Marshal(v), &field); err != nil {
+				return nil, g.Error(err, "could not parse partition field: %s", g.Marshal(v))
+			}
+		default:
+			return nil, g.Error("invalid partition field: %#v", item)
+		}
+
+		if field.Date != "" {
+			field.Granularity = PartitionGranularity(strings.ToLower(string(field.Granularity)))
+			if field.Granularity == "" {
+				field.Granularity = PartitionGranularityDay
+			} else if !g.In(field.Granularity, PartitionGranularityYear, PartitionGranularityMonth, PartitionGranularityDay, PartitionGranularityHour) {
+				return nil, g.Error("invalid partition granularity for %s: %s", field.Date, field.Granularity)
+			}
+		} else if field.Column == "" {
+			return nil, g.Error("partition field requires a column or a date: %s", g.Marshal(item))
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// ColumnName returns the name of the source column of the field
+func (pf PartitionField) ColumnName() string {
+	return lo.Ternary(pf.Date != "", pf.Date, pf.Column)
+}
+
+// Keys returns the partition keys of the field
+func (pf PartitionField) Keys() []string {
+	if pf.Date == "" {
+		return []string{pf.Column}
+	}
+
+	keys := []string{"year", "month", "day", "hour"}
+	switch pf.Granularity {
+	case PartitionGranularityYear:
+		return keys[:1]
+	case PartitionGranularityMonth:
+		return keys[:2]
+	case PartitionGranularityHour:
+		return keys[:4]
+	}
+	return keys[:3]
+}
+
+// Values returns the partition path values of the field for a value
+func (pf PartitionField) Values(sp *iop.StreamProcessor, i int, val any) []string {
+	keys := pf.Keys()
+	values := make([]string, len(keys))
+
+	if pf.Date == "" {
+		values[0] = iop.HiveDefaultPartition
+		if val != nil {
+			if sVal := sp.CastToString(i, val); sVal != "" {
+				values[0] = iop.HivePartitionEscape(sVal)
+			}
+		}
+		return values
+	}
+
+	t, err := cast.ToTimeE(val)
+	if val == nil || err != nil {
+		for j := range values {
+			values[j] = iop.HiveDefaultPartition
+		}
+		return values
+	}
+
+	parts := []string{t.Format("2006"), t.Format("01"), t.Format("02"), t.Format("15")}
+	copy(values, parts)
+	return values
+}
+
+// partitionStream is an open stream of rows of a partition
+type partitionStream struct {
+	rows     chan []any
+	lastUsed int64
+}
+
+// partitionRouter routes the rows of a datastream into
+// datastreams per partition, keeping a bounded number open
+type partitionRouter struct {
+	fields   []PartitionField
+	url      string
+	maxOpen  int
+	props    map[string]string
+	open     map[string]*partitionStream
+	counters map[string]*int64 // file counters per partition folder
+	used     int64
+
+	// start starts writing the datastream of a partition folder
+	start func(ds *iop.Datastream, nextFileURL func(suffix string) string)
+}
+
+func newPartitionRouter(url string, fields []PartitionField, maxOpen int, props map[string]string, start func(ds *iop.Datastream, nextFileURL func(suffix string) string)) *partitionRouter {
+	return &partitionRouter{
+		fields:   fields,
+		url:      url,
+		maxOpen:  lo.Ternary(maxOpen > 0, maxOpen, 50),
+		props:    props,
+		open:     map[string]*partitionStream{},
+		counters: map[string]*int64{},
+		start:    start,
+	}
+}
+
+// route reads the rows of the datastream into the partitions
+func (pr *partitionRouter) route(ds *iop.Datastream) (err error) {
+	defer pr.closeAll()
+
+	var fieldIndexes []int
+	var keepIndexes []int
+	var keepColumns iop.Columns
+
+	for batch := range ds.BatchChan {
+		if batch.ColumnsChanged() || batch.IsFirst() {
+			// new columns, new files
+			pr.closeAll()
+
+			colMap := batch.Columns.FieldMap(true)
+			fieldIndexes = make([]int, len(pr.fields))
+			partitionCols := map[int]bool{}
+			for i, field := range pr.fields {
+				index, ok := colMap[strings.ToLower(field.ColumnName())]
+				if !ok {
+					return g.Error("partition column not found: %s", field.ColumnName())
+				}
+				fieldIndexes[i] = index
+				if field.Date == "" {
+					partitionCols[index] = true // partition columns are not written in files
+				}
+			}
+
+			keepIndexes = []int{}
+			keepColumns = iop.Columns{}
+			for i, col := range batch.Columns {
+				if !partitionCols[i] {
+					col.Position = len(keepColumns) + 1
+					keepIndexes = append(keepIndexes, i)
+					keepColumns = append(keepColumns, col)
+				}
+			}
+		}
+
+		for row := range batch.Rows {
+			pathParts := []string{}
+			for i, field := range pr.fields {
+				index := fieldIndexes[i]
+				val := lo.Ternary(index < len(row), row[index], nil)
+				for j, value := range field.Values(ds.Sp, index, val) {
+					pathParts = append(pathParts, field.Keys()[j]+"="+value)
+				}
+			}
+
+			newRow := make([]any, len(keepIndexes))
+			for i, index := range keepIndexes {
+				if index < len(row) {
+					newRow[i] = row[index]
+				}
+			}
+
+			ps := pr.get(ds.Context.Ctx, strings.Join(pathParts, "/"), keepColumns)
+			select {
+			case <-ds.Context.Ctx.Done():
+				return ds.Context.Err()
+			case ps.rows <- newRow:
+			}
+		}
+	}
+
+	return nil
+}
+
+// get returns the open stream of the partition, opening it if needed
+func (pr *partitionRouter) get(ctx context.Context, partPath string, columns iop.Columns) *partitionStream {
+	pr.used++
+	if ps, ok := pr.open[partPath]; ok {
+		ps.lastUsed = pr.used
+		return ps
+	}
+
+	// close the least recently used
+	if len(pr.open) >= pr.maxOpen {
+		lruPath := ""
+		for path, ps := range pr.open {
+			if lruPath == "" || ps.lastUsed < pr.open[lruPath].lastUsed {
+				lruPath = path
+			}
+		}
+		pr.close(lruPath)
+	}
+
+	ps := &partitionStream{rows: make(chan []any, 100), lastUsed: pr.used}
+	pr.open[partPath] = ps
+
+	counter, ok := pr.counters[partPath]
+	if !ok {
+		counter = new(int64)
+		pr.counters[partPath] = counter
+	}
+
+	partURL := pr.url + "/" + partPath
+	nextFileURL := func(suffix string) string {
+		return fmt.Sprintf("%s/part-%04d%s", partURL, atomic.AddInt64(counter, 1), suffix)
+	}
+
+	nextFunc := func(it *iop.Iterator) bool {
+		row, ok := <-ps.rows
+		if ok {
+			it.Row = row
+		}
+		return ok
+	}
+
+	pds := iop.NewDatastreamIt(ctx, columns, nextFunc)
+	pds.Inferred = true
+	pds.SetConfig(pr.props)
+
+	g.Trace("opened partition %s", partURL)
+	pr.start(pds, nextFileURL)
+
+	return ps
+}
+
+func (pr *partitionRouter) close(partPath string) {
+	if ps, ok := pr.open[partPath]; ok {
+		close(ps.rows)
+		delete(pr.open, partPath)
+	}
+}
+
+func (pr *partitionRouter) closeAll() {
+	for partPath := range pr.open {
+		pr.close(partPath)
+	}
+}
+
+// PartitionColumns returns the typed Hive partition columns parsed from the
+// `key=value` folders of the file nodes, below the base url
+func PartitionColumns(nodes dbio.FileNodes, baseURL string) (columns iop.Columns) {
+	baseKeys, _ := iop.HivePartitions(strings.TrimSuffix(baseURL, "/") + "/")
+
+	names := []string{}
+	partitions := []map[string]*string{}
+	for _, uri := range nodes.URIs() {
+		if strings.HasSuffix(uri, "/") {
+			continue
+		}
+
+		keys, values := iop.HivePartitions(uri)
+		for _, key := range keys {
+			if g.In(key, baseKeys...) {
+				delete(values, key)
+			} else if !g.In(key, names...) {
+				names = append(names, key)
+			}
+		}
+		partitions = append(partitions, values)
+	}
+
+	return inferPartitionColumns(names, partitions)
+}
+
+// inferPartitionColumns infers the types of the partition columns from their values
+func inferPartitionColumns(names []string, partitions []map[string]*string) (columns iop.Columns) {
+	if len(names) == 0 {
+		return nil
+	}
+
+	for _, name := range names {
+		columns = append(columns, iop.Column{Name: name, Type: iop.StringType, Position: len(columns) + 1})
+	}
+
+	data := iop.NewDataset(columns)
+	for _, values := range partitions {
+		row := make([]any, len(names))
+		for i, name := range names {
+			if val := values[name]; val != nil {
+				row[i] = *val
+			}
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	data.InferColumnTypes()
+
+	return data.Columns
+}
+
+// FilterPartitions returns the file nodes whose partition values match the
+// filter expression (e.g. `dt >= '2024-01-01' and region in ('east', 'west')`).
+// Nodes missing a partition key of the expression are kept.
+func FilterPartitions(nodes dbio.FileNodes, columns iop.Columns, filter string) (filtered dbio.FileNodes, err error) {
+	pf, err := newPartitionFilter(filter, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		if node.IsDir {
+			filtered = append(filtered, node)
+			continue
+		}
+
+		_, values := iop.HivePartitions(node.URI)
+		if match, err := pf.Match(values); err != nil {
+			return nil, err
+		} else if match {
+			filtered = append(filtered, node)
+		}
+	}
+
+	g.Debug("partition_filter kept %d of %d files", len(filtered), len(nodes))
+
+	return filtered, nil
+}
+
+// whereFilter filters the rows of file streams with the `where` expression,
+// which has the partition_filter syntax
+type whereFilter struct {
+	where string
+	expr  *filterNode
+	keys  []string
+}
+
+func newWhereFilter(where string) (wf *whereFilter, err error) {
+	expr, err := parsePartitionFilter(where)
+	if err != nil {
+		return nil, g.Error(err, "could not parse where: %s", where)
+	}
+
+	wf = &whereFilter{where: where, expr: expr}
+	var addKeys func(node *filterNode)
+	addKeys = func(node *filterNode) {
+		if node.key != "" && !lo.Contains(wf.keys, strings.ToLower(node.key)) {
+			wf.keys = append(wf.keys, strings.ToLower(node.key))
+		}
+		for _, child := range node.children {
+			addKeys(child)
+		}
+	}
+	addKeys(expr)
+
+	return wf, nil
+}
+
+// parquetFilters returns the comparisons of the top level `and`, which all
+// rows must match, to skip the parquet row groups with their statistics
+func (wf *whereFilter) parquetFilters() (filters []iop.ParquetFilter) {
+	nodes := []*filterNode{wf.expr}
+	if wf.expr.op == "and" {
+		nodes = wf.expr.children
+	}
+
+	for _, node := range nodes {
+		if g.In(node.op, "=", ">", ">=", "<", "<=") && node.literals[0] != nil {
+			filters = append(filters, iop.ParquetFilter{Column: node.key, Op: node.op, Value: *node.literals[0]})
+		}
+	}
+	return filters
+}
+
+// selectFields returns the selected fields with the keys of the expression,
+// so that they are read to be evaluated
+func (wf *whereFilter) selectFields(fields []string) []string {
+	if len(fields) == 0 || lo.Contains(fields, "*") {
+		return fields // all fields
+	}
+
+	fields = append([]string{}, fields...)
+	for _, key := range wf.keys {
+		if !lo.ContainsBy(fields, func(f string) bool { return strings.EqualFold(f, key) }) {
+			fields = append(fields, key)
+		}
+	}
+	return fields
+}
+
+// Apply returns the datastream of the rows matching the expression.
+// The errors are captured in the dataflow.
+func (wf *whereFilter) Apply(df *iop.Dataflow, ds *iop.Datastream) *iop.Datastream {
+	var pe *partitionEvaluator
+	indexes := map[string]int{}
+
+	return ds.Filter(func(row []any) bool {
+		if pe == nil {
+			pe = &partitionEvaluator{sp: ds.Sp, columns: ds.Columns.FieldMap(true), types: ds.Columns}
+			for _, key := range wf.keys {
+				if index, ok := pe.columns[key]; ok {
+					indexes[key] = index
+				} else {
+					df.Context.CaptureErr(g.Error("column %s of where not found", key))
+				}
+			}
+		}
+
+		pe.values = map[string]*string{}
+		for key, index := range indexes {
+			if index >= len(row) || row[index] == nil {
+				pe.values[key] = nil
+				continue
+			}
+
+			var val string
+			if t, ok := row[index].(time.Time); ok {
+				val = t.Format(time.RFC3339Nano)
+			} else {
+				val = cast.ToString(row[index])
+			}
+			pe.values[key] = &val
+		}
+
+		result, err := pe.eval(wf.expr)
+		if err != nil {
+			df.Context.CaptureErr(g.Error(err, "could not evaluate where: %s", wf.where))
+			return false
+		}
+		return result == filterTrue
+	})
+}
+
+// partitionFilter is a parsed partition filter expression
+type partitionFilter struct {
+	filter string
+	expr   *filterNode
+	pe     *partitionEvaluator
+}
+
+func newPartitionFilter(filter string, columns iop.Columns) (pf *partitionFilter, err error) {
+	expr, err := parsePartitionFilter(filter)
+	if err != nil {
+		return nil, g.Error(err, "could not parse partition_filter: %s", filter)
+	}
+
+	pe := &partitionEvaluator{sp: iop.NewStreamProcessor(), columns: columns.FieldMap(true), types: columns}
+	return &partitionFilter{filter: filter, expr: expr, pe: pe}, nil
+}
+
+// Match returns whether the partition values match the filter.
+// It is true when a partition key of the expression is missing.
+func (pf *partitionFilter) Match(values map[string]*string) (bool, error) {
+	pf.pe.values = values
+	result, err := pf.pe.eval(pf.expr)
+	if err != nil {
+		return false, g.Error(err, "could not evaluate partition_filter: %s", pf.filter)
+	}
+	return result != filterFalse, nil
+}
+
+// filterNode is a node of a parsed partition filter expression
+type filterNode struct {
+	op       string // and, or, not, is_null, is_not_null, in, =, !=, >, >=, <, <=
+	key      string
+	literals []*string // nil for null
+	children []*filterNode
+}
+
+// filterResult is the result of a filter evaluation. It is unknown when
+// the partition key is not in the path, in which case the file is kept
+type filterResult int
+
+const (
+	filterFalse filterResult = iota
+	filterTrue
+	filterUnknown
+)
+
+// filterTokenRegex tokenizes a filter: quoted strings, operators, parenthesis, commas and words
+var filterTokenRegex = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"]|"")*"|>=|<=|!=|<>|==|[=<>(),]|[^\s=<>!(),'"]+`)
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func parsePartitionFilter(filter string) (expr *filterNode, err error) {
+	p := &filterParser{tokens: filterTokenRegex.FindAllString(filter, -1)}
+	if len(p.tokens) == 0 {
+		return nil, g.Error("empty expression")
+	}
+
+	expr, err = p.parseOr()
+	if err != nil {
+		return nil, err
+	} else if p.pos < len(p.tokens) {
+		return nil, g.Error("unexpected token: %s", p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *filterParser) parseOr() (*filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &filterNode{op: "or", children: []*filterNode{left}}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, right)
+	}
+	return lo.Ternary(len(node.children) == 1, left, node), nil
+}
+
+func (p *filterParser) parseAnd() (*filterNode, error) {
+	left, err := p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &filterNode{op: "and", children: []*filterNode{left}}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, right)
+	}
+	return lo.Ternary(len(node.children) == 1, left, node), nil
+}
+
+func (p *filterParser) parseCondition() (*filterNode, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, g.Error("unexpected end of expression")
+	case token == "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		} else if p.next() != ")" {
+			return nil, g.Error("missing closing parenthesis")
+		}
+		return node, nil
+	case strings.EqualFold(token, "not"):
+		child, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		return &filterNode{op: "not", children: []*filterNode{child}}, nil
+	}
+
+	node := &filterNode{key: strings.Trim(token, "`\"")}
+	op := strings.ToLower(p.next())
+	switch op {
+	case "is":
+		node.op = "is_null"
+		if strings.EqualFold(p.peek(), "not") {
+			p.next()
+			node.op = "is_not_null"
+		}
+		if !strings.EqualFold(p.next(), "null") {
+			return nil, g.Error("expected null after `is` for %s", node.key)
+		}
+	case "in", "not":
+		node.op = "in"
+		if op == "not" {
+			if !strings.EqualFold(p.next(), "in") {
+				return nil, g.Error("expected `in` after `not` for %s", node.key)
+			}
+			node.op = "not_in"
+		}
+		if p.next() != "(" {
+			return nil, g.Error("expected list after `in` for %s", node.key)
+		}
+		for {
+			literal, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			node.literals = append(node.literals, literal)
+			if sep := p.next(); sep == ")" {
+				break
+			} else if sep != "," {
+				return nil, g.Error("invalid list for %s", node.key)
+			}
+		}
+	case "=", "==", "!=", "<>", ">", ">=", "<", "<=":
+		node.op = lo.Ternary(op == "==", "=", lo.Ternary(op == "<>", "!=", op))
+		literal, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		node.literals = []*string{literal}
+	default:
+		return nil, g.Error("invalid operator for %s: %s", node.key, op)
+	}
+
+	return node, nil
+}
+
+func (p *filterParser) parseLiteral() (*string, error) {
+	token := p.next()
+	switch {
+	case token == "" || g.In(token, "(", ")", ","):
+		return nil, g.Error("expected a value, got: %s", token)
+	case strings.EqualFold(token, "null"):
+		return nil, nil
+	case strings.HasPrefix(token, "'"):
+		val := strings.ReplaceAll(token[1:len(token)-1], "''", "'")
+		return &val, nil
+	case strings.HasPrefix(token, `"`):
+		val := strings.ReplaceAll(token[1:len(token)-1], `""`, `"`)
+		return &val, nil
+	}
+	return &token, nil
+}
+
+// partitionEvaluator evaluates a filter against the partition values of a file
+type partitionEvaluator struct {
+	sp      *iop.StreamProcessor
+	columns map[string]int
+	types   iop.Columns
+	values  map[string]*string
+}
+
+func (pe *partitionEvaluator) eval(node *filterNode) (result filterResult, err error) {
+	switch node.op {
+	case "and", "or":
+		result = lo.Ternary(node.op == "and", filterTrue, filterFalse)
+		for _, child := range node.children {
+			childResult, err := pe.eval(child)
+			if err != nil {
+				return result, err
+			}
+			switch {
+			case node.op == "and" && childResult == filterFalse:
+				return filterFalse, nil
+			case node.op == "or" && childResult == filterTrue:
+				return filterTrue, nil
+			case childResult == filterUnknown:
+				result = filterUnknown
+			}
+		}
+		return result, nil
+	case "not":
+		result, err = pe.eval(node.children[0])
+		switch result {
+		case filterTrue:
+			return filterFalse, err
+		case filterFalse:
+			return filterTrue, err
+		}
+		return result, err
+	}
+
+	val, ok := pe.values[node.key]
+	if !ok {
+		for key, v := range pe.values {
+			if strings.EqualFold(key, node.key) {
+				val, ok = v, true
+				break
+			}
+		}
+		if !ok {
+			return filterUnknown, nil
+		}
+	}
+
+	switch node.op {
+	case "is_null":
+		return toFilterResult(val == nil), nil
+	case "is_not_null":
+		return toFilterResult(val != nil), nil
+	case "in", "not_in":
+		found := false
+		for _, literal := range node.literals {
+			if val == nil || literal == nil {
+				continue
+			}
+			cmp, err := pe.compare(node.key, *val, *literal)
+			if err != nil {
+				return result, err
+			} else if cmp == 0 {
+				found = true
+				break
+			}
+		}
+		return toFilterResult(lo.Ternary(node.op == "in", found, !found && val != nil)), nil
+	}
+
+	literal := node.literals[0]
+	if val == nil || literal == nil {
+		return filterFalse, nil // null comparisons are false
+	}
+
+	cmp, err := pe.compare(node.key, *val, *literal)
+	if err != nil {
+		return result, err
+	}
+
+	switch node.op {
+	case "=":
+		return toFilterResult(cmp == 0), nil
+	case "!=":
+		return toFilterResult(cmp != 0), nil
+	case ">":
+		return toFilterResult(cmp > 0), nil
+	case ">=":
+		return toFilterResult(cmp >= 0), nil
+	case "<":
+		return toFilterResult(cmp < 0), nil
+	case "<=":
+		return toFilterResult(cmp <= 0), nil
+	}
+
+	return result, g.Error("invalid operator: %s", node.op)
+}
+
+// compare compares a partition value with a literal, according to the partition column type
+func (pe *partitionEvaluator) compare(key, val, literal string) (int, error) {
+	colType := iop.StringType
+	if index, ok := pe.columns[strings.ToLower(key)]; ok && index < len(pe.types) {
+		colType = pe.types[index].Type
+	}
+
+	switch {
+	case colType.IsNumber():
+		v, err1 := cast.ToFloat64E(val)
+		l, err2 := cast.ToFloat64E(literal)
+		if err1 == nil && err2 == nil {
+			return lo.Ternary(v < l, -1, lo.Ternary(v > l, 1, 0)), nil
+		}
+	case colType.IsDate() || colType.IsDatetime():
+		v, err1 := pe.sp.ParseTime(val)
+		l, err2 := pe.sp.ParseTime(literal)
+		if err2 != nil {
+			return 0, g.Error(err2, "could not parse date value for %s: %s", key, literal)
+		} else if err1 == nil {
+			return v.Compare(l), nil
+		}
+	}
+
+	return strings.Compare(val, literal), nil
+}
+
+func toFilterResult(b bool) filterResult {
+	return lo.Ternary(b, filterTrue, filterFalse)
+}
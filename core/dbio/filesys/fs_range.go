@@ -0,0 +1,134 @@
+package filesys
+
+import (
+	"io"
+	"sync/atomic"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+)
+
+// rangeReaderClient is a file system client which can read byte ranges
+// of a file, with HTTP range requests
+type rangeReaderClient interface {
+	FileSysClient
+	objectSize(uri string) (size int64, err error)
+	readRange(uri string, offset, length int64) (reader io.ReadCloser, err error)
+}
+
+// RangeReader reads a remote file with range requests. It implements
+// io.ReaderAt and io.Seeker, so that formats such as parquet only
+// fetch the needed bytes (the footer and the selected column chunks)
+// instead of downloading the whole file.
+type RangeReader struct {
+	fs        rangeReaderClient
+	uri       string
+	size      int64
+	offset    int64
+	bytesRead int64
+	requests  int64
+}
+
+// NewRangeReader returns a range reader of the file, if the file system supports it
+func NewRangeReader(fs FileSysClient, uri string) (r *RangeReader, err error) {
+	client, ok := fs.Self().(rangeReaderClient)
+	if !ok {
+		return nil, g.Error("range requests are not supported for %s", fs.FsType())
+	}
+
+	size, err := client.objectSize(uri)
+	if err != nil {
+		return nil, g.Error(err, "could not get size of %s", uri)
+	}
+
+	return &RangeReader{fs: client, uri: uri, size: size}, nil
+}
+
+// Size returns the size of the file
+func (r *RangeReader) Size() int64 {
+	return r.size
+}
+
+// BytesRead returns the number of bytes fetched, and the number of requests
+func (r *RangeReader) BytesRead() (bytes, requests int64) {
+	return atomic.LoadInt64(&r.bytesRead), atomic.LoadInt64(&r.requests)
+}
+
+// ReadAt reads len(p) bytes at the offset, with one range request
+func (r *RangeReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, g.Error("negative offset: %d", off)
+	} else if off >= r.size {
+		return 0, io.EOF
+	} else if len(p) == 0 {
+		return 0, nil
+	}
+
+	length := min(int64(len(p)), r.size-off)
+	reader, err := r.fs.readRange(r.uri, off, length)
+	if err != nil {
+		return 0, g.Error(err, "could not read range %d-%d of %s", off, off+length-1, r.uri)
+	}
+	defer reader.Close()
+
+	n, err = io.ReadFull(reader, p[:length])
+	atomic.AddInt64(&r.bytesRead, int64(n))
+	atomic.AddInt64(&r.requests, 1)
+	if err != nil {
+		return n, g.Error(err, "could not read range %d-%d of %s", off, off+length-1, r.uri)
+	} else if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Read reads from the current offset
+func (r *RangeReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	return
+}
+
+// Seek sets the offset of the next Read
+func (r *RangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, g.Error("invalid whence: %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, g.Error("negative position: %d", offset)
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// consumeParquetRange consumes a remote parquet file with range requests.
+// ok is false if the file cannot be read with range requests.
+func consumeParquetRange(fs FileSysClient, ds *iop.Datastream, uri string) (ok bool, err error) {
+	// decrypted or archived files are streamed whole
+	if _, member, isArchive := SplitArchiveURI(uri); isArchive && member != "" {
+		return false, nil
+	} else if decrypt := fs.GetProp("DECRYPT"); decrypt != "" && decrypt != "false" {
+		return false, nil
+	}
+
+	reader, err := NewRangeReader(fs, uri)
+	if err != nil {
+		g.Trace("not using range requests: %s", err.Error())
+		return false, nil
+	}
+
+	ds.Defer(func() {
+		bytes, requests := reader.BytesRead()
+		g.Debug("read %d of %d bytes from %s with %d range requests", bytes, reader.Size(), uri, requests)
+	})
+
+	return true, ds.ConsumeParquetReaderSeeker(reader)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"runtime"
 	"strings"
@@ -12,8 +13,10 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -223,6 +226,41 @@ func (fs *S3FileSysClient) delete(uri string) (err error) {
 	return
 }
 
+// move copies the object server-side, then deletes the source.
+// Falls back to a streamed copy if the server-side copy fails
+// (e.g. objects larger than 5GB).
+func (fs *S3FileSysClient) move(srcURI, tgtURI string) (err error) {
+	srcKey, err := fs.GetPath(srcURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+srcURI)
+	}
+	tgtKey, err := fs.GetPath(tgtURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+tgtURI)
+	}
+
+	svc := s3.New(fs.getSession())
+	_, err = svc.CopyObjectWithContext(fs.Context().Ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(fs.bucket),
+		CopySource: aws.String((&url.URL{Path: fs.bucket + "/" + srcKey}).EscapedPath()),
+		Key:        aws.String(tgtKey),
+	})
+	if err != nil {
+		g.Debug("could not copy %s server-side, streaming instead: %s", srcURI, err.Error())
+		return fs.BaseFileSysClient.move(srcURI, tgtURI)
+	}
+
+	_, err = svc.DeleteObjectWithContext(fs.Context().Ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(srcKey),
+	})
+	if err != nil {
+		return g.Error(err, "could not delete "+srcURI)
+	}
+
+	return
+}
+
 func (fs *S3FileSysClient) getConcurrency() int {
 	conc := cast.ToInt(fs.GetProp("CONCURRENCY"))
 	if conc == 0 {
@@ -231,6 +269,45 @@ func (fs *S3FileSysClient) getConcurrency() int {
 	return conc
 }
 
+// objectSize returns the size of the object
+func (fs *S3FileSysClient) objectSize(uri string) (size int64, err error) {
+	key, err := fs.GetPath(uri)
+	if err != nil {
+		return
+	}
+
+	svc := s3.New(fs.getSession())
+	output, err := svc.HeadObjectWithContext(fs.Context().Ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, g.Error(err, "could not get object info of "+key)
+	}
+
+	return aws.Int64Value(output.ContentLength), nil
+}
+
+// readRange returns a reader of the byte range of the object
+func (fs *S3FileSysClient) readRange(uri string, offset, length int64) (reader io.ReadCloser, err error) {
+	key, err := fs.GetPath(uri)
+	if err != nil {
+		return
+	}
+
+	svc := s3.New(fs.getSession())
+	output, err := svc.GetObjectWithContext(fs.Context().Ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(g.F("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		return nil, g.Error(err, "could not get object range of "+key)
+	}
+
+	return output.Body, nil
+}
+
 // GetReader return a reader for the given path
 // path should specify the full path with scheme:
 // `s3://my_bucket/key/to/file.txt` or `s3://my_bucket/key/to/directory`
@@ -331,27 +408,31 @@ func (fs *S3FileSysClient) Write(uri string, reader io.Reader) (bw int64, err er
 		return
 	}
 
-	uploader := s3manager.NewUploader(fs.getSession())
-	uploader.Concurrency = fs.Context().Wg.Limit
-
-	// Create pipe to get bytes written
-	pr, pw := io.Pipe()
-	fs.Context().Wg.Write.Add()
-	go func() {
-		defer fs.Context().Wg.Write.Done()
-		defer pw.Close()
-		bw, err = io.Copy(pw, reader)
-		if err != nil {
-			fs.Context().CaptureErr(g.Error(err, "Error Copying"))
+	// parts are buffered and retried individually, a failed
+	// upload is aborted so no orphan parts are left behind
+	upload := getUploadConfig(fs)
+	uploader := s3manager.NewUploader(fs.getSession(), func(u *s3manager.Uploader) {
+		u.Concurrency = lo.Ternary(upload.Concurrency > 0, upload.Concurrency, fs.Context().Wg.Limit)
+		if upload.PartSize > 0 {
+			u.PartSize = max(upload.PartSize, s3manager.MinUploadPartSize)
 		}
-	}()
+		if upload.Retries > 0 {
+			u.RequestOptions = append(u.RequestOptions, func(r *request.Request) {
+				r.Retryer = client.DefaultRetryer{NumMaxRetries: upload.Retries}
+			})
+		}
+	})
+
+	// count the bytes written
+	counter := &countingReader{Reader: reader}
 
 	// Upload the file to S3.
 	_, err = uploader.UploadWithContext(fs.Context().Ctx, &s3manager.UploadInput{
 		Bucket: aws.String(fs.bucket),
 		Key:    aws.String(key),
-		Body:   pr,
+		Body:   counter,
 	})
+	bw = counter.Count()
 	if err != nil {
 		err = g.Error(err, "failed to upload file: "+key)
 		return
@@ -490,6 +571,9 @@ func (fs *S3FileSysClient) doList(svc *s3.S3, input *s3.ListObjectsV2Input, urlP
 			if obj.Owner != nil {
 				node.Owner = *obj.Owner.DisplayName
 			}
+			if obj.ETag != nil {
+				node.ETag = *obj.ETag
+			}
 
 			nodes.AddWhere(pattern, ts, node)
 			if len(nodes) >= maxItems {
@@ -251,6 +251,25 @@ func (fs *SftpFileSysClient) delete(urlStr string) (err error) {
 	return nil
 }
 
+// move renames the file, replacing an existing one
+func (fs *SftpFileSysClient) move(srcURI, tgtURI string) (err error) {
+	srcPath, err := fs.GetPath(srcURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+srcURI)
+	}
+	tgtPath, err := fs.GetPath(tgtURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+tgtURI)
+	}
+
+	if err = fs.client.MkdirAll(path.Dir(tgtPath)); err != nil {
+		return g.Error(err, "could not create directory of %s", tgtPath)
+	} else if err = fs.client.PosixRename(srcPath, tgtPath); err != nil {
+		return g.Error(err, "could not move %s to %s", srcPath, tgtPath)
+	}
+	return nil
+}
+
 // MkdirAll creates child directories
 func (fs *SftpFileSysClient) MkdirAll(path string) (err error) {
 	return fs.client.MkdirAll(path)
@@ -1,21 +1,41 @@
 package filesys
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/xml"
 	"fmt"
 	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	arrowParquet "github.com/apache/arrow/go/v16/parquet"
 	"github.com/apache/arrow/go/v16/parquet/compress"
 	"github.com/flarco/g/net"
 	"github.com/linkedin/goavro/v2"
 	"github.com/parquet-go/parquet-go"
+	"github.com/samber/lo"
 	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/spf13/cast"
+	"golang.org/x/net/webdav"
 
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 
@@ -136,7 +156,7 @@ func TestFileSysLocalFormat(t *testing.T) {
 		"10:00:00", // timez_type
 	})
 
-	for _, format := range []FileType{FileTypeJson, FileTypeJsonLines, FileTypeCsv, FileTypeParquet} {
+	for _, format := range []FileType{FileTypeJson, FileTypeJsonLines, FileTypeCsv, FileTypeParquet, FileTypeAvro} {
 		if t.Failed() {
 			break
 		}
@@ -483,6 +503,1488 @@ func TestFileSysLocalAvro(t *testing.T) {
 
 }
 
+func TestFileSysLocalAvroWrite(t *testing.T) {
+	t.Parallel()
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "amount", Type: iop.DecimalType, DbPrecision: 10, DbScale: 2, Sourced: true},
+			{Name: "active", Type: iop.BoolType},
+			{Name: "birth date", Type: iop.DateType},
+			{Name: "updated_at", Type: iop.TimestampType},
+			{Name: "name", Type: iop.StringType},
+			{Name: "payload", Type: iop.JsonType},
+		}...,
+	)
+
+	ts := time.Date(2023, 2, 2, 2, 2, 2, 123456000, time.UTC)
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	data.Append([]any{int64(1), "123.45", true, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), ts, "john", `{"a":1}`})
+	data.Append([]any{int64(2), "-0.5", false, time.Date(1960, 6, 1, 0, 0, 0, 0, time.UTC), ts, "mary", `[1,2]`})
+	data.Append([]any{int64(3), nil, nil, nil, nil, nil, nil})
+
+	for _, compression := range []iop.CompressorType{iop.NoneCompressorType, iop.GzipCompressorType, iop.SnappyCompressorType, iop.ZStandardCompressorType} {
+		folder := g.F("test/test_write/avro_%s", compression)
+		os.RemoveAll(folder)
+
+		fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=avro", "FILE_MAX_ROWS=2", "COMPRESSION="+string(compression), "AVRO_NATIVE_TYPES=true")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		df, err := iop.MakeDataFlow(data.Stream())
+		assert.NoError(t, err)
+		_, err = WriteDataflow(fs, df, folder)
+		if !assert.NoError(t, err, compression) {
+			return
+		}
+
+		nodes, err := fs.List(folder + "/")
+		assert.NoError(t, err)
+		assert.Len(t, nodes, 2, compression)
+
+		df2, err := fs.ReadDataflow(folder)
+		if !assert.NoError(t, err, compression) {
+			return
+		}
+
+		data2, err := df2.Collect()
+		if !assert.NoError(t, err, compression) {
+			return
+		}
+
+		if assert.Len(t, data2.Rows, 3, compression) {
+			assert.Equal(t, "birth_date", data2.Columns[3].Name)
+			assert.Equal(t, iop.DecimalType, data2.Columns[1].Type)
+			assert.Equal(t, iop.DateType, data2.Columns[3].Type)
+			assert.Equal(t, iop.TimestampType, data2.Columns[4].Type)
+
+			rows := map[int64][]any{}
+			for _, row := range data2.Rows {
+				rows[cast.ToInt64(row[0])] = row
+			}
+
+			assert.Equal(t, "123.45", cast.ToString(rows[1][1]))
+			assert.Equal(t, "-0.50", cast.ToString(rows[2][1]))
+			assert.True(t, cast.ToBool(rows[1][2]))
+			assert.Equal(t, "1960-06-01", cast.ToTime(rows[2][3]).UTC().Format("2006-01-02"))
+			assert.True(t, ts.Equal(cast.ToTime(rows[1][4])), rows[1][4])
+			assert.Equal(t, "mary", rows[2][5])
+			assert.Equal(t, `{"a":1}`, rows[1][6])
+			assert.Nil(t, rows[3][1])
+			assert.Nil(t, rows[3][5])
+		}
+
+		// without the option, values are read as their JSON representation,
+		// with the nullable unions unwrapped
+		fs2, _ := NewFileSysClient(dbio.TypeFileLocal)
+		df3, err := fs2.ReadDataflow(folder)
+		if assert.NoError(t, err, compression) {
+			data3, err := df3.Collect()
+			if assert.NoError(t, err, compression) && assert.Len(t, data3.Rows, 3, compression) {
+				assert.Equal(t, iop.BigIntType, data3.Columns[0].Type)
+				assert.Equal(t, iop.StringType, data3.Columns[5].Type)
+				rows := map[int64][]any{}
+				for _, row := range data3.Rows {
+					rows[cast.ToInt64(row[0])] = row
+				}
+				if assert.Contains(t, rows, int64(2)) {
+					assert.Equal(t, "mary", rows[2][5])
+					assert.False(t, cast.ToBool(rows[2][2]))
+					assert.Equal(t, ts.UnixMicro(), cast.ToInt64(rows[2][4]))
+				}
+				if assert.Contains(t, rows, int64(3)) {
+					assert.Nil(t, rows[3][5])
+				}
+			}
+		}
+
+		if !t.Failed() {
+			os.RemoveAll(folder)
+		}
+	}
+}
+
+func TestFileSysLocalOrc(t *testing.T) {
+	t.Parallel()
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "amount", Type: iop.DecimalType, DbPrecision: 10, DbScale: 2, Sourced: true},
+			{Name: "active", Type: iop.BoolType},
+			{Name: "birth_date", Type: iop.DateType},
+			{Name: "updated_at", Type: iop.TimestampType},
+			{Name: "name", Type: iop.StringType},
+			{Name: "payload", Type: iop.JsonType},
+		}...,
+	)
+
+	ts := time.Date(2023, 2, 2, 2, 2, 2, 123456000, time.UTC)
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	data.Append([]any{int64(1), "123.45", true, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), ts, "john", `{"a":1}`})
+	data.Append([]any{int64(2), "-0.5", false, time.Date(1960, 6, 1, 0, 0, 0, 0, time.UTC), ts, "mary", `[1,2]`})
+	data.Append([]any{int64(3), nil, nil, nil, nil, nil, nil})
+
+	for _, compression := range []iop.CompressorType{iop.NoneCompressorType, iop.GzipCompressorType, iop.SnappyCompressorType, iop.ZStandardCompressorType} {
+		folder := g.F("test/test_write/orc_%s", compression)
+		os.RemoveAll(folder)
+
+		fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=orc", "FILE_MAX_ROWS=2", "STRIPE_SIZE=10", "COMPRESSION="+string(compression))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		df, err := iop.MakeDataFlow(data.Stream())
+		assert.NoError(t, err)
+		_, err = WriteDataflow(fs, df, folder)
+		if !assert.NoError(t, err, compression) {
+			return
+		}
+
+		nodes, err := fs.List(folder + "/")
+		assert.NoError(t, err)
+		if assert.Len(t, nodes, 2, compression) {
+			assert.True(t, strings.HasSuffix(nodes[0].URI, ".orc"), nodes[0].URI)
+		}
+
+		df2, err := fs.ReadDataflow(folder)
+		if !assert.NoError(t, err, compression) {
+			return
+		}
+
+		data2, err := df2.Collect()
+		if !assert.NoError(t, err, compression) {
+			return
+		}
+
+		if assert.Len(t, data2.Rows, 3, compression) {
+			assert.Equal(t, iop.DecimalType, data2.Columns[1].Type)
+			assert.Equal(t, iop.BoolType, data2.Columns[2].Type)
+			assert.Equal(t, iop.DateType, data2.Columns[3].Type)
+			assert.Equal(t, iop.TimestampType, data2.Columns[4].Type)
+
+			rows := map[int64][]any{}
+			for _, row := range data2.Rows {
+				rows[cast.ToInt64(row[0])] = row
+			}
+
+			assert.Equal(t, "123.45", cast.ToString(rows[1][1]))
+			assert.Equal(t, "-0.50", cast.ToString(rows[2][1]))
+			assert.True(t, cast.ToBool(rows[1][2]))
+			assert.Equal(t, "1960-06-01", cast.ToTime(rows[2][3]).UTC().Format("2006-01-02"))
+			assert.True(t, ts.Equal(cast.ToTime(rows[1][4])), rows[1][4])
+			assert.Equal(t, "mary", rows[2][5])
+			assert.Equal(t, `{"a":1}`, rows[1][6])
+			assert.Nil(t, rows[3][1])
+			assert.Nil(t, rows[3][5])
+		}
+
+		if !t.Failed() {
+			os.RemoveAll(folder)
+		}
+	}
+}
+
+func TestFileSysLocalArrow(t *testing.T) {
+	t.Parallel()
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "amount", Type: iop.DecimalType, DbPrecision: 10, DbScale: 2, Sourced: true},
+			{Name: "active", Type: iop.BoolType},
+			{Name: "birth_date", Type: iop.DateType},
+			{Name: "updated_at", Type: iop.TimestampType},
+			{Name: "name", Type: iop.StringType},
+		}...,
+	)
+
+	ts := time.Date(2023, 2, 2, 2, 2, 2, 123456000, time.UTC)
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	data.Append([]any{int64(1), "123.45", true, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), ts, "john"})
+	data.Append([]any{int64(2), "-0.5", false, time.Date(1960, 6, 1, 0, 0, 0, 0, time.UTC), ts, "mary"})
+	data.Append([]any{int64(3), nil, nil, nil, nil, nil})
+
+	checkRows := func(data2 iop.Dataset, name string) {
+		if !assert.Len(t, data2.Rows, 3, name) {
+			return
+		}
+		assert.Equal(t, iop.DecimalType, data2.Columns[1].Type)
+		assert.Equal(t, iop.DateType, data2.Columns[3].Type)
+
+		rows := map[int64][]any{}
+		for _, row := range data2.Rows {
+			rows[cast.ToInt64(row[0])] = row
+		}
+
+		assert.Equal(t, "123.45", cast.ToString(rows[1][1]))
+		assert.Equal(t, "-0.50", cast.ToString(rows[2][1]))
+		assert.True(t, cast.ToBool(rows[1][2]))
+		assert.Equal(t, "1960-06-01", cast.ToTime(rows[2][3]).UTC().Format("2006-01-02"))
+		assert.True(t, ts.Equal(cast.ToTime(rows[1][4])), rows[1][4])
+		assert.Equal(t, "mary", rows[2][5])
+		assert.Nil(t, rows[3][1])
+		assert.Nil(t, rows[3][5])
+	}
+
+	for _, compression := range []iop.CompressorType{iop.NoneCompressorType, iop.ZStandardCompressorType, iop.AutoCompressorType} {
+		folder := g.F("test/test_write/arrow_%s", compression)
+		os.RemoveAll(folder)
+
+		fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=arrow", "FILE_MAX_ROWS=2", "COMPRESSION="+string(compression))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		df, err := iop.MakeDataFlow(data.Stream())
+		assert.NoError(t, err)
+		_, err = WriteDataflow(fs, df, folder)
+		if !assert.NoError(t, err, string(compression)) {
+			return
+		}
+
+		nodes, err := fs.List(folder + "/")
+		assert.NoError(t, err)
+		if assert.Len(t, nodes, 2, string(compression)) {
+			assert.True(t, strings.HasSuffix(nodes[0].URI, ".arrow"), nodes[0].URI)
+		}
+
+		df2, err := fs.ReadDataflow(folder)
+		if !assert.NoError(t, err, string(compression)) {
+			return
+		}
+
+		data2, err := df2.Collect()
+		if !assert.NoError(t, err, string(compression)) {
+			return
+		}
+		checkRows(data2, string(compression))
+
+		if !t.Failed() {
+			os.RemoveAll(folder)
+		}
+	}
+
+	// single file in the streaming format
+	path := "test/test_write/arrow_single.arrows"
+	os.Remove(path)
+
+	fs, err := NewFileSysClient(dbio.TypeFileLocal)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	df, err := iop.MakeDataFlow(data.Stream())
+	assert.NoError(t, err)
+	_, err = WriteDataflow(fs, df, path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	df2, err := fs.ReadDataflow(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	data2, err := df2.Collect()
+	if assert.NoError(t, err) {
+		checkRows(data2, "arrows")
+	}
+
+	if !t.Failed() {
+		os.Remove(path)
+	}
+}
+
+func TestFileSysLocalFixedWidth(t *testing.T) {
+	t.Parallel()
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "name", Type: iop.StringType},
+			{Name: "amount", Type: iop.DecimalType},
+			{Name: "birth_date", Type: iop.DateType},
+		}...,
+	)
+
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	data.Append([]any{int64(1), "john", "123.45", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)})
+	data.Append([]any{int64(2), "a very long name", "-0.5", time.Date(1960, 6, 1, 0, 0, 0, 0, time.UTC)})
+	data.Append([]any{int64(3), nil, nil, nil})
+
+	spec := `[{"name":"id","length":4,"type":"bigint"},{"name":"name","length":8},{"name":"amount","length":9,"type":"decimal"},{"name":"birth_date","length":8,"format":"YYYYMMDD"}]`
+
+	folder := "test/test_write/fixed_width"
+	os.RemoveAll(folder)
+
+	fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=fixed_width", "FILE_MAX_ROWS=2", "FIXED_WIDTH_COLUMNS="+spec)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	df, err := iop.MakeDataFlow(data.Stream())
+	assert.NoError(t, err)
+	_, err = WriteDataflow(fs, df, folder)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	nodes, err := fs.List(folder + "/")
+	assert.NoError(t, err)
+	if assert.Len(t, nodes, 2) {
+		assert.True(t, strings.HasSuffix(nodes[0].URI, ".txt"), nodes[0].URI)
+		bytes, err := os.ReadFile(strings.TrimPrefix(nodes[0].URI, "file://"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "   1john       123.4520230101\n   2a very l     -0.519600601\n", string(bytes))
+		}
+	}
+
+	df2, err := fs.ReadDataflow(folder)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data2, err := df2.Collect()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, data2.Rows, 3) {
+		assert.Equal(t, iop.BigIntType, data2.Columns[0].Type)
+		assert.Equal(t, iop.DecimalType, data2.Columns[2].Type)
+
+		rows := map[int64][]any{}
+		for _, row := range data2.Rows {
+			rows[cast.ToInt64(row[0])] = row
+		}
+
+		assert.Equal(t, "a very l", rows[2][1])
+		assert.Equal(t, "123.45", cast.ToString(rows[1][2]))
+		assert.Equal(t, "1960-06-01", cast.ToTime(rows[2][3]).UTC().Format("2006-01-02"))
+		assert.Nil(t, rows[3][1])
+		assert.Nil(t, rows[3][3])
+	}
+
+	if !t.Failed() {
+		os.RemoveAll(folder)
+	}
+}
+
+func TestFileSysLocalPartitionBy(t *testing.T) {
+	t.Parallel()
+
+	fields, err := ParsePartitionBy(`["region", {"date": "created_at", "granularity": "month"}]`)
+	if assert.NoError(t, err) && assert.Len(t, fields, 2) {
+		assert.Equal(t, []string{"region"}, fields[0].Keys())
+		assert.Equal(t, []string{"year", "month"}, fields[1].Keys())
+	}
+
+	fields, err = ParsePartitionBy("region, country")
+	if assert.NoError(t, err) && assert.Len(t, fields, 2) {
+		assert.Equal(t, "country", fields[1].Column)
+	}
+
+	_, err = ParsePartitionBy(`{"date": "created_at", "granularity": "week"}`)
+	assert.Error(t, err)
+
+	assert.Equal(t, "a%2Fb%3D1", iop.HivePartitionEscape("a/b=1"))
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "region", Type: iop.StringType},
+			{Name: "created_at", Type: iop.TimestampType},
+		}...,
+	)
+
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	data.Append([]any{int64(1), "east", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)})
+	data.Append([]any{int64(2), "west", time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)})
+	data.Append([]any{int64(3), "east", time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)})
+	data.Append([]any{int64(4), "east", time.Date(2024, 5, 3, 10, 0, 0, 0, time.UTC)})
+	data.Append([]any{int64(5), nil, nil})
+
+	folder := "test/test_write/partitioned"
+	os.RemoveAll(folder)
+
+	partitionBy := `["region", {"date": "created_at", "granularity": "month"}]`
+	fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=csv", "PARTITION_BY="+partitionBy, "PARTITION_MAX_OPEN=2")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	df, err := iop.MakeDataFlow(data.Stream())
+	assert.NoError(t, err)
+	_, err = WriteDataflow(fs, df, folder)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	paths := []string{}
+	nodes, err := fs.ListRecursive(folder + "/")
+	assert.NoError(t, err)
+	for _, uri := range nodes.URIs() {
+		if index := strings.Index(uri, "/partitioned/"); index > -1 && !strings.HasSuffix(uri, "/") {
+			paths = append(paths, uri[index+len("/partitioned/"):])
+		}
+	}
+
+	// east/05 is closed when west/05 is opened (max 2 open), so a second file is created
+	assert.ElementsMatch(t, []string{
+		"region=east/year=2024/month=05/part-0001.csv",
+		"region=east/year=2024/month=05/part-0002.csv",
+		"region=east/year=2024/month=06/part-0001.csv",
+		"region=west/year=2024/month=05/part-0001.csv",
+		"region=__HIVE_DEFAULT_PARTITION__/year=__HIVE_DEFAULT_PARTITION__/month=__HIVE_DEFAULT_PARTITION__/part-0001.csv",
+	}, paths)
+
+	df2, err := fs.ReadDataflow(folder + "/region=east/year=2024/month=05")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data2, err := df2.Collect()
+	if assert.NoError(t, err) && assert.Len(t, data2.Rows, 2) {
+		assert.Equal(t, []string{"id", "created_at"}, data2.Columns.Names())
+	}
+
+	if !t.Failed() {
+		os.RemoveAll(folder)
+	}
+}
+
+func TestFileSysLocalArchive(t *testing.T) {
+	t.Parallel()
+
+	folder := "test/test_read/archive"
+	os.RemoveAll(folder)
+	assert.NoError(t, os.MkdirAll(folder, 0755))
+
+	files := []struct{ name, content string }{
+		{"data/a.csv", "id,name\n1,a\n2,b\n"},
+		{"data/b.csv", "id,name\n3,c\n"},
+		{"other/c.csv", "id,name\n4,d\n"},
+	}
+
+	// zip archive
+	zipBuf := new(bytes.Buffer)
+	zw := zip.NewWriter(zipBuf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		assert.NoError(t, err)
+		w.Write([]byte(f.content))
+	}
+	assert.NoError(t, zw.Close())
+	assert.NoError(t, os.WriteFile(folder+"/export.zip", zipBuf.Bytes(), 0644))
+
+	// tar and tar.gz archives
+	tarBuf := new(bytes.Buffer)
+	tw := tar.NewWriter(tarBuf)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "data/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for _, f := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Size: int64(len(f.content)), Mode: 0644}))
+		tw.Write([]byte(f.content))
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, os.WriteFile(folder+"/export.tar", tarBuf.Bytes(), 0644))
+
+	gzBuf := new(bytes.Buffer)
+	gw := gzip.NewWriter(gzBuf)
+	gw.Write(tarBuf.Bytes())
+	assert.NoError(t, gw.Close())
+	assert.NoError(t, os.WriteFile(folder+"/export.tar.gz", gzBuf.Bytes(), 0644))
+
+	archiveURI, memberPath, ok := SplitArchiveURI("s3://bucket/exports/export.tar.gz/data/*.csv")
+	assert.True(t, ok)
+	assert.Equal(t, "s3://bucket/exports/export.tar.gz", archiveURI)
+	assert.Equal(t, "data/*.csv", memberPath)
+	_, _, ok = SplitArchiveURI("s3://bucket/exports/*.zip")
+	assert.False(t, ok)
+
+	type testCase struct {
+		url string
+		ids []int
+	}
+
+	cases := []testCase{}
+	for _, name := range []string{"export.zip", "export.tar", "export.tar.gz"} {
+		cases = append(cases,
+			testCase{url: folder + "/" + name, ids: []int{1, 2, 3, 4}},
+			testCase{url: folder + "/" + name + "/data", ids: []int{1, 2, 3}},
+			testCase{url: folder + "/" + name + "/*/b.csv", ids: []int{3}},
+			testCase{url: folder + "/" + name + "/other/c.csv", ids: []int{4}},
+		)
+	}
+
+	for _, c := range cases {
+		localFs, err := NewFileSysClient(dbio.TypeFileLocal)
+		if !assert.NoError(t, err) {
+			return
+		}
+		fs := &countingReadFileSysClient{LocalFileSysClient: localFs.(*LocalFileSysClient)}
+		var instance FileSysClient = fs
+		fs.instance = &instance
+
+		df, err := fs.ReadDataflow(c.url)
+		if !assert.NoError(t, err, c.url) {
+			continue
+		}
+
+		data, err := df.Collect()
+		if !assert.NoError(t, err, c.url) {
+			continue
+		}
+
+		ids := lo.Map(data.Rows, func(row []any, i int) int { return cast.ToInt(row[0]) })
+		sort.Ints(ids)
+		assert.Equal(t, c.ids, ids, c.url)
+
+		// the archive is the processed file
+		if readNodes := fs.Client().ReadNodes(); assert.Len(t, readNodes, 1, c.url) {
+			archiveURI, _, _ := SplitArchiveURI(c.url)
+			assert.True(t, strings.HasSuffix(readNodes[0].URI, archiveURI), c.url)
+		}
+
+		// the archive is read once, not once per member
+		assert.LessOrEqual(t, fs.reads.Load(), int64(1), c.url)
+	}
+
+	// missing member
+	fs, _ := NewFileSysClient(dbio.TypeFileLocal)
+	_, err := fs.ReadDataflow(folder + "/export.tar/missing/*.csv")
+	assert.Error(t, err)
+
+	if !t.Failed() {
+		os.RemoveAll(folder)
+	}
+}
+
+// countingReadFileSysClient counts the readers opened
+type countingReadFileSysClient struct {
+	*LocalFileSysClient
+	reads atomic.Int64
+}
+
+func (fs *countingReadFileSysClient) GetReader(uri string) (reader io.Reader, err error) {
+	fs.reads.Add(1)
+	return fs.LocalFileSysClient.GetReader(uri)
+}
+
+func TestFileSysLocalCompression(t *testing.T) {
+	t.Parallel()
+
+	folder := "test/test_write/compression"
+	os.RemoveAll(folder)
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "name", Type: iop.StringType},
+		}...,
+	)
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	data.Append([]any{int64(1), "a"})
+	data.Append([]any{int64(2), "b"})
+	data.Append([]any{int64(3), "c"})
+
+	for _, compression := range []iop.CompressorType{iop.Lz4CompressorType, iop.BrotliCompressorType, iop.XzCompressorType} {
+		fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=csv")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// the compression is inferred from the suffix
+		fileURL := folder + "/data.csv" + iop.NewCompressor(compression).Suffix()
+		df, err := iop.MakeDataFlow(data.Stream())
+		assert.NoError(t, err)
+		_, err = WriteDataflow(fs, df, fileURL)
+		if !assert.NoError(t, err, compression) {
+			continue
+		}
+
+		content, _ := os.ReadFile(fileURL)
+		assert.False(t, strings.HasPrefix(string(content), "id,name"), compression)
+
+		df, err = fs.ReadDataflow(fileURL)
+		if !assert.NoError(t, err, compression) {
+			continue
+		}
+		data2, err := df.Collect()
+		if assert.NoError(t, err, compression) && assert.Len(t, data2.Rows, 3, compression) {
+			assert.Equal(t, []string{"id", "name"}, data2.Columns.Names())
+		}
+	}
+
+	// bzip2 is only read, `printf 'id,name\n1,a\n2,b\n3,c\n' | bzip2 -9 | base64`
+	bz2, _ := base64.StdEncoding.DecodeString("QlpoOTFBWSZTWXT6LXoAAAjZAAAQAAQ4AD4jIAAxANABRp6GQKJMSUerPcvBY0XckU4UJB0+i16A")
+	fileURL := folder + "/data.csv.bz2"
+	os.MkdirAll(folder, 0755)
+	assert.NoError(t, os.WriteFile(fileURL, bz2, 0644))
+	fs, _ := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=csv")
+	df, err := fs.ReadDataflow(fileURL)
+	if assert.NoError(t, err) {
+		data2, err := df.Collect()
+		if assert.NoError(t, err) && assert.Len(t, data2.Rows, 3) {
+			assert.Equal(t, []string{"id", "name"}, data2.Columns.Names())
+		}
+	}
+
+	df, _ = iop.MakeDataFlow(data.Stream())
+	_, err = WriteDataflow(fs, df, folder+"/data_write.csv.bz2")
+	assert.ErrorContains(t, err, "not supported for writing")
+
+	if !t.Failed() {
+		os.RemoveAll(folder)
+	}
+}
+
+func TestFileSysLocalEncryption(t *testing.T) {
+	t.Parallel()
+
+	folder := "test/test_write/encryption"
+	os.RemoveAll(folder)
+
+	entity, err := openpgp.NewEntity("sling", "test", "test@slingdata.io", &packet.Config{DefaultHash: crypto.SHA256})
+	if !assert.NoError(t, err) {
+		return
+	}
+	publicKey, privateKey := &bytes.Buffer{}, &bytes.Buffer{}
+	w, _ := armor.Encode(privateKey, openpgp.PrivateKeyType, nil)
+	assert.NoError(t, entity.SerializePrivate(w, nil)) // signs the hash preferences
+	w.Close()
+	w, _ = armor.Encode(publicKey, openpgp.PublicKeyType, nil)
+	assert.NoError(t, entity.Serialize(w))
+	w.Close()
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "name", Type: iop.StringType},
+		}...,
+	)
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	data.Append([]any{int64(1), "a"})
+	data.Append([]any{int64(2), "b"})
+	data.Append([]any{int64(3), "c"})
+
+	// the key is taken from the props
+	fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=csv", "ENCRYPT=pgp", "PGP_PUBLIC_KEY="+publicKey.String())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, fileURL := range []string{folder + "/data.csv.gz.pgp", folder + "/parts"} {
+		df, err := iop.MakeDataFlow(data.Stream())
+		assert.NoError(t, err)
+		_, err = WriteDataflow(fs, df, lo.Ternary(strings.HasSuffix(fileURL, "parts"), fileURL+"/*.csv", fileURL))
+		if !assert.NoError(t, err, fileURL) {
+			continue
+		}
+
+		nodes, err := fs.ListRecursive(fileURL)
+		assert.NoError(t, err)
+		for _, node := range nodes {
+			if node.IsDir {
+				continue
+			}
+			assert.True(t, strings.HasSuffix(node.URI, ".pgp"), node.URI)
+			content, _ := os.ReadFile(strings.TrimPrefix(node.URI, "file://"))
+			assert.NotContains(t, string(content), "id,name")
+		}
+
+		// without the private key, the files cannot be read
+		fsRead, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=csv", "DECRYPT=true")
+		assert.NoError(t, err)
+		_, err = fsRead.ReadDataflow(fileURL)
+		assert.Error(t, err)
+
+		config := g.Marshal(iop.EncryptConfig{Type: iop.PgpEncryptorType, PrivateKey: privateKey.String()})
+		fsRead, err = NewFileSysClient(dbio.TypeFileLocal, "FORMAT=csv", "DECRYPT="+config)
+		assert.NoError(t, err)
+		df, err = fsRead.ReadDataflow(fileURL)
+		if !assert.NoError(t, err, fileURL) {
+			continue
+		}
+		data2, err := df.Collect()
+		if assert.NoError(t, err, fileURL) && assert.Len(t, data2.Rows, 3, fileURL) {
+			assert.Equal(t, []string{"id", "name"}, data2.Columns.Names())
+		}
+	}
+
+	// encryption requires a public key
+	fs, _ = NewFileSysClient(dbio.TypeFileLocal, "FORMAT=csv", "ENCRYPT=pgp")
+	df, _ := iop.MakeDataFlow(data.Stream())
+	_, err = WriteDataflow(fs, df, folder+"/missing.csv")
+	assert.Error(t, err)
+
+	if !t.Failed() {
+		os.RemoveAll(folder)
+	}
+}
+
+func TestFileSysLocalEgress(t *testing.T) {
+	t.Parallel()
+
+	folder := "test/test_write/egress"
+	os.RemoveAll(folder)
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "name", Type: iop.StringType},
+		}...,
+	)
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	for i := 0; i < 100; i++ {
+		data.Append([]any{int64(i), g.RandString(g.AlphaRunes, 10)})
+	}
+
+	fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=csv", "FILE_MAX_ROWS=30", "UPLOAD_PART_SIZE=5242880", "UPLOAD_PARALLEL=4", "UPLOAD_RETRIES=2")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, uploadConfig{PartSize: 5242880, Concurrency: 4, Retries: 2}, getUploadConfig(fs))
+
+	df, err := iop.MakeDataFlow(data.Stream())
+	assert.NoError(t, err)
+	bw, err := WriteDataflow(fs, df, folder)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the egress bytes are the bytes written in all the files
+	nodes, err := fs.ListRecursive(folder)
+	assert.NoError(t, err)
+	size := uint64(0)
+	for _, node := range nodes {
+		size += node.Size
+	}
+	_, egressBytes := df.Bytes()
+	assert.Greater(t, bw, int64(0))
+	assert.EqualValues(t, bw, egressBytes)
+	assert.EqualValues(t, size, egressBytes)
+
+	if !t.Failed() {
+		os.RemoveAll(folder)
+	}
+}
+
+// uploadStandIn records the parts of a multipart upload,
+// failing the first attempt of the first part
+type uploadStandIn struct {
+	mux      sync.Mutex
+	attempts map[string]int    // attempts of each part
+	parts    map[string][]byte // uploaded parts
+	failed   string            // the part failing once
+	object   []byte            // the completed object
+}
+
+// uploadPart records the part, returns false if the attempt fails
+func (s *uploadStandIn) uploadPart(part string, data []byte) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.attempts[part]++
+	if s.failed == "" {
+		s.failed = part
+		return false
+	}
+	s.parts[part] = data
+	return true
+}
+
+// complete assembles the object with the parts in order
+func (s *uploadStandIn) complete(parts []string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.object = nil
+	for _, part := range parts {
+		s.object = append(s.object, s.parts[part]...)
+	}
+}
+
+// TestFileSysUploadRetry checks that the S3, GCS and Azure uploads are
+// split in parts of the upload part size, and that a failed part is retried.
+// Not parallel, the GCS client uses STORAGE_EMULATOR_HOST.
+func TestFileSysUploadRetry(t *testing.T) {
+	payload := make([]byte, 11*1024*1024) // 3 parts of 5MB
+	rand.New(rand.NewSource(1)).Read(payload)
+	uploadProps := []string{"UPLOAD_PART_SIZE=5242880", "UPLOAD_PARALLEL=2", "UPLOAD_RETRIES=2"}
+
+	failPart := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`<Error><Code>ServiceUnavailable</Code><Message>part failed</Message></Error>`))
+	}
+
+	type testCase struct {
+		name    string
+		handler func(s *uploadStandIn, serverURL string) http.HandlerFunc
+		props   func(serverURL string) []string
+		fsType  dbio.Type
+	}
+
+	cases := []testCase{
+		{
+			name:   "s3",
+			fsType: dbio.TypeFileS3,
+			props: func(serverURL string) []string {
+				return []string{"BUCKET=bucket", "ENDPOINT=" + serverURL, "ACCESS_KEY_ID=key", "SECRET_ACCESS_KEY=secret"}
+			},
+			handler: func(s *uploadStandIn, serverURL string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					query := r.URL.Query()
+					body, _ := io.ReadAll(r.Body)
+					switch {
+					case r.Method == http.MethodPost && query.Has("uploads"):
+						w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>data/file.csv</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`))
+					case r.Method == http.MethodPut && query.Has("partNumber"):
+						if !s.uploadPart(query.Get("partNumber"), body) {
+							failPart(w)
+							return
+						}
+						w.Header().Set("ETag", `"`+query.Get("partNumber")+`"`)
+					case r.Method == http.MethodPost && query.Has("uploadId"):
+						completed := struct {
+							Parts []struct {
+								PartNumber string `xml:"PartNumber"`
+							} `xml:"Part"`
+						}{}
+						xml.Unmarshal(body, &completed)
+						s.complete(lo.Map(completed.Parts, func(p struct {
+							PartNumber string `xml:"PartNumber"`
+						}, i int) string {
+							return p.PartNumber
+						}))
+						w.Write([]byte(`<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>data/file.csv</Key><ETag>"done"</ETag></CompleteMultipartUploadResult>`))
+					default:
+						w.WriteHeader(http.StatusNotImplemented)
+					}
+				}
+			},
+		},
+		{
+			name:   "gcs",
+			fsType: dbio.TypeFileGoogle,
+			props: func(serverURL string) []string {
+				return []string{"BUCKET=bucket", "CRED_API_KEY=key"}
+			},
+			handler: func(s *uploadStandIn, serverURL string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					body, _ := io.ReadAll(r.Body)
+					switch {
+					case r.URL.Path == "/upload/storage/v1/b/bucket/o" && r.URL.Query().Get("uploadType") == "resumable":
+						w.Header().Set("Location", serverURL+"/upload/session")
+					case r.URL.Path == "/upload/session":
+						// the chunks are `bytes <start>-<end>/*`, the last one has the total size
+						var start, end int
+						var total string
+						fmt.Sscanf(strings.Replace(r.Header.Get("Content-Range"), "/", " ", 1), "bytes %d-%d %s", &start, &end, &total)
+						if !s.uploadPart(cast.ToString(start), body) {
+							failPart(w)
+							return
+						} else if total == "*" {
+							w.Header().Set("X-Http-Status-Code-Override", "308")
+							return
+						}
+
+						offsets := lo.Map(lo.Keys(s.parts), func(k string, i int) int { return cast.ToInt(k) })
+						sort.Ints(offsets)
+						s.complete(lo.Map(offsets, func(o int, i int) string { return cast.ToString(o) }))
+						w.Header().Set("Content-Type", "application/json")
+						w.Write([]byte(g.Marshal(g.M("bucket", "bucket", "name", "data/file.csv", "size", total))))
+					default:
+						w.WriteHeader(http.StatusNotImplemented)
+					}
+				}
+			},
+		},
+		{
+			name:   "azure",
+			fsType: dbio.TypeFileAzure,
+			props: func(serverURL string) []string {
+				return []string{"ACCOUNT=account", "CONTAINER=container", "SAS_SVC_URL=" + serverURL + "/?sig=signature"}
+			},
+			handler: func(s *uploadStandIn, serverURL string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					query := r.URL.Query()
+					body, _ := io.ReadAll(r.Body)
+					switch {
+					case r.Method == http.MethodPut && query.Get("comp") == "block":
+						if !s.uploadPart(query.Get("blockid"), body) {
+							failPart(w)
+							return
+						}
+						w.WriteHeader(http.StatusCreated)
+					case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+						blockList := struct {
+							Latest []string `xml:"Latest"`
+						}{}
+						xml.Unmarshal(body, &blockList)
+						s.complete(blockList.Latest)
+						w.WriteHeader(http.StatusCreated)
+					default:
+						w.WriteHeader(http.StatusNotImplemented)
+					}
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		standIn := &uploadStandIn{attempts: map[string]int{}, parts: map[string][]byte{}}
+		server := httptest.NewServer(nil)
+		server.Config.Handler = c.handler(standIn, server.URL)
+		if c.fsType == dbio.TypeFileGoogle {
+			t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+		}
+
+		fs, err := NewFileSysClient(c.fsType, append(c.props(server.URL), uploadProps...)...)
+		if !assert.NoError(t, err, c.name) {
+			server.Close()
+			continue
+		}
+
+		bw, err := fs.Write(fs.Prefix("/data/file.csv"), bytes.NewReader(payload))
+		server.Close()
+		if !assert.NoError(t, err, c.name) {
+			continue
+		}
+
+		assert.EqualValues(t, len(payload), bw, c.name)
+		assert.Len(t, standIn.parts, 3, c.name)
+		assert.NotEmpty(t, standIn.failed, c.name)
+		assert.Equal(t, 2, standIn.attempts[standIn.failed], c.name)
+		assert.True(t, bytes.Equal(payload, standIn.object), c.name)
+	}
+}
+
+func TestFileSysLocalAtomic(t *testing.T) {
+	t.Parallel()
+
+	folder := "test/test_write/atomic"
+	os.RemoveAll(folder)
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "name", Type: iop.StringType},
+		}...,
+	)
+	newDf := func() *iop.Dataflow {
+		data := iop.NewDataset(columns)
+		data.Inferred = true
+		for i := 1; i <= 5; i++ {
+			data.Append([]any{int64(i), g.F("name-%d", i)})
+		}
+		df, err := iop.MakeDataFlow(data.Stream())
+		assert.NoError(t, err)
+		return df
+	}
+
+	fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=csv", "ATOMIC=true", "EXEC_ID=exec1",
+		"FILE_MAX_ROWS=2", "SUCCESS_FILE=true", "MANIFEST=true")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// previous output is replaced
+	assert.NoError(t, os.MkdirAll(folder+"/out", 0755))
+	assert.NoError(t, os.WriteFile(folder+"/out/old.csv", []byte("id\n1\n"), 0644))
+
+	_, err = WriteDataflow(fs, newDf(), folder+"/out")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoDirExists(t, folder+"/"+stagingFolder)
+	assert.NoFileExists(t, folder+"/out/old.csv")
+	assert.FileExists(t, folder+"/out/_SUCCESS")
+
+	manifestBytes, err := os.ReadFile(folder + "/out/_manifest.json")
+	if assert.NoError(t, err) {
+		manifest := Manifest{}
+		assert.NoError(t, g.Unmarshal(string(manifestBytes), &manifest))
+		assert.Equal(t, "exec1", manifest.ExecID)
+		assert.EqualValues(t, 5, manifest.Rows)
+		if assert.Len(t, manifest.Files, 3) {
+			rows := int64(0)
+			for _, file := range manifest.Files {
+				assert.FileExists(t, strings.TrimPrefix(file.URI, "file://"))
+				assert.Greater(t, file.Bytes, int64(0))
+				rows += file.Rows
+			}
+			assert.EqualValues(t, 5, rows)
+		}
+	}
+
+	// single file, no marker
+	fs.SetProp("FILE_MAX_ROWS", "0")
+	_, err = WriteDataflow(fs, newDf(), folder+"/single.csv")
+	if assert.NoError(t, err) {
+		assert.FileExists(t, folder+"/single.csv")
+		assert.NoFileExists(t, folder+"/_SUCCESS")
+		assert.NoDirExists(t, folder+"/"+stagingFolder)
+	}
+
+	// a failed move restores the previous output
+	outFiles := func() (files map[string]string) {
+		files = map[string]string{}
+		filepath.Walk(folder+"/out", func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				b, _ := os.ReadFile(path)
+				files[path] = string(b)
+			}
+			return err
+		})
+		return files
+	}
+	previous := outFiles()
+	assert.Len(t, previous, 5) // 3 parts, manifest and marker
+
+	// while moving the previous output aside, and the staged files into place
+	for _, failAt := range []int{3, 6, 8} {
+		failingFs := &failingMoveFileSysClient{LocalFileSysClient: fs.(*LocalFileSysClient), failAt: failAt}
+		var instance FileSysClient = failingFs
+		failingFs.instance = &instance
+		failingFs.SetProp("EXEC_ID", g.F("exec%d", failAt))
+		failingFs.SetProp("FILE_MAX_ROWS", "1")
+
+		_, err = WriteDataflow(failingFs, newDf(), folder+"/out")
+		if assert.Error(t, err, failAt) {
+			assert.Contains(t, err.Error(), "forced move failure", failAt)
+			assert.Equal(t, previous, outFiles(), failAt)
+			assert.NoDirExists(t, folder+"/"+stagingFolder, failAt)
+		}
+	}
+
+	if !t.Failed() {
+		os.RemoveAll(folder)
+	}
+}
+
+// failingMoveFileSysClient fails the nth move
+type failingMoveFileSysClient struct {
+	*LocalFileSysClient
+	failAt int
+	moves  int
+}
+
+func (fs *failingMoveFileSysClient) move(srcURI, tgtURI string) (err error) {
+	if fs.moves++; fs.moves == fs.failAt {
+		return g.Error("forced move failure")
+	}
+	return fs.LocalFileSysClient.move(srcURI, tgtURI)
+}
+
+func TestFileSysLocalPartitionRead(t *testing.T) {
+	t.Parallel()
+
+	folder := "test/test_read/partitioned"
+	os.RemoveAll(folder)
+	defer os.RemoveAll(folder)
+
+	files := map[string]string{
+		"dt=2024-01-01/region=east/data.csv":                       "id,name\n1,a\n2,b\n",
+		"dt=2024-01-01/region=west/data.csv":                       "id,name\n3,c\n",
+		"dt=2024-01-02/region=east/data.csv":                       "id,name\n4,d\n",
+		"dt=2024-01-03/region=a%2Fb/data.csv":                      "id,name\n5,e\n",
+		"dt=2024-01-03/region=__HIVE_DEFAULT_PARTITION__/data.csv": "id,name\n6,f\n",
+	}
+	for path, content := range files {
+		path = folder + "/" + path
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	keys, values := iop.HivePartitions("s3://bucket/events/dt=2024-01-01/region=a%2Fb/file.csv")
+	assert.Equal(t, []string{"dt", "region"}, keys)
+	assert.Equal(t, "a/b", *values["region"])
+
+	type testCase struct {
+		filter string
+		ids    []int
+		err    bool
+	}
+
+	cases := []testCase{
+		{filter: "", ids: []int{1, 2, 3, 4, 5, 6}},
+		{filter: "dt >= '2024-01-02'", ids: []int{4, 5, 6}},
+		{filter: "dt = '2024-01-01' and region != 'east'", ids: []int{3}},
+		{filter: "region in ('east', 'a/b') or region is null", ids: []int{1, 2, 4, 5, 6}},
+		{filter: "not (dt < '2024-01-03') and region is not null", ids: []int{5}},
+		{filter: "other = 1", ids: []int{1, 2, 3, 4, 5, 6}}, // unknown keys are kept
+		{filter: "dt > '2024-02-01'", err: true},
+		{filter: "dt >", err: true},
+	}
+
+	for _, c := range cases {
+		fs, err := NewFileSysClient(dbio.TypeFileLocal, "PARTITION_FILTER="+c.filter, "HIVE_PARTITIONING=true")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		df, err := fs.ReadDataflow(folder)
+		if c.err {
+			assert.Error(t, err, c.filter)
+			continue
+		} else if !assert.NoError(t, err, c.filter) {
+			continue
+		}
+
+		data, err := df.Collect()
+		if !assert.NoError(t, err, c.filter) {
+			continue
+		}
+
+		assert.Equal(t, []string{"id", "name", "dt", "region"}, data.Columns.Names(), c.filter)
+		assert.Equal(t, iop.DateType, data.Columns[2].Type, c.filter)
+
+		ids := []int{}
+		for _, row := range data.Rows {
+			id := cast.ToInt(row[0])
+			ids = append(ids, id)
+			switch id {
+			case 1:
+				assert.Equal(t, "2024-01-01", cast.ToTime(row[2]).Format("2006-01-02"))
+				assert.Equal(t, "east", row[3])
+			case 5:
+				assert.Equal(t, "a/b", row[3])
+			case 6:
+				assert.Nil(t, row[3])
+			}
+		}
+		assert.ElementsMatch(t, c.ids, ids, c.filter)
+	}
+
+	// the partitions are not added as columns by default, but still prune files
+	fs, err := NewFileSysClient(dbio.TypeFileLocal, "PARTITION_FILTER=dt = '2024-01-02'")
+	if !assert.NoError(t, err) {
+		return
+	}
+	df, err := fs.ReadDataflow(folder)
+	if assert.NoError(t, err) {
+		data, err := df.Collect()
+		if assert.NoError(t, err) && assert.Len(t, data.Rows, 1) {
+			assert.Equal(t, []string{"id", "name"}, data.Columns.Names())
+			assert.EqualValues(t, 4, cast.ToInt(data.Rows[0][0]))
+		}
+	}
+}
+
+func TestFileSysLocalDelta(t *testing.T) {
+	t.Parallel()
+
+	folder := "test/test_write/delta_table"
+	os.RemoveAll(folder)
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "region", Type: iop.StringType},
+			{Name: "amount", Type: iop.FloatType},
+		}...,
+	)
+
+	makeData := func(rows ...[]any) iop.Dataset {
+		data := iop.NewDataset(columns)
+		data.Inferred = true
+		for _, row := range rows {
+			data.Append(row)
+		}
+		return data
+	}
+
+	write := func(mode string, data iop.Dataset) bool {
+		fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=delta", "SLING_MODE="+mode, `PARTITION_BY=["region"]`)
+		if !assert.NoError(t, err) {
+			return false
+		}
+		df, err := iop.MakeDataFlow(data.Stream())
+		if !assert.NoError(t, err) {
+			return false
+		}
+		_, err = WriteDataflow(fs, df, folder)
+		return assert.NoError(t, err, mode)
+	}
+
+	readIDs := func() (ids []int) {
+		fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=delta")
+		if !assert.NoError(t, err) {
+			return
+		}
+		df, err := fs.ReadDataflow(folder)
+		if !assert.NoError(t, err) {
+			return
+		}
+		data, err := df.Collect()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.ElementsMatch(t, []string{"id", "amount", "region"}, data.Columns.Names())
+		for _, row := range data.Rows {
+			ids = append(ids, cast.ToInt(row[0]))
+		}
+		return ids
+	}
+
+	// create
+	if !write("full-refresh", makeData([]any{int64(1), "east", 1.5}, []any{int64(2), "west", 2.5}, []any{int64(3), "east", nil})) {
+		return
+	}
+	assert.ElementsMatch(t, []int{1, 2, 3}, readIDs())
+
+	// append
+	if !write("incremental", makeData([]any{int64(4), "west", 4.5}, []any{int64(5), nil, 5.5})) {
+		return
+	}
+	assert.ElementsMatch(t, []int{1, 2, 3, 4, 5}, readIDs())
+
+	// overwrite
+	if !write("full-refresh", makeData([]any{int64(6), "north", 6.5})) {
+		return
+	}
+	assert.ElementsMatch(t, []int{6}, readIDs())
+
+	fs, err := NewFileSysClient(dbio.TypeFileLocal)
+	assert.NoError(t, err)
+
+	snapshot, err := ReadDeltaSnapshot(fs, folder)
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 2, snapshot.Version)
+		assert.Equal(t, []string{"region"}, snapshot.MetaData.PartitionColumns)
+		assert.Len(t, snapshot.Files, 1)
+		for _, add := range snapshot.Files {
+			assert.Equal(t, "north", *add.PartitionValues["region"])
+		}
+
+		cols, err := snapshot.Columns()
+		if assert.NoError(t, err) && assert.Len(t, cols, 3) {
+			assert.Equal(t, iop.BigIntType, cols[0].Type)
+			assert.Equal(t, iop.FloatType, cols[2].Type)
+		}
+	}
+
+	// version 1 added a null partition
+	log1, err := os.ReadFile(folder + "/_delta_log/00000000000000000001.json")
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(log1), `"partitionValues":{"region":null}`)
+		assert.Contains(t, string(log1), `"mode":"Append"`)
+	}
+
+	// unsupported protocol features fail instead of returning wrong data
+	protocols := []struct{ action, expected string }{
+		{`{"protocol":{"minReaderVersion":2,"minWriterVersion":5}}`, "column mapping"},
+		{`{"protocol":{"minReaderVersion":3,"minWriterVersion":7,"readerFeatures":["deletionVectors"]}}`, "deletionVectors"},
+		{`{"add":{"path":"x.parquet","partitionValues":{},"size":1,"deletionVector":{"storageType":"u"}}}`, "deletion vectors"},
+	}
+	log3 := folder + "/_delta_log/00000000000000000003.json"
+	for _, protocol := range protocols {
+		assert.NoError(t, os.WriteFile(log3, []byte(protocol.action+"\n"), 0644))
+		_, err = ReadDeltaSnapshot(fs, folder)
+		if assert.Error(t, err, protocol.expected) {
+			assert.Contains(t, err.Error(), protocol.expected)
+		}
+	}
+	os.Remove(log3)
+
+	// a missing table is an empty snapshot
+	snapshot, err = ReadDeltaSnapshot(fs, folder+"_missing")
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, -1, snapshot.Version)
+		assert.Empty(t, snapshot.Files)
+	}
+
+	if !t.Failed() {
+		os.RemoveAll(folder)
+	}
+}
+
+func TestFileSysLocalIceberg(t *testing.T) {
+	t.Parallel()
+
+	folder := "test/test_read/iceberg_table"
+	location := "s3://warehouse/db/events" // original location, resolved to the folder
+	os.RemoveAll(folder)
+	defer os.RemoveAll(folder)
+
+	fs, err := NewFileSysClient(dbio.TypeFileLocal)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// data files
+	columns := iop.NewColumns(iop.Columns{{Name: "id", Type: iop.BigIntType}, {Name: "region", Type: iop.StringType}}...)
+	dataFiles := map[string][][]any{
+		"data/region=east/00000-1.parquet": {{int64(1), "east"}, {int64(2), "east"}},
+		"data/region=west/00000-2.parquet": {{int64(3), "west"}},
+	}
+	for path, rows := range dataFiles {
+		data := iop.NewDataset(columns)
+		data.Inferred = true
+		data.Rows = rows
+		df, err := iop.MakeDataFlow(data.Stream())
+		assert.NoError(t, err)
+		fs.SetProp("FORMAT", "parquet")
+		_, err = WriteDataflow(fs, df, folder+"/"+path)
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+
+	writeAvro := func(path, schema string, records ...map[string]any) {
+		var buf bytes.Buffer
+		w, err := goavro.NewOCFWriter(goavro.OCFConfig{W: &buf, Schema: schema, CompressionName: "deflate"})
+		if assert.NoError(t, err) {
+			for _, record := range records {
+				assert.NoError(t, w.Append([]any{record}))
+			}
+		}
+		assert.NoError(t, os.WriteFile(folder+"/"+path, buf.Bytes(), 0644))
+	}
+
+	manifestSchema := `{"type":"record","name":"manifest_entry","fields":[
+		{"name":"status","type":"int"},
+		{"name":"snapshot_id","type":["null","long"],"default":null},
+		{"name":"data_file","type":{"type":"record","name":"r2","fields":[
+			{"name":"content","type":"int","default":0},
+			{"name":"file_path","type":"string"},
+			{"name":"file_format","type":"string"},
+			{"name":"partition","type":{"type":"record","name":"r102","fields":[{"name":"region","type":["null","string"],"default":null}]}},
+			{"name":"record_count","type":"long"},
+			{"name":"file_size_in_bytes","type":"long"}]}}]}`
+	manifestListSchema := `{"type":"record","name":"manifest_file","fields":[
+		{"name":"manifest_path","type":"string"},
+		{"name":"manifest_length","type":"long"},
+		{"name":"partition_spec_id","type":"int"},
+		{"name":"content","type":"int","default":0},
+		{"name":"added_snapshot_id","type":"long"}]}`
+
+	entry := func(status int, path, region string) map[string]any {
+		return map[string]any{
+			"status":      status,
+			"snapshot_id": goavro.Union("long", int64(1)),
+			"data_file": map[string]any{
+				"content":            0,
+				"file_path":          location + "/" + path,
+				"file_format":        "PARQUET",
+				"partition":          map[string]any{"region": goavro.Union("string", region)},
+				"record_count":       int64(len(dataFiles[path])),
+				"file_size_in_bytes": int64(100),
+			},
+		}
+	}
+	manifest := func(path string) map[string]any {
+		return map[string]any{"manifest_path": location + "/" + path, "manifest_length": int64(100), "partition_spec_id": 0, "content": 0, "added_snapshot_id": int64(1)}
+	}
+
+	assert.NoError(t, os.MkdirAll(folder+"/metadata", 0755))
+	writeAvro("metadata/m1.avro", manifestSchema, entry(1, "data/region=east/00000-1.parquet", "east"))
+	writeAvro("metadata/m2.avro", manifestSchema, entry(1, "data/region=west/00000-2.parquet", "west"), entry(2, "data/region=west/00000-0.parquet", "west"))
+	writeAvro("metadata/snap-1.avro", manifestListSchema, manifest("metadata/m1.avro"))
+	writeAvro("metadata/snap-2.avro", manifestListSchema, manifest("metadata/m1.avro"), manifest("metadata/m2.avro"))
+
+	snapshots := []map[string]any{
+		{"snapshot-id": 1, "timestamp-ms": 1704067200000, "manifest-list": location + "/metadata/snap-1.avro"},
+		{"snapshot-id": 2, "timestamp-ms": 1704153600000, "manifest-list": location + "/metadata/snap-2.avro"},
+	}
+	for version := 1; version <= 2; version++ {
+		metadata := g.M(
+			"format-version", 2,
+			"location", location,
+			"current-snapshot-id", version,
+			"snapshots", snapshots[:version],
+			"default-spec-id", 0,
+			"partition-specs", []any{g.M("spec-id", 0, "fields", []any{g.M("name", "region", "transform", "identity", "source-id", 2, "field-id", 1000)})},
+		)
+		assert.NoError(t, os.WriteFile(g.F("%s/metadata/v%d.metadata.json", folder, version), []byte(g.Marshal(metadata)), 0644))
+	}
+	assert.NoError(t, os.WriteFile(folder+"/metadata/version-hint.text", []byte("2"), 0644))
+
+	type testCase struct {
+		props []string
+		ids   []int
+	}
+
+	cases := []testCase{
+		{props: []string{}, ids: []int{1, 2, 3}},
+		{props: []string{"SNAPSHOT_ID=1"}, ids: []int{1, 2}},
+		{props: []string{"SNAPSHOT_TIMESTAMP=2024-01-01 12:00:00"}, ids: []int{1, 2}},
+		{props: []string{"PARTITION_FILTER=region = 'west'"}, ids: []int{3}},
+	}
+
+	for _, c := range cases {
+		fs, err := NewFileSysClient(dbio.TypeFileLocal, append(c.props, "FORMAT=iceberg")...)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		df, err := fs.ReadDataflow(folder)
+		if !assert.NoError(t, err, c.props) {
+			continue
+		}
+
+		data, err := df.Collect()
+		if !assert.NoError(t, err, c.props) {
+			continue
+		}
+
+		assert.Equal(t, []string{"id", "region"}, data.Columns.Names(), c.props)
+		ids := lo.Map(data.Rows, func(row []any, i int) int { return cast.ToInt(row[0]) })
+		assert.ElementsMatch(t, c.ids, ids, c.props)
+	}
+
+	// read through the metadata file url, of the first version
+	fs, _ = NewFileSysClient(dbio.TypeFileLocal, "FORMAT=iceberg")
+	df, err := fs.ReadDataflow("file://" + folder + "/metadata/v1.metadata.json")
+	if assert.NoError(t, err) {
+		data, err := df.Collect()
+		if assert.NoError(t, err) {
+			ids := lo.Map(data.Rows, func(row []any, i int) int { return cast.ToInt(row[0]) })
+			assert.ElementsMatch(t, []int{1, 2}, ids)
+		}
+	}
+
+	// snapshot resolution
+	table, err := NewIcebergTable(fs, folder+"/metadata/v1.metadata.json")
+	if assert.NoError(t, err) {
+		assert.Equal(t, NormalizeURI(fs, folder), table.URL)
+		snapshot, err := table.Snapshot(0, time.Time{})
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, 1, snapshot.SnapshotID)
+		}
+		_, err = table.Snapshot(5, time.Time{})
+		assert.Error(t, err)
+	}
+
+	// row-level deletes are not supported, instead of returning deleted rows
+	deleteEntry := entry(1, "data/region=east/00000-1-deletes.parquet", "east")
+	deleteEntry["data_file"].(map[string]any)["content"] = 1 // position deletes
+	deleteManifest := manifest("metadata/m3.avro")
+	deleteManifest["content"] = 1
+	writeAvro("metadata/m3.avro", manifestSchema, deleteEntry)
+	writeAvro("metadata/snap-3.avro", manifestListSchema, manifest("metadata/m1.avro"), deleteManifest)
+
+	table, err = NewIcebergTable(fs, folder)
+	if assert.NoError(t, err) {
+		snapshot := &IcebergSnapshot{SnapshotID: 3, ManifestList: location + "/metadata/snap-3.avro"}
+		_, err = table.DataFiles(snapshot)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "row-level delete files")
+		}
+	}
+}
+
 func TestFileSysDOSpaces(t *testing.T) {
 	fs, err := NewFileSysClient(
 		dbio.TypeFileS3,
@@ -956,6 +2458,299 @@ func TestFileSysFtp(t *testing.T) {
 	// assert.EqualValues(t, 1036, df2.Count())
 }
 
+// webHDFSStandIn is an in-memory stand-in for the WebHDFS REST API.
+// OPEN and CREATE are redirected to a "datanode" path, like a namenode does,
+// unless noRedirect is set (CREATE then makes an empty file).
+type webHDFSStandIn struct {
+	mux        sync.Mutex
+	files      map[string][]byte
+	dirs       map[string]bool
+	user       string
+	noRedirect bool
+}
+
+func (s *webHDFSStandIn) mkdirs(p string) {
+	for ; p != "/" && p != "."; p = path.Dir(p) {
+		s.dirs[p] = true
+	}
+}
+
+func (s *webHDFSStandIn) status(p string) (status g.Map, ok bool) {
+	if s.dirs[p] || p == "/" {
+		return g.M("pathSuffix", path.Base(p), "type", "DIRECTORY", "length", 0, "modificationTime", 1700000000000), true
+	} else if data, ok := s.files[p]; ok {
+		return g.M("pathSuffix", path.Base(p), "type", "FILE", "length", len(data), "modificationTime", 1700000000000), true
+	}
+	return nil, false
+}
+
+func (s *webHDFSStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	writeJSON := func(status int, payload any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(g.Marshal(payload)))
+	}
+	notFound := func(p string) {
+		writeJSON(http.StatusNotFound, g.M("RemoteException", g.M(
+			"exception", "FileNotFoundException",
+			"message", "File does not exist: "+p,
+		)))
+	}
+
+	if p, ok := strings.CutPrefix(r.URL.Path, "/datanode"); ok {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write(s.files[p])
+		case http.MethodPut:
+			s.files[p], _ = io.ReadAll(r.Body)
+			s.mkdirs(path.Dir(p))
+			w.WriteHeader(http.StatusCreated)
+		}
+		return
+	}
+
+	p := path.Clean("/" + strings.TrimPrefix(r.URL.Path, "/webhdfs/v1"))
+	s.user = r.URL.Query().Get("user.name")
+
+	switch r.URL.Query().Get("op") {
+	case "GETFILESTATUS":
+		if status, ok := s.status(p); ok {
+			writeJSON(http.StatusOK, g.M("FileStatus", status))
+		} else {
+			notFound(p)
+		}
+	case "LISTSTATUS":
+		if _, ok := s.status(p); !ok {
+			notFound(p)
+			return
+		}
+		statuses := []g.Map{}
+		children := append(lo.Keys(s.dirs), lo.Keys(s.files)...)
+		sort.Strings(children)
+		for _, child := range children {
+			if path.Dir(child) == p {
+				status, _ := s.status(child)
+				statuses = append(statuses, status)
+			}
+		}
+		writeJSON(http.StatusOK, g.M("FileStatuses", g.M("FileStatus", statuses)))
+	case "OPEN", "CREATE":
+		if _, ok := s.files[p]; !ok && r.Method == http.MethodGet {
+			notFound(p)
+			return
+		} else if s.noRedirect && r.Method == http.MethodPut {
+			s.files[p], _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		http.Redirect(w, r, "http://"+r.Host+"/datanode"+p, http.StatusTemporaryRedirect)
+	case "MKDIRS":
+		s.mkdirs(p)
+		writeJSON(http.StatusOK, g.M("boolean", true))
+	case "DELETE":
+		_, existed := s.status(p)
+		for _, child := range append(lo.Keys(s.dirs), lo.Keys(s.files)...) {
+			if child == p || strings.HasPrefix(child, p+"/") {
+				delete(s.dirs, child)
+				delete(s.files, child)
+			}
+		}
+		writeJSON(http.StatusOK, g.M("boolean", existed))
+	default:
+		http.Error(w, "unsupported operation", http.StatusBadRequest)
+	}
+}
+
+func TestFileSysHDFS(t *testing.T) {
+	t.Parallel()
+
+	standIn := &webHDFSStandIn{files: map[string][]byte{}, dirs: map[string]bool{}}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	root := "hdfs://" + strings.TrimPrefix(server.URL, "http://")
+	fs, err := NewFileSysClientFromURL(root, "USER=sling")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	csvBytes, err := os.ReadFile("test/test1/csv/test1.csv")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testPath := root + "/data/test1.csv"
+	bw, err := fs.Write(testPath, bytes.NewReader(csvBytes))
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(csvBytes), bw)
+	assert.Equal(t, csvBytes, standIn.files["/data/test1.csv"])
+	assert.True(t, standIn.dirs["/data"])
+	assert.Equal(t, "sling", standIn.user)
+
+	// the data is only sent to a datanode, fail if not redirected
+	standIn.mux.Lock()
+	standIn.noRedirect = true
+	standIn.mux.Unlock()
+	_, err = fs.Write(root+"/data/direct.csv", bytes.NewReader(csvBytes))
+	assert.ErrorContains(t, err, "not redirected to a datanode")
+	standIn.mux.Lock()
+	delete(standIn.files, "/data/direct.csv")
+	standIn.noRedirect = false
+	standIn.mux.Unlock()
+
+	reader, err := fs.GetReader(testPath)
+	if assert.NoError(t, err) {
+		testBytes, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, csvBytes, testBytes)
+	}
+
+	_, err = fs.GetReader(root + "/data/missing.csv")
+	assert.ErrorContains(t, err, "FileNotFoundException")
+
+	// write several files into a folder
+	df, err := fs.ReadDataflow(testPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	fs.SetProp("FILE_MAX_ROWS", "400")
+	_, err = WriteDataflow(fs, df, root+"/data/parts")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1000, df.Count())
+	fs.SetProp("FILE_MAX_ROWS", "0")
+
+	nodes, err := fs.ListRecursive(root + "/data/")
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 4)
+
+	nodes, err = fs.ListRecursive(root + "/data/parts/*.csv")
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 3)
+
+	df, err = fs.ReadDataflow(root + "/data/parts")
+	if assert.NoError(t, err) {
+		data, err := df.Collect()
+		assert.NoError(t, err)
+		assert.Len(t, data.Rows, 1000)
+	}
+
+	err = Delete(fs, root+"/data/parts")
+	assert.NoError(t, err)
+
+	nodes, err = fs.List(root + "/data/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{testPath}, nodes.URIs())
+}
+
+func TestFileSysWebDAV(t *testing.T) {
+	t.Parallel()
+
+	davHandler := &webdav.Handler{FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); ok && user == "sling" && password == "secret" {
+			davHandler.ServeHTTP(w, r)
+		} else if r.Header.Get("Authorization") == "Bearer my-token" {
+			davHandler.ServeHTTP(w, r)
+		} else {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	root := "webdav://" + strings.TrimPrefix(server.URL, "http://")
+	fs, err := NewFileSysClientFromURL(root, "USER=sling", "PASSWORD=secret", "HTTPS=false")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	csvBytes, err := os.ReadFile("test/test1/csv/test1.csv")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// parent folders are created
+	testPath := root + "/data/in/test1.csv"
+	_, err = fs.Write(testPath, bytes.NewReader(csvBytes))
+	assert.NoError(t, err)
+
+	reader, err := fs.GetReader(testPath)
+	if assert.NoError(t, err) {
+		testBytes, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, csvBytes, testBytes)
+	}
+
+	nodes, err := fs.List(testPath)
+	if assert.NoError(t, err) && assert.Len(t, nodes, 1) {
+		assert.Equal(t, testPath, nodes[0].URI)
+		assert.EqualValues(t, len(csvBytes), nodes[0].Size)
+		assert.False(t, nodes[0].IsDir)
+	}
+
+	nodes, err = fs.List(root + "/data/missing.csv")
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 0)
+
+	_, err = fs.GetReader(root + "/data/missing.csv")
+	assert.ErrorContains(t, err, "status 404")
+
+	// write several files into a folder
+	df, err := fs.ReadDataflow(testPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	fs.SetProp("FILE_MAX_ROWS", "400")
+	_, err = WriteDataflow(fs, df, root+"/data/parts")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1000, df.Count())
+	fs.SetProp("FILE_MAX_ROWS", "0")
+
+	nodes, err = fs.List(root + "/data/")
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, 2, lo.CountBy(nodes, func(n dbio.FileNode) bool { return n.IsDir }))
+
+	nodes, err = fs.ListRecursive(root + "/data/")
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 4)
+
+	nodes, err = fs.ListRecursive(root + "/data/parts/*.csv")
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 3)
+
+	// bearer token auth
+	fsToken, err := NewFileSysClientFromURL(root, "TOKEN=my-token", "HTTPS=false")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	df, err = fsToken.ReadDataflow(root + "/data/parts")
+	if assert.NoError(t, err) {
+		data, err := df.Collect()
+		assert.NoError(t, err)
+		assert.Len(t, data.Rows, 1000)
+	}
+
+	err = Delete(fsToken, root+"/data/parts")
+	assert.NoError(t, err)
+
+	nodes, err = fsToken.ListRecursive(root + "/data/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{testPath}, nodes.URIs())
+
+	// wrong credentials
+	fsWrong, err := NewFileSysClientFromURL(root, "USER=sling", "PASSWORD=wrong", "HTTPS=false")
+	if assert.NoError(t, err) {
+		_, err = fsWrong.List(root + "/data/")
+		assert.ErrorContains(t, err, "status 401")
+	}
+}
+
 func TestFileSysHTTP(t *testing.T) {
 	fs, err := NewFileSysClient(
 		dbio.TypeFileHTTP, //"HTTP_USER=user", "HTTP_PASSWORD=password",
@@ -990,6 +2785,163 @@ func TestFileSysHTTP(t *testing.T) {
 	}
 }
 
+func TestFileSysHTTPParquetRange(t *testing.T) {
+	t.Parallel()
+
+	folder := "test/test_write/parquet_range"
+	os.RemoveAll(folder)
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "name", Type: iop.StringType},
+			{Name: "notes", Type: iop.StringType},
+		}...,
+	)
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	for i := 0; i < 5000; i++ {
+		data.Append([]any{int64(i), g.F("name_%d", i), g.RandString(g.AlphaRunes, 200)})
+	}
+
+	localFs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT=parquet")
+	if !assert.NoError(t, err) {
+		return
+	}
+	df, err := iop.MakeDataFlow(data.Stream())
+	assert.NoError(t, err)
+	_, err = WriteDataflow(localFs, df, folder+"/data.parquet")
+	if !assert.NoError(t, err) {
+		return
+	}
+	stat, err := os.Stat(folder + "/data.parquet")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// serve the file, counting the range requests and bytes
+	var bytesServed, rangeRequests int64
+	fileServer := http.FileServer(http.Dir(folder))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "" {
+			fileServer.ServeHTTP(w, r) // listing
+			return
+		}
+		atomic.AddInt64(&rangeRequests, 1)
+		fileServer.ServeHTTP(&countingResponseWriter{ResponseWriter: w, bytes: &bytesServed}, r)
+	}))
+	defer server.Close()
+
+	fs, err := NewFileSysClient(dbio.TypeFileHTTP, "FORMAT=parquet")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	uri := server.URL + "/data.parquet"
+	reader, err := NewRangeReader(fs, uri)
+	if assert.NoError(t, err) {
+		assert.Equal(t, stat.Size(), reader.Size())
+	}
+
+	df, err = fs.ReadDataflow(uri, FileStreamConfig{Select: []string{"id", "name"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	data2, err := df.Collect()
+	if assert.NoError(t, err) && assert.Len(t, data2.Rows, 5000) {
+		assert.Equal(t, []string{"id", "name"}, data2.Columns.Names())
+		assert.EqualValues(t, 4999, data2.Rows[4999][0])
+		assert.Equal(t, "name_4999", data2.Rows[4999][1])
+	}
+
+	// the notes column chunks were not fetched
+	assert.Greater(t, atomic.LoadInt64(&rangeRequests), int64(0))
+	assert.Less(t, atomic.LoadInt64(&bytesServed), stat.Size()/2)
+
+	if !t.Failed() {
+		os.RemoveAll(folder)
+	}
+}
+
+func TestFileSysLocalWhere(t *testing.T) {
+	t.Parallel()
+
+	folder := "test/test_write/where"
+	os.RemoveAll(folder)
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "name", Type: iop.StringType},
+		}...,
+	)
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	for i := 0; i < 5000; i++ {
+		data.Append([]any{int64(i), g.F("name_%d", i)})
+	}
+
+	for _, format := range []FileType{FileTypeParquet, FileTypeCsv} {
+		fs, err := NewFileSysClient(dbio.TypeFileLocal, "FORMAT="+string(format))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		path := g.F("%s/data.%s", folder, format)
+		df, err := iop.MakeDataFlow(data.Stream())
+		assert.NoError(t, err)
+		_, err = WriteDataflow(fs, df, path)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		fs.SetProp("WHERE", "id >= 4000 and name != 'name_4500'")
+		df, err = fs.ReadDataflow(path, FileStreamConfig{Select: []string{"name"}})
+		if !assert.NoError(t, err, format) {
+			return
+		}
+		data2, err := df.Collect()
+		if assert.NoError(t, err, format) && assert.Len(t, data2.Rows, 999, format) {
+			assert.Equal(t, []string{"name"}, data2.Columns.Names(), format)
+			assert.Equal(t, "name_4000", data2.Rows[0][0], format)
+		}
+
+		fs.SetProp("WHERE", "id < 10 or name in ('name_20', 'name_30')")
+		df, err = fs.ReadDataflow(path)
+		if !assert.NoError(t, err, format) {
+			return
+		}
+		data2, err = df.Collect()
+		if assert.NoError(t, err, format) && assert.Len(t, data2.Rows, 12, format) {
+			assert.Equal(t, []string{"id", "name"}, data2.Columns.Names(), format)
+		}
+
+		fs.SetProp("WHERE", "missing = 1")
+		df, err = fs.ReadDataflow(path)
+		if err == nil {
+			_, err = df.Collect()
+		}
+		if assert.Error(t, err, format) {
+			assert.Contains(t, err.Error(), "column missing of where not found", format)
+		}
+	}
+
+	if !t.Failed() {
+		os.RemoveAll(folder)
+	}
+}
+
+// countingResponseWriter counts the bytes written
+type countingResponseWriter struct {
+	http.ResponseWriter
+	bytes *int64
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	atomic.AddInt64(w.bytes, int64(len(p)))
+	return w.ResponseWriter.Write(p)
+}
+
 func testManyCSV(t *testing.T) {
 	fs, err := NewFileSysClient(dbio.TypeFileHTTP, "concurrencyLimit=5")
 	nodes, err := fs.List("https://people.sc.fsu.edu/~jburkardt/data/csv/csv.html")
@@ -1025,3 +2977,59 @@ func testManyCSV(t *testing.T) {
 		}
 	}
 }
+
+type testFileSysClient struct {
+	BaseFileSysClient
+	deleted   []string
+	deleteErr error
+}
+
+func (fs *testFileSysClient) Init(ctx context.Context) (err error) {
+	return nil
+}
+
+func (fs *testFileSysClient) Prefix(suffix ...string) string {
+	return "testfs://bucket" + strings.Join(suffix, "")
+}
+
+func (fs *testFileSysClient) Delete(path string) (err error) {
+	if fs.deleteErr != nil {
+		return fs.deleteErr
+	}
+	fs.deleted = append(fs.deleted, path)
+	return nil
+}
+
+func TestFileSysRegister(t *testing.T) {
+	err := RegisterFileSystem(dbio.TypeFileS3, func() FileSysClient { return &testFileSysClient{} })
+	assert.Error(t, err)
+
+	err = RegisterFileSystem(dbio.TypeDbPostgres, func() FileSysClient { return &testFileSysClient{} })
+	assert.Error(t, err)
+
+	err = RegisterFileSystem("testfs", func() FileSysClient { return &testFileSysClient{} })
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	fsType, ok := dbio.ValidateType("testfs")
+	assert.True(t, ok)
+	assert.True(t, fsType.IsFile())
+	assert.Equal(t, "FileSys - testfs", fsType.NameLong())
+
+	fs, err := NewFileSysClientFromURL("testfs://bucket/folder/file.csv")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, fsType, fs.FsType())
+	assert.Equal(t, "testfs://bucket/folder/file.csv", fs.GetProp("URL"))
+
+	err = fs.Self().delete("testfs://bucket/folder/file.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"testfs://bucket/folder/file.csv"}, fs.(*testFileSysClient).deleted)
+
+	// Delete ignores failures, DeleteStrict returns them
+	fs.(*testFileSysClient).deleteErr = g.Error("permission denied")
+	assert.NoError(t, Delete(fs, "testfs://bucket/folder/file.csv"))
+	assert.ErrorContains(t, DeleteStrict(fs, "testfs://bucket/folder/file.csv"), "permission denied")
+}
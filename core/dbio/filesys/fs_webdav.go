@@ -0,0 +1,433 @@
+package filesys
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/spf13/cast"
+)
+
+// WebDAVFileSysClient is for WebDAV servers (Nextcloud, SharePoint, Apache, etc.)
+// http://www.webdav.org/specs/rfc4918.html
+type WebDAVFileSysClient struct {
+	BaseFileSysClient
+	client  *http.Client
+	baseURL string
+}
+
+// webdavMultiStatus is the response of a PROPFIND request
+type webdavMultiStatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		PropStat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ContentLength string `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+				Owner         string `xml:"owner"`
+				ResourceType  struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+const webdavPropFindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+  <d:prop>
+    <d:resourcetype/>
+    <d:getcontentlength/>
+    <d:getlastmodified/>
+    <d:owner/>
+  </d:prop>
+</d:propfind>`
+
+// Init initializes the fs client
+func (fs *WebDAVFileSysClient) Init(ctx context.Context) (err error) {
+	var instance FileSysClient
+	instance = fs
+	fs.BaseFileSysClient.instance = &instance
+	fs.BaseFileSysClient.context = g.NewContext(ctx)
+	return fs.Connect()
+}
+
+// Prefix returns the url prefix
+func (fs *WebDAVFileSysClient) Prefix(suffix ...string) string {
+	return g.F("%s://%s:%s", fs.FsType().String(), fs.GetProp("host"), fs.GetProp("port")) + strings.Join(suffix, "")
+}
+
+// GetPath returns the path of url
+func (fs *WebDAVFileSysClient) GetPath(uri string) (path string, err error) {
+	// normalize, in case url is provided without prefix
+	uri = NormalizeURI(fs, uri)
+
+	_, path, err = ParseURL(uri)
+	if err != nil {
+		return
+	}
+
+	return path, err
+}
+
+// Connect sets up the http client, with the TLS options
+func (fs *WebDAVFileSysClient) Connect() (err error) {
+	if fs.GetProp("URL") != "" {
+		u, err := url.Parse(fs.GetProp("URL"))
+		if err != nil {
+			return g.Error(err, "could not parse WebDAV URL")
+		}
+
+		if user := u.User.Username(); user != "" {
+			fs.SetProp("USER", user)
+		}
+		if password, _ := u.User.Password(); password != "" {
+			fs.SetProp("PASSWORD", password)
+		}
+		if host := u.Hostname(); host != "" {
+			fs.SetProp("HOST", host)
+		}
+		if port := u.Port(); port != "" {
+			fs.SetProp("PORT", port)
+		}
+	}
+
+	// https, unless disabled
+	useHTTPS := fs.GetProp("HTTPS") == "" || cast.ToBool(fs.GetProp("HTTPS"))
+
+	if fs.GetProp("HOST") == "" {
+		return g.Error("WebDAV host is required")
+	} else if fs.GetProp("PORT") == "" {
+		fs.SetProp("PORT", lo.Ternary(useHTTPS, "443", "80"))
+	}
+
+	scheme := lo.Ternary(useHTTPS, "https", "http")
+	fs.baseURL = g.F("%s://%s:%s", scheme, fs.GetProp("HOST"), fs.GetProp("PORT"))
+
+	tlsConfig, err := fs.tlsConfig()
+	if err != nil {
+		return g.Error(err, "could not set WebDAV TLS options")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	fs.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+// tlsConfig returns the TLS config from the `insecure`, `cert_file`,
+// `cert_key_file` and `cert_ca_file` properties
+func (fs *WebDAVFileSysClient) tlsConfig() (tlsConfig *tls.Config, err error) {
+	tlsConfig = &tls.Config{InsecureSkipVerify: cast.ToBool(fs.GetProp("INSECURE"))}
+
+	if cert, key := fs.GetProp("CERT_FILE"), fs.GetProp("CERT_KEY_FILE"); cert != "" && key != "" {
+		clientCert, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, g.Error(err, "Failed to load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	if caCert := fs.GetProp("CERT_CA_FILE"); caCert != "" {
+		caBytes, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, g.Error(err, "Failed to load CA certificate")
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if ok := tlsConfig.RootCAs.AppendCertsFromPEM(caBytes); !ok {
+			return nil, g.Error("Failed to parse PEM file")
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// request sends a WebDAV request, with basic or bearer auth
+func (fs *WebDAVFileSysClient) request(method, path string, headers map[string]string, body io.Reader) (resp *http.Response, err error) {
+	reqURL := fs.baseURL + (&url.URL{Path: "/" + strings.TrimPrefix(path, "/")}).EscapedPath()
+	req, err := http.NewRequestWithContext(fs.Context().Ctx, method, reqURL, body)
+	if err != nil {
+		return nil, g.Error(err, "could not construct request")
+	}
+
+	if token := fs.GetProp("TOKEN", "BEARER_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if user := fs.GetProp("USER"); user != "" {
+		req.SetBasicAuth(user, fs.GetProp("PASSWORD"))
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	g.Trace("%s %s", method, reqURL)
+	resp, err = fs.client.Do(req)
+	if err != nil {
+		return nil, g.Error(err, "could not %s %s", method, path)
+	}
+
+	return resp, nil
+}
+
+// requestOK sends a WebDAV request, and returns an error if the status is not successful
+func (fs *WebDAVFileSysClient) requestOK(method, path string, headers map[string]string, body io.Reader) (resp *http.Response, err error) {
+	resp, err = fs.request(method, path, headers, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBytes, _ := io.ReadAll(resp.Body)
+		return resp, g.Error("could not %s %s (status %d): %s", method, path, resp.StatusCode, string(respBytes))
+	}
+
+	return resp, nil
+}
+
+// propFind returns the nodes of the path (depth 0) or of the path
+// and its children (depth 1). A missing path returns no nodes.
+func (fs *WebDAVFileSysClient) propFind(path string, depth int) (nodes dbio.FileNodes, err error) {
+	headers := map[string]string{
+		"Depth":        cast.ToString(depth),
+		"Content-Type": "application/xml; charset=utf-8",
+	}
+	resp, err := fs.request("PROPFIND", path, headers, strings.NewReader(webdavPropFindBody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nodes, nil
+	} else if resp.StatusCode != http.StatusMultiStatus && resp.StatusCode >= 300 {
+		respBytes, _ := io.ReadAll(resp.Body)
+		return nil, g.Error("could not list %s (status %d): %s", path, resp.StatusCode, string(respBytes))
+	}
+
+	result := webdavMultiStatus{}
+	if err = xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, g.Error(err, "could not parse PROPFIND response of %s", path)
+	}
+
+	for _, response := range result.Responses {
+		href, err := url.Parse(response.Href)
+		if err != nil {
+			return nil, g.Error(err, "could not parse href: %s", response.Href)
+		}
+
+		node := dbio.FileNode{URI: fs.Prefix("/") + strings.Trim(href.Path, "/")}
+		for _, propStat := range response.PropStat {
+			if !strings.Contains(propStat.Status, " 200 ") {
+				continue
+			}
+
+			prop := propStat.Prop
+			node.IsDir = prop.ResourceType.Collection != nil
+			node.Size = cast.ToUint64(prop.ContentLength)
+			node.Owner = prop.Owner
+			if modified, err := time.Parse(time.RFC1123, prop.LastModified); err == nil {
+				node.Updated = modified.Unix()
+			}
+		}
+		nodes.Add(node)
+	}
+
+	return
+}
+
+// isSelf returns true if the node is the listed path itself
+func (fs *WebDAVFileSysClient) isSelf(node dbio.FileNode, path string) bool {
+	return strings.TrimSuffix(node.URI, "/") == fs.Prefix("/")+strings.Trim(path, "/")
+}
+
+// List list objects in path
+func (fs *WebDAVFileSysClient) List(url string) (nodes dbio.FileNodes, err error) {
+	path, err := fs.GetPath(url)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+url)
+		return
+	}
+
+	// without a trailing slash, a folder is listed as itself
+	depth := lo.Ternary(strings.HasSuffix(path, "/"), 1, 0)
+	propNodes, err := fs.propFind(path, depth)
+	if err != nil {
+		return nodes, g.Error(err, "error listing path: %#v", path)
+	}
+
+	for _, node := range propNodes {
+		if depth == 1 && node.IsDir && fs.isSelf(node, path) {
+			continue
+		}
+		nodes.Add(node)
+	}
+
+	return
+}
+
+// ListRecursive list objects in path recursively
+func (fs *WebDAVFileSysClient) ListRecursive(uri string) (nodes dbio.FileNodes, err error) {
+	path, err := fs.GetPath(uri)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+uri)
+		return
+	}
+
+	pattern, err := makeGlob(NormalizeURI(fs, uri))
+	if err != nil {
+		err = g.Error(err, "Error Parsing url pattern: "+uri)
+		return
+	}
+
+	ts := fs.GetRefTs().Unix()
+
+	path = strings.TrimSuffix(path, "/")
+	if pattern != nil {
+		// list from the folder of the glob
+		path = strings.TrimSuffix(strings.Split(path, "*")[0], "/")
+		if i := strings.LastIndex(path, "/"); i > -1 {
+			path = path[:i]
+		}
+	}
+
+	// many servers disable `Depth: infinity`, so list each folder
+	propNodes, err := fs.propFind(path+"/", 1)
+	if err != nil {
+		return nodes, g.Error(err, "error listing path: %#v", path)
+	}
+
+	for _, node := range propNodes {
+		if node.IsDir && fs.isSelf(node, path) {
+			continue
+		} else if node.IsDir {
+			subNodes, err := fs.ListRecursive(strings.TrimSuffix(node.URI, "/") + "/")
+			if err != nil {
+				return nil, g.Error(err, "error listing sub path")
+			}
+			nodes.AddWhere(pattern, ts, subNodes...)
+		} else {
+			nodes.AddWhere(pattern, ts, node)
+		}
+	}
+
+	return
+}
+
+// delete deletes the path, recursively
+func (fs *WebDAVFileSysClient) delete(urlStr string) (err error) {
+	path, err := fs.GetPath(urlStr)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+urlStr)
+		return
+	}
+
+	resp, err := fs.request(http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return g.Error(err, "error deleting path")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		respBytes, _ := io.ReadAll(resp.Body)
+		return g.Error("error deleting path %s (status %d): %s", path, resp.StatusCode, string(respBytes))
+	}
+	return nil
+}
+
+// MkdirAll creates child directories
+func (fs *WebDAVFileSysClient) MkdirAll(uri string) (err error) {
+	path, err := fs.GetPath(uri)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+uri)
+		return
+	}
+
+	return fs.mkdirAll(path)
+}
+
+// mkdirAll creates each folder of the path with MKCOL
+func (fs *WebDAVFileSysClient) mkdirAll(path string) (err error) {
+	folder := ""
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		folder = folder + "/" + part
+
+		resp, err := fs.request("MKCOL", folder+"/", nil, nil)
+		if err != nil {
+			return g.Error(err, "could not create directory %s", folder)
+		}
+		resp.Body.Close()
+
+		// 405 (Method Not Allowed) is returned when the folder exists
+		if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMethodNotAllowed {
+			return g.Error("could not create directory %s (status %d)", folder, resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+// Write uploads a file, replacing an existing one.
+// Parent directories are created as needed.
+func (fs *WebDAVFileSysClient) Write(urlStr string, reader io.Reader) (bw int64, err error) {
+	filePath, err := fs.GetPath(urlStr)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+urlStr)
+		return
+	}
+
+	// manage concurrency
+	defer fs.Context().Wg.Write.Done()
+	fs.Context().Wg.Write.Add()
+
+	if err = fs.mkdirAll(path.Dir(filePath)); err != nil {
+		return 0, g.Error(err, "could not create parent directory of %s", filePath)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		n, err := io.Copy(pw, reader)
+		bw = n
+		pw.CloseWithError(err)
+	}()
+
+	headers := map[string]string{"Content-Type": "application/octet-stream"}
+	resp, err := fs.requestOK(http.MethodPut, filePath, headers, pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		return bw, g.Error(err, "could not write to %s", filePath)
+	}
+	resp.Body.Close()
+
+	return
+}
+
+// GetReader return a reader for the given path
+func (fs *WebDAVFileSysClient) GetReader(urlStr string) (reader io.Reader, err error) {
+	path, err := fs.GetPath(urlStr)
+	if err != nil {
+		err = g.Error(err, "Error Parsing url: "+urlStr)
+		return
+	}
+
+	resp, err := fs.requestOK(http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, g.Error(err, "could not open %s", path)
+	}
+
+	return resp.Body, nil
+}
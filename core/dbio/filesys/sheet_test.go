@@ -2,11 +2,19 @@ package filesys
 
 import (
 	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/samber/lo"
 	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+	"google.golang.org/api/sheets/v4"
 
 	"github.com/flarco/g"
 	"github.com/stretchr/testify/assert"
@@ -99,6 +107,100 @@ func TestExcel(t *testing.T) {
 	os.RemoveAll("test/test.excel6.xlsx")
 }
 
+func TestExcelMultiSheet(t *testing.T) {
+	t.Parallel()
+
+	columns := iop.NewColumns(
+		iop.Columns{
+			{Name: "id", Type: iop.BigIntType},
+			{Name: "region", Type: iop.StringType},
+			{Name: "amount", Type: iop.FloatType},
+		}...,
+	)
+
+	data := iop.NewDataset(columns)
+	data.Inferred = true
+	data.Append([]any{int64(1), "east", 1.5})
+	data.Append([]any{int64(2), "west", 2.5})
+	data.Append([]any{int64(3), "east", 3.5})
+
+	// row splits go into separate sheets
+	path := "test/test_write/excel_sheets.xlsx"
+	os.Remove(path)
+
+	fs, err := NewFileSysClient(dbio.TypeFileLocal, "FILE_MAX_ROWS=2", "SHEET=data")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	df, err := iop.MakeDataFlow(data.Stream())
+	assert.NoError(t, err)
+	_, err = WriteDataflow(fs, df, path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	xls, err := iop.NewExcelFromFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"data", "data_2"}, xls.Sheets)
+
+	// read all sheets into one stream
+	fs, err = NewFileSysClient(dbio.TypeFileLocal, "SHEET=*", "HEADER=true")
+	if !assert.NoError(t, err) {
+		return
+	}
+	df2, err := fs.ReadDataflow(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	data2, err := df2.Collect()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"id", "region", "amount", iop.ExcelSheetNameColumn}, data2.Columns.Names())
+	if assert.Len(t, data2.Rows, 3) {
+		assert.Equal(t, "data", data2.Rows[0][3])
+		assert.Equal(t, "data_2", data2.Rows[2][3])
+		assert.EqualValues(t, 3, data2.Rows[2][0])
+	}
+
+	// partitions go into separate sheets
+	fs, err = NewFileSysClient(dbio.TypeFileLocal, `PARTITION_BY=["region"]`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	df, err = iop.MakeDataFlow(data.Stream())
+	assert.NoError(t, err)
+	_, err = WriteDataflow(fs, df, path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	xls, err = iop.NewExcelFromFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.ElementsMatch(t, []string{"region=east", "region=west"}, xls.Sheets)
+
+	// list of sheets
+	xls.Props = map[string]string{"header": "true"}
+	sheets, multi := xls.MatchSheets(`["region=w*", "missing"]`)
+	assert.True(t, multi)
+	assert.Equal(t, []string{"region=west"}, sheets)
+
+	data3, err := xls.GetDatasetFromSheets(sheets)
+	if assert.NoError(t, err) && assert.Len(t, data3.Rows, 1) {
+		assert.Equal(t, []string{"id", "amount", iop.ExcelSheetNameColumn}, data3.Columns.Names())
+		assert.Equal(t, "region=west", data3.Rows[0][2])
+	}
+
+	if !t.Failed() {
+		os.Remove(path)
+	}
+}
+
 func TestGoogleSheet(t *testing.T) {
 
 	url := "https://docs.google.com/spreadsheets/d/1Wo7d_2oiYpWy1hYGqHIy0DSPWki24Xif3FnlRjNGzo4/edit#gid=0"
@@ -180,3 +282,161 @@ func TestGoogleSheet(t *testing.T) {
 	err = ggs.DeleteSheet("new")
 	assert.NoError(t, err)
 }
+
+// sheetsStandIn is an in-memory stand-in for the Google Sheets API
+type sheetsStandIn struct {
+	mux    sync.Mutex
+	titles []string
+	values map[string][][]string
+}
+
+func (s *sheetsStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v4/spreadsheets/")
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(path, ":batchUpdate"):
+		req := sheets.BatchUpdateSpreadsheetRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, request := range req.Requests {
+			if request.AddSheet != nil {
+				s.titles = append(s.titles, request.AddSheet.Properties.Title)
+			}
+			if request.DeleteSheet != nil {
+				title := s.titles[request.DeleteSheet.SheetId]
+				s.titles = lo.Without(s.titles, title)
+				delete(s.values, title)
+			}
+		}
+		json.NewEncoder(w).Encode(sheets.BatchUpdateSpreadsheetResponse{})
+	case r.Method == http.MethodPut && strings.Contains(path, "/values/"):
+		vr := sheets.ValueRange{}
+		if err := json.NewDecoder(r.Body).Decode(&vr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// range is `<sheet>!<column><row>`, with single column letters
+		parts := strings.SplitN(strings.SplitN(path, "/values/", 2)[1], "!", 2)
+		title, cell := parts[0], parts[1]
+		col, row := int(cell[0]-'A'), cast.ToInt(cell[1:])-1
+
+		rows := s.values[title]
+		for i, vals := range vr.Values {
+			for len(rows) <= row+i {
+				rows = append(rows, []string{})
+			}
+			for j, val := range vals {
+				for len(rows[row+i]) <= col+j {
+					rows[row+i] = append(rows[row+i], "")
+				}
+				rows[row+i][col+j] = cast.ToString(val)
+			}
+		}
+		s.values[title] = rows
+		json.NewEncoder(w).Encode(sheets.UpdateValuesResponse{UpdatedRange: vr.Range})
+	case r.Method == http.MethodGet && !strings.Contains(path, "/"):
+		resp := sheets.Spreadsheet{SpreadsheetId: path}
+		for i, title := range s.titles {
+			grid := &sheets.GridData{}
+			for _, row := range s.values[title] {
+				rowData := &sheets.RowData{}
+				for _, val := range row {
+					rowData.Values = append(rowData.Values, &sheets.CellData{FormattedValue: val})
+				}
+				grid.RowData = append(grid.RowData, rowData)
+			}
+			resp.Sheets = append(resp.Sheets, &sheets.Sheet{
+				Properties: &sheets.SheetProperties{SheetId: int64(i), Title: title},
+				Data:       []*sheets.GridData{grid},
+			})
+		}
+		json.NewEncoder(w).Encode(resp)
+	default:
+		http.Error(w, "not implemented: "+r.Method+" "+r.URL.Path, http.StatusNotImplemented)
+	}
+}
+
+func TestGoogleSheetsStandIn(t *testing.T) {
+	standIn := &sheetsStandIn{titles: []string{"Sheet1"}, values: map[string][][]string{}}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	fs, err := NewFileSysClient(
+		dbio.TypeFileGSheets,
+		"ENDPOINT="+server.URL+"/",
+		"SPREADSHEET_ID=sheet-id",
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data := iop.NewDataset(iop.NewColumnsFromFields("id", "name"))
+	data.Append([]any{1, "alice"})
+	data.Append([]any{2, "bob"})
+	data.Append([]any{3, "carl"})
+
+	write := func(uri string, data iop.Dataset) error {
+		df, err := iop.MakeDataFlow(data.Stream())
+		if err != nil {
+			return err
+		}
+		_, err = WriteDataflow(fs, df, uri)
+		return err
+	}
+
+	// full-refresh creates and overwrites the sheet
+	fs.SetProp("SLING_MODE", "full-refresh")
+	err = write("people", data)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, [][]string{{"id", "name"}, {"1", "alice"}, {"2", "bob"}, {"3", "carl"}}, standIn.values["people"])
+
+	// incremental appends, without the header
+	fs.SetProp("SLING_MODE", "incremental")
+	err = write("gsheets://sheet-id/people", data)
+	assert.NoError(t, err)
+	if assert.Len(t, standIn.values["people"], 7) {
+		assert.Equal(t, []string{"1", "alice"}, standIn.values["people"][4])
+	}
+
+	df, err := fs.ReadDataflow("gsheets://sheet-id/people")
+	if assert.NoError(t, err) {
+		result, err := iop.MergeDataflow(df).Collect(0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"id", "name"}, result.Columns.Names())
+		assert.Len(t, result.Rows, 6)
+	}
+
+	// overwrite blanks out the remaining rows
+	fs.SetProp("MODE", "overwrite")
+	data.Rows = data.Rows[:1]
+	err = write("people", data)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "alice"}, standIn.values["people"][1])
+	assert.Equal(t, []string{"", ""}, standIn.values["people"][6])
+
+	// sheet and start cell from the options
+	fs.SetProp("SHEET", "report")
+	fs.SetProp("RANGE", "B3")
+	err = write("gsheets://sheet-id", data)
+	assert.NoError(t, err)
+	if assert.Len(t, standIn.values["report"], 4) {
+		assert.Equal(t, []string{"", "id", "name"}, standIn.values["report"][2])
+		assert.Equal(t, []string{"", "1", "alice"}, standIn.values["report"][3])
+	}
+
+	fs.SetProp("SHEET", "")
+	fs.SetProp("MODE", "sideways")
+	err = write("people", data)
+	assert.Error(t, err)
+
+	nodes, err := fs.List("gsheets://sheet-id")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gsheets://sheet-id/Sheet1", "gsheets://sheet-id/people", "gsheets://sheet-id/report"}, nodes.URIs())
+}
@@ -0,0 +1,670 @@
+package iop
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/decimal128"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+)
+
+// ArrowFormat is the format of an Arrow IPC file
+type ArrowFormat string
+
+const (
+	// ArrowFormatFile is the random access file format, also known as Feather v2
+	ArrowFormatFile ArrowFormat = "file"
+	// ArrowFormatStream is the streaming format, usually with the `.arrows` extension
+	ArrowFormatStream ArrowFormat = "stream"
+)
+
+// ArrowCodec is the compression codec of the record batch buffers
+type ArrowCodec string
+
+const (
+	ArrowCodecNone ArrowCodec = "none"
+	ArrowCodecLz4  ArrowCodec = "lz4"
+	ArrowCodecZstd ArrowCodec = "zstd"
+)
+
+const (
+	arrowFileMagic      = "ARROW1"
+	arrowBatchRows      = 10000
+	arrowDecimalDefPrec = 38
+	arrowDecimalDefSc   = 9
+)
+
+// ArrowSchema returns the arrow schema of the columns.
+// Fields are nullable, with decimals, dates and microsecond timestamps.
+func ArrowSchema(columns Columns) *arrow.Schema {
+	fields := make([]arrow.Field, len(columns))
+	for i, col := range columns {
+		fields[i] = arrow.Field{Name: col.Name, Type: arrowDataType(col), Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// arrowDataType returns the arrow data type of a column
+func arrowDataType(col Column) arrow.DataType {
+	switch {
+	case col.IsBool():
+		return arrow.FixedWidthTypes.Boolean
+	case col.Type == SmallIntType:
+		return arrow.PrimitiveTypes.Int16
+	case col.Type == IntegerType:
+		return arrow.PrimitiveTypes.Int32
+	case col.IsInteger():
+		return arrow.PrimitiveTypes.Int64
+	case col.Type == FloatType:
+		return arrow.PrimitiveTypes.Float64
+	case col.IsDecimal():
+		precision, scale := col.DbPrecision, col.DbScale
+		if !col.Sourced || precision == 0 {
+			precision = lo.Ternary(precision == 0, arrowDecimalDefPrec, precision)
+			scale = lo.Ternary(scale == 0, arrowDecimalDefSc, scale)
+		}
+		return &arrow.Decimal128Type{Precision: int32(min(precision, arrowDecimalDefPrec)), Scale: int32(scale)}
+	case col.Type == DateType:
+		return arrow.FixedWidthTypes.Date32
+	case col.Type == TimestampzType:
+		return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+	case col.IsDatetime():
+		return &arrow.TimestampType{Unit: arrow.Microsecond}
+	case col.Type == BinaryType:
+		return arrow.BinaryTypes.Binary
+	}
+	return arrow.BinaryTypes.String
+}
+
+// ArrowColumns returns the columns of an arrow schema
+func ArrowColumns(schema *arrow.Schema) Columns {
+	fields := lo.Map(schema.Fields(), func(f arrow.Field, i int) string { return f.Name })
+	cols := NewColumnsFromFields(fields...)
+	for i, field := range schema.Fields() {
+		cols[i].Type, cols[i].DbPrecision, cols[i].DbScale = arrowColumnType(field.Type)
+		cols[i].DbType = field.Type.Name()
+		cols[i].Sourced = !g.In(cols[i].Type, DecimalType, FloatType) || cols[i].DbPrecision > 0
+	}
+	return cols
+}
+
+// arrowColumnType returns the column type of an arrow data type
+func arrowColumnType(dataType arrow.DataType) (colType ColumnType, precision, scale int) {
+	switch dt := dataType.(type) {
+	case *arrow.Decimal128Type:
+		return DecimalType, int(dt.Precision), int(dt.Scale)
+	case *arrow.Decimal256Type:
+		return DecimalType, int(dt.Precision), int(dt.Scale)
+	case *arrow.TimestampType:
+		return lo.Ternary(dt.TimeZone != "", TimestampzType, TimestampType), 0, 0
+	case *arrow.DictionaryType:
+		return arrowColumnType(dt.ValueType)
+	}
+
+	switch dataType.ID() {
+	case arrow.BOOL:
+		return BoolType, 0, 0
+	case arrow.INT8, arrow.UINT8, arrow.INT16:
+		return SmallIntType, 0, 0
+	case arrow.UINT16, arrow.INT32:
+		return IntegerType, 0, 0
+	case arrow.UINT32, arrow.INT64, arrow.UINT64:
+		return BigIntType, 0, 0
+	case arrow.FLOAT16, arrow.FLOAT32, arrow.FLOAT64:
+		return FloatType, 0, 0
+	case arrow.DATE32, arrow.DATE64:
+		return DateType, 0, 0
+	case arrow.TIME32, arrow.TIME64:
+		return TimeType, 0, 0
+	case arrow.BINARY, arrow.LARGE_BINARY, arrow.FIXED_SIZE_BINARY:
+		return BinaryType, 0, 0
+	case arrow.LIST, arrow.LARGE_LIST, arrow.FIXED_SIZE_LIST, arrow.STRUCT, arrow.MAP:
+		return JsonType, 0, 0
+	}
+	return StringType, 0, 0
+}
+
+// arrowValue returns the stream value of the array at index i
+func arrowValue(arr arrow.Array, i int) any {
+	if arr.IsNull(i) {
+		return nil
+	}
+
+	switch a := arr.(type) {
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Int8:
+		return int64(a.Value(i))
+	case *array.Int16:
+		return int64(a.Value(i))
+	case *array.Int32:
+		return int64(a.Value(i))
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return int64(a.Value(i))
+	case *array.Uint16:
+		return int64(a.Value(i))
+	case *array.Uint32:
+		return int64(a.Value(i))
+	case *array.Uint64:
+		return a.Value(i)
+	case *array.Float16:
+		return float64(a.Value(i).Float32())
+	case *array.Float32:
+		return float64(a.Value(i))
+	case *array.Float64:
+		return a.Value(i)
+	case *array.Decimal128:
+		return a.Value(i).ToString(a.DataType().(*arrow.Decimal128Type).Scale)
+	case *array.Decimal256:
+		return a.Value(i).ToString(a.DataType().(*arrow.Decimal256Type).Scale)
+	case *array.Date32:
+		return a.Value(i).ToTime()
+	case *array.Date64:
+		return a.Value(i).ToTime()
+	case *array.Timestamp:
+		return a.Value(i).ToTime(a.DataType().(*arrow.TimestampType).Unit)
+	case *array.Time32:
+		return a.Value(i).ToTime(a.DataType().(*arrow.Time32Type).Unit).Format("15:04:05.000000")
+	case *array.Time64:
+		return a.Value(i).ToTime(a.DataType().(*arrow.Time64Type).Unit).Format("15:04:05.000000")
+	case *array.String:
+		return a.Value(i)
+	case *array.LargeString:
+		return a.Value(i)
+	case *array.Binary:
+		return string(a.Value(i))
+	case *array.LargeBinary:
+		return string(a.Value(i))
+	case *array.FixedSizeBinary:
+		return string(a.Value(i))
+	case *array.Dictionary:
+		return arrowValue(a.Dictionary(), a.GetValueIndex(i))
+	}
+
+	// nested values are passed as json
+	return g.Marshal(arr.GetOneForMarshal(i))
+}
+
+// appendArrowValue appends a stream value to the builder
+func appendArrowValue(builder array.Builder, val any) (err error) {
+	if val == nil {
+		builder.AppendNull()
+		return nil
+	}
+
+	switch b := builder.(type) {
+	case *array.BooleanBuilder:
+		v, err := cast.ToBoolE(val)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int16Builder:
+		v, err := cast.ToInt16E(val)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int32Builder:
+		v, err := cast.ToInt32E(val)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int64Builder:
+		v, err := cast.ToInt64E(val)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float64Builder:
+		v, err := cast.ToFloat64E(val)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Decimal128Builder:
+		dt := b.Type().(*arrow.Decimal128Type)
+		v, err := decimal128.FromString(strings.TrimSpace(cast.ToString(val)), dt.Precision, dt.Scale)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Date32Builder:
+		v, err := cast.ToTimeE(val)
+		if err != nil {
+			return err
+		}
+		b.Append(arrow.Date32FromTime(time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)))
+	case *array.TimestampBuilder:
+		v, err := cast.ToTimeE(val)
+		if err != nil {
+			return err
+		}
+		ts, err := arrow.TimestampFromTime(v.UTC(), b.Type().(*arrow.TimestampType).Unit)
+		if err != nil {
+			return err
+		}
+		b.Append(ts)
+	case *array.BinaryBuilder:
+		if v, ok := val.([]byte); ok {
+			b.Append(v)
+		} else {
+			b.AppendString(cast.ToString(val))
+		}
+	case *array.StringBuilder:
+		switch v := val.(type) {
+		case string:
+			b.Append(v)
+		case time.Time:
+			b.Append(v.Format(time.RFC3339Nano))
+		case map[string]any, []any:
+			b.Append(g.Marshal(v))
+		default:
+			s, err := cast.ToStringE(val)
+			if err != nil {
+				return err
+			}
+			b.Append(s)
+		}
+	default:
+		return g.Error("unsupported arrow builder: %T", builder)
+	}
+
+	return nil
+}
+
+// ArrowRecordReader reads the rows of a datastream as arrow record batches.
+// It implements array.RecordReader, to hand the rows to arrow-based tools.
+type ArrowRecordReader struct {
+	ds        *Datastream
+	schema    *arrow.Schema
+	columns   Columns
+	rows      chan []any
+	builder   *array.RecordBuilder
+	batchSize int
+	record    arrow.Record
+	err       error
+	refCount  int64
+}
+
+// NewArrowRecordReader creates an arrow record reader of the datastream,
+// with batches of up to batchSize rows (default 10000)
+func (ds *Datastream) NewArrowRecordReader(batchSize int) (ar *ArrowRecordReader, err error) {
+	if err = ds.WaitReady(); err != nil {
+		return nil, g.Error(err, "datastream is not ready")
+	}
+
+	ar = &ArrowRecordReader{
+		ds:        ds,
+		columns:   ds.Columns,
+		schema:    ArrowSchema(ds.Columns),
+		rows:      ds.Rows(),
+		batchSize: lo.Ternary(batchSize > 0, batchSize, arrowBatchRows),
+		refCount:  1,
+	}
+	ar.builder = array.NewRecordBuilder(memory.DefaultAllocator, ar.schema)
+
+	return ar, nil
+}
+
+// Schema returns the arrow schema of the datastream columns
+func (ar *ArrowRecordReader) Schema() *arrow.Schema { return ar.schema }
+
+// Record returns the current record batch, valid until the next call of Next
+func (ar *ArrowRecordReader) Record() arrow.Record { return ar.record }
+
+// Err returns the error which stopped the reader, if any
+func (ar *ArrowRecordReader) Err() error { return ar.err }
+
+// Next builds the next record batch, returning false when done
+func (ar *ArrowRecordReader) Next() bool {
+	if ar.record != nil {
+		ar.record.Release()
+		ar.record = nil
+	}
+	if ar.err != nil {
+		return false
+	}
+
+	count := 0
+	for row := range ar.rows {
+		for i, col := range ar.columns {
+			var val any
+			if i < len(row) {
+				val = row[i]
+			}
+			if err := appendArrowValue(ar.builder.Field(i), val); err != nil {
+				ar.err = g.Error(err, "could not convert value for column %s: %#v", col.Name, val)
+				ar.ds.Context.Cancel()
+				return false
+			}
+		}
+
+		if count++; count >= ar.batchSize {
+			break
+		}
+	}
+
+	if count == 0 {
+		ar.err = ar.ds.Err()
+		return false
+	}
+
+	ar.record = ar.builder.NewRecord()
+	return true
+}
+
+// Retain increases the reference count
+func (ar *ArrowRecordReader) Retain() {
+	atomic.AddInt64(&ar.refCount, 1)
+}
+
+// Release decreases the reference count, releasing the memory when zero
+func (ar *ArrowRecordReader) Release() {
+	if atomic.AddInt64(&ar.refCount, -1) == 0 {
+		if ar.record != nil {
+			ar.record.Release()
+			ar.record = nil
+		}
+		ar.builder.Release()
+	}
+}
+
+var _ array.RecordReader = (*ArrowRecordReader)(nil)
+
+// arrowReader iterates over the rows of arrow record batches
+type arrowReader struct {
+	next   func() (arrow.Record, error) // returns nil when done
+	close  func()
+	record arrow.Record
+	row    int
+	done   bool
+}
+
+func (a *arrowReader) nextFunc(it *Iterator) bool {
+	if a.done {
+		return false
+	}
+
+	for a.record == nil || a.row >= int(a.record.NumRows()) {
+		if a.record != nil {
+			a.record.Release()
+			a.record = nil
+		}
+
+		record, err := a.next()
+		if err != nil {
+			it.Context.CaptureErr(g.Error(err, "could not read arrow record batch"))
+			return false
+		} else if record == nil {
+			a.done = true
+			if a.close != nil {
+				a.close()
+			}
+			return false
+		}
+		a.record, a.row = record, 0
+	}
+
+	it.Row = make([]any, len(it.ds.Columns))
+	for i, arr := range a.record.Columns() {
+		if i < len(it.Row) {
+			it.Row[i] = arrowValue(arr, a.row)
+		}
+	}
+	a.row++
+
+	return true
+}
+
+// ConsumeArrowRecordReader uses the provided arrow record reader to stream rows
+func (ds *Datastream) ConsumeArrowRecordReader(rr array.RecordReader) (err error) {
+	rr.Retain()
+	reader := &arrowReader{
+		next: func() (arrow.Record, error) {
+			if !rr.Next() {
+				if err := rr.Err(); err != nil && err != io.EOF {
+					return nil, err
+				}
+				return nil, nil
+			}
+			record := rr.Record()
+			record.Retain() // the reader releases it on the next call
+			return record, nil
+		},
+		close: rr.Release,
+	}
+
+	ds.Columns = ArrowColumns(rr.Schema())
+	ds.Inferred = ds.Columns.Sourced()
+	ds.it = ds.NewIterator(ds.Columns, reader.nextFunc)
+	ds.SetFileURI()
+
+	err = ds.Start()
+	if err != nil {
+		return g.Error(err, "could start datastream")
+	}
+
+	return
+}
+
+// ConsumeArrowReaderSeeker uses the provided reader to stream rows
+// of an arrow IPC file (Feather v2) or stream
+func (ds *Datastream) ConsumeArrowReaderSeeker(reader io.ReadSeeker) (err error) {
+	magic := make([]byte, len(arrowFileMagic))
+	n, _ := io.ReadFull(reader, magic)
+	if _, err = reader.Seek(0, io.SeekStart); err != nil {
+		return g.Error(err, "could not seek to start of arrow file")
+	}
+
+	if string(magic[:n]) != arrowFileMagic {
+		return ds.consumeArrowStream(reader)
+	}
+
+	readerAt, ok := reader.(ipc.ReadAtSeeker)
+	if !ok {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return g.Error(err, "could not read arrow file")
+		}
+		readerAt = bytes.NewReader(data)
+	}
+
+	fr, err := ipc.NewFileReader(readerAt)
+	if err != nil {
+		return g.Error(err, "could not open arrow file")
+	}
+
+	i := 0
+	reader2 := &arrowReader{
+		next: func() (arrow.Record, error) {
+			if i >= fr.NumRecords() {
+				return nil, nil
+			}
+			i++
+			return fr.RecordAt(i - 1)
+		},
+		close: func() { fr.Close() },
+	}
+
+	ds.Columns = ArrowColumns(fr.Schema())
+	ds.Inferred = ds.Columns.Sourced()
+	ds.it = ds.NewIterator(ds.Columns, reader2.nextFunc)
+	ds.SetFileURI()
+
+	err = ds.Start()
+	if err != nil {
+		return g.Error(err, "could start datastream")
+	}
+
+	return
+}
+
+// ConsumeArrowReader uses the provided reader to stream rows
+// of an arrow IPC stream, or file (Feather v2)
+func (ds *Datastream) ConsumeArrowReader(reader io.Reader) (err error) {
+	bufReader := bufio.NewReader(reader)
+	magic, _ := bufReader.Peek(len(arrowFileMagic))
+	if string(magic) != arrowFileMagic {
+		return ds.consumeArrowStream(bufReader)
+	}
+
+	// the file format needs random access
+	data, err := io.ReadAll(bufReader)
+	if err != nil {
+		return g.Error(err, "could not read arrow file")
+	}
+	return ds.ConsumeArrowReaderSeeker(bytes.NewReader(data))
+}
+
+func (ds *Datastream) consumeArrowStream(reader io.Reader) (err error) {
+	sr, err := ipc.NewReader(reader)
+	if err != nil {
+		return g.Error(err, "could not open arrow stream")
+	}
+	defer sr.Release()
+
+	return ds.ConsumeArrowRecordReader(sr)
+}
+
+// ArrowWriter writes rows into an arrow IPC file (Feather v2) or stream
+type ArrowWriter struct {
+	Writer  io.Writer
+	columns Columns
+	schema  *arrow.Schema
+	builder *array.RecordBuilder
+	writer  interface {
+		Write(rec arrow.Record) error
+		Close() error
+	}
+	counter    *arrowCountingWriter
+	batchCount int
+	batchBytes int64
+}
+
+// arrowCountingWriter counts the bytes written. The arrow file writer
+// only seeks to get the current position.
+type arrowCountingWriter struct {
+	w       io.Writer
+	written int64
+}
+
+func (cw *arrowCountingWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	cw.written += int64(n)
+	return
+}
+
+func (cw *arrowCountingWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, g.Error("arrow writer cannot seek")
+	}
+	return cw.written, nil
+}
+
+// NewArrowWriter creates a new arrow writer, with a schema generated from the columns
+func NewArrowWriter(w io.Writer, columns Columns, format ArrowFormat, codec ArrowCodec) (aw *ArrowWriter, err error) {
+	aw = &ArrowWriter{
+		Writer:  w,
+		columns: columns,
+		schema:  ArrowSchema(columns),
+		counter: &arrowCountingWriter{w: w},
+	}
+
+	opts := []ipc.Option{ipc.WithSchema(aw.schema)}
+	switch codec {
+	case ArrowCodecNone, "":
+	case ArrowCodecLz4:
+		opts = append(opts, ipc.WithLZ4())
+	case ArrowCodecZstd:
+		opts = append(opts, ipc.WithZstd())
+	default:
+		return nil, g.Error("unsupported arrow codec: %s", codec)
+	}
+
+	switch format {
+	case ArrowFormatFile, "":
+		if aw.writer, err = ipc.NewFileWriter(aw.counter, opts...); err != nil {
+			return nil, g.Error(err, "could not create arrow file writer")
+		}
+	case ArrowFormatStream:
+		aw.writer = ipc.NewWriter(aw.counter, opts...)
+	default:
+		return nil, g.Error("unsupported arrow format: %s", format)
+	}
+
+	aw.builder = array.NewRecordBuilder(memory.DefaultAllocator, aw.schema)
+
+	return aw, nil
+}
+
+// WriteRow adds a row to the current record batch, writing the batch when full
+func (aw *ArrowWriter) WriteRow(row []any) (err error) {
+	for i, col := range aw.columns {
+		var val any
+		if i < len(row) {
+			val = row[i]
+		}
+		if err = appendArrowValue(aw.builder.Field(i), val); err != nil {
+			return g.Error(err, "could not convert value for column %s: %#v", col.Name, val)
+		}
+		aw.batchBytes += int64(len(cast.ToString(val)))
+	}
+
+	aw.batchCount++
+	if aw.batchCount >= arrowBatchRows {
+		return aw.writeBatch()
+	}
+
+	return nil
+}
+
+// writeBatch writes the current record batch
+func (aw *ArrowWriter) writeBatch() (err error) {
+	if aw.batchCount == 0 {
+		return nil
+	}
+
+	record := aw.builder.NewRecord()
+	defer record.Release()
+
+	aw.batchCount, aw.batchBytes = 0, 0
+	if err = aw.writer.Write(record); err != nil {
+		return g.Error(err, "could not write arrow record batch")
+	}
+
+	return nil
+}
+
+// BytesWritten returns the number of bytes written, including the pending batch
+func (aw *ArrowWriter) BytesWritten() int64 {
+	return aw.counter.written + aw.batchBytes
+}
+
+// Close writes the pending record batch and the footer
+func (aw *ArrowWriter) Close() (err error) {
+	defer aw.builder.Release()
+
+	if err = aw.writeBatch(); err != nil {
+		return err
+	}
+
+	if err = aw.writer.Close(); err != nil {
+		return g.Error(err, "could not close arrow writer")
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package iop
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+)
+
+func arrowTestDataset() (data Dataset, ts time.Time) {
+	columns := NewColumns(Columns{
+		{Name: "id", Type: BigIntType},
+		{Name: "amount", Type: DecimalType, DbPrecision: 10, DbScale: 2, Sourced: true},
+		{Name: "active", Type: BoolType},
+		{Name: "birth", Type: DateType},
+		{Name: "updated", Type: TimestampType},
+		{Name: "name", Type: StringType},
+	}...)
+
+	ts = time.Date(2024, 3, 5, 10, 20, 30, 123456000, time.UTC)
+	data = NewDataset(columns)
+	data.Inferred = true
+	data.Append([]any{int64(1), "12.34", true, "2001-02-03", ts, "alice"})
+	data.Append([]any{int64(2), "-0.5", false, "1960-12-31", ts, "bob"})
+	data.Append([]any{int64(3), nil, nil, nil, nil, nil})
+	return
+}
+
+func TestArrowRecordReader(t *testing.T) {
+	data, ts := arrowTestDataset()
+
+	// datastream to arrow records
+	ds := data.Stream()
+	ar, err := ds.NewArrowRecordReader(2)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ar.Release()
+
+	assert.Equal(t, []string{"id", "amount", "active", "birth", "updated", "name"}, arrowFieldNames(ar.Schema().Fields()))
+	assert.Equal(t, arrow.DECIMAL128, ar.Schema().Field(1).Type.ID())
+	assert.Equal(t, arrow.DATE32, ar.Schema().Field(3).Type.ID())
+
+	var records []arrow.Record
+	for ar.Next() {
+		record := ar.Record()
+		record.Retain()
+		records = append(records, record)
+	}
+	assert.NoError(t, ar.Err())
+	if !assert.Len(t, records, 2) {
+		return
+	}
+	assert.EqualValues(t, 2, records[0].NumRows())
+	assert.EqualValues(t, 1, records[1].NumRows())
+	assert.Equal(t, "12.34", records[0].Column(1).ValueStr(0))
+
+	// arrow records to datastream
+	rr, err := array.NewRecordReader(ar.Schema(), records)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, record := range records {
+		record.Release()
+	}
+
+	ds2 := NewDatastream(nil)
+	if !assert.NoError(t, ds2.ConsumeArrowRecordReader(rr)) {
+		return
+	}
+	rr.Release()
+
+	data2, err := ds2.Collect(0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, data.Columns.Names(), data2.Columns.Names())
+	assert.Equal(t, DecimalType, data2.Columns[1].Type)
+	assert.Equal(t, 2, data2.Columns[1].DbScale)
+	if assert.Len(t, data2.Rows, 3) {
+		assert.EqualValues(t, 1, data2.Rows[0][0])
+		assert.Equal(t, "12.34", cast.ToString(data2.Rows[0][1]))
+		assert.Equal(t, "-0.50", cast.ToString(data2.Rows[1][1]))
+		assert.Equal(t, time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC), cast.ToTime(data2.Rows[0][3]).UTC())
+		assert.True(t, ts.Equal(cast.ToTime(data2.Rows[0][4])), data2.Rows[0][4])
+		assert.Equal(t, "bob", data2.Rows[1][5])
+		assert.Nil(t, data2.Rows[2][1])
+		assert.Nil(t, data2.Rows[2][5])
+	}
+}
+
+func TestArrowWriter(t *testing.T) {
+	data, ts := arrowTestDataset()
+
+	for _, format := range []ArrowFormat{ArrowFormatFile, ArrowFormatStream} {
+		for _, codec := range []ArrowCodec{ArrowCodecNone, ArrowCodecLz4, ArrowCodecZstd} {
+			var buf bytes.Buffer
+			aw, err := NewArrowWriter(&buf, data.Columns, format, codec)
+			if !assert.NoError(t, err) {
+				return
+			}
+			for _, row := range data.Rows {
+				assert.NoError(t, aw.WriteRow(row))
+			}
+			assert.NoError(t, aw.Close())
+
+			if format == ArrowFormatFile {
+				assert.Equal(t, arrowFileMagic, buf.String()[:len(arrowFileMagic)])
+			}
+
+			ds := NewDatastream(nil)
+			if !assert.NoError(t, ds.ConsumeArrowReader(bytes.NewReader(buf.Bytes())), string(format)+"/"+string(codec)) {
+				continue
+			}
+			data2, err := ds.Collect(0)
+			if !assert.NoError(t, err, string(format)+"/"+string(codec)) {
+				continue
+			}
+
+			if assert.Len(t, data2.Rows, 3, string(format)+"/"+string(codec)) {
+				assert.Equal(t, "alice", data2.Rows[0][5])
+				assert.Equal(t, "12.34", cast.ToString(data2.Rows[0][1]))
+				assert.True(t, ts.Equal(cast.ToTime(data2.Rows[1][4])))
+				assert.Nil(t, data2.Rows[2][2])
+			}
+		}
+	}
+}
+
+func TestArrowValues(t *testing.T) {
+	mem := memory.NewGoAllocator()
+
+	// nested and dictionary values
+	lb := array.NewListBuilder(mem, arrow.PrimitiveTypes.Int64)
+	defer lb.Release()
+	vb := lb.ValueBuilder().(*array.Int64Builder)
+	lb.Append(true)
+	vb.AppendValues([]int64{1, 2}, nil)
+	lb.AppendNull()
+	list := lb.NewArray()
+	defer list.Release()
+
+	assert.Equal(t, "[1,2]", arrowValue(list, 0))
+	assert.Nil(t, arrowValue(list, 1))
+
+	db := array.NewDictionaryBuilder(mem, &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String})
+	defer db.Release()
+	assert.NoError(t, db.(*array.BinaryDictionaryBuilder).AppendString("a"))
+	assert.NoError(t, db.(*array.BinaryDictionaryBuilder).AppendString("b"))
+	dict := db.NewArray()
+	defer dict.Release()
+	assert.Equal(t, "b", arrowValue(dict, 1))
+
+	colType, _, _ := arrowColumnType(dict.DataType())
+	assert.Equal(t, StringType, colType)
+	colType, _, _ = arrowColumnType(list.DataType())
+	assert.Equal(t, JsonType, colType)
+}
+
+func arrowFieldNames(fields []arrow.Field) (names []string) {
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	return
+}
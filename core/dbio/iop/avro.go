@@ -1,11 +1,17 @@
 package iop
 
 import (
+	"bufio"
+	"encoding/binary"
 	"io"
+	"math/big"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/flarco/g"
 	"github.com/jmespath/go-jmespath"
+	"github.com/klauspost/compress/zstd"
 	"github.com/linkedin/goavro/v2"
 	"github.com/samber/lo"
 	"github.com/spf13/cast"
@@ -18,10 +24,47 @@ type Avro struct {
 	Data   *Dataset
 	colMap map[string]int
 	codec  *goavro.Codec
+	unions []bool // whether the field is a union, such as ["null", "long"]
+
+	// NativeTypes reads the values with their native types (logical types,
+	// nullable unions), instead of their JSON representation
+	NativeTypes bool
 }
 
+// AvroCodec is the compression codec of the blocks in an avro file
+type AvroCodec string
+
+const (
+	AvroCodecNull      AvroCodec = "null"
+	AvroCodecDeflate   AvroCodec = "deflate"
+	AvroCodecSnappy    AvroCodec = "snappy"
+	AvroCodecZstandard AvroCodec = "zstandard"
+)
+
+const (
+	avroMagic          = "Obj\x01"
+	avroSyncLength     = 16
+	avroBlockMaxRows   = 10000
+	avroBlockMaxBytes  = 1024 * 1024
+	avroMetaKeyCodec   = "avro.codec"
+	avroRecordName     = "sling_record"
+	avroDecimalDefPrec = 28
+	avroDecimalDefSc   = 9
+)
+
 func NewAvroStream(reader io.ReadSeeker, columns Columns) (a *Avro, err error) {
-	ar, err := goavro.NewOCFReader(reader)
+	// goavro does not support zstandard, transcode blocks if needed
+	codec, err := peekAvroCodec(reader)
+	if err != nil {
+		return nil, g.Error(err, "could not read avro header")
+	}
+
+	var avroReader io.Reader = reader
+	if codec == AvroCodecZstandard {
+		avroReader = newAvroTranscodeReader(reader)
+	}
+
+	ar, err := goavro.NewOCFReader(avroReader)
 	if err != nil {
 		err = g.Error(err, "could not read avro reader")
 		return
@@ -35,20 +78,6 @@ func NewAvroStream(reader io.ReadSeeker, columns Columns) (a *Avro, err error) {
 
 func (a *Avro) Columns() Columns {
 
-	typeMap := map[string]ColumnType{
-		"string": StringType,
-		"int":    IntegerType,
-		"long":   BigIntType,
-		"float":  DecimalType,
-		"double": DecimalType,
-		"bytes":  BinaryType,
-		"null":   StringType,
-		"array":  JsonType,
-		"map":    JsonType,
-		"record": JsonType,
-		"enum":   StringType,
-	}
-
 	type avroField struct {
 		Name string `json:"name"`
 		Type any    `json:"type"`
@@ -69,13 +98,28 @@ func (a *Avro) Columns() Columns {
 	)
 
 	cols := NewColumnsFromFields(fields...)
+	a.unions = make([]bool, len(cols))
 	for i, field := range schema.Fields {
-		key := g.Marshal(field.Type)
+		if a.NativeTypes {
+			cols[i].Type, cols[i].DbPrecision, cols[i].DbScale, a.unions[i] = avroColumnType(field.Type)
+			cols[i].Sourced = !g.In(cols[i].Type, DecimalType) || cols[i].DbPrecision > 0
+			continue
+		}
+
+		// nullable types are unions with null, such as ["null", "long"]
+		fieldType := field.Type
+		if members, ok := fieldType.([]any); ok {
+			if members = lo.Filter(members, func(m any, i int) bool { return m != "null" }); len(members) == 1 {
+				fieldType, a.unions[i] = members[0], true
+			}
+		}
+
+		key := g.Marshal(fieldType)
 		key = strings.TrimPrefix(key, `"`)
 		key = strings.TrimSuffix(key, `"`)
 
 		if strings.HasPrefix(key, "{") {
-			keyI, err := jmespath.Search("type", field.Type)
+			keyI, err := jmespath.Search("type", fieldType)
 			if err == nil {
 				key = cast.ToString(keyI)
 			}
@@ -84,7 +128,7 @@ func (a *Avro) Columns() Columns {
 		}
 
 		cols[i].Type = StringType
-		if typ, ok := typeMap[key]; ok {
+		if typ, ok := avroTextualTypeMap[key]; ok {
 			cols[i].Type = typ
 			cols[i].Sourced = !g.In(typ, DecimalType)
 		}
@@ -93,6 +137,81 @@ func (a *Avro) Columns() Columns {
 	return cols
 }
 
+// avroTextualTypeMap are the column types of the avro types,
+// when reading the JSON representation of the values
+var avroTextualTypeMap = map[string]ColumnType{
+	"string": StringType,
+	"int":    IntegerType,
+	"long":   BigIntType,
+	"float":  DecimalType,
+	"double": DecimalType,
+	"bytes":  BinaryType,
+	"null":   StringType,
+	"array":  JsonType,
+	"map":    JsonType,
+	"record": JsonType,
+	"enum":   StringType,
+}
+
+// avroColumnType returns the column type of an avro field type
+func avroColumnType(fieldType any) (colType ColumnType, precision, scale int, union bool) {
+
+	typeMap := map[string]ColumnType{
+		"string":  StringType,
+		"boolean": BoolType,
+		"int":     IntegerType,
+		"long":    BigIntType,
+		"float":   DecimalType,
+		"double":  DecimalType,
+		"bytes":   BinaryType,
+		"null":    StringType,
+		"array":   JsonType,
+		"map":     JsonType,
+		"record":  JsonType,
+		"enum":    StringType,
+		"fixed":   BinaryType,
+	}
+
+	logicalTypeMap := map[string]ColumnType{
+		"decimal":          DecimalType,
+		"date":             DateType,
+		"time-millis":      TimeType,
+		"time-micros":      TimeType,
+		"timestamp-millis": TimestampType,
+		"timestamp-micros": TimestampType,
+	}
+
+	switch ft := fieldType.(type) {
+	case string:
+		if typ, ok := typeMap[ft]; ok {
+			return typ, 0, 0, false
+		}
+	case []any:
+		// nullable types are unions with null, such as ["null", "long"]
+		members := lo.Filter(ft, func(m any, i int) bool { return m != "null" })
+		if len(members) == 1 {
+			colType, precision, scale, _ = avroColumnType(members[0])
+			return colType, precision, scale, true
+		}
+		return JsonType, 0, 0, true
+	case map[string]any:
+		if logicalType := cast.ToString(ft["logicalType"]); logicalType != "" {
+			if typ, ok := logicalTypeMap[logicalType]; ok {
+				if typ == DecimalType {
+					precision = cast.ToInt(ft["precision"])
+					scale = cast.ToInt(ft["scale"])
+				}
+				return typ, precision, scale, false
+			}
+		}
+		if typ, ok := typeMap[cast.ToString(ft["type"])]; ok {
+			return typ, 0, 0, false
+		}
+	}
+
+	return StringType, 0, 0, false
+}
+
 func (a *Avro) nextFunc(it *Iterator) bool {
 	if !a.Reader.Scan() {
 		return false
@@ -109,6 +228,38 @@ func (a *Avro) nextFunc(it *Iterator) bool {
 		return false
 	}
 
+	if !a.NativeTypes {
+		return a.setTextualRow(it, datum)
+	}
+
+	rec, ok := datum.(map[string]any)
+	if !ok {
+		it.Context.CaptureErr(g.Error("could not convert Avro record: %#v", datum))
+		return false
+	}
+
+	it.Row = make([]interface{}, len(it.ds.Columns))
+	for k, v := range rec {
+		i, ok := a.colMap[strings.ToLower(k)]
+		if !ok {
+			continue
+		}
+
+		// unwrap union values, such as {"long": 1}
+		if m, ok := v.(map[string]any); ok && a.unions[i] && len(m) == 1 {
+			for _, val := range m {
+				v = val
+			}
+		}
+
+		it.Row[i] = avroNativeValue(it.ds.Columns[i], v)
+	}
+
+	return true
+}
+
+// setTextualRow sets the row with the JSON representation of the record
+func (a *Avro) setTextualRow(it *Iterator, datum any) bool {
 	buf, err := a.codec.TextualFromNative(nil, datum)
 	if err != nil {
 		it.Context.CaptureErr(g.Error(err, "could not convert to Avro record"))
@@ -125,6 +276,14 @@ func (a *Avro) nextFunc(it *Iterator) bool {
 	for k, v := range rec {
 		col := it.ds.Columns[a.colMap[strings.ToLower(k)]]
 		i := col.Position - 1
+
+		// unwrap union values, such as {"long": 1}
+		if m, ok := v.(map[string]any); ok && a.unions[i] && len(m) == 1 {
+			for _, val := range m {
+				v = val
+			}
+		}
+
 		if col.Type == JsonType {
 			v = g.Marshal(v)
 		}
@@ -133,3 +292,484 @@ func (a *Avro) nextFunc(it *Iterator) bool {
 
 	return true
 }
+
+// avroNativeValue converts a native goavro value into a stream value
+func avroNativeValue(col Column, val any) any {
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case *big.Rat:
+		return v.FloatString(lo.Ternary(col.DbScale > 0, col.DbScale, avroDecimalDefSc))
+	case time.Duration:
+		t := time.Time{}.Add(v)
+		return t.Format("15:04:05.000000")
+	case []byte:
+		return string(v)
+	case float32:
+		return float64(v)
+	case map[string]any, []any:
+		return g.Marshal(v)
+	}
+
+	if col.Type == JsonType {
+		return g.Marshal(val)
+	}
+	return val
+}
+
+// AvroWriter writes rows into an avro object container file
+type AvroWriter struct {
+	Writer       io.Writer
+	columns      Columns
+	fields       []avroWriterField
+	ocfWriter    *goavro.OCFWriter
+	counter      *avroCountingWriter
+	records      []any
+	recordsBytes int64 // estimated size of the pending records
+
+	// goavro does not support zstandard, so the blocks are written
+	// with the null codec and transcoded
+	transcodeW    *io.PipeWriter
+	transcodeDone chan error
+}
+
+type avroWriterField struct {
+	name      string // sanitized avro field name
+	unionName string // the goavro union branch name, such as `long.timestamp-micros`
+}
+
+// avroCountingWriter counts the bytes written
+type avroCountingWriter struct {
+	w       io.Writer
+	written int64
+}
+
+func (cw *avroCountingWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	cw.written += int64(n)
+	return
+}
+
+// NewAvroWriter creates a new avro writer, with a schema generated from the columns
+func NewAvroWriter(w io.Writer, columns Columns, avroCodec AvroCodec) (aw *AvroWriter, err error) {
+	aw = &AvroWriter{
+		Writer:  w,
+		columns: columns,
+		counter: &avroCountingWriter{w: w},
+	}
+
+	avroCodec = lo.Ternary(avroCodec == "", AvroCodecNull, avroCodec)
+	var ocfW io.Writer = aw.counter
+
+	switch avroCodec {
+	case AvroCodecNull, AvroCodecDeflate, AvroCodecSnappy:
+	case AvroCodecZstandard:
+		pipeR, pipeW := io.Pipe()
+		aw.transcodeW, aw.transcodeDone = pipeW, make(chan error, 1)
+		go func() {
+			err := transcodeAvro(aw.counter, bufio.NewReader(pipeR), AvroCodecZstandard)
+			pipeR.CloseWithError(err)
+			aw.transcodeDone <- err
+		}()
+		ocfW, avroCodec = pipeW, AvroCodecNull
+	default:
+		return nil, g.Error("unsupported avro codec: %s", avroCodec)
+	}
+
+	schema, fields := getAvroSchema(columns)
+	aw.fields = fields
+
+	aw.ocfWriter, err = goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               ocfW,
+		Schema:          schema,
+		CompressionName: string(avroCodec),
+	})
+	if err != nil {
+		aw.closeTranscode()
+		return nil, g.Error(err, "could not create avro writer")
+	}
+
+	return aw, nil
+}
+
+// getAvroSchema generates the avro record schema of the columns.
+// Fields are nullable, with logical types for decimals, dates and timestamps.
+func getAvroSchema(cols Columns) (schema string, fields []avroWriterField) {
+	schemaFields := make([]map[string]any, len(cols))
+	fields = make([]avroWriterField, len(cols))
+
+	for i, col := range cols {
+		var fieldType any
+		field := avroWriterField{name: avroFieldName(col.Name, i)}
+
+		switch {
+		case col.IsBool():
+			fieldType, field.unionName = "boolean", "boolean"
+		case col.Type == SmallIntType:
+			fieldType, field.unionName = "int", "int"
+		case col.IsInteger():
+			fieldType, field.unionName = "long", "long"
+		case col.Type == FloatType:
+			fieldType, field.unionName = "double", "double"
+		case col.IsDecimal():
+			precision, scale := col.DbPrecision, col.DbScale
+			if !col.Sourced || precision == 0 {
+				precision = lo.Ternary(precision == 0, avroDecimalDefPrec, precision)
+				scale = lo.Ternary(scale == 0, avroDecimalDefSc, scale)
+			}
+			fieldType = map[string]any{"type": "bytes", "logicalType": "decimal", "precision": precision, "scale": scale}
+			field.unionName = "bytes.decimal"
+		case col.Type == DateType:
+			fieldType = map[string]any{"type": "int", "logicalType": "date"}
+			field.unionName = "int.date"
+		case col.IsDatetime():
+			if g.In(col.DbPrecision, 1, 2, 3) {
+				fieldType = map[string]any{"type": "long", "logicalType": "timestamp-millis"}
+				field.unionName = "long.timestamp-millis"
+			} else {
+				fieldType = map[string]any{"type": "long", "logicalType": "timestamp-micros"}
+				field.unionName = "long.timestamp-micros"
+			}
+		case col.Type == BinaryType:
+			fieldType, field.unionName = "bytes", "bytes"
+		default:
+			fieldType, field.unionName = "string", "string"
+		}
+
+		fields[i] = field
+		schemaFields[i] = map[string]any{
+			"name":    field.name,
+			"type":    []any{"null", fieldType},
+			"default": nil,
+		}
+	}
+
+	schema = g.Marshal(map[string]any{
+		"type":   "record",
+		"name":   avroRecordName,
+		"fields": schemaFields,
+	})
+
+	return
+}
+
+var avroInvalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// avroFieldName returns a valid avro field name,
+// matching [A-Za-z_][A-Za-z0-9_]*
+func avroFieldName(name string, index int) string {
+	name = avroInvalidNameChars.ReplaceAllString(name, "_")
+	if name == "" {
+		return g.F("col_%d", index+1)
+	} else if name[0] >= '0' && name[0] <= '9' {
+		return "_" + name
+	}
+	return name
+}
+
+// WriteRow adds a row to the current block, writing the block when full
+func (aw *AvroWriter) WriteRow(row []any) (err error) {
+	rec := make(map[string]any, len(aw.columns))
+
+	for i, col := range aw.columns {
+		field := aw.fields[i]
+		if i >= len(row) || row[i] == nil {
+			rec[field.name] = goavro.Union("null", nil)
+			continue
+		}
+
+		val, err := aw.avroValue(col, field, row[i])
+		if err != nil {
+			return g.Error(err, "could not convert value for column %s: %#v", col.Name, row[i])
+		}
+		rec[field.name] = goavro.Union(field.unionName, val)
+	}
+
+	aw.records = append(aw.records, rec)
+	for _, val := range row {
+		aw.recordsBytes += int64(len(cast.ToString(val)))
+	}
+
+	if len(aw.records) >= avroBlockMaxRows || aw.recordsBytes >= avroBlockMaxBytes {
+		return aw.writeBlock()
+	}
+
+	return nil
+}
+
+// avroValue converts a stream value into a native goavro value
+func (aw *AvroWriter) avroValue(col Column, field avroWriterField, val any) (any, error) {
+	switch field.unionName {
+	case "boolean":
+		return cast.ToBoolE(val)
+	case "int":
+		return cast.ToInt32E(val)
+	case "long":
+		return cast.ToInt64E(val)
+	case "double":
+		return cast.ToFloat64E(val)
+	case "bytes.decimal":
+		rat, ok := new(big.Rat).SetString(strings.TrimSpace(cast.ToString(val)))
+		if !ok {
+			return nil, g.Error("invalid decimal value")
+		}
+		return rat, nil
+	case "int.date", "long.timestamp-millis", "long.timestamp-micros":
+		t, err := cast.ToTimeE(val)
+		if err != nil {
+			return nil, err
+		}
+		return t.UTC(), nil
+	case "bytes":
+		if b, ok := val.([]byte); ok {
+			return b, nil
+		}
+		return []byte(cast.ToString(val)), nil
+	}
+
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case map[string]any, []any:
+		return g.Marshal(v), nil
+	}
+	return cast.ToStringE(val)
+}
+
+// writeBlock writes the pending records as a block
+func (aw *AvroWriter) writeBlock() (err error) {
+	if len(aw.records) == 0 {
+		return nil
+	}
+
+	err = aw.ocfWriter.Append(aw.records)
+	aw.records, aw.recordsBytes = aw.records[:0], 0
+	if err != nil {
+		return g.Error(err, "could not write avro block")
+	}
+
+	return nil
+}
+
+// BytesWritten returns the number of bytes written, including the pending block
+func (aw *AvroWriter) BytesWritten() int64 {
+	return aw.counter.written + aw.recordsBytes
+}
+
+// Close writes the pending block
+func (aw *AvroWriter) Close() error {
+	err := aw.writeBlock()
+	if tErr := aw.closeTranscode(); err == nil {
+		err = tErr
+	}
+	return err
+}
+
+// closeTranscode waits for the zstandard transcoding to finish
+func (aw *AvroWriter) closeTranscode() error {
+	if aw.transcodeW == nil {
+		return nil
+	}
+	aw.transcodeW.Close()
+	if err := <-aw.transcodeDone; err != nil {
+		return g.Error(err, "could not compress avro blocks with zstandard")
+	}
+	return nil
+}
+
+// avroHeader is the header of an avro object container file
+type avroHeader struct {
+	metadata map[string][]byte
+	sync     []byte
+}
+
+// Bytes encodes the header: magic bytes, metadata map & sync marker
+func (h avroHeader) Bytes() []byte {
+	buf := []byte(avroMagic)
+	if len(h.metadata) > 0 {
+		buf = binary.AppendVarint(buf, int64(len(h.metadata)))
+		for _, key := range lo.Keys(h.metadata) {
+			buf = binary.AppendVarint(buf, int64(len(key)))
+			buf = append(buf, key...)
+			buf = binary.AppendVarint(buf, int64(len(h.metadata[key])))
+			buf = append(buf, h.metadata[key]...)
+		}
+	}
+	buf = binary.AppendVarint(buf, 0)
+	return append(buf, h.sync...)
+}
+
+// readAvroHeader reads the header of an avro object container file
+func readAvroHeader(r *bufio.Reader) (h avroHeader, err error) {
+	magic := make([]byte, len(avroMagic))
+	if _, err = io.ReadFull(r, magic); err != nil {
+		return h, g.Error(err, "could not read avro magic bytes")
+	} else if string(magic) != avroMagic {
+		return h, g.Error("invalid avro magic bytes")
+	}
+
+	readBytes := func() ([]byte, error) {
+		size, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		} else if size < 0 {
+			return nil, g.Error("invalid avro bytes size: %d", size)
+		}
+		b := make([]byte, size)
+		_, err = io.ReadFull(r, b)
+		return b, err
+	}
+
+	h.metadata = map[string][]byte{}
+	for {
+		count, err := binary.ReadVarint(r)
+		if err != nil {
+			return h, g.Error(err, "could not read avro metadata")
+		} else if count == 0 {
+			break
+		} else if count < 0 {
+			count = -count
+			if _, err = binary.ReadVarint(r); err != nil { // block size in bytes
+				return h, g.Error(err, "could not read avro metadata")
+			}
+		}
+
+		for i := int64(0); i < count; i++ {
+			key, err := readBytes()
+			if err != nil {
+				return h, g.Error(err, "could not read avro metadata key")
+			}
+			val, err := readBytes()
+			if err != nil {
+				return h, g.Error(err, "could not read avro metadata value")
+			}
+			h.metadata[string(key)] = val
+		}
+	}
+
+	h.sync = make([]byte, avroSyncLength)
+	if _, err = io.ReadFull(r, h.sync); err != nil {
+		return h, g.Error(err, "could not read avro sync marker")
+	}
+
+	return h, nil
+}
+
+// peekAvroCodec returns the codec of the avro file, and seeks back to the start
+func peekAvroCodec(reader io.ReadSeeker) (codec AvroCodec, err error) {
+	header, err := readAvroHeader(bufio.NewReader(reader))
+	if err != nil {
+		return
+	}
+
+	if _, err = reader.Seek(0, io.SeekStart); err != nil {
+		return codec, g.Error(err, "could not seek to start of avro file")
+	}
+
+	codec = AvroCodec(header.metadata[avroMetaKeyCodec])
+	return lo.Ternary(codec == "", AvroCodecNull, codec), nil
+}
+
+// newAvroTranscodeReader returns a reader of the avro file
+// with its blocks decompressed (codec `null`)
+func newAvroTranscodeReader(reader io.Reader) io.Reader {
+	pipeR, pipeW := io.Pipe()
+
+	go func() {
+		pipeW.CloseWithError(transcodeAvro(pipeW, bufio.NewReader(reader), AvroCodecNull))
+	}()
+
+	return pipeR
+}
+
+// transcodeAvro rewrites the blocks of an avro file with another codec.
+// Only the null and zstandard codecs are handled, goavro handles the others.
+func transcodeAvro(w io.Writer, r *bufio.Reader, toCodec AvroCodec) (err error) {
+	header, err := readAvroHeader(r)
+	if err != nil {
+		return err
+	}
+
+	codec := AvroCodec(header.metadata[avroMetaKeyCodec])
+	header.metadata[avroMetaKeyCodec] = []byte(toCodec)
+	if _, err = w.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	var zstdDec *zstd.Decoder
+	if codec == AvroCodecZstandard {
+		if zstdDec, err = zstd.NewReader(nil); err != nil {
+			return g.Error(err, "could not create zstd decoder")
+		}
+		defer zstdDec.Close()
+	}
+
+	var zstdEnc *zstd.Encoder
+	if toCodec == AvroCodecZstandard {
+		if zstdEnc, err = zstd.NewWriter(nil); err != nil {
+			return g.Error(err, "could not create zstd encoder")
+		}
+		defer zstdEnc.Close()
+	}
+
+	for {
+		count, err := binary.ReadVarint(r)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return g.Error(err, "could not read avro block count")
+		}
+
+		size, err := binary.ReadVarint(r)
+		if err != nil {
+			return g.Error(err, "could not read avro block size")
+		}
+
+		data := make([]byte, size+avroSyncLength)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return g.Error(err, "could not read avro block")
+		}
+
+		data, err = avroDecompressBlock(codec, data[:size], zstdDec)
+		if err != nil {
+			return g.Error(err, "could not decompress avro block")
+		}
+
+		data, err = avroCompressBlock(toCodec, data, zstdEnc)
+		if err != nil {
+			return g.Error(err, "could not compress avro block")
+		}
+
+		buf := binary.AppendVarint(nil, count)
+		buf = binary.AppendVarint(buf, int64(len(data)))
+		buf = append(buf, data...)
+		buf = append(buf, header.sync...)
+		if _, err = w.Write(buf); err != nil {
+			return err
+		}
+	}
+}
+
+// avroCompressBlock compresses the block data with the codec
+func avroCompressBlock(codec AvroCodec, data []byte, zstdEnc *zstd.Encoder) ([]byte, error) {
+	switch codec {
+	case AvroCodecNull:
+		return data, nil
+	case AvroCodecZstandard:
+		return zstdEnc.EncodeAll(data, nil), nil
+	}
+	return nil, g.Error("unsupported avro codec: %s", codec)
+}
+
+// avroDecompressBlock decompresses the block data with the codec
+func avroDecompressBlock(codec AvroCodec, data []byte, zstdDec *zstd.Decoder) ([]byte, error) {
+	switch codec {
+	case AvroCodecNull, "":
+		return data, nil
+	case AvroCodecZstandard:
+		return zstdDec.DecodeAll(data, nil)
+	}
+	return nil, g.Error("unsupported avro codec: %s", codec)
+}
@@ -3,15 +3,20 @@ package iop
 import (
 	"archive/zip"
 	"bufio"
+	"bytes"
+	"compress/bzip2"
 	"compress/gzip"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/andybalholm/brotli"
 	"github.com/flarco/g"
 	"github.com/klauspost/compress/s2"
 	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
 )
 
 // Compressor implements differnt kind of compression
@@ -38,6 +43,14 @@ const (
 	SnappyCompressorType CompressorType = "snappy"
 	// ZStandardCompressorType is for ZStandard
 	ZStandardCompressorType CompressorType = "zstd"
+	// Lz4CompressorType is for LZ4 (frame format)
+	Lz4CompressorType CompressorType = "lz4"
+	// BrotliCompressorType is for Brotli
+	BrotliCompressorType CompressorType = "brotli"
+	// Bzip2CompressorType is for bzip2
+	Bzip2CompressorType CompressorType = "bzip2"
+	// XzCompressorType is for xz
+	XzCompressorType CompressorType = "xz"
 )
 
 var AllCompressorType = []struct {
@@ -50,6 +63,10 @@ var AllCompressorType = []struct {
 	{GzipCompressorType, "GzipCompressorType"},
 	{SnappyCompressorType, "SnappyCompressorType"},
 	{ZStandardCompressorType, "ZStandardCompressorType"},
+	{Lz4CompressorType, "Lz4CompressorType"},
+	{BrotliCompressorType, "BrotliCompressorType"},
+	{Bzip2CompressorType, "Bzip2CompressorType"},
+	{XzCompressorType, "XzCompressorType"},
 }
 
 // CompressorTypePtr returns a pointer to the CompressorType value passed in.
@@ -130,6 +147,14 @@ func NewCompressor(cpType CompressorType) Compressor {
 		compressor = &SnappyCompressor{cpType: cpType, suffix: ".snappy"}
 	case ZStandardCompressorType:
 		compressor = &ZStandardCompressor{cpType: cpType, suffix: ".zst"}
+	case Lz4CompressorType:
+		compressor = &Lz4Compressor{cpType: cpType, suffix: ".lz4"}
+	case BrotliCompressorType:
+		compressor = &BrotliCompressor{cpType: cpType, suffix: ".br"}
+	case Bzip2CompressorType:
+		compressor = &Bzip2Compressor{cpType: cpType, suffix: ".bz2"}
+	case XzCompressorType:
+		compressor = &XzCompressor{cpType: cpType, suffix: ".xz"}
 	default:
 		compressor = &NoneCompressor{cpType: NoneCompressorType, suffix: ""}
 	}
@@ -274,29 +299,198 @@ func (cp *ZStandardCompressor) Suffix() string {
 	return cp.suffix
 }
 
-// AutoDecompress auto detects compression to decompress. Otherwise return same reader
+type Lz4Compressor struct {
+	Compressor
+	cpType CompressorType
+	suffix string
+}
+
+// Compress uses lz4 to compress
+func (cp *Lz4Compressor) Compress(reader io.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	w := lz4.NewWriter(pw)
+	go func() {
+		_, err := io.Copy(w, reader)
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			pw.CloseWithError(g.Error(err, "could not compress stream with lz4"))
+			return
+		}
+		pw.Close()
+	}()
+
+	return pr
+}
+
+// Decompress uses lz4 to decompress
+func (cp *Lz4Compressor) Decompress(reader io.Reader) (lReader io.Reader, err error) {
+	return lz4.NewReader(reader), nil
+}
+
+func (cp *Lz4Compressor) Suffix() string {
+	return cp.suffix
+}
+
+type BrotliCompressor struct {
+	Compressor
+	cpType CompressorType
+	suffix string
+}
+
+// Compress uses brotli to compress
+func (cp *BrotliCompressor) Compress(reader io.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	w := brotli.NewWriterLevel(pw, brotli.BestSpeed)
+	go func() {
+		_, err := io.Copy(w, reader)
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			pw.CloseWithError(g.Error(err, "could not compress stream with brotli"))
+			return
+		}
+		pw.Close()
+	}()
+
+	return pr
+}
+
+// Decompress uses brotli to decompress. Brotli streams have no
+// magic bytes, so they are only detected with the `.br` suffix
+func (cp *BrotliCompressor) Decompress(reader io.Reader) (bReader io.Reader, err error) {
+	return brotli.NewReader(reader), nil
+}
+
+func (cp *BrotliCompressor) Suffix() string {
+	return cp.suffix
+}
+
+type Bzip2Compressor struct {
+	Compressor
+	cpType CompressorType
+	suffix string
+}
+
+// Compress is not supported for bzip2, since the standard library only
+// provides a decompressor. The returned reader fails on the first read
+func (cp *Bzip2Compressor) Compress(reader io.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	pw.CloseWithError(g.Error("bzip2 compression is not supported for writing, only for reading"))
+	return pr
+}
+
+// Decompress uses bzip2 to decompress
+func (cp *Bzip2Compressor) Decompress(reader io.Reader) (bReader io.Reader, err error) {
+	return bzip2.NewReader(reader), nil
+}
+
+func (cp *Bzip2Compressor) Suffix() string {
+	return cp.suffix
+}
+
+type XzCompressor struct {
+	Compressor
+	cpType CompressorType
+	suffix string
+}
+
+// Compress uses xz to compress
+func (cp *XzCompressor) Compress(reader io.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := xz.NewWriter(pw)
+		if err == nil {
+			if _, err = io.Copy(w, reader); err == nil {
+				err = w.Close()
+			}
+		}
+		if err != nil {
+			pw.CloseWithError(g.Error(err, "could not compress stream with xz"))
+			return
+		}
+		pw.Close()
+	}()
+
+	return pr
+}
+
+// Decompress uses xz to decompress
+func (cp *XzCompressor) Decompress(reader io.Reader) (xReader io.Reader, err error) {
+	xReader, err = xz.NewReader(reader)
+	if err != nil {
+		return nil, g.Error(err, "could not decompress stream with xz")
+	}
+	return xReader, nil
+}
+
+func (cp *XzCompressor) Suffix() string {
+	return cp.suffix
+}
+
+// AutoDecompress auto detects compression to decompress (with the magic bytes
+// of gzip, zstd, snappy, lz4, bzip2 and xz). Otherwise return same reader
 func AutoDecompress(reader io.Reader) (gReader io.Reader, err error) {
 	bReader, ok := reader.(*bufio.Reader)
 	if !ok {
 		bReader = bufio.NewReader(reader)
 	}
 
-	testBytes, err := bReader.Peek(2)
-	if err != nil {
-		// return bReader, g.Error(err, "Error Peeking")
+	testBytes, _ := bReader.Peek(10)
+	if len(testBytes) < 2 {
+		return bReader, nil
+	}
+
+	cpType := DetectCompressorType(testBytes)
+	if cpType == NoneCompressorType {
 		return bReader, nil
 	}
 
+	gReader, err = NewCompressor(cpType).Decompress(bReader)
+	if err != nil {
+		return bReader, g.Error(err, "Error using %s decompressor", cpType)
+	}
+
+	return gReader, err
+}
+
+// DetectCompressorType returns the compression of a stream from its first bytes
+func DetectCompressorType(header []byte) CompressorType {
+	switch {
 	// https://stackoverflow.com/a/28332019
-	if testBytes[0] == 31 && testBytes[1] == 139 {
-		// is gzip
-		gReader, err = gzip.NewReader(bReader)
-		if err != nil {
-			return bReader, g.Error(err, "Error using gzip.NewReader")
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return GzipCompressorType
+	case bytes.HasPrefix(header, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return ZStandardCompressorType
+	// https://github.com/google/snappy/blob/master/framing_format.txt
+	case bytes.HasPrefix(header, []byte("\xff\x06\x00\x00sNaPpY")):
+		return SnappyCompressorType
+	case bytes.HasPrefix(header, []byte{0x04, 0x22, 0x4d, 0x18}):
+		return Lz4CompressorType
+	case bytes.HasPrefix(header, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return XzCompressorType
+	case len(header) >= 10 && bytes.HasPrefix(header, []byte("BZh")) && header[3] >= '1' && header[3] <= '9':
+		// block magic (pi) or end of stream magic (sqrt pi)
+		magic := header[4:10]
+		if bytes.Equal(magic, []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}) || bytes.Equal(magic, []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}) {
+			return Bzip2CompressorType
 		}
-	} else {
-		gReader = bReader
 	}
+	return NoneCompressorType
+}
 
-	return gReader, err
+// CompressorTypeFromSuffix returns the compression from the file name suffix
+func CompressorTypeFromSuffix(name string) CompressorType {
+	name = strings.ToLower(name)
+	for _, cpType := range []CompressorType{
+		GzipCompressorType, SnappyCompressorType, ZStandardCompressorType,
+		Lz4CompressorType, BrotliCompressorType, Bzip2CompressorType, XzCompressorType,
+	} {
+		if strings.HasSuffix(name, NewCompressor(cpType).Suffix()) {
+			return cpType
+		}
+	}
+	return NoneCompressorType
 }
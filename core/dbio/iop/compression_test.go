@@ -1,6 +1,7 @@
 package iop
 
 import (
+	"bytes"
 	"io"
 	"strings"
 	"testing"
@@ -45,4 +46,57 @@ func TestCompression(t *testing.T) {
 	g.AssertNoError(t, err)
 	assert.Equal(t, value, string(result))
 
+	// lz4, brotli, xz
+	for _, cpType := range []CompressorType{Lz4CompressorType, BrotliCompressorType, XzCompressorType} {
+		cp = NewCompressor(cpType)
+		cReader = cp.Compress(strings.NewReader(value))
+		compressed, err := io.ReadAll(cReader)
+		if !assert.NoError(t, err, cpType) {
+			continue
+		}
+
+		dReader, err = cp.Decompress(strings.NewReader(string(compressed)))
+		g.AssertNoError(t, err)
+		result, err = io.ReadAll(dReader)
+		g.AssertNoError(t, err)
+		assert.Equal(t, value, string(result), cpType)
+
+		// detected with the magic bytes, except brotli
+		dReader, err = AutoDecompress(strings.NewReader(string(compressed)))
+		g.AssertNoError(t, err)
+		result, err = io.ReadAll(dReader)
+		g.AssertNoError(t, err)
+		if cpType == BrotliCompressorType {
+			assert.Equal(t, string(compressed), string(result))
+		} else {
+			assert.Equal(t, value, string(result), cpType)
+		}
+	}
+
+	// bzip2 is only decompressed, `printf testing | bzip2 -9`
+	compressed := []byte{
+		0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x21, 0x3d,
+		0x8f, 0x48, 0x00, 0x00, 0x02, 0x81, 0x80, 0x02, 0xa1, 0x0c, 0x00, 0x20,
+		0x00, 0x30, 0xc0, 0x08, 0x63, 0x45, 0x11, 0x0b, 0x85, 0xdc, 0x91, 0x4e,
+		0x14, 0x24, 0x08, 0x4f, 0x63, 0xd2, 0x00,
+	}
+	assert.Equal(t, Bzip2CompressorType, DetectCompressorType(compressed))
+	dReader, err = AutoDecompress(bytes.NewReader(compressed))
+	g.AssertNoError(t, err)
+	result, err = io.ReadAll(dReader)
+	g.AssertNoError(t, err)
+	assert.Equal(t, value, string(result))
+
+	_, err = io.ReadAll(NewCompressor(Bzip2CompressorType).Compress(strings.NewReader(value)))
+	assert.ErrorContains(t, err, "not supported for writing")
+
+	// not compressed
+	assert.Equal(t, NoneCompressorType, DetectCompressorType([]byte("BZh1,id,name\n")))
+	dReader, err = AutoDecompress(strings.NewReader(value))
+	g.AssertNoError(t, err)
+	result, _ = io.ReadAll(dReader)
+	assert.Equal(t, value, string(result))
+
+	assert.Equal(t, XzCompressorType, CompressorTypeFromSuffix("s3://bucket/file.CSV.XZ"))
+	assert.Equal(t, NoneCompressorType, CompressorTypeFromSuffix("s3://bucket/file.csv"))
 }
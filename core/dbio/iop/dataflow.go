@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/flarco/g"
@@ -490,13 +491,13 @@ func (df *Dataflow) Count() (cnt uint64) {
 	return
 }
 
-// AddEgressBytes add egress bytes
+// AddEgressBytes add egress bytes. Safe for concurrent uploads.
 func (df *Dataflow) AddEgressBytes(bytes uint64) {
-	df.EgressBytes = df.EgressBytes + bytes
+	atomic.AddUint64(&df.EgressBytes, bytes)
 }
 
 func (df *Dataflow) Bytes() (inBytes, outBytes uint64) {
-	return df.DsTotalBytes(), df.EgressBytes
+	return df.DsTotalBytes(), atomic.LoadUint64(&df.EgressBytes)
 }
 
 func (df *Dataflow) DsTotalBytes() (bytes uint64) {
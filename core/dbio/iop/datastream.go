@@ -15,6 +15,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	arrowParquet "github.com/apache/arrow/go/v16/parquet"
 	arrowCompress "github.com/apache/arrow/go/v16/parquet/compress"
 	"github.com/flarco/g"
 	"github.com/flarco/g/csv"
@@ -61,6 +62,7 @@ type Datastream struct {
 	bwCsv         *csv.Writer // for correct byte written
 	ID            string
 	Metadata      Metadata // map of column name to metadata type
+	Partitions    Columns  // hive partition columns, valued from the `key=value` folders of the stream url
 	paused        bool
 	pauseChan     chan struct{}
 	unpauseChan   chan struct{}
@@ -721,6 +723,33 @@ loop:
 			}
 		}
 
+		for _, partCol := range ds.Partitions {
+			if _, exists := ds.Columns.FieldMap(true)[strings.ToLower(partCol.Name)]; exists {
+				g.Debug("skipping partition column %s, since it exists in the files", partCol.Name)
+				continue
+			}
+
+			col := partCol
+			col.Position = len(ds.Columns) + 1
+			col.Description = "Sling.Partition"
+			col.Metadata = map[string]string{"sling_metadata": "partition"}
+			ds.Columns = append(ds.Columns, col)
+
+			key := partCol.Name
+			var lastURL string
+			var lastVal any
+			metaValuesMap[col.Position-1] = func(it *Iterator) any {
+				if streamURL := cast.ToString(ds.Metadata.StreamURL.Value); streamURL != lastURL {
+					_, values := HivePartitions(streamURL)
+					lastURL, lastVal = streamURL, nil
+					if val := values[key]; val != nil {
+						lastVal = *val
+					}
+				}
+				return lastVal
+			}
+		}
+
 		if ds.Metadata.RowNum.Key != "" {
 			ds.Metadata.RowNum.Key = ensureName(ds.Metadata.RowNum.Key)
 			col := Column{
@@ -1348,12 +1377,20 @@ func (ds *Datastream) ConsumeCsvReader(reader io.Reader) (err error) {
 	return
 }
 
-// ConsumeParquetReader uses the provided reader to stream rows
-func (ds *Datastream) ConsumeParquetReaderSeeker(reader *os.File) (err error) {
+// ConsumeParquetReaderSeeker uses the provided reader to stream rows.
+// Only the selected columns are read.
+func (ds *Datastream) ConsumeParquetReaderSeeker(reader arrowParquet.ReaderAtSeeker) (err error) {
 	selected := ds.Columns.Names()
 
+	// skip the row groups which cannot match the incremental value or the where filters
+	filters := []ParquetFilter{}
+	g.Unmarshal(ds.config.Map["parquet_filters"], &filters)
+	if col, val := ds.config.Map["sling_incremental_col"], ds.config.Map["sling_incremental_val"]; col != "" && val != "" {
+		filters = append(filters, ParquetFilter{Column: col, Op: ">", Value: val})
+	}
+
 	// p, err := NewParquetStream(reader, Columns{}) // old version
-	p, err := NewParquetArrowReader(reader, selected)
+	p, err := NewParquetArrowReader(reader, selected, filters...)
 	if err != nil {
 		return g.Error(err, "could create parquet stream")
 	}
@@ -1404,6 +1441,7 @@ func (ds *Datastream) ConsumeAvroReaderSeeker(reader io.ReadSeeker) (err error)
 	if err != nil {
 		return g.Error(err, "could create avro stream")
 	}
+	a.NativeTypes = cast.ToBool(ds.Sp.Config.Map["avro_native_types"])
 
 	ds.Columns = a.Columns()
 	ds.Inferred = ds.Columns.Sourced()
@@ -1445,6 +1483,53 @@ func (ds *Datastream) ConsumeAvroReader(reader io.Reader) (err error) {
 	return ds.ConsumeAvroReaderSeeker(file)
 }
 
+// ConsumeOrcReaderSeeker uses the provided reader to stream rows
+func (ds *Datastream) ConsumeOrcReaderSeeker(reader io.ReadSeeker) (err error) {
+	o, err := NewOrcStream(reader, Columns{})
+	if err != nil {
+		return g.Error(err, "could create orc stream")
+	}
+
+	ds.Columns = o.Columns()
+	ds.Inferred = ds.Columns.Sourced()
+	ds.it = ds.NewIterator(ds.Columns, o.nextFunc)
+	ds.SetFileURI()
+
+	err = ds.Start()
+	if err != nil {
+		return g.Error(err, "could start datastream")
+	}
+
+	return
+}
+
+// ConsumeOrcReader uses the provided reader to stream rows
+func (ds *Datastream) ConsumeOrcReader(reader io.Reader) (err error) {
+	// need to write to temp file prior, since the footer is at the end
+	tempDir := env.GetTempFolder()
+	orcPath := path.Join(tempDir, g.NewTsID("orc.temp")+".orc")
+	ds.Defer(func() { os.Remove(orcPath) })
+
+	file, err := os.Create(orcPath)
+	if err != nil {
+		return g.Error(err, "Unable to create temp file: "+orcPath)
+	}
+
+	g.Debug("downloading to temp file on disk: %s", orcPath)
+	bw, err := io.Copy(file, reader)
+	if err != nil {
+		return g.Error(err, "Unable to write to temp file: "+orcPath)
+	}
+	g.Debug("wrote %d bytes to %s", bw, orcPath)
+
+	_, err = file.Seek(0, 0) // reset to beginning
+	if err != nil {
+		return g.Error(err, "Unable to seek to beginning of temp file: "+orcPath)
+	}
+
+	return ds.ConsumeOrcReaderSeeker(file)
+}
+
 // ConsumeSASReaderSeeker uses the provided reader to stream rows
 func (ds *Datastream) ConsumeSASReaderSeeker(reader io.ReadSeeker) (err error) {
 	s, err := NewSASStream(reader, Columns{})
@@ -1747,6 +1832,44 @@ func (ds *Datastream) Map(newColumns Columns, transf func([]any) []any) (nDs *Da
 	return nDs
 }
 
+// Filter returns a datastream of the rows for which keep returns true
+func (ds *Datastream) Filter(keep func([]any) bool) (nDs *Datastream) {
+
+	rows := MakeRowsChan()
+	nextFunc := func(it *Iterator) bool {
+		for it.Row = range rows {
+			return true
+		}
+		return false
+	}
+
+	// the columns are known once ready
+	if err := ds.WaitReady(); err != nil {
+		ds.Context.CaptureErr(err)
+	}
+
+	nDs = NewDatastreamIt(ds.Context.Ctx, ds.Columns, nextFunc)
+	nDs.Inferred = true
+
+	go func() {
+		defer close(rows)
+		for batch0 := range ds.BatchChan {
+			for row := range batch0.Rows {
+				if keep(row) {
+					rows <- row
+				}
+			}
+		}
+	}()
+
+	err := nDs.Start()
+	if err != nil {
+		ds.Context.CaptureErr(err)
+	}
+
+	return nDs
+}
+
 // MapParallel applies the provided function to every row in parallel and returns the result. Order is not maintained.
 func (ds *Datastream) MapParallel(transf func([]any) []any, numWorkers int) (nDs *Datastream) {
 	var wg sync.WaitGroup
@@ -2099,6 +2222,88 @@ func (ds *Datastream) NewJsonLinesReaderChnl(rowLimit int, bytesLimit int64) (re
 	return readerChn
 }
 
+// NewXmlReaderChnl provides a channel of readers as the limit is reached
+// each channel flows as fast as the consumer consumes
+func (ds *Datastream) NewXmlReaderChnl(rowLimit int, bytesLimit int64, config XmlConfig) (readerChn chan *BatchReader) {
+	readerChn = make(chan *BatchReader, 100)
+
+	pipeR, pipeW := io.Pipe()
+
+	go func() {
+		var xw *XmlWriter
+		var br *BatchReader
+		var err error
+
+		defer close(readerChn)
+
+		nextPipe := func(batch *Batch) error {
+			if xw != nil {
+				if err := xw.Close(); err != nil {
+					return g.Error(err, "could not close xml writer")
+				}
+			}
+
+			pipeW.Close() // close the prior reader
+
+			// new reader
+			pipeR, pipeW = io.Pipe()
+
+			br = &BatchReader{batch, batch.Columns, pipeR, 0}
+			readerChn <- br
+
+			xw, err = NewXmlWriter(pipeW, batch.Columns, config, ds.Sp)
+			if err != nil {
+				return g.Error(err, "could not create xml writer")
+			}
+
+			return nil
+		}
+
+		for batch := range ds.BatchChan {
+			if batch.ColumnsChanged() || batch.IsFirst() {
+				err := nextPipe(batch)
+				if err != nil {
+					ds.Context.CaptureErr(err)
+					pipeW.CloseWithError(err)
+					return
+				}
+			}
+
+			for row := range batch.Rows {
+
+				err := xw.WriteRow(row)
+				if err != nil {
+					ds.Context.CaptureErr(g.Error(err, "error writing row"))
+					ds.Context.Cancel()
+					pipeW.Close()
+					return
+				}
+
+				br.Counter++
+
+				if (rowLimit > 0 && br.Counter >= rowLimit) || (bytesLimit > 0 && xw.BytesWritten() >= bytesLimit) {
+					err = nextPipe(batch)
+					if err != nil {
+						ds.Context.CaptureErr(err)
+						pipeW.CloseWithError(err)
+						return
+					}
+				}
+			}
+		}
+
+		if xw != nil {
+			if err := xw.Close(); err != nil {
+				ds.Context.CaptureErr(g.Error(err, "could not close xml writer"))
+			}
+		}
+		pipeW.Close()
+
+	}()
+
+	return readerChn
+}
+
 // NewParquetArrowReaderChnl provides a channel of readers as the limit is reached
 // each channel flows as fast as the consumer consumes
 // WARN: Not using this one since it doesn't write Decimals properly.
@@ -2140,6 +2345,8 @@ func (ds *Datastream) NewParquetArrowReaderChnl(rowLimit int, bytesLimit int64,
 				codec = arrowCompress.Codecs.Zstd
 			case GzipCompressorType:
 				codec = arrowCompress.Codecs.Gzip
+			case BrotliCompressorType:
+				codec = arrowCompress.Codecs.Brotli
 			case NoneCompressorType:
 				codec = arrowCompress.Codecs.Uncompressed
 			}
@@ -2268,6 +2475,10 @@ func (ds *Datastream) NewParquetReaderChnl(rowLimit int, bytesLimit int64, compr
 				codec = &parquet.Zstd
 			case GzipCompressorType:
 				codec = &parquet.Gzip
+			case BrotliCompressorType:
+				codec = &parquet.Brotli
+			case Lz4CompressorType:
+				codec = &parquet.Lz4Raw
 			case NoneCompressorType:
 				codec = &parquet.Uncompressed
 			}
@@ -2321,6 +2532,379 @@ func (ds *Datastream) NewParquetReaderChnl(rowLimit int, bytesLimit int64, compr
 	return readerChn
 }
 
+// NewAvroReaderChnl provides a channel of readers as the limit is reached
+// each channel flows as fast as the consumer consumes
+func (ds *Datastream) NewAvroReaderChnl(rowLimit int, bytesLimit int64, compression CompressorType) (readerChn chan *BatchReader) {
+	readerChn = make(chan *BatchReader, 100)
+
+	pipeR, pipeW := io.Pipe()
+
+	go func() {
+		var aw *AvroWriter
+		var br *BatchReader
+		var err error
+
+		defer close(readerChn)
+
+		nextPipe := func(batch *Batch) error {
+			if aw != nil {
+				if err := aw.Close(); err != nil {
+					return g.Error(err, "could not close avro writer")
+				}
+			}
+
+			pipeW.Close() // close the prior reader
+
+			// new reader
+			pipeR, pipeW = io.Pipe()
+
+			br = &BatchReader{batch, batch.Columns, pipeR, 0}
+			readerChn <- br
+
+			// default codec is snappy
+			codec := AvroCodecSnappy
+
+			switch compression {
+			case GzipCompressorType:
+				codec = AvroCodecDeflate
+			case ZStandardCompressorType:
+				codec = AvroCodecZstandard
+			case NoneCompressorType:
+				codec = AvroCodecNull
+			}
+
+			aw, err = NewAvroWriter(pipeW, batch.Columns, codec)
+			if err != nil {
+				return g.Error(err, "could not create avro writer")
+			}
+
+			return nil
+		}
+
+		for batch := range ds.BatchChan {
+			if batch.ColumnsChanged() || batch.IsFirst() {
+				err := nextPipe(batch)
+				if err != nil {
+					ds.Context.CaptureErr(err)
+					pipeW.CloseWithError(err)
+					return
+				}
+			}
+
+			for row := range batch.Rows {
+
+				err := aw.WriteRow(row)
+				if err != nil {
+					ds.Context.CaptureErr(g.Error(err, "error writing row"))
+					ds.Context.Cancel()
+					pipeW.Close()
+					return
+				}
+
+				br.Counter++
+
+				if (rowLimit > 0 && br.Counter >= rowLimit) || (bytesLimit > 0 && aw.BytesWritten() >= bytesLimit) {
+					err = nextPipe(batch)
+					if err != nil {
+						ds.Context.CaptureErr(err)
+						pipeW.CloseWithError(err)
+						return
+					}
+				}
+			}
+		}
+
+		if aw != nil {
+			if err := aw.Close(); err != nil {
+				ds.Context.CaptureErr(g.Error(err, "could not close avro writer"))
+			}
+		}
+		pipeW.Close()
+
+	}()
+
+	return readerChn
+}
+
+// NewOrcReaderChnl provides a channel of readers as the limit is reached
+// each channel flows as fast as the consumer consumes
+func (ds *Datastream) NewOrcReaderChnl(rowLimit int, bytesLimit int64, compression CompressorType, stripeSize int64) (readerChn chan *BatchReader) {
+	readerChn = make(chan *BatchReader, 100)
+
+	pipeR, pipeW := io.Pipe()
+
+	go func() {
+		var ow *OrcWriter
+		var br *BatchReader
+		var err error
+
+		defer close(readerChn)
+
+		nextPipe := func(batch *Batch) error {
+			if ow != nil {
+				if err := ow.Close(); err != nil {
+					return g.Error(err, "could not close orc writer")
+				}
+			}
+
+			pipeW.Close() // close the prior reader
+
+			// new reader
+			pipeR, pipeW = io.Pipe()
+
+			br = &BatchReader{batch, batch.Columns, pipeR, 0}
+			readerChn <- br
+
+			// default codec is zlib
+			codec := OrcCompressionZlib
+
+			switch compression {
+			case SnappyCompressorType:
+				codec = OrcCompressionSnappy
+			case ZStandardCompressorType:
+				codec = OrcCompressionZstd
+			case NoneCompressorType:
+				codec = OrcCompressionNone
+			}
+
+			ow, err = NewOrcWriter(pipeW, batch.Columns, codec, stripeSize)
+			if err != nil {
+				return g.Error(err, "could not create orc writer")
+			}
+
+			return nil
+		}
+
+		for batch := range ds.BatchChan {
+			if batch.ColumnsChanged() || batch.IsFirst() {
+				err := nextPipe(batch)
+				if err != nil {
+					ds.Context.CaptureErr(err)
+					pipeW.CloseWithError(err)
+					return
+				}
+			}
+
+			for row := range batch.Rows {
+
+				err := ow.WriteRow(row)
+				if err != nil {
+					ds.Context.CaptureErr(g.Error(err, "error writing row"))
+					ds.Context.Cancel()
+					pipeW.Close()
+					return
+				}
+
+				br.Counter++
+
+				if (rowLimit > 0 && br.Counter >= rowLimit) || (bytesLimit > 0 && ow.BytesWritten() >= bytesLimit) {
+					err = nextPipe(batch)
+					if err != nil {
+						ds.Context.CaptureErr(err)
+						pipeW.CloseWithError(err)
+						return
+					}
+				}
+			}
+		}
+
+		if ow != nil {
+			if err := ow.Close(); err != nil {
+				ds.Context.CaptureErr(g.Error(err, "could not close orc writer"))
+			}
+		}
+		pipeW.Close()
+
+	}()
+
+	return readerChn
+}
+
+// NewArrowReaderChnl provides a channel of readers as the limit is reached
+// each channel flows as fast as the consumer consumes
+func (ds *Datastream) NewArrowReaderChnl(rowLimit int, bytesLimit int64, compression CompressorType, format ArrowFormat) (readerChn chan *BatchReader) {
+	readerChn = make(chan *BatchReader, 100)
+
+	pipeR, pipeW := io.Pipe()
+
+	go func() {
+		var aw *ArrowWriter
+		var br *BatchReader
+		var err error
+
+		defer close(readerChn)
+
+		nextPipe := func(batch *Batch) error {
+			if aw != nil {
+				if err := aw.Close(); err != nil {
+					return g.Error(err, "could not close arrow writer")
+				}
+			}
+
+			pipeW.Close() // close the prior reader
+
+			// new reader
+			pipeR, pipeW = io.Pipe()
+
+			br = &BatchReader{batch, batch.Columns, pipeR, 0}
+			readerChn <- br
+
+			// default codec is lz4, like Feather v2
+			codec := ArrowCodecLz4
+
+			switch compression {
+			case ZStandardCompressorType:
+				codec = ArrowCodecZstd
+			case NoneCompressorType:
+				codec = ArrowCodecNone
+			}
+
+			aw, err = NewArrowWriter(pipeW, batch.Columns, format, codec)
+			if err != nil {
+				return g.Error(err, "could not create arrow writer")
+			}
+
+			return nil
+		}
+
+		for batch := range ds.BatchChan {
+			if batch.ColumnsChanged() || batch.IsFirst() {
+				err := nextPipe(batch)
+				if err != nil {
+					ds.Context.CaptureErr(err)
+					pipeW.CloseWithError(err)
+					return
+				}
+			}
+
+			for row := range batch.Rows {
+
+				err := aw.WriteRow(row)
+				if err != nil {
+					ds.Context.CaptureErr(g.Error(err, "error writing row"))
+					ds.Context.Cancel()
+					pipeW.Close()
+					return
+				}
+
+				br.Counter++
+
+				if (rowLimit > 0 && br.Counter >= rowLimit) || (bytesLimit > 0 && aw.BytesWritten() >= bytesLimit) {
+					err = nextPipe(batch)
+					if err != nil {
+						ds.Context.CaptureErr(err)
+						pipeW.CloseWithError(err)
+						return
+					}
+				}
+			}
+		}
+
+		if aw != nil {
+			if err := aw.Close(); err != nil {
+				ds.Context.CaptureErr(g.Error(err, "could not close arrow writer"))
+			}
+		}
+		pipeW.Close()
+
+	}()
+
+	return readerChn
+}
+
+// NewFixedWidthReaderChnl provides a channel of readers of fixed-width
+// records, with values padded or truncated to the column lengths
+func (ds *Datastream) NewFixedWidthReaderChnl(rowLimit int, bytesLimit int64, spec FixedWidthColumns) (readerChn chan *BatchReader) {
+	readerChn = make(chan *BatchReader, 100)
+
+	pipeR, pipeW := io.Pipe()
+
+	layouts := make([]string, len(spec))
+	for i, col := range spec {
+		if col.Format != "" {
+			layouts[i] = Iso8601ToGoLayout(col.Format)
+		}
+	}
+
+	go func() {
+		var br *BatchReader
+		var colIndexes []int
+		tbw := int64(0)
+
+		defer close(readerChn)
+
+		nextPipe := func(batch *Batch) error {
+			pipeW.Close() // close the prior reader
+			tbw = 0       // reset
+
+			// map the spec columns to the batch columns
+			fieldMap := batch.Columns.FieldMap(true)
+			colIndexes = make([]int, len(spec))
+			for i, col := range spec {
+				j, ok := fieldMap[strings.ToLower(col.Name)]
+				if !ok {
+					return g.Error("fixed-width column '%s' not found in stream", col.Name)
+				}
+				colIndexes[i] = j
+			}
+
+			// new reader
+			pipeR, pipeW = io.Pipe()
+
+			br = &BatchReader{batch, batch.Columns, pipeR, 0}
+			readerChn <- br
+
+			return nil
+		}
+
+		for batch := range ds.BatchChan {
+			if batch.ColumnsChanged() || batch.IsFirst() {
+				err := nextPipe(batch)
+				if err != nil {
+					ds.Context.CaptureErr(err)
+					pipeW.CloseWithError(err)
+					return
+				}
+			}
+
+			for row := range batch.Rows {
+				values := make([]string, len(spec))
+				for i, j := range colIndexes {
+					if t, ok := row[j].(time.Time); ok && layouts[i] != "" {
+						values[i] = t.Format(layouts[i])
+					} else {
+						values[i] = ds.Sp.CastToString(j, row[j], batch.Columns[j].Type)
+					}
+				}
+
+				bw, err := pipeW.Write([]byte(spec.Record(values) + "\n"))
+				tbw = tbw + cast.ToInt64(bw)
+				if err != nil {
+					ds.Context.CaptureErr(g.Error(err, "error writing row"))
+					ds.Context.Cancel()
+					pipeW.Close()
+					return
+				}
+
+				br.Counter++
+
+				if (rowLimit > 0 && br.Counter >= rowLimit) || (bytesLimit > 0 && tbw >= bytesLimit) {
+					err = nextPipe(batch)
+					if err != nil {
+						ds.Context.CaptureErr(err)
+						pipeW.CloseWithError(err)
+						return
+					}
+				}
+			}
+		}
+
+		pipeW.Close()
+	}()
+
+	return readerChn
+}
+
 // NewCsvReader creates a Reader with limit. If limit == 0, then read all rows.
 func (ds *Datastream) NewCsvReader(rowLimit int, bytesLimit int64) *io.PipeReader {
 	pipeR, pipeW := io.Pipe()
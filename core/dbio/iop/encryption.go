@@ -0,0 +1,219 @@
+package iop
+
+import (
+	"bufio"
+	"bytes"
+	_ "crypto/sha256" // hash functions for openpgp
+	_ "crypto/sha512"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/flarco/g"
+)
+
+// Encryptor encrypts and decrypts streams
+type Encryptor interface {
+	Encrypt(io.Reader) (io.Reader, error)
+	Decrypt(io.Reader) (io.Reader, error)
+	Suffix() string
+}
+
+// EncryptorType is the type of encryption
+type EncryptorType string
+
+const (
+	// PgpEncryptorType is for OpenPGP encryption
+	PgpEncryptorType EncryptorType = "pgp"
+	// AgeEncryptorType is for age encryption (not supported yet)
+	AgeEncryptorType EncryptorType = "age"
+)
+
+// EncryptConfig is the configuration of the encryption.
+// Keys can be the key itself (armored) or a file path.
+// When missing, keys are taken from the connection props or env vars
+// (`PGP_PUBLIC_KEY`, `PGP_PRIVATE_KEY`, `PGP_PASSPHRASE`).
+type EncryptConfig struct {
+	Type       EncryptorType `json:"type,omitempty" yaml:"type,omitempty"`
+	PublicKey  string        `json:"public_key,omitempty" yaml:"public_key,omitempty"`
+	PrivateKey string        `json:"private_key,omitempty" yaml:"private_key,omitempty"`
+	Passphrase string        `json:"passphrase,omitempty" yaml:"passphrase,omitempty"`
+	Armor      bool          `json:"armor,omitempty" yaml:"armor,omitempty"`
+}
+
+// NewEncryptor creates a new encryptor, reading the provided keys
+func NewEncryptor(config EncryptConfig) (Encryptor, error) {
+	switch config.Type {
+	case PgpEncryptorType, "":
+		return NewPgpEncryptor(config)
+	case AgeEncryptorType:
+		return nil, g.Error("age encryption is not supported yet, use pgp")
+	}
+	return nil, g.Error("invalid encryption type: %s", config.Type)
+}
+
+// EncryptionSuffixes are the file suffixes of encrypted files
+var EncryptionSuffixes = []string{".pgp", ".gpg", ".asc", ".age"}
+
+// TrimEncryptionSuffix removes the encryption suffix from the file name
+func TrimEncryptionSuffix(name string) string {
+	for _, suffix := range EncryptionSuffixes {
+		if strings.HasSuffix(strings.ToLower(name), suffix) {
+			return name[:len(name)-len(suffix)]
+		}
+	}
+	return name
+}
+
+type PgpEncryptor struct {
+	armor      bool
+	recipients openpgp.EntityList
+	keyRing    openpgp.EntityList
+}
+
+// NewPgpEncryptor creates a pgp encryptor. The public key is needed to
+// encrypt, the private key (with its passphrase) is needed to decrypt.
+func NewPgpEncryptor(config EncryptConfig) (e *PgpEncryptor, err error) {
+	e = &PgpEncryptor{armor: config.Armor}
+
+	if config.PublicKey != "" {
+		if e.recipients, err = readPgpKeyRing(config.PublicKey); err != nil {
+			return nil, g.Error(err, "could not read pgp public key")
+		}
+	}
+
+	if config.PrivateKey != "" {
+		if e.keyRing, err = readPgpKeyRing(config.PrivateKey); err != nil {
+			return nil, g.Error(err, "could not read pgp private key")
+		}
+
+		// decrypt the private keys with the passphrase
+		passphrase := []byte(config.Passphrase)
+		for _, entity := range e.keyRing {
+			if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+				if err = entity.PrivateKey.Decrypt(passphrase); err != nil {
+					return nil, g.Error(err, "could not decrypt pgp private key, check the passphrase")
+				}
+			}
+			for _, subKey := range entity.Subkeys {
+				if subKey.PrivateKey != nil && subKey.PrivateKey.Encrypted {
+					if err = subKey.PrivateKey.Decrypt(passphrase); err != nil {
+						return nil, g.Error(err, "could not decrypt pgp private sub key, check the passphrase")
+					}
+				}
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// readPgpKeyRing reads an armored or binary key ring, from the key or a file path
+func readPgpKeyRing(key string) (entities openpgp.EntityList, err error) {
+	keyBytes := []byte(strings.TrimSpace(key))
+	if !bytes.HasPrefix(keyBytes, []byte("-----BEGIN")) {
+		if keyBytes, err = os.ReadFile(key); err != nil {
+			return nil, g.Error(err, "could not read pgp key file")
+		}
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(keyBytes), []byte("-----BEGIN")) {
+		entities, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(keyBytes))
+	} else {
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(keyBytes))
+	}
+	if err != nil {
+		return nil, g.Error(err, "could not read pgp key")
+	} else if len(entities) == 0 {
+		return nil, g.Error("no pgp key found")
+	}
+
+	return entities, nil
+}
+
+// Encrypt encrypts the stream for the public key recipients
+func (e *PgpEncryptor) Encrypt(reader io.Reader) (io.Reader, error) {
+	if len(e.recipients) == 0 {
+		return nil, g.Error("pgp encryption requires a public key (PGP_PUBLIC_KEY)")
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		var out io.WriteCloser = pw
+		if e.armor {
+			armorW, err := armor.Encode(pw, "PGP MESSAGE", nil)
+			if err != nil {
+				pw.CloseWithError(g.Error(err, "could not armor pgp message"))
+				return
+			}
+			out = armorW
+		}
+
+		w, err := openpgp.Encrypt(out, e.recipients, nil, &openpgp.FileHints{IsBinary: true}, nil)
+		if err != nil {
+			pw.CloseWithError(g.Error(err, "could not encrypt stream with pgp"))
+			return
+		}
+
+		if _, err = io.Copy(w, reader); err != nil {
+			pw.CloseWithError(g.Error(err, "could not encrypt stream with pgp"))
+			return
+		}
+		w.Close()
+		if out != pw {
+			out.Close()
+		}
+		pw.Close()
+	}()
+
+	return pr, nil
+}
+
+// Decrypt decrypts the (armored or binary) stream with the private key
+func (e *PgpEncryptor) Decrypt(reader io.Reader) (io.Reader, error) {
+	if len(e.keyRing) == 0 {
+		return nil, g.Error("pgp decryption requires a private key (PGP_PRIVATE_KEY)")
+	}
+
+	bReader := bufio.NewReader(reader)
+	if testBytes, _ := bReader.Peek(10); bytes.HasPrefix(testBytes, []byte("-----BEGIN")) {
+		block, err := armor.Decode(bReader)
+		if err != nil {
+			return nil, g.Error(err, "could not decode armored pgp message")
+		}
+		reader = block.Body
+	} else {
+		reader = bReader
+	}
+
+	md, err := openpgp.ReadMessage(reader, e.keyRing, nil, nil)
+	if err != nil {
+		return nil, g.Error(err, "could not decrypt pgp message")
+	}
+
+	return &stickyErrReader{Reader: md.UnverifiedBody}, nil
+}
+
+// stickyErrReader returns the first error (such as io.EOF) on all later reads.
+// The pgp body checks the integrity again at each read past EOF, which fails.
+type stickyErrReader struct {
+	io.Reader
+	err error
+}
+
+func (r *stickyErrReader) Read(p []byte) (n int, err error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, r.err = r.Reader.Read(p)
+	return n, r.err
+}
+
+func (e *PgpEncryptor) Suffix() string {
+	if e.armor {
+		return ".asc"
+	}
+	return ".pgp"
+}
@@ -0,0 +1,79 @@
+package iop
+
+import (
+	"bytes"
+	"crypto"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/flarco/g"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPgpTestKeys generates an armored pgp key pair
+func newPgpTestKeys(t *testing.T, config *packet.Config) (publicKey, privateKey string) {
+	entity, err := openpgp.NewEntity("sling", "test", "test@slingdata.io", config)
+	g.AssertNoError(t, err)
+
+	// the private key is serialized first, since it signs the hash preferences
+	privBuf := &bytes.Buffer{}
+	w, err := armor.Encode(privBuf, openpgp.PrivateKeyType, nil)
+	g.AssertNoError(t, err)
+	g.AssertNoError(t, entity.SerializePrivate(w, nil))
+	w.Close()
+
+	pubBuf := &bytes.Buffer{}
+	w, err = armor.Encode(pubBuf, openpgp.PublicKeyType, nil)
+	g.AssertNoError(t, err)
+	g.AssertNoError(t, entity.Serialize(w))
+	w.Close()
+
+	return pubBuf.String(), privBuf.String()
+}
+
+func TestEncryption(t *testing.T) {
+	value := "id,name\n1,a\n2,b\n"
+
+	// RSA keys, and ECC keys (Ed25519 signing with a Curve25519 encryption sub key)
+	rsaConfig := &packet.Config{DefaultHash: crypto.SHA256}
+	eccConfig := &packet.Config{DefaultHash: crypto.SHA256, Algorithm: packet.PubKeyAlgoEdDSA, Curve: packet.Curve25519}
+
+	for i, config := range []*packet.Config{rsaConfig, eccConfig, rsaConfig, eccConfig} {
+		armored := i >= 2
+		publicKey, privateKey := newPgpTestKeys(t, config)
+
+		encryptor, err := NewEncryptor(EncryptConfig{Type: PgpEncryptorType, PublicKey: publicKey, Armor: armored})
+		g.AssertNoError(t, err)
+		decryptor, err := NewEncryptor(EncryptConfig{PrivateKey: privateKey})
+		g.AssertNoError(t, err)
+
+		eReader, err := encryptor.Encrypt(strings.NewReader(value))
+		g.AssertNoError(t, err)
+		encrypted, err := io.ReadAll(eReader)
+		g.AssertNoError(t, err)
+		assert.NotContains(t, string(encrypted), "id,name")
+		assert.Equal(t, armored, strings.HasPrefix(string(encrypted), "-----BEGIN PGP MESSAGE"))
+
+		dReader, err := decryptor.Decrypt(bytes.NewReader(encrypted))
+		g.AssertNoError(t, err)
+		result, err := io.ReadAll(dReader)
+		g.AssertNoError(t, err)
+		assert.Equal(t, value, string(result))
+
+		// keys are required
+		_, err = decryptor.Encrypt(strings.NewReader(value))
+		assert.Error(t, err)
+		_, err = encryptor.Decrypt(bytes.NewReader(encrypted))
+		assert.Error(t, err)
+	}
+
+	assert.Equal(t, "data.csv.gz", TrimEncryptionSuffix("data.csv.gz.pgp"))
+	assert.Equal(t, "data.csv", TrimEncryptionSuffix("data.csv.ASC"))
+
+	_, err := NewEncryptor(EncryptConfig{Type: AgeEncryptorType})
+	assert.Error(t, err)
+}
@@ -0,0 +1,245 @@
+package iop
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+)
+
+// FixedWidthColumn is the position of a column in a fixed-width record
+type FixedWidthColumn struct {
+	Name   string     `json:"name"`
+	Start  int        `json:"start,omitempty"` // 1-based position of the first character
+	Length int        `json:"length"`
+	Type   ColumnType `json:"type,omitempty"`
+	Format string     `json:"format,omitempty"` // datetime format, such as YYYY-MM-DD
+}
+
+// baseType returns the column type without the length, precision or scale
+func (fc FixedWidthColumn) baseType() ColumnType {
+	return ColumnType(strings.TrimSpace(strings.Split(string(fc.Type), "(")[0]))
+}
+
+// FixedWidthColumns are the columns of a fixed-width record
+type FixedWidthColumns []FixedWidthColumn
+
+// ParseFixedWidthColumns parses the JSON column spec of a fixed-width file.
+// A column without a start position follows the previous column.
+func ParseFixedWidthColumns(payload string) (cols FixedWidthColumns, err error) {
+	if strings.TrimSpace(payload) == "" {
+		return cols, nil
+	}
+
+	err = g.Unmarshal(payload, &cols)
+	if err != nil {
+		return cols, g.Error(err, "could not parse fixed-width columns")
+	}
+
+	end := 0
+	for i, col := range cols {
+		if col.Name == "" {
+			return cols, g.Error("fixed-width column #%d has no name", i+1)
+		} else if col.Length <= 0 {
+			return cols, g.Error("fixed-width column '%s' needs a positive length", col.Name)
+		} else if col.Start < 0 {
+			return cols, g.Error("fixed-width column '%s' has an invalid start (%d)", col.Name, col.Start)
+		}
+
+		if col.Start == 0 {
+			cols[i].Start = end + 1
+		}
+		end = cols[i].Start + col.Length - 1
+	}
+
+	return cols, nil
+}
+
+// Columns returns the stream columns, with the provided types
+func (fwc FixedWidthColumns) Columns() (columns Columns) {
+	for i, fc := range fwc {
+		col := Column{Name: fc.Name, Type: fc.Type, Position: i + 1}
+		col.SetLengthPrecisionScale()
+		columns = append(columns, col)
+	}
+	return
+}
+
+// TypedColumns returns the columns which have a type specified
+func (fwc FixedWidthColumns) TypedColumns() (columns Columns) {
+	for _, col := range fwc.Columns() {
+		if col.Type != "" {
+			columns = append(columns, col)
+		}
+	}
+	return
+}
+
+// RecordLength returns the length of a record
+func (fwc FixedWidthColumns) RecordLength() (length int) {
+	for _, col := range fwc {
+		length = max(length, col.Start+col.Length-1)
+	}
+	return
+}
+
+// Values extracts the column values of a record.
+// Values are trimmed of the padding spaces.
+func (fwc FixedWidthColumns) Values(record string) (values []string) {
+	var runes []rune
+	if !isASCII(record) {
+		runes = []rune(record) // positions are in characters
+	}
+
+	values = make([]string, len(fwc))
+	for i, col := range fwc {
+		start, end := col.Start-1, col.Start-1+col.Length
+		if runes != nil {
+			if start < len(runes) {
+				values[i] = string(runes[start:min(end, len(runes))])
+			}
+		} else if start < len(record) {
+			values[i] = record[start:min(end, len(record))]
+		}
+
+		if typ := col.baseType(); typ.IsString() || typ == "" {
+			values[i] = strings.TrimRight(values[i], " ")
+		} else {
+			values[i] = strings.TrimSpace(values[i])
+		}
+	}
+	return
+}
+
+// Record makes a fixed-width record from the column values,
+// padding or truncating each value to its length.
+// Numbers are right-aligned, others are left-aligned.
+func (fwc FixedWidthColumns) Record(values []string) string {
+	record := []rune(strings.Repeat(" ", fwc.RecordLength()))
+	for i, col := range fwc {
+		if i >= len(values) {
+			break
+		}
+
+		val := []rune(values[i])
+		if len(val) > col.Length {
+			val = val[:col.Length]
+		}
+
+		offset := col.Start - 1
+		if col.baseType().IsNumber() {
+			offset = offset + col.Length - len(val)
+		}
+		copy(record[offset:], val)
+	}
+	return string(record)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// ConsumeFixedWidthReader uses the provided reader to stream rows
+// of a fixed-width text file
+func (ds *Datastream) ConsumeFixedWidthReader(reader io.Reader) (err error) {
+	spec := ds.config.FixedWidthColumns
+	if len(spec) == 0 {
+		return g.Error("fixed-width format requires the column positions (name, start, length)")
+	}
+
+	// decompress if needed
+	readerDecompr, err := AutoDecompress(reader)
+	if err != nil {
+		err = g.Error(err, "could not AutoDecompress")
+		ds.Context.CaptureErr(err)
+		return err
+	}
+
+	// decode File if requested by transform
+	reader = readerDecompr
+	if newReader, ok := ds.transformReader(readerDecompr); ok {
+		reader = newReader
+	}
+
+	layouts := make([]string, len(spec))
+	for i, col := range spec {
+		if col.Format != "" {
+			layouts[i] = Iso8601ToGoLayout(col.Format)
+		}
+	}
+
+	bufReader := bufio.NewReader(reader)
+	readLine := func() (line string, err error) {
+		line, err = bufReader.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil // last line without a line break
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	// skip the header records
+	for i := 0; i < ds.config.SkipHeaderLines; i++ {
+		if _, err = readLine(); err == io.EOF {
+			break
+		} else if err != nil {
+			return g.Error(err, "could not read header record")
+		}
+	}
+
+	// hold the last lines, to skip the trailer records
+	pending := []string{}
+	nextFunc := func(it *Iterator) bool {
+		for len(pending) <= ds.config.SkipTrailerLines {
+			line, err := readLine()
+			if err == io.EOF {
+				return false
+			} else if err != nil {
+				it.Context.CaptureErr(g.Error(err, "Error reading file"))
+				return false
+			}
+			pending = append(pending, line)
+		}
+
+		line := pending[0]
+		pending = pending[1:]
+
+		it.Row = make([]any, len(spec))
+		for i, val := range spec.Values(line) {
+			switch {
+			case val == "" && (ds.config.EmptyAsNull || !it.ds.Columns[i].IsString()):
+				it.Row[i] = nil
+			case layouts[i] != "":
+				if t, err := time.Parse(layouts[i], val); err == nil {
+					it.Row[i] = t
+				} else {
+					it.Row[i] = val
+				}
+			default:
+				it.Row[i] = val
+			}
+		}
+
+		return true
+	}
+
+	// types are applied with the columns config
+	ds.SetFields(lo.Map(spec, func(col FixedWidthColumn, i int) string { return col.Name }))
+	ds.it = ds.NewIterator(ds.Columns, nextFunc)
+	ds.SetFileURI()
+
+	err = ds.Start()
+	if err != nil {
+		return g.Error(err, "could start datastream")
+	}
+
+	return
+}
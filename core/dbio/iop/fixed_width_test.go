@@ -0,0 +1,87 @@
+package iop
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedWidthColumns(t *testing.T) {
+	cols, err := ParseFixedWidthColumns(`[
+		{"name": "id", "start": 1, "length": 5, "type": "integer"},
+		{"name": "name", "length": 10},
+		{"name": "amount", "start": 17, "length": 8, "type": "decimal(10,2)"},
+		{"name": "birth", "length": 8, "type": "date", "format": "YYYYMMDD"}
+	]`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []int{1, 6, 17, 25}, []int{cols[0].Start, cols[1].Start, cols[2].Start, cols[3].Start})
+	assert.Equal(t, 32, cols.RecordLength())
+
+	typed := cols.TypedColumns()
+	if assert.Len(t, typed, 3) {
+		assert.Equal(t, DecimalType, typed[1].Type)
+		assert.Equal(t, 10, typed[1].DbPrecision)
+		assert.Equal(t, 2, typed[1].DbScale)
+	}
+
+	record := cols.Record([]string{"42", "a very long name", "-12.5", "20010203"})
+	assert.Equal(t, "   42a very lon    -12.520010203", record)
+	assert.Equal(t, []string{"42", "a very lon", "-12.5", "20010203"}, cols.Values(record))
+
+	// short record, multi-byte characters
+	assert.Equal(t, []string{"7", "Zoë", "", ""}, cols.Values("    7Zoë   "))
+
+	_, err = ParseFixedWidthColumns(`[{"name": "id"}]`)
+	assert.Error(t, err)
+}
+
+func TestFixedWidthReader(t *testing.T) {
+	content := strings.Join([]string{
+		"HDR20240101",
+		"    1john      123.45 20010203",
+		"    2mary          -3 19601231",
+		"    3                         ",
+		"TRL00000003",
+	}, "\r\n")
+
+	ds := NewDatastream(nil)
+	ds.SetConfig(map[string]string{
+		"fixed_width_columns": `[
+			{"name": "id", "length": 5, "type": "bigint"},
+			{"name": "name", "length": 10},
+			{"name": "amount", "length": 7, "type": "decimal"},
+			{"name": "birth", "start": 23, "length": 8, "format": "YYYYMMDD"}
+		]`,
+		"skip_header_lines":  "1",
+		"skip_trailer_lines": "1",
+	})
+
+	err := ds.ConsumeFixedWidthReader(strings.NewReader(content))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data, err := ds.Collect(0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{"id", "name", "amount", "birth"}, data.Columns.Names())
+	assert.Equal(t, BigIntType, data.Columns[0].Type)
+	assert.Equal(t, DecimalType, data.Columns[2].Type)
+	if assert.Len(t, data.Rows, 3) {
+		assert.EqualValues(t, 1, data.Rows[0][0])
+		assert.Equal(t, "john", data.Rows[0][1])
+		assert.Equal(t, "123.45", cast.ToString(data.Rows[0][2]))
+		assert.Equal(t, time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC), cast.ToTime(data.Rows[0][3]).UTC())
+		assert.Equal(t, "-3", cast.ToString(data.Rows[1][2]))
+		assert.Nil(t, data.Rows[2][1])
+		assert.Nil(t, data.Rows[2][3])
+	}
+}
@@ -0,0 +1,1052 @@
+package iop
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+)
+
+const (
+	orcMagic              = "ORC"
+	orcTailReadSize       = 16 * 1024
+	orcDefaultBlockSize   = 256 * 1024
+	orcDefaultStripeSize  = 64 * 1024 * 1024
+	orcTimestampBaseEpoch = 1420070400 // 2015-01-01 00:00:00 UTC
+	orcDecimalDefPrec     = 38
+	orcDecimalDefSc       = 9
+	orcWriterVersion      = 6
+)
+
+// orcTypeKind is the kind of a type in an ORC schema
+type orcTypeKind int
+
+const (
+	orcKindBoolean orcTypeKind = iota
+	orcKindByte
+	orcKindShort
+	orcKindInt
+	orcKindLong
+	orcKindFloat
+	orcKindDouble
+	orcKindString
+	orcKindBinary
+	orcKindTimestamp
+	orcKindList
+	orcKindMap
+	orcKindStruct
+	orcKindUnion
+	orcKindDecimal
+	orcKindDate
+	orcKindVarchar
+	orcKindChar
+	orcKindTimestampInstant
+)
+
+// orcStreamKind is the kind of a stream in an ORC stripe
+type orcStreamKind int
+
+const (
+	orcStreamPresent orcStreamKind = iota
+	orcStreamData
+	orcStreamLength
+	orcStreamDictionaryData
+	orcStreamDictionaryCount
+	orcStreamSecondary
+	orcStreamRowIndex
+	orcStreamBloomFilter
+	orcStreamBloomFilterUtf8
+)
+
+// orcEncodingKind is the encoding of a column in an ORC stripe
+type orcEncodingKind int
+
+const (
+	orcEncodingDirect orcEncodingKind = iota
+	orcEncodingDictionary
+	orcEncodingDirectV2
+	orcEncodingDictionaryV2
+)
+
+// orcType is a type of the ORC schema. The schema is a flattened
+// tree of types, the root (id 0) being the struct of the columns
+type orcType struct {
+	kind       orcTypeKind
+	subtypes   []int
+	fieldNames []string
+	maxLength  int
+	precision  int
+	scale      int
+}
+
+type orcStripeInfo struct {
+	offset       uint64
+	indexLength  uint64
+	dataLength   uint64
+	footerLength uint64
+	numberOfRows uint64
+}
+
+type orcStream struct {
+	kind   orcStreamKind
+	column int
+	length uint64
+}
+
+type orcStreamKey struct {
+	column int
+	kind   orcStreamKind
+}
+
+type orcColumnEncoding struct {
+	kind           orcEncodingKind
+	dictionarySize int
+}
+
+// Orc is an ORC file reader. It reads the primitive types (boolean, integers,
+// floats, strings, binary, decimal, date and timestamps), with the none, zlib,
+// snappy and zstd compressions. Compound types (list, map, struct and union)
+// and the lzo and lz4 compressions are not supported.
+type Orc struct {
+	Path    string
+	reader  io.ReadSeeker
+	codec   *orcCodec
+	types   []orcType
+	stripes []orcStripeInfo
+	numRows uint64
+
+	// decoded values of the current stripe, per column
+	stripe  int
+	values  [][]any
+	row     int
+	rowsCnt int
+}
+
+// NewOrcStream reads the tail (postscript and footer) of an ORC file
+func NewOrcStream(reader io.ReadSeeker, columns Columns) (o *Orc, err error) {
+	o = &Orc{reader: reader}
+
+	size, err := reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, g.Error(err, "could not seek to end of orc file")
+	} else if size <= int64(len(orcMagic)) {
+		return nil, g.Error("invalid orc file, size is %d bytes", size)
+	}
+
+	tail, err := o.readAt(max(0, size-orcTailReadSize), int(min(size, orcTailReadSize)))
+	if err != nil {
+		return nil, g.Error(err, "could not read orc file tail")
+	}
+
+	// postscript, its length is the last byte
+	psLength := int(tail[len(tail)-1])
+	if psLength+1 > len(tail) {
+		return nil, g.Error("invalid orc postscript length: %d", psLength)
+	}
+	ps := tail[len(tail)-1-psLength : len(tail)-1]
+
+	var footerLength uint64
+	var compression OrcCompression
+	var magic string
+	blockSize := uint64(orcDefaultBlockSize)
+	err = orcProtoFields(ps, func(f orcProtoField) error {
+		switch f.num {
+		case 1:
+			footerLength = f.value
+		case 2:
+			compression = OrcCompression(f.value)
+		case 3:
+			blockSize = f.value
+		case 8000:
+			magic = string(f.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, g.Error(err, "could not decode orc postscript")
+	} else if magic != orcMagic {
+		return nil, g.Error("invalid orc file, magic bytes not found")
+	}
+
+	o.codec, err = newOrcCodec(compression, int(blockSize))
+	if err != nil {
+		return nil, g.Error(err, "could not read orc file")
+	}
+
+	footerOffset := size - 1 - int64(psLength) - int64(footerLength)
+	if footerOffset < 0 {
+		return nil, g.Error("invalid orc footer length: %d", footerLength)
+	}
+
+	footer, err := o.readAt(footerOffset, int(footerLength))
+	if err == nil {
+		footer, err = o.codec.Decode(footer)
+	}
+	if err != nil {
+		return nil, g.Error(err, "could not read orc footer")
+	}
+
+	if err = o.decodeFooter(footer); err != nil {
+		return nil, g.Error(err, "could not decode orc footer")
+	} else if len(o.types) == 0 || o.types[0].kind != orcKindStruct {
+		return nil, g.Error("invalid orc schema, root type is not a struct")
+	}
+
+	root := o.types[0]
+	for i, id := range root.subtypes {
+		if id >= len(o.types) {
+			return nil, g.Error("invalid orc schema, type %d not found", id)
+		} else if kind := o.types[id].kind; kind >= orcKindList && kind <= orcKindUnion {
+			return nil, g.Error("unsupported orc type %s for column %s", orcTypeName(kind), root.fieldNames[i])
+		}
+	}
+
+	return o, nil
+}
+
+func (o *Orc) readAt(offset int64, length int) (data []byte, err error) {
+	if _, err = o.reader.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data = make([]byte, length)
+	_, err = io.ReadFull(o.reader, data)
+	return data, err
+}
+
+func (o *Orc) decodeFooter(footer []byte) error {
+	return orcProtoFields(footer, func(f orcProtoField) (err error) {
+		switch f.num {
+		case 3:
+			var si orcStripeInfo
+			err = orcProtoFields(f.bytes, func(f orcProtoField) error {
+				switch f.num {
+				case 1:
+					si.offset = f.value
+				case 2:
+					si.indexLength = f.value
+				case 3:
+					si.dataLength = f.value
+				case 4:
+					si.footerLength = f.value
+				case 5:
+					si.numberOfRows = f.value
+				}
+				return nil
+			})
+			o.stripes = append(o.stripes, si)
+		case 4:
+			var t orcType
+			err = orcProtoFields(f.bytes, func(f orcProtoField) error {
+				switch f.num {
+				case 1:
+					t.kind = orcTypeKind(f.value)
+				case 2:
+					subtypes, err := f.uints()
+					for _, st := range subtypes {
+						t.subtypes = append(t.subtypes, int(st))
+					}
+					return err
+				case 3:
+					t.fieldNames = append(t.fieldNames, string(f.bytes))
+				case 4:
+					t.maxLength = int(f.value)
+				case 5:
+					t.precision = int(f.value)
+				case 6:
+					t.scale = int(f.value)
+				}
+				return nil
+			})
+			o.types = append(o.types, t)
+		case 6:
+			o.numRows = f.value
+		}
+		return err
+	})
+}
+
+// Columns returns the columns of the root struct fields
+func (o *Orc) Columns() Columns {
+	root := o.types[0]
+	cols := NewColumnsFromFields(root.fieldNames...)
+	for i, id := range root.subtypes {
+		if i >= len(cols) || id >= len(o.types) {
+			break
+		}
+		t := o.types[id]
+		cols[i].Type, cols[i].DbPrecision, cols[i].DbScale = orcColumnType(t)
+		cols[i].DbType = strings.ToLower(orcTypeName(t.kind))
+		cols[i].Sourced = !g.In(cols[i].Type, DecimalType, FloatType) || cols[i].DbPrecision > 0
+	}
+	return cols
+}
+
+// orcColumnType returns the column type of an ORC type
+func orcColumnType(t orcType) (colType ColumnType, precision, scale int) {
+	switch t.kind {
+	case orcKindBoolean:
+		return BoolType, 0, 0
+	case orcKindByte, orcKindShort:
+		return SmallIntType, 0, 0
+	case orcKindInt:
+		return IntegerType, 0, 0
+	case orcKindLong:
+		return BigIntType, 0, 0
+	case orcKindFloat, orcKindDouble:
+		return FloatType, 0, 0
+	case orcKindString:
+		return StringType, 0, 0
+	case orcKindVarchar, orcKindChar:
+		return StringType, t.maxLength, 0
+	case orcKindBinary:
+		return BinaryType, 0, 0
+	case orcKindTimestamp:
+		return TimestampType, 0, 0
+	case orcKindTimestampInstant:
+		return TimestampzType, 0, 0
+	case orcKindDecimal:
+		return DecimalType, t.precision, t.scale
+	case orcKindDate:
+		return DateType, 0, 0
+	}
+	return StringType, 0, 0
+}
+
+func orcTypeName(kind orcTypeKind) string {
+	names := []string{
+		"BOOLEAN", "TINYINT", "SMALLINT", "INT", "BIGINT", "FLOAT", "DOUBLE",
+		"STRING", "BINARY", "TIMESTAMP", "ARRAY", "MAP", "STRUCT", "UNIONTYPE",
+		"DECIMAL", "DATE", "VARCHAR", "CHAR", "TIMESTAMP WITH LOCAL TIME ZONE",
+	}
+	if int(kind) < len(names) {
+		return names[kind]
+	}
+	return g.F("UNKNOWN(%d)", kind)
+}
+
+func (o *Orc) nextFunc(it *Iterator) bool {
+	for o.row >= o.rowsCnt {
+		if o.stripe >= len(o.stripes) {
+			return false
+		}
+
+		values, err := o.readStripe(o.stripes[o.stripe])
+		if err != nil {
+			it.Context.CaptureErr(g.Error(err, "could not read ORC stripe %d", o.stripe))
+			return false
+		}
+
+		o.values, o.row, o.rowsCnt = values, 0, int(o.stripes[o.stripe].numberOfRows)
+		o.stripe++
+	}
+
+	it.Row = make([]interface{}, len(it.ds.Columns))
+	for i := range o.values {
+		if i < len(it.Row) {
+			it.Row[i] = o.values[i][o.row]
+		}
+	}
+	o.row++
+
+	return true
+}
+
+// orcStripe holds the decompressed streams of a stripe
+type orcStripe struct {
+	types     []orcType
+	streams   map[orcStreamKey][]byte
+	encodings []orcColumnEncoding
+	location  *time.Location
+}
+
+// readStripe decodes the values of the root struct fields of a stripe
+func (o *Orc) readStripe(si orcStripeInfo) (values [][]any, err error) {
+	data, err := o.readAt(int64(si.offset), int(si.indexLength+si.dataLength+si.footerLength))
+	if err != nil {
+		return nil, g.Error(err, "could not read stripe")
+	}
+
+	footer, err := o.codec.Decode(data[si.indexLength+si.dataLength:])
+	if err != nil {
+		return nil, g.Error(err, "could not decompress stripe footer")
+	}
+
+	s := &orcStripe{types: o.types, streams: map[orcStreamKey][]byte{}, location: time.UTC}
+	var streams []orcStream
+	err = orcProtoFields(footer, func(f orcProtoField) (err error) {
+		switch f.num {
+		case 1:
+			var stream orcStream
+			err = orcProtoFields(f.bytes, func(f orcProtoField) error {
+				switch f.num {
+				case 1:
+					stream.kind = orcStreamKind(f.value)
+				case 2:
+					stream.column = int(f.value)
+				case 3:
+					stream.length = f.value
+				}
+				return nil
+			})
+			streams = append(streams, stream)
+		case 2:
+			var encoding orcColumnEncoding
+			err = orcProtoFields(f.bytes, func(f orcProtoField) error {
+				switch f.num {
+				case 1:
+					encoding.kind = orcEncodingKind(f.value)
+				case 2:
+					encoding.dictionarySize = int(f.value)
+				}
+				return nil
+			})
+			s.encodings = append(s.encodings, encoding)
+		case 3:
+			if tz := string(f.bytes); tz != "" {
+				if loc, err := time.LoadLocation(tz); err == nil {
+					s.location = loc
+				} else {
+					g.Warn("could not load orc writer timezone %s, using UTC", tz)
+				}
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, g.Error(err, "could not decode stripe footer")
+	}
+
+	var offset uint64
+	for _, stream := range streams {
+		start := offset
+		offset += stream.length
+		if offset > uint64(len(data)) {
+			return nil, g.Error("invalid length for stream of column %d", stream.column)
+		} else if g.In(stream.kind, orcStreamRowIndex, orcStreamBloomFilter, orcStreamBloomFilterUtf8) {
+			continue
+		}
+
+		key := orcStreamKey{stream.column, stream.kind}
+		if s.streams[key], err = o.codec.Decode(data[start:offset]); err != nil {
+			return nil, g.Error(err, "could not decompress stream of column %d", stream.column)
+		}
+	}
+
+	root := o.types[0]
+	values = make([][]any, len(root.subtypes))
+	for i, id := range root.subtypes {
+		colValues, err := s.decode(id, int(si.numberOfRows))
+		if err != nil {
+			return nil, g.Error(err, "could not decode column %s", root.fieldNames[i])
+		}
+		values[i] = colValues
+	}
+
+	return values, nil
+}
+
+func (s *orcStripe) encoding(id int) orcColumnEncoding {
+	if id < len(s.encodings) {
+		return s.encodings[id]
+	}
+	return orcColumnEncoding{}
+}
+
+// decode decodes count values of the column, nil for the null values
+func (s *orcStripe) decode(id, count int) (values []any, err error) {
+	present := make([]bool, count)
+	nonNull := count
+	if data, ok := s.streams[orcStreamKey{id, orcStreamPresent}]; ok {
+		if present, err = decodeOrcBools(data, count); err != nil {
+			return nil, g.Error(err, "could not decode present stream")
+		}
+		nonNull = lo.CountBy(present, func(p bool) bool { return p })
+	} else {
+		for i := range present {
+			present[i] = true
+		}
+	}
+
+	decoded, err := s.decodeValues(id, nonNull)
+	if err != nil {
+		return nil, err
+	}
+
+	values = make([]any, count)
+	j := 0
+	for i := range values {
+		if present[i] {
+			values[i] = decoded[j]
+			j++
+		}
+	}
+	return values, nil
+}
+
+// decodeValues decodes n non-null values of the column
+func (s *orcStripe) decodeValues(id, n int) (values []any, err error) {
+	t := s.types[id]
+	encoding := s.encoding(id)
+	v2 := g.In(encoding.kind, orcEncodingDirectV2, orcEncodingDictionaryV2)
+	stream := func(kind orcStreamKind) []byte { return s.streams[orcStreamKey{id, kind}] }
+
+	values = make([]any, n)
+	if n == 0 {
+		return values, nil
+	}
+
+	switch t.kind {
+	case orcKindBoolean:
+		bools, err := decodeOrcBools(stream(orcStreamData), n)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range bools {
+			values[i] = v
+		}
+
+	case orcKindByte:
+		bytes, err := decodeOrcBytes(stream(orcStreamData), n)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range bytes {
+			values[i] = int64(int8(v))
+		}
+
+	case orcKindShort, orcKindInt, orcKindLong:
+		ints, err := decodeOrcInts(stream(orcStreamData), n, true, v2)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range ints {
+			values[i] = v
+		}
+
+	case orcKindFloat, orcKindDouble:
+		size := lo.Ternary(t.kind == orcKindFloat, 4, 8)
+		data := stream(orcStreamData)
+		if len(data) < n*size {
+			return nil, g.Error("invalid length of float stream")
+		}
+		for i := range values {
+			if size == 4 {
+				values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+			} else {
+				values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+			}
+		}
+
+	case orcKindString, orcKindVarchar, orcKindChar, orcKindBinary:
+		if g.In(encoding.kind, orcEncodingDictionary, orcEncodingDictionaryV2) {
+			lengths, err := decodeOrcInts(stream(orcStreamLength), encoding.dictionarySize, false, v2)
+			if err != nil {
+				return nil, g.Error(err, "could not decode dictionary lengths")
+			}
+			dictionary, err := orcSplitBytes(stream(orcStreamDictionaryData), lengths)
+			if err != nil {
+				return nil, g.Error(err, "could not decode dictionary")
+			}
+			indices, err := decodeOrcInts(stream(orcStreamData), n, false, v2)
+			if err != nil {
+				return nil, g.Error(err, "could not decode dictionary indices")
+			}
+			for i, index := range indices {
+				if index < 0 || int(index) >= len(dictionary) {
+					return nil, g.Error("invalid dictionary index: %d", index)
+				}
+				values[i] = dictionary[index]
+			}
+		} else {
+			lengths, err := decodeOrcInts(stream(orcStreamLength), n, false, v2)
+			if err != nil {
+				return nil, g.Error(err, "could not decode lengths")
+			}
+			strs, err := orcSplitBytes(stream(orcStreamData), lengths)
+			if err != nil {
+				return nil, err
+			}
+			for i, v := range strs {
+				values[i] = v
+			}
+		}
+
+	case orcKindDecimal:
+		r := &orcByteReader{data: stream(orcStreamData)}
+		scales, err := decodeOrcInts(stream(orcStreamSecondary), n, true, v2)
+		if err != nil {
+			return nil, g.Error(err, "could not decode decimal scales")
+		}
+		for i := range values {
+			unscaled, err := r.bigVarint()
+			if err != nil {
+				return nil, g.Error(err, "could not decode decimal")
+			}
+			denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(scales[i]), nil)
+			values[i] = new(big.Rat).SetFrac(unscaled, denom).FloatString(t.scale)
+		}
+
+	case orcKindDate:
+		days, err := decodeOrcInts(stream(orcStreamData), n, true, v2)
+		if err != nil {
+			return nil, err
+		}
+		for i, d := range days {
+			values[i] = time.Unix(d*86400, 0).UTC()
+		}
+
+	case orcKindTimestamp, orcKindTimestampInstant:
+		seconds, err := decodeOrcInts(stream(orcStreamData), n, true, v2)
+		if err != nil {
+			return nil, err
+		}
+		nanos, err := decodeOrcInts(stream(orcStreamSecondary), n, false, v2)
+		if err != nil {
+			return nil, g.Error(err, "could not decode timestamp nanoseconds")
+		}
+
+		// timestamps are relative to 2015-01-01 in the writer timezone,
+		// and are returned as the wall clock time in UTC
+		loc := lo.Ternary(t.kind == orcKindTimestamp, s.location, time.UTC)
+		base := time.Date(2015, 1, 1, 0, 0, 0, 0, loc).Unix()
+		for i := range values {
+			epoch, nano := seconds[i]+base, orcDecodeNanos(nanos[i])
+			if epoch < 0 && nano > 999999 {
+				epoch-- // seconds are truncated towards zero
+			}
+			ts := time.Unix(epoch, nano).In(loc)
+			values[i] = time.Date(ts.Year(), ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(), time.UTC)
+		}
+
+	default:
+		return nil, g.Error("unsupported orc type: %s", orcTypeName(t.kind))
+	}
+
+	return values, nil
+}
+
+// orcSplitBytes splits the data into strings of the lengths
+func orcSplitBytes(data []byte, lengths []int64) (strs []string, err error) {
+	strs = make([]string, len(lengths))
+	pos := int64(0)
+	for i, length := range lengths {
+		if length < 0 || pos+length > int64(len(data)) {
+			return nil, g.Error("invalid string length: %d", length)
+		}
+		strs[i] = string(data[pos : pos+length])
+		pos += length
+	}
+	return strs, nil
+}
+
+// orcDecodeNanos decodes the nanoseconds of a timestamp, which have their
+// trailing zeros removed, the count being stored in the 3 lowest bits
+func orcDecodeNanos(v int64) int64 {
+	zeros := v & 0x07
+	v >>= 3
+	if zeros != 0 {
+		for i := int64(0); i <= zeros; i++ {
+			v *= 10
+		}
+	}
+	return v
+}
+
+func orcEncodeNanos(nanos int64) int64 {
+	if nanos == 0 {
+		return 0
+	} else if nanos%100 != 0 {
+		return nanos << 3
+	}
+
+	nanos /= 100
+	zeros := int64(1)
+	for nanos%10 == 0 && zeros < 7 {
+		nanos /= 10
+		zeros++
+	}
+	return nanos<<3 | zeros
+}
+
+// OrcWriter writes rows into an ORC file, in stripes of the stripe size
+type OrcWriter struct {
+	Writer      io.Writer
+	columns     Columns
+	types       []orcType
+	codec       *orcCodec
+	stripeSize  int64
+	buffers     []*orcColumnBuffer
+	stripes     []orcStripeInfo
+	stripeRows  uint64
+	numRows     uint64
+	valueCounts []uint64
+	hasNulls    []bool
+	written     int64
+}
+
+// orcColumnBuffer holds the values of a column for the current stripe
+type orcColumnBuffer struct {
+	present   []bool
+	hasNull   bool
+	bools     []bool
+	ints      []int64
+	lengths   []int64
+	secondary []int64
+	data      []byte
+}
+
+func (b *orcColumnBuffer) size() int64 {
+	return int64(len(b.data) + len(b.bools)/8 + 8*(len(b.ints)+len(b.lengths)+len(b.secondary)))
+}
+
+// NewOrcWriter creates a new ORC writer, with a schema generated from the columns.
+// A stripe is written once its buffered values reach the stripe size.
+func NewOrcWriter(w io.Writer, columns Columns, compression OrcCompression, stripeSize int64) (ow *OrcWriter, err error) {
+	ow = &OrcWriter{
+		Writer:      w,
+		columns:     columns,
+		stripeSize:  lo.Ternary(stripeSize > 0, stripeSize, int64(orcDefaultStripeSize)),
+		valueCounts: make([]uint64, len(columns)+1),
+		hasNulls:    make([]bool, len(columns)+1),
+	}
+
+	ow.codec, err = newOrcCodec(compression, orcDefaultBlockSize)
+	if err != nil {
+		return nil, g.Error(err, "could not create orc writer")
+	}
+
+	root := orcType{kind: orcKindStruct}
+	ow.types = []orcType{root}
+	for i, col := range columns {
+		ow.types[0].subtypes = append(ow.types[0].subtypes, i+1)
+		ow.types[0].fieldNames = append(ow.types[0].fieldNames, col.Name)
+		ow.types = append(ow.types, orcWriterType(col))
+	}
+	ow.resetBuffers()
+
+	if err = ow.write([]byte(orcMagic)); err != nil {
+		return nil, g.Error(err, "could not write orc header")
+	}
+
+	return ow, nil
+}
+
+// orcWriterType returns the ORC type of a column
+func orcWriterType(col Column) orcType {
+	switch {
+	case col.IsBool():
+		return orcType{kind: orcKindBoolean}
+	case col.Type == SmallIntType:
+		return orcType{kind: orcKindShort}
+	case col.Type == IntegerType:
+		return orcType{kind: orcKindInt}
+	case col.IsInteger():
+		return orcType{kind: orcKindLong}
+	case col.Type == FloatType:
+		return orcType{kind: orcKindDouble}
+	case col.IsDecimal():
+		precision, scale := col.DbPrecision, col.DbScale
+		if !col.Sourced || precision == 0 {
+			precision = lo.Ternary(precision == 0, orcDecimalDefPrec, precision)
+			scale = lo.Ternary(scale == 0, orcDecimalDefSc, scale)
+		}
+		return orcType{kind: orcKindDecimal, precision: min(precision, orcDecimalDefPrec), scale: scale}
+	case col.Type == DateType:
+		return orcType{kind: orcKindDate}
+	case col.IsDatetime():
+		return orcType{kind: orcKindTimestamp}
+	case col.Type == BinaryType:
+		return orcType{kind: orcKindBinary}
+	}
+	return orcType{kind: orcKindString}
+}
+
+func (ow *OrcWriter) resetBuffers() {
+	ow.buffers = make([]*orcColumnBuffer, len(ow.columns))
+	for i := range ow.buffers {
+		ow.buffers[i] = &orcColumnBuffer{}
+	}
+	ow.stripeRows = 0
+}
+
+// WriteRow adds a row to the current stripe, writing the stripe when full
+func (ow *OrcWriter) WriteRow(row []any) (err error) {
+	var stripeSize int64
+	for i, col := range ow.columns {
+		buffer := ow.buffers[i]
+		if i >= len(row) || row[i] == nil {
+			buffer.present = append(buffer.present, false)
+			buffer.hasNull = true
+			continue
+		}
+
+		if err = ow.appendValue(buffer, ow.types[i+1], row[i]); err != nil {
+			return g.Error(err, "could not convert value for column %s: %#v", col.Name, row[i])
+		}
+		buffer.present = append(buffer.present, true)
+		stripeSize += buffer.size()
+	}
+
+	ow.stripeRows++
+	ow.numRows++
+
+	if stripeSize >= ow.stripeSize {
+		return ow.writeStripe()
+	}
+
+	return nil
+}
+
+// appendValue converts a stream value into the column buffer values
+func (ow *OrcWriter) appendValue(buffer *orcColumnBuffer, t orcType, val any) error {
+	switch t.kind {
+	case orcKindBoolean:
+		v, err := cast.ToBoolE(val)
+		if err != nil {
+			return err
+		}
+		buffer.bools = append(buffer.bools, v)
+	case orcKindShort, orcKindInt, orcKindLong:
+		v, err := cast.ToInt64E(val)
+		if err != nil {
+			return err
+		}
+		buffer.ints = append(buffer.ints, v)
+	case orcKindDouble:
+		v, err := cast.ToFloat64E(val)
+		if err != nil {
+			return err
+		}
+		buffer.data = binary.LittleEndian.AppendUint64(buffer.data, math.Float64bits(v))
+	case orcKindDecimal:
+		rat, ok := new(big.Rat).SetString(strings.TrimSpace(cast.ToString(val)))
+		if !ok {
+			return g.Error("invalid decimal value")
+		}
+		unscaled, ok := new(big.Int).SetString(strings.Replace(rat.FloatString(t.scale), ".", "", 1), 10)
+		if !ok {
+			return g.Error("invalid decimal value")
+		}
+		buffer.data = appendBigVarint(buffer.data, unscaled)
+		buffer.secondary = append(buffer.secondary, int64(t.scale))
+	case orcKindDate:
+		v, err := cast.ToTimeE(val)
+		if err != nil {
+			return err
+		}
+		date := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+		buffer.ints = append(buffer.ints, date.Unix()/86400)
+	case orcKindTimestamp:
+		v, err := cast.ToTimeE(val)
+		if err != nil {
+			return err
+		}
+		v = v.UTC()
+		seconds, nanos := v.Unix(), int64(v.Nanosecond())
+		if seconds < 0 && nanos > 999999 {
+			seconds++ // seconds are truncated towards zero
+		}
+		buffer.ints = append(buffer.ints, seconds-orcTimestampBaseEpoch)
+		buffer.secondary = append(buffer.secondary, orcEncodeNanos(nanos))
+	case orcKindBinary:
+		b, ok := val.([]byte)
+		if !ok {
+			b = []byte(cast.ToString(val))
+		}
+		buffer.data = append(buffer.data, b...)
+		buffer.lengths = append(buffer.lengths, int64(len(b)))
+	default:
+		var s string
+		switch v := val.(type) {
+		case string:
+			s = v
+		case time.Time:
+			s = v.Format(time.RFC3339Nano)
+		case map[string]any, []any:
+			s = g.Marshal(v)
+		default:
+			var err error
+			if s, err = cast.ToStringE(val); err != nil {
+				return err
+			}
+		}
+		buffer.data = append(buffer.data, s...)
+		buffer.lengths = append(buffer.lengths, int64(len(s)))
+	}
+	return nil
+}
+
+// writeStripe encodes, compresses and writes the current stripe
+func (ow *OrcWriter) writeStripe() (err error) {
+	if ow.stripeRows == 0 {
+		return nil
+	}
+
+	var streams []orcStream
+	var data []byte
+	encodings := []orcColumnEncoding{{kind: orcEncodingDirect}} // root struct
+
+	addStream := func(column int, kind orcStreamKind, raw []byte) error {
+		compressed, err := ow.codec.Encode(raw)
+		if err != nil {
+			return g.Error(err, "could not compress stream of column %d", column)
+		}
+		streams = append(streams, orcStream{kind: kind, column: column, length: uint64(len(compressed))})
+		data = append(data, compressed...)
+		return nil
+	}
+
+	ow.valueCounts[0] += ow.stripeRows
+	for i, buffer := range ow.buffers {
+		id := i + 1
+		t := ow.types[id]
+
+		if buffer.hasNull {
+			if err = addStream(id, orcStreamPresent, encodeOrcBools(buffer.present)); err != nil {
+				return err
+			}
+			ow.hasNulls[id] = true
+		}
+		ow.valueCounts[id] += uint64(lo.CountBy(buffer.present, func(p bool) bool { return p }))
+
+		encoding := orcColumnEncoding{kind: orcEncodingDirectV2}
+		switch t.kind {
+		case orcKindBoolean:
+			encoding.kind = orcEncodingDirect
+			err = addStream(id, orcStreamData, encodeOrcBools(buffer.bools))
+		case orcKindShort, orcKindInt, orcKindLong, orcKindDate:
+			err = addStream(id, orcStreamData, encodeOrcInts(buffer.ints, true))
+		case orcKindDouble:
+			encoding.kind = orcEncodingDirect
+			err = addStream(id, orcStreamData, buffer.data)
+		case orcKindDecimal:
+			if err = addStream(id, orcStreamData, buffer.data); err == nil {
+				err = addStream(id, orcStreamSecondary, encodeOrcInts(buffer.secondary, true))
+			}
+		case orcKindTimestamp:
+			if err = addStream(id, orcStreamData, encodeOrcInts(buffer.ints, true)); err == nil {
+				err = addStream(id, orcStreamSecondary, encodeOrcInts(buffer.secondary, false))
+			}
+		default:
+			if err = addStream(id, orcStreamData, buffer.data); err == nil {
+				err = addStream(id, orcStreamLength, encodeOrcInts(buffer.lengths, false))
+			}
+		}
+		if err != nil {
+			return err
+		}
+		encodings = append(encodings, encoding)
+	}
+
+	// stripe footer
+	footer := orcProtoMessage{}
+	for _, stream := range streams {
+		msg := orcProtoMessage{}.uint(1, uint64(stream.kind)).uint(2, uint64(stream.column)).uint(3, stream.length)
+		footer = footer.bytes(1, msg)
+	}
+	for _, encoding := range encodings {
+		footer = footer.bytes(2, orcProtoMessage{}.uint(1, uint64(encoding.kind)))
+	}
+	footer = footer.string(3, "UTC")
+
+	compressedFooter, err := ow.codec.Encode(footer)
+	if err != nil {
+		return g.Error(err, "could not compress stripe footer")
+	}
+
+	ow.stripes = append(ow.stripes, orcStripeInfo{
+		offset:       uint64(ow.written),
+		dataLength:   uint64(len(data)),
+		footerLength: uint64(len(compressedFooter)),
+		numberOfRows: ow.stripeRows,
+	})
+
+	if err = ow.write(append(data, compressedFooter...)); err != nil {
+		return g.Error(err, "could not write orc stripe")
+	}
+
+	ow.resetBuffers()
+	return nil
+}
+
+func (ow *OrcWriter) write(data []byte) error {
+	n, err := ow.Writer.Write(data)
+	ow.written += int64(n)
+	return err
+}
+
+// BytesWritten returns the number of bytes written, including the pending stripe
+func (ow *OrcWriter) BytesWritten() int64 {
+	pending := int64(0)
+	for _, buffer := range ow.buffers {
+		pending += buffer.size()
+	}
+	return ow.written + pending
+}
+
+// Close writes the pending stripe, the file footer and postscript
+func (ow *OrcWriter) Close() (err error) {
+	defer ow.codec.Close()
+
+	if err = ow.writeStripe(); err != nil {
+		return err
+	}
+
+	footer := orcProtoMessage{}.uint(1, uint64(len(orcMagic))).uint(2, uint64(ow.written))
+	for _, si := range ow.stripes {
+		msg := orcProtoMessage{}.
+			uint(1, si.offset).
+			uint(2, si.indexLength).
+			uint(3, si.dataLength).
+			uint(4, si.footerLength).
+			uint(5, si.numberOfRows)
+		footer = footer.bytes(3, msg)
+	}
+	for _, t := range ow.types {
+		msg := orcProtoMessage{}.uint(1, uint64(t.kind))
+		if len(t.subtypes) > 0 {
+			msg = msg.packed(2, lo.Map(t.subtypes, func(id int, i int) uint64 { return uint64(id) }))
+		}
+		for _, name := range t.fieldNames {
+			msg = msg.string(3, name)
+		}
+		if t.kind == orcKindDecimal {
+			msg = msg.uint(5, uint64(t.precision)).uint(6, uint64(t.scale))
+		}
+		footer = footer.bytes(4, msg)
+	}
+	footer = footer.uint(6, ow.numRows)
+	for id := range ow.types {
+		stats := orcProtoMessage{}.uint(1, ow.valueCounts[id]).uint(10, uint64(lo.Ternary(ow.hasNulls[id], 1, 0)))
+		footer = footer.bytes(7, stats)
+	}
+	footer = footer.uint(8, 0) // no row index
+
+	compressedFooter, err := ow.codec.Encode(footer)
+	if err != nil {
+		return g.Error(err, "could not compress orc footer")
+	}
+
+	ps := orcProtoMessage{}.
+		uint(1, uint64(len(compressedFooter))).
+		uint(2, uint64(ow.codec.kind)).
+		uint(3, uint64(ow.codec.blockSize)).
+		packed(4, []uint64{0, 12}).
+		uint(5, 0).
+		uint(6, orcWriterVersion).
+		string(8000, orcMagic)
+
+	tail := append(compressedFooter, ps...)
+	tail = append(tail, byte(len(ps)))
+	if err = ow.write(tail); err != nil {
+		return g.Error(err, "could not write orc footer")
+	}
+
+	return nil
+}
@@ -0,0 +1,807 @@
+package iop
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"math/big"
+
+	"github.com/flarco/g"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// orcProtoField is a decoded protobuf field of an ORC metadata message
+type orcProtoField struct {
+	num   protowire.Number
+	typ   protowire.Type
+	value uint64 // varint value
+	bytes []byte // length-delimited value
+}
+
+// orcProtoFields decodes the fields of a protobuf message,
+// calling fn for each varint or length-delimited field
+func orcProtoFields(b []byte, fn func(f orcProtoField) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return g.Error(protowire.ParseError(n), "invalid orc metadata tag")
+		}
+		b = b[n:]
+
+		f := orcProtoField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.value, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return g.Error(protowire.ParseError(n), "invalid orc metadata field %d", num)
+		}
+		b = b[n:]
+
+		if typ != protowire.VarintType && typ != protowire.BytesType {
+			continue
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uints returns the values of a repeated integer field, packed or not
+func (f orcProtoField) uints() (vals []uint64, err error) {
+	if f.typ == protowire.VarintType {
+		return []uint64{f.value}, nil
+	}
+	b := f.bytes
+	for len(b) > 0 {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return nil, g.Error(protowire.ParseError(n), "invalid orc packed field %d", f.num)
+		}
+		vals = append(vals, v)
+		b = b[n:]
+	}
+	return vals, nil
+}
+
+// orcProtoMessage builds a protobuf message of an ORC metadata structure
+type orcProtoMessage []byte
+
+func (m orcProtoMessage) uint(num protowire.Number, v uint64) orcProtoMessage {
+	m = protowire.AppendTag(m, num, protowire.VarintType)
+	return protowire.AppendVarint(m, v)
+}
+
+func (m orcProtoMessage) bytes(num protowire.Number, b []byte) orcProtoMessage {
+	m = protowire.AppendTag(m, num, protowire.BytesType)
+	return protowire.AppendBytes(m, b)
+}
+
+func (m orcProtoMessage) string(num protowire.Number, s string) orcProtoMessage {
+	m = protowire.AppendTag(m, num, protowire.BytesType)
+	return protowire.AppendString(m, s)
+}
+
+func (m orcProtoMessage) packed(num protowire.Number, vals []uint64) orcProtoMessage {
+	var b []byte
+	for _, v := range vals {
+		b = protowire.AppendVarint(b, v)
+	}
+	return m.bytes(num, b)
+}
+
+// OrcCompression is the compression kind of an ORC file.
+// The lzo and lz4 compressions are not supported.
+type OrcCompression int
+
+const (
+	OrcCompressionNone   OrcCompression = 0
+	OrcCompressionZlib   OrcCompression = 1
+	OrcCompressionSnappy OrcCompression = 2
+	OrcCompressionLzo    OrcCompression = 3
+	OrcCompressionLz4    OrcCompression = 4
+	OrcCompressionZstd   OrcCompression = 5
+)
+
+func (c OrcCompression) String() string {
+	switch c {
+	case OrcCompressionNone:
+		return "none"
+	case OrcCompressionZlib:
+		return "zlib"
+	case OrcCompressionSnappy:
+		return "snappy"
+	case OrcCompressionLzo:
+		return "lzo"
+	case OrcCompressionLz4:
+		return "lz4"
+	case OrcCompressionZstd:
+		return "zstd"
+	}
+	return g.F("unknown(%d)", int(c))
+}
+
+// orcCodec compresses and decompresses ORC streams. Compressed streams
+// are a sequence of chunks, each with a 3-byte header holding the
+// chunk length and whether the chunk is stored uncompressed (original)
+type orcCodec struct {
+	kind      OrcCompression
+	blockSize int
+	zstdEnc   *zstd.Encoder
+	zstdDec   *zstd.Decoder
+}
+
+func newOrcCodec(kind OrcCompression, blockSize int) (c *orcCodec, err error) {
+	c = &orcCodec{kind: kind, blockSize: blockSize}
+	switch kind {
+	case OrcCompressionNone, OrcCompressionZlib, OrcCompressionSnappy:
+	case OrcCompressionZstd:
+		if c.zstdEnc, err = zstd.NewWriter(nil); err != nil {
+			return nil, g.Error(err, "could not create zstd encoder")
+		}
+		if c.zstdDec, err = zstd.NewReader(nil); err != nil {
+			return nil, g.Error(err, "could not create zstd decoder")
+		}
+	default:
+		return nil, g.Error("unsupported orc compression: %s", kind)
+	}
+	return c, nil
+}
+
+func (c *orcCodec) Close() {
+	if c.zstdEnc != nil {
+		c.zstdEnc.Close()
+	}
+	if c.zstdDec != nil {
+		c.zstdDec.Close()
+	}
+}
+
+// Encode compresses the data into chunks of the block size
+func (c *orcCodec) Encode(data []byte) (out []byte, err error) {
+	if c.kind == OrcCompressionNone {
+		return data, nil
+	}
+
+	for len(data) > 0 {
+		chunk := data[:min(len(data), c.blockSize)]
+		data = data[len(chunk):]
+
+		var compressed []byte
+		switch c.kind {
+		case OrcCompressionZlib:
+			var buf bytes.Buffer
+			fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+			if err != nil {
+				return nil, err
+			}
+			if _, err = fw.Write(chunk); err != nil {
+				return nil, err
+			}
+			if err = fw.Close(); err != nil {
+				return nil, err
+			}
+			compressed = buf.Bytes()
+		case OrcCompressionSnappy:
+			compressed = s2.EncodeSnappy(nil, chunk)
+		case OrcCompressionZstd:
+			compressed = c.zstdEnc.EncodeAll(chunk, nil)
+		}
+
+		// keep the original chunk when compression does not help
+		header := len(compressed) << 1
+		if len(compressed) >= len(chunk) {
+			compressed = chunk
+			header = len(chunk)<<1 | 1
+		}
+		out = append(out, byte(header), byte(header>>8), byte(header>>16))
+		out = append(out, compressed...)
+	}
+
+	return out, nil
+}
+
+// Decode decompresses the chunks of a stream
+func (c *orcCodec) Decode(data []byte) (out []byte, err error) {
+	if c.kind == OrcCompressionNone {
+		return data, nil
+	}
+
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return nil, g.Error("invalid orc chunk header")
+		}
+		header := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+		length := header >> 1
+		data = data[3:]
+		if length > len(data) {
+			return nil, g.Error("invalid orc chunk length: %d", length)
+		}
+		chunk := data[:length]
+		data = data[length:]
+
+		if header&1 == 1 {
+			out = append(out, chunk...)
+			continue
+		}
+
+		switch c.kind {
+		case OrcCompressionZlib:
+			decompressed, err := io.ReadAll(flate.NewReader(bytes.NewReader(chunk)))
+			if err != nil {
+				return nil, g.Error(err, "could not decompress zlib chunk")
+			}
+			out = append(out, decompressed...)
+		case OrcCompressionSnappy:
+			decompressed, err := s2.Decode(nil, chunk)
+			if err != nil {
+				return nil, g.Error(err, "could not decompress snappy chunk")
+			}
+			out = append(out, decompressed...)
+		case OrcCompressionZstd:
+			if out, err = c.zstdDec.DecodeAll(chunk, out); err != nil {
+				return nil, g.Error(err, "could not decompress zstd chunk")
+			}
+		default:
+			return nil, g.Error("unsupported orc compression: %s", c.kind)
+		}
+	}
+
+	return out, nil
+}
+
+// orcByteReader reads the bytes of a decompressed stream
+type orcByteReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *orcByteReader) ReadByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *orcByteReader) next(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *orcByteReader) uvarint() (uint64, error) {
+	return binary.ReadUvarint(r)
+}
+
+func (r *orcByteReader) varint() (int64, error) {
+	v, err := binary.ReadUvarint(r)
+	return zigzagDecode(v), err
+}
+
+// bigVarint reads an unbounded zigzag varint, used for decimals
+func (r *orcByteReader) bigVarint() (*big.Int, error) {
+	val, shift := new(big.Int), uint(0)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		val.Or(val, new(big.Int).Lsh(big.NewInt(int64(b&0x7f)), shift))
+		shift += 7
+		if b < 0x80 {
+			break
+		}
+	}
+
+	// zigzag decoding
+	negative := val.Bit(0) == 1
+	val.Rsh(val, 1)
+	if negative {
+		val.Not(val)
+	}
+	return val, nil
+}
+
+func appendBigVarint(buf []byte, val *big.Int) []byte {
+	// zigzag encoding
+	v := new(big.Int).Lsh(val, 1)
+	if val.Sign() < 0 {
+		v.Not(v)
+	}
+
+	mask := big.NewInt(0x7f)
+	for {
+		b := byte(new(big.Int).And(v, mask).Uint64())
+		v.Rsh(v, 7)
+		if v.Sign() == 0 {
+			return append(buf, b)
+		}
+		buf = append(buf, b|0x80)
+	}
+}
+
+func zigzagDecode(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func zigzagEncode(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// decodeOrcBytes decodes a byte run length encoded stream
+func decodeOrcBytes(data []byte, count int) (vals []byte, err error) {
+	r := &orcByteReader{data: data}
+	vals = make([]byte, 0, count)
+	for len(vals) < count {
+		control, err := r.ReadByte()
+		if err != nil {
+			return nil, g.Error(err, "could not read orc byte run")
+		}
+
+		if control < 0x80 {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, g.Error(err, "could not read orc byte run")
+			}
+			for i := 0; i < int(control)+3; i++ {
+				vals = append(vals, b)
+			}
+		} else {
+			literals, err := r.next(0x100 - int(control))
+			if err != nil {
+				return nil, g.Error(err, "could not read orc byte literals")
+			}
+			vals = append(vals, literals...)
+		}
+	}
+	return vals[:count], nil
+}
+
+// encodeOrcBytes byte run length encodes the values
+func encodeOrcBytes(vals []byte) (buf []byte) {
+	for i := 0; i < len(vals); {
+		// runs of at least 3 identical bytes
+		run := 1
+		for i+run < len(vals) && run < 130 && vals[i+run] == vals[i] {
+			run++
+		}
+		if run >= 3 {
+			buf = append(buf, byte(run-3), vals[i])
+			i += run
+			continue
+		}
+
+		// literals, until the next run
+		start := i
+		for i < len(vals) && i-start < 128 {
+			if i+2 < len(vals) && vals[i] == vals[i+1] && vals[i] == vals[i+2] {
+				break
+			}
+			i++
+		}
+		buf = append(buf, byte(0x100-(i-start)))
+		buf = append(buf, vals[start:i]...)
+	}
+	return buf
+}
+
+// decodeOrcBools decodes a boolean stream, a byte run length
+// encoded stream of bits, most significant bit first
+func decodeOrcBools(data []byte, count int) (vals []bool, err error) {
+	packed, err := decodeOrcBytes(data, (count+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	vals = make([]bool, count)
+	for i := range vals {
+		vals[i] = packed[i/8]&(0x80>>(i%8)) != 0
+	}
+	return vals, nil
+}
+
+func encodeOrcBools(vals []bool) []byte {
+	packed := make([]byte, (len(vals)+7)/8)
+	for i, v := range vals {
+		if v {
+			packed[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return encodeOrcBytes(packed)
+}
+
+// decodeOrcInts decodes an integer run length encoded stream,
+// of version 1 (DIRECT / DICTIONARY encodings) or 2
+func decodeOrcInts(data []byte, count int, signed, v2 bool) (vals []int64, err error) {
+	r := &orcByteReader{data: data}
+	vals = make([]int64, 0, count)
+	for len(vals) < count {
+		if v2 {
+			vals, err = decodeOrcIntRunV2(r, vals, signed)
+		} else {
+			vals, err = decodeOrcIntRunV1(r, vals, signed)
+		}
+		if err != nil {
+			return nil, g.Error(err, "could not decode orc integers")
+		}
+	}
+	return vals[:count], nil
+}
+
+func decodeOrcIntRunV1(r *orcByteReader, vals []int64, signed bool) ([]int64, error) {
+	readValue := func() (int64, error) {
+		if signed {
+			return r.varint()
+		}
+		v, err := r.uvarint()
+		return int64(v), err
+	}
+
+	control, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	if control < 0x80 {
+		delta, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		base, err := readValue()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(control)+3; i++ {
+			vals = append(vals, base+int64(i)*int64(int8(delta)))
+		}
+		return vals, nil
+	}
+
+	for i := 0; i < 0x100-int(control); i++ {
+		v, err := readValue()
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+	return vals, nil
+}
+
+// orcDecodeBitWidth returns the bit width of a 5-bit encoded width
+func orcDecodeBitWidth(n int) int {
+	switch {
+	case n <= 23:
+		return n + 1
+	case n == 24:
+		return 26
+	case n == 25:
+		return 28
+	case n == 26:
+		return 30
+	case n == 27:
+		return 32
+	case n == 28:
+		return 40
+	case n == 29:
+		return 48
+	case n == 30:
+		return 56
+	}
+	return 64
+}
+
+// orcEncodeBitWidth returns the 5-bit encoding of a fixed bit width
+func orcEncodeBitWidth(width int) int {
+	switch {
+	case width <= 24:
+		return max(width, 1) - 1
+	case width <= 26:
+		return 24
+	case width <= 28:
+		return 25
+	case width <= 30:
+		return 26
+	case width <= 32:
+		return 27
+	case width <= 40:
+		return 28
+	case width <= 48:
+		return 29
+	case width <= 56:
+		return 30
+	}
+	return 31
+}
+
+// orcClosestFixedBits returns the closest bit width which can be encoded
+func orcClosestFixedBits(width int) int {
+	return orcDecodeBitWidth(orcEncodeBitWidth(width))
+}
+
+// readOrcPacked reads count big-endian bit packed values
+func readOrcPacked(r *orcByteReader, count, width int) ([]uint64, error) {
+	data, err := r.next((count*width + 7) / 8)
+	if err != nil {
+		return nil, err
+	}
+
+	vals := make([]uint64, count)
+	bitPos := 0
+	for i := range vals {
+		var v uint64
+		for bits := width; bits > 0; {
+			b := data[bitPos/8]
+			avail := 8 - bitPos%8
+			take := min(avail, bits)
+			chunk := (b >> (avail - take)) & (1<<take - 1)
+			v = v<<take | uint64(chunk)
+			bits -= take
+			bitPos += take
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func appendOrcPacked(buf []byte, vals []uint64, width int) []byte {
+	var current byte
+	bitsLeft := 8
+	for _, v := range vals {
+		for bits := width; bits > 0; {
+			take := min(bitsLeft, bits)
+			chunk := byte(v>>(bits-take)) & (1<<take - 1)
+			current |= chunk << (bitsLeft - take)
+			bits -= take
+			bitsLeft -= take
+			if bitsLeft == 0 {
+				buf = append(buf, current)
+				current, bitsLeft = 0, 8
+			}
+		}
+	}
+	if bitsLeft < 8 {
+		buf = append(buf, current)
+	}
+	return buf
+}
+
+func readOrcBigEndian(r *orcByteReader, size int) (uint64, error) {
+	data, err := r.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func decodeOrcIntRunV2(r *orcByteReader, vals []int64, signed bool) ([]int64, error) {
+	decode := func(v uint64) int64 {
+		if signed {
+			return zigzagDecode(v)
+		}
+		return int64(v)
+	}
+
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch first >> 6 {
+	case 0: // short repeat
+		size := int(first>>3)&0x07 + 1
+		count := int(first)&0x07 + 3
+		v, err := readOrcBigEndian(r, size)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < count; i++ {
+			vals = append(vals, decode(v))
+		}
+
+	case 1: // direct
+		second, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		width := orcDecodeBitWidth(int(first>>1) & 0x1f)
+		count := (int(first)&0x01)<<8 | int(second) + 1
+		packed, err := readOrcPacked(r, count, width)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range packed {
+			vals = append(vals, decode(v))
+		}
+
+	case 2: // patched base
+		header, err := r.next(3)
+		if err != nil {
+			return nil, err
+		}
+		width := orcDecodeBitWidth(int(first>>1) & 0x1f)
+		count := (int(first)&0x01)<<8 | int(header[0]) + 1
+		baseSize := int(header[1]>>5) + 1
+		patchWidth := orcDecodeBitWidth(int(header[1]) & 0x1f)
+		gapWidth := int(header[2]>>5) + 1
+		patchCount := int(header[2]) & 0x1f
+
+		// base value is sign-magnitude, big-endian
+		ubase, err := readOrcBigEndian(r, baseSize)
+		if err != nil {
+			return nil, err
+		}
+		base := int64(ubase)
+		if mask := uint64(1) << (baseSize*8 - 1); ubase&mask != 0 {
+			base = -int64(ubase &^ mask)
+		}
+
+		packed, err := readOrcPacked(r, count, width)
+		if err != nil {
+			return nil, err
+		}
+		patches, err := readOrcPacked(r, patchCount, orcClosestFixedBits(gapWidth+patchWidth))
+		if err != nil {
+			return nil, err
+		}
+
+		// apply the patches, gaps of more than 255 span several entries
+		patchMask := uint64(1)<<patchWidth - 1
+		pos := 0
+		for _, patch := range patches {
+			pos += int(patch >> patchWidth)
+			if val := patch & patchMask; val != 0 || patch>>patchWidth != 255 {
+				if pos >= len(packed) {
+					return nil, g.Error("invalid orc patch position")
+				}
+				packed[pos] |= val << width
+			}
+		}
+
+		for _, v := range packed {
+			vals = append(vals, base+int64(v))
+		}
+
+	case 3: // delta
+		second, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		width := int(first>>1) & 0x1f
+		if width != 0 {
+			width = orcDecodeBitWidth(width)
+		}
+		count := (int(first)&0x01)<<8 | int(second) + 1
+
+		var base int64
+		if signed {
+			base, err = r.varint()
+		} else {
+			var ubase uint64
+			ubase, err = r.uvarint()
+			base = int64(ubase)
+		}
+		if err != nil {
+			return nil, err
+		}
+		deltaBase, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+
+		vals = append(vals, base)
+		if count == 1 {
+			break
+		}
+
+		if width == 0 {
+			for i := 1; i < count; i++ {
+				vals = append(vals, base+int64(i)*deltaBase)
+			}
+			break
+		}
+
+		prev := base + deltaBase
+		vals = append(vals, prev)
+		deltas, err := readOrcPacked(r, count-2, width)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range deltas {
+			if deltaBase < 0 {
+				prev -= int64(d)
+			} else {
+				prev += int64(d)
+			}
+			vals = append(vals, prev)
+		}
+	}
+
+	return vals, nil
+}
+
+// encodeOrcInts integer run length encodes the values (version 2),
+// with short repeats, fixed delta runs and direct literals
+func encodeOrcInts(vals []int64, signed bool) (buf []byte) {
+	encode := func(v int64) uint64 {
+		if signed {
+			return zigzagEncode(v)
+		}
+		return uint64(v)
+	}
+
+	appendDirect := func(literals []int64) {
+		width := 1
+		packed := make([]uint64, len(literals))
+		for i, v := range literals {
+			packed[i] = encode(v)
+			for width < 64 && packed[i]>>width != 0 {
+				width++
+			}
+		}
+		width = orcClosestFixedBits(width)
+		count := len(literals) - 1
+		buf = append(buf, byte(1<<6|orcEncodeBitWidth(width)<<1|count>>8), byte(count))
+		buf = appendOrcPacked(buf, packed, width)
+	}
+
+	for i := 0; i < len(vals); {
+		// runs of identical values
+		run := 1
+		for i+run < len(vals) && run < 512 && vals[i+run] == vals[i] {
+			run++
+		}
+
+		switch {
+		case run >= 3 && run <= 10:
+			v := encode(vals[i])
+			size := 1
+			for size < 8 && v>>(size*8) != 0 {
+				size++
+			}
+			buf = append(buf, byte((size-1)<<3|(run-3)))
+			for s := size - 1; s >= 0; s-- {
+				buf = append(buf, byte(v>>(s*8)))
+			}
+			i += run
+		case run > 10:
+			// delta encoding with a fixed delta of 0
+			count := run - 1
+			buf = append(buf, byte(3<<6|count>>8), byte(count))
+			if signed {
+				buf = binary.AppendUvarint(buf, zigzagEncode(vals[i]))
+			} else {
+				buf = binary.AppendUvarint(buf, uint64(vals[i]))
+			}
+			buf = binary.AppendUvarint(buf, zigzagEncode(0))
+			i += run
+		default:
+			// literals, until the next run
+			start := i
+			for i < len(vals) && i-start < 512 {
+				if i+2 < len(vals) && vals[i] == vals[i+1] && vals[i] == vals[i+2] {
+					break
+				}
+				i++
+			}
+			appendDirect(vals[start:i])
+		}
+	}
+
+	return buf
+}
@@ -0,0 +1,321 @@
+package iop
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrcIntegerRLE(t *testing.T) {
+	// examples of the ORC specification, unsigned values
+	type testCase struct {
+		name     string
+		data     []byte
+		expected []int64
+	}
+
+	cases := []testCase{
+		{
+			name:     "short_repeat",
+			data:     []byte{0x0a, 0x27, 0x10},
+			expected: []int64{10000, 10000, 10000, 10000, 10000},
+		},
+		{
+			name:     "direct",
+			data:     []byte{0x5e, 0x03, 0x5c, 0xa1, 0xab, 0x1e, 0xde, 0xad, 0xbe, 0xef},
+			expected: []int64{23713, 43806, 57005, 48879},
+		},
+		{
+			name: "patched_base",
+			data: []byte{
+				0x8e, 0x13, 0x2b, 0x21, 0x07, 0xd0, 0x1e, 0x00, 0x14, 0x70, 0x28, 0x32, 0x3c, 0x46,
+				0x50, 0x5a, 0x64, 0x6e, 0x78, 0x82, 0x8c, 0x96, 0xa0, 0xaa, 0xb4, 0xbe, 0xfc, 0xe8,
+			},
+			expected: []int64{
+				2030, 2000, 2020, 1000000, 2040, 2050, 2060, 2070, 2080, 2090,
+				2100, 2110, 2120, 2130, 2140, 2150, 2160, 2170, 2180, 2190,
+			},
+		},
+		{
+			name:     "delta",
+			data:     []byte{0xc6, 0x09, 0x02, 0x02, 0x22, 0x42, 0x42, 0x46},
+			expected: []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29},
+		},
+	}
+
+	for _, c := range cases {
+		vals, err := decodeOrcInts(c.data, len(c.expected), false, true)
+		if assert.NoError(t, err, c.name) {
+			assert.Equal(t, c.expected, vals, c.name)
+		}
+	}
+
+	// version 1
+	vals, err := decodeOrcInts([]byte{0x61, 0x00, 0x07}, 100, false, false)
+	if assert.NoError(t, err) {
+		assert.Len(t, vals, 100)
+		assert.EqualValues(t, 7, vals[99])
+	}
+	vals, err = decodeOrcInts([]byte{0x61, 0xff, 0x64}, 100, false, false)
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 100, vals[0])
+		assert.EqualValues(t, 1, vals[99])
+	}
+	vals, err = decodeOrcInts([]byte{0xfb, 0x02, 0x03, 0x06, 0x07, 0xb}, 5, false, false)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int64{2, 3, 6, 7, 11}, vals)
+	}
+
+	// round trip
+	for _, signed := range []bool{true, false} {
+		input := []int64{1, 1, 1, 1, 5, 9, 1 << 40, 3}
+		for i := 0; i < 600; i++ {
+			input = append(input, 42) // long run
+		}
+		for i := 0; i < 700; i++ {
+			input = append(input, int64(i*i)) // literals
+		}
+		if signed {
+			input = append(input, -1, -1000, -1<<62)
+		}
+		vals, err := decodeOrcInts(encodeOrcInts(input, signed), len(input), signed, true)
+		if assert.NoError(t, err) {
+			assert.Equal(t, input, vals)
+		}
+	}
+}
+
+func TestOrcByteRLE(t *testing.T) {
+	vals, err := decodeOrcBytes([]byte{0x61, 0x00}, 100)
+	if assert.NoError(t, err) {
+		assert.Equal(t, make([]byte, 100), vals)
+	}
+
+	vals, err = decodeOrcBytes([]byte{0xfe, 0x44, 0x45}, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte{0x44, 0x45}, vals)
+	}
+
+	input := []byte{1, 2, 3, 3, 3, 3, 4}
+	for i := 0; i < 300; i++ {
+		input = append(input, byte(i%3), 9, 9, 9)
+	}
+	vals, err = decodeOrcBytes(encodeOrcBytes(input), len(input))
+	if assert.NoError(t, err) {
+		assert.Equal(t, input, vals)
+	}
+
+	bools := []bool{true, false, false, true, true, true, false, true, true, false, true}
+	decoded, err := decodeOrcBools(encodeOrcBools(bools), len(bools))
+	if assert.NoError(t, err) {
+		assert.Equal(t, bools, decoded)
+	}
+}
+
+// orcTestData returns the columns and rows of the ORC tests, 300 rows
+// (the same in test/orc_pyarrow.py)
+func orcTestData() (columns Columns, rows [][]any) {
+	columns = NewColumns(Columns{
+		{Name: "id", Type: BigIntType},
+		{Name: "small", Type: SmallIntType},
+		{Name: "name", Type: StringType},
+		{Name: "active", Type: BoolType},
+		{Name: "amount", Type: DecimalType, DbPrecision: 10, DbScale: 2, Sourced: true},
+		{Name: "rate", Type: FloatType},
+		{Name: "birth", Type: DateType},
+		{Name: "updated", Type: TimestampType},
+		{Name: "payload", Type: BinaryType},
+	}...)
+
+	ts := orcTestTimestamp
+	base := [][]any{
+		{1, 2, "alice", true, "12.34", 1.5, "2001-02-03", ts, []byte("abc")},
+		{2, nil, "bob", false, "-0.5", -2.25, "1960-12-31", ts.Add(-60 * 365 * 24 * time.Hour), nil},
+		{3, -4, nil, nil, nil, nil, nil, nil, "xyz"},
+	}
+	for i := 0; i < 100; i++ {
+		for _, row := range base {
+			rows = append(rows, append([]any{i*10 + row[0].(int)}, row[1:]...))
+		}
+	}
+	return columns, rows
+}
+
+var orcTestTimestamp = time.Date(2024, 3, 5, 10, 20, 30, 123456000, time.UTC)
+
+// readOrcTest reads the ORC file and checks it holds the test data
+func readOrcTest(t *testing.T, reader io.ReadSeeker, name string) {
+	columns, _ := orcTestData()
+	ts := orcTestTimestamp
+
+	o, err := NewOrcStream(reader, nil)
+	if !assert.NoError(t, err, name) {
+		return
+	}
+	assert.EqualValues(t, 300, o.numRows, name)
+
+	cols := o.Columns()
+	assert.Equal(t, columns.Names(), cols.Names(), name)
+	assert.Equal(t, []ColumnType{BigIntType, SmallIntType, StringType, BoolType, DecimalType, FloatType, DateType, TimestampType, BinaryType}, lo.Map(cols, func(c Column, i int) ColumnType { return c.Type }), name)
+	assert.Equal(t, 10, cols[4].DbPrecision, name)
+	assert.Equal(t, 2, cols[4].DbScale, name)
+
+	ds := NewDatastream(cols)
+	ds.it = ds.NewIterator(cols, o.nextFunc)
+	if !assert.NoError(t, ds.Start(), name) {
+		return
+	}
+	data, err := ds.Collect(0)
+	if !assert.NoError(t, err, name) || !assert.Len(t, data.Rows, 300, name) {
+		return
+	}
+
+	row := data.Rows[0]
+	assert.EqualValues(t, 1, row[0], name)
+	assert.EqualValues(t, 2, row[1], name)
+	assert.Equal(t, "alice", row[2], name)
+	assert.Equal(t, "true", cast.ToString(row[3]), name)
+	assert.Equal(t, "12.34", cast.ToString(row[4]), name)
+	assert.Equal(t, 1.5, cast.ToFloat64(row[5]), name)
+	assert.Equal(t, time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC), row[6], name)
+	assert.Equal(t, ts, row[7], name)
+	assert.Equal(t, "abc", cast.ToString(row[8]), name)
+
+	row = data.Rows[1]
+	assert.Nil(t, row[1], name)
+	assert.Equal(t, "-0.50", cast.ToString(row[4]), name)
+	assert.Equal(t, time.Date(1960, 12, 31, 0, 0, 0, 0, time.UTC), row[6], name)
+	assert.Equal(t, ts.Add(-60*365*24*time.Hour), row[7], name)
+	assert.Nil(t, row[8], name)
+
+	row = data.Rows[299]
+	assert.EqualValues(t, 993, row[0], name)
+	assert.EqualValues(t, -4, row[1], name)
+	assert.Nil(t, row[2], name)
+	assert.Nil(t, row[3], name)
+	assert.Equal(t, "xyz", cast.ToString(row[8]), name)
+}
+
+var orcTestCompressions = []OrcCompression{OrcCompressionNone, OrcCompressionZlib, OrcCompressionSnappy, OrcCompressionZstd}
+
+// writeOrcTest writes the test data, with the stripe size
+func writeOrcTest(t *testing.T, w io.Writer, compression OrcCompression, stripeSize int64) (ow *OrcWriter) {
+	columns, rows := orcTestData()
+	ow, err := NewOrcWriter(w, columns, compression, stripeSize)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	for _, row := range rows {
+		if !assert.NoError(t, ow.WriteRow(row)) {
+			return nil
+		}
+	}
+	assert.NoError(t, ow.Close())
+	return ow
+}
+
+func TestOrcWriter(t *testing.T) {
+	for _, compression := range orcTestCompressions {
+		// small stripe size, to write several stripes
+		var buf bytes.Buffer
+		ow := writeOrcTest(t, &buf, compression, 100)
+		if ow == nil {
+			return
+		}
+		assert.Greater(t, len(ow.stripes), 1, compression.String())
+
+		readOrcTest(t, bytes.NewReader(buf.Bytes()), compression.String())
+	}
+}
+
+// TestOrcPyarrow checks the reader with the files written by pyarrow for each
+// compression, and that pyarrow reads the files of the writer
+func TestOrcPyarrow(t *testing.T) {
+	if exec.Command("python3", "-c", "import pyarrow.orc").Run() != nil {
+		t.Skip("python3 with pyarrow is not installed")
+	}
+
+	folder := t.TempDir()
+	out, err := exec.Command("python3", "test/orc_pyarrow.py", "write", folder).CombinedOutput()
+	if !assert.NoError(t, err, string(out)) {
+		return
+	}
+
+	for _, compression := range orcTestCompressions {
+		name := compression.String()
+
+		// files of pyarrow
+		file, err := os.Open(path.Join(folder, name+".orc"))
+		if assert.NoError(t, err, name) {
+			readOrcTest(t, file, "pyarrow "+name)
+			file.Close()
+		}
+
+		// files of the writer
+		filePath := path.Join(folder, "sling."+name+".orc")
+		file, err = os.Create(filePath)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		ow := writeOrcTest(t, file, compression, 100)
+		file.Close()
+		if ow == nil {
+			continue
+		}
+
+		out, err := exec.Command("python3", "test/orc_pyarrow.py", "read", filePath).Output()
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		rows := []map[string]any{}
+		if !assert.NoError(t, json.Unmarshal(out, &rows), name) || !assert.Len(t, rows, 300, name) {
+			continue
+		}
+
+		assert.Equal(t, map[string]any{
+			"id": 1.0, "small": 2.0, "name": "alice", "active": true, "amount": "12.34", "rate": 1.5,
+			"birth": "2001-02-03", "updated": "2024-03-05T10:20:30.123456", "payload": "abc",
+		}, rows[0], name)
+		assert.Equal(t, map[string]any{
+			"id": 2.0, "small": nil, "name": "bob", "active": false, "amount": "-0.50", "rate": -2.25,
+			"birth": "1960-12-31", "updated": orcTestTimestamp.Add(-60 * 365 * 24 * time.Hour).Format("2006-01-02T15:04:05.000000"), "payload": nil,
+		}, rows[1], name)
+		assert.Equal(t, map[string]any{
+			"id": 993.0, "small": -4.0, "name": nil, "active": nil, "amount": nil, "rate": nil,
+			"birth": nil, "updated": nil, "payload": "xyz",
+		}, rows[299], name)
+	}
+}
+
+func TestOrcUnsupported(t *testing.T) {
+	_, err := newOrcCodec(OrcCompressionLz4, orcDefaultBlockSize)
+	assert.ErrorContains(t, err, "unsupported orc compression: lz4")
+
+	// compound types fail when opening the file
+	var buf bytes.Buffer
+	ow, err := NewOrcWriter(&buf, Columns{{Name: "tags", Type: StringType}}, OrcCompressionNone, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ow.types[1].kind = orcKindList
+	assert.NoError(t, ow.Close())
+
+	_, err = NewOrcStream(bytes.NewReader(buf.Bytes()), nil)
+	assert.ErrorContains(t, err, "unsupported orc type ARRAY for column tags")
+}
+
+func TestOrcTimestampNanos(t *testing.T) {
+	for _, nanos := range []int64{0, 1, 100, 1000, 123456000, 999999999, 500000000} {
+		assert.Equal(t, nanos, orcDecodeNanos(orcEncodeNanos(nanos)))
+	}
+	assert.EqualValues(t, 0x0a, orcEncodeNanos(1000)) // 1 << 3 | 2
+}
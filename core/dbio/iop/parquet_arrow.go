@@ -7,7 +7,6 @@ import (
 	"log"
 	"math"
 	"math/big"
-	"os"
 	"reflect"
 	"runtime/debug"
 	"strings"
@@ -16,6 +15,7 @@ import (
 	"github.com/apache/arrow/go/v16/parquet"
 	"github.com/apache/arrow/go/v16/parquet/compress"
 	"github.com/apache/arrow/go/v16/parquet/file"
+	"github.com/apache/arrow/go/v16/parquet/metadata"
 	"github.com/apache/arrow/go/v16/parquet/schema"
 	"github.com/flarco/g"
 	"github.com/samber/lo"
@@ -31,6 +31,8 @@ type ParquetArrowReader struct {
 
 	selectedColIndices []int
 	colMap             map[string]int
+	fileColumns        Columns
+	filters            []ParquetFilter
 	nextRow            chan nextRow
 	done               bool
 }
@@ -40,7 +42,16 @@ type nextRow struct {
 	err error
 }
 
-func NewParquetArrowReader(reader *os.File, selected []string) (p *ParquetArrowReader, err error) {
+// ParquetFilter is a comparison which all rows must match, such as the
+// incremental value or a where condition. Row groups whose min/max
+// statistics show that no row can match are skipped.
+type ParquetFilter struct {
+	Column string `json:"column"`
+	Op     string `json:"op"` // =, >, >=, <, <=
+	Value  string `json:"value"`
+}
+
+func NewParquetArrowReader(reader parquet.ReaderAtSeeker, selected []string, filters ...ParquetFilter) (p *ParquetArrowReader, err error) {
 	ctx := g.NewContext(context.Background())
 
 	// recover from panic
@@ -56,10 +67,11 @@ func NewParquetArrowReader(reader *os.File, selected []string) (p *ParquetArrowR
 		return p, g.Error(err, "could not open parquet reader")
 	}
 
-	p = &ParquetArrowReader{Reader: r, nextRow: make(chan nextRow, 10), Context: &ctx}
+	p = &ParquetArrowReader{Reader: r, nextRow: make(chan nextRow, 10), Context: &ctx, filters: filters}
 
 	columns := p.Columns()
 	p.colMap = columns.FieldMap(true)
+	p.fileColumns = columns
 
 	p.selectedColIndices = lo.Map(columns, func(c Column, i int) int { return i })
 
@@ -215,12 +227,17 @@ func (p *ParquetArrowReader) readRowsLoop() {
 		}
 	}()
 
-	count := 0
+	count, skipped := 0, 0
 	columns := p.Columns()
 	for r := 0; r < p.Reader.NumRowGroups(); r++ {
 		rowGroup := p.Reader.RowGroup(r)
 		rowGroupMeta := rowGroup.MetaData()
 
+		if p.skipRowGroup(rowGroupMeta) {
+			skipped++
+			continue
+		}
+
 		scanners := make([]*ParquetArrowDumper, len(p.selectedColIndices))
 		fields := make([]string, len(p.selectedColIndices))
 
@@ -284,10 +301,116 @@ func (p *ParquetArrowReader) readRowsLoop() {
 		}
 	}
 
+	if skipped > 0 {
+		g.Debug("skipped %d of %d parquet row groups with the filters %s", skipped, p.Reader.NumRowGroups(), g.Marshal(p.filters))
+	}
+
 	p.done = true
 	p.Reader.Close()
 }
 
+// skipRowGroup returns true if the statistics of the row
+// group show that no row can match the filters
+func (p *ParquetArrowReader) skipRowGroup(rowGroupMeta *metadata.RowGroupMetaData) bool {
+	for _, filter := range p.filters {
+		index, ok := p.colMap[strings.ToLower(filter.Column)]
+		if !ok {
+			continue
+		}
+
+		colChunk, err := rowGroupMeta.ColumnChunk(index)
+		if err != nil {
+			continue
+		}
+
+		stats, err := colChunk.Statistics()
+		if err != nil || stats == nil || !stats.HasMinMax() {
+			continue
+		}
+
+		minVal, maxVal, val, ok := parquetStatsBounds(p.fileColumns[index], stats, filter.Value)
+		if !ok {
+			continue
+		}
+
+		var skip bool
+		switch filter.Op {
+		case "=":
+			skip = compareValues(val, minVal) < 0 || compareValues(val, maxVal) > 0
+		case ">":
+			skip = compareValues(maxVal, val) <= 0
+		case ">=":
+			skip = compareValues(maxVal, val) < 0
+		case "<":
+			skip = compareValues(minVal, val) >= 0
+		case "<=":
+			skip = compareValues(minVal, val) > 0
+		}
+		if skip {
+			return true
+		}
+	}
+	return false
+}
+
+// parquetStatsBounds returns the min and max statistics, and the filter value,
+// as comparable values. ok is false when the column type is not supported.
+func parquetStatsBounds(col Column, stats metadata.TypedStatistics, value string) (minVal, maxVal, val any, ok bool) {
+	switch s := stats.(type) {
+	case *metadata.Int32Statistics:
+		minVal, maxVal = int64(s.Min()), int64(s.Max())
+	case *metadata.Int64Statistics:
+		minVal, maxVal = s.Min(), s.Max()
+	case *metadata.Float32Statistics:
+		minVal, maxVal = float64(s.Min()), float64(s.Max())
+	case *metadata.Float64Statistics:
+		minVal, maxVal = s.Min(), s.Max()
+	case *metadata.ByteArrayStatistics:
+		minVal, maxVal = string(s.Min()), string(s.Max())
+	default:
+		return nil, nil, nil, false
+	}
+
+	var err error
+	switch {
+	case col.Type == DatetimeType:
+		if _, isInt := minVal.(int64); !isInt {
+			return nil, nil, nil, false
+		}
+		if minVal, err = convertTimestamp(col, minVal); err != nil {
+			return nil, nil, nil, false
+		}
+		if maxVal, err = convertTimestamp(col, maxVal); err != nil {
+			return nil, nil, nil, false
+		}
+		val, err = cast.ToTimeE(value)
+	case col.Type == StringType:
+		_, isString := minVal.(string)
+		val, ok = value, isString
+		return minVal, maxVal, val, ok
+	case col.IsInteger() || col.Type == FloatType:
+		minVal, maxVal = cast.ToFloat64(minVal), cast.ToFloat64(maxVal)
+		val, err = cast.ToFloat64E(value)
+	default:
+		return nil, nil, nil, false // decimals, dates, bools...
+	}
+
+	return minVal, maxVal, val, err == nil
+}
+
+// compareValues compares values of the same type (float64, string or time)
+func compareValues(a, b any) int {
+	switch aVal := a.(type) {
+	case float64:
+		return lo.Ternary(aVal < b.(float64), -1, lo.Ternary(aVal > b.(float64), 1, 0))
+	case string:
+		return strings.Compare(aVal, b.(string))
+	case time.Time:
+		return aVal.Compare(b.(time.Time))
+	}
+	return 0
+}
+
 func (p *ParquetArrowReader) nextFunc(it *Iterator) bool {
 
 retry:
@@ -1,6 +1,8 @@
 package iop
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -8,6 +10,7 @@ import (
 	"github.com/flarco/g"
 	parquet "github.com/parquet-go/parquet-go"
 	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestParquetRead1(t *testing.T) {
@@ -175,3 +178,72 @@ func TestParquetDecimal(t *testing.T) {
 	// check file with core/dbio/scripts/check_parquet.py
 
 }
+
+func TestParquetRowGroupFilters(t *testing.T) {
+	columns := NewColumns(
+		Columns{
+			{Name: "id", Type: BigIntType},
+			{Name: "name", Type: StringType},
+			{Name: "updated_at", Type: DatetimeType},
+		}...,
+	)
+
+	// write 4 row groups of 1000 rows
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	buf := new(bytes.Buffer)
+	pw, err := NewParquetWriter(buf, columns, &parquet.Snappy)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 4000; i++ {
+		if i > 0 && i%1000 == 0 {
+			err = pw.Writer.Flush() // ends the row group
+			if !assert.NoError(t, err) {
+				return
+			}
+		}
+		row := []any{int64(i), fmt.Sprintf("name_%04d", i), start.Add(time.Duration(i) * time.Hour)}
+		if !assert.NoError(t, pw.WriteRow(row)) {
+			return
+		}
+	}
+	if !assert.NoError(t, pw.Close()) {
+		return
+	}
+
+	read := func(config map[string]string) (data Dataset, err error) {
+		ds := NewDatastream(nil)
+		ds.SetConfig(config)
+		if err = ds.ConsumeParquetReaderSeeker(bytes.NewReader(buf.Bytes())); err != nil {
+			return
+		}
+		return ds.Collect(0)
+	}
+
+	// the row groups are skipped, the rows of the other row groups are returned
+	filters := []ParquetFilter{{Column: "id", Op: ">=", Value: "2500"}}
+	data, err := read(map[string]string{"parquet_filters": g.Marshal(filters)})
+	if assert.NoError(t, err) && assert.Len(t, data.Rows, 2000) {
+		assert.EqualValues(t, 2000, data.Rows[0][0])
+	}
+
+	filters = []ParquetFilter{{Column: "name", Op: "=", Value: "name_1500"}, {Column: "id", Op: "<", Value: "3000"}}
+	data, err = read(map[string]string{"parquet_filters": g.Marshal(filters)})
+	if assert.NoError(t, err) && assert.Len(t, data.Rows, 1000) {
+		assert.EqualValues(t, 1000, data.Rows[0][0])
+	}
+
+	filters = []ParquetFilter{{Column: "id", Op: ">", Value: "3999"}}
+	data, err = read(map[string]string{"parquet_filters": g.Marshal(filters)})
+	if assert.NoError(t, err) {
+		assert.Len(t, data.Rows, 0)
+		assert.Equal(t, []string{"id", "name", "updated_at"}, data.Columns.Names())
+	}
+
+	// incremental values skip the row groups, and the rows are filtered
+	incrementalVal := start.Add(3500 * time.Hour).Format("2006-01-02 15:04:05")
+	data, err = read(map[string]string{"sling_incremental_col": "updated_at", "sling_incremental_val": incrementalVal})
+	if assert.NoError(t, err) && assert.Len(t, data.Rows, 499) {
+		assert.EqualValues(t, 3501, data.Rows[0][0])
+	}
+}
@@ -0,0 +1,82 @@
+package iop
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HiveDefaultPartition is the partition value used for null values
+const HiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// HivePartitionEscape escapes the characters of a partition
+// value which are not allowed in a path, like Hive does
+func HivePartitionEscape(val string) string {
+	var sb strings.Builder
+	for _, r := range val {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune("\"#%'*/:=?\\{[]^", r) {
+			sb.WriteString(fmt.Sprintf("%%%02X", r))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// HivePartitionUnescape reverts HivePartitionEscape
+func HivePartitionUnescape(val string) string {
+	if !strings.Contains(val, "%") {
+		return val
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(val); i++ {
+		if val[i] == '%' && i+2 < len(val) {
+			if b, err := strconv.ParseUint(val[i+1:i+3], 16, 8); err == nil {
+				sb.WriteByte(byte(b))
+				i += 2
+				continue
+			}
+		}
+		sb.WriteByte(val[i])
+	}
+	return sb.String()
+}
+
+// HivePartitions returns the `key=value` folder segments of a file uri,
+// in order. Null partitions (HiveDefaultPartition) have a nil value.
+func HivePartitions(uri string) (keys []string, values map[string]*string) {
+	values = map[string]*string{}
+
+	uri = strings.TrimPrefix(uri, "file://")
+	if i := strings.Index(uri, "://"); i > -1 {
+		uri = uri[i+3:]
+	}
+	uri = strings.ReplaceAll(uri, `\`, "/")
+
+	parts := strings.Split(uri, "/")
+	if !strings.HasSuffix(uri, "/") {
+		parts = parts[:len(parts)-1] // file name
+	}
+
+	for _, part := range parts {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || key == "" {
+			continue
+		}
+
+		key = HivePartitionUnescape(key)
+		if _, exists := values[key]; !exists {
+			keys = append(keys, key)
+		}
+
+		if val == HiveDefaultPartition {
+			values[key] = nil
+		} else {
+			val = HivePartitionUnescape(val)
+			values[key] = &val
+		}
+	}
+
+	return keys, values
+}
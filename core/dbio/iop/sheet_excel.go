@@ -4,11 +4,13 @@ import (
 	"context"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/360EntSecGroup-Skylar/excelize"
 	"github.com/flarco/g"
+	"github.com/samber/lo"
 	"github.com/spf13/cast"
 )
 
@@ -23,6 +25,10 @@ func NewExcelDataset(reader io.Reader, props map[string]string) (data Dataset, e
 	sheetName := props["sheet"]
 	sheetRange := ""
 
+	if sheetNames, multi := xls.MatchSheets(sheetName); multi {
+		return xls.GetDatasetFromSheets(sheetNames)
+	}
+
 	if sheetName == "" {
 		sheetName = xls.Sheets[0]
 	} else if sheetNameArr := strings.Split(sheetName, "!"); len(sheetNameArr) == 2 {
@@ -42,6 +48,10 @@ func NewExcelDataset(reader io.Reader, props map[string]string) (data Dataset, e
 	return
 }
 
+// ExcelSheetNameColumn is the column with the sheet name,
+// when reading several sheets into one stream
+var ExcelSheetNameColumn = "_sling_sheet_name"
+
 // Excel represent an Excel object pointing to its file
 type Excel struct {
 	spreadsheet
@@ -129,6 +139,116 @@ func (xls *Excel) GetDataset(sheet string) (data Dataset) {
 	return xls.makeDatasetAuto(xls.File.GetRows(sheet))
 }
 
+// MatchSheets returns the sheets to read when several are selected,
+// with `*` for all sheets or a JSON list of sheet names / patterns.
+// A list item can have a range, such as `Sheet1!A:E`.
+func (xls *Excel) MatchSheets(sheet string) (sheetNames []string, multi bool) {
+	sheet = strings.TrimSpace(sheet)
+
+	patterns := []string{}
+	if sheet == "*" {
+		patterns = []string{"*"}
+	} else if strings.HasPrefix(sheet, "[") {
+		if err := g.Unmarshal(sheet, &patterns); err != nil {
+			g.Warn("could not parse sheet list (%s): %s", sheet, err.Error())
+			return nil, false
+		}
+	} else {
+		return nil, false
+	}
+
+	for _, pattern := range patterns {
+		name, sheetRange, _ := strings.Cut(pattern, "!")
+		for _, sheetName := range xls.Sheets {
+			if matched, _ := filepath.Match(name, sheetName); !matched {
+				continue
+			} else if sheetRange != "" {
+				sheetName = sheetName + "!" + sheetRange
+			}
+			if !lo.Contains(sheetNames, sheetName) {
+				sheetNames = append(sheetNames, sheetName)
+			}
+		}
+	}
+
+	return sheetNames, true
+}
+
+// GetDatasetFromSheets returns one dataset of the provided sheets,
+// with the sheet name in the `_sling_sheet_name` column.
+// Columns are matched by name, a column with different types is a string.
+func (xls *Excel) GetDatasetFromSheets(sheetNames []string) (data Dataset, err error) {
+	data = NewDataset(nil)
+	data.Sp.SetConfig(xls.Props)
+	if len(sheetNames) == 0 {
+		return data, g.Error("no sheet matched (available: %s)", strings.Join(xls.Sheets, ", "))
+	}
+
+	colIndex := map[string]int{}
+	recast := false
+	for _, sheetName := range sheetNames {
+		var sheetData Dataset
+		if name, sheetRange, found := strings.Cut(sheetName, "!"); found {
+			sheetData, err = xls.GetDatasetFromRange(name, sheetRange)
+			if err != nil {
+				return data, g.Error(err, "Unable to get range data for %s", sheetName)
+			}
+			sheetName = name
+		} else {
+			sheetData = xls.GetDataset(sheetName)
+		}
+
+		// map the sheet columns to the dataset columns
+		indexes := make([]int, len(sheetData.Columns))
+		for i, col := range sheetData.Columns {
+			j, ok := colIndex[strings.ToLower(col.Name)]
+			if !ok {
+				j = len(data.Columns)
+				colIndex[strings.ToLower(col.Name)] = j
+				col.Position = j + 1
+				data.Columns = append(data.Columns, col)
+			} else if data.Columns[j].Type != col.Type && col.Type != "" {
+				data.Columns[j].Type = StringType
+				recast = true
+			}
+			indexes[i] = j
+		}
+
+		for _, row0 := range sheetData.Rows {
+			row := make([]any, len(colIndex)+1)
+			for i, val := range row0 {
+				if i < len(indexes) {
+					row[indexes[i]] = val
+				}
+			}
+			row[len(row)-1] = sheetName
+			data.Rows = append(data.Rows, row)
+		}
+	}
+
+	data.Columns = append(data.Columns, Column{
+		Name:     ExcelSheetNameColumn,
+		Type:     StringType,
+		Position: len(data.Columns) + 1,
+	})
+	for i, row := range data.Rows {
+		if len(row) < len(data.Columns) {
+			// columns were added with a later sheet
+			newRow := make([]any, len(data.Columns))
+			copy(newRow, row[:len(row)-1])
+			newRow[len(newRow)-1] = row[len(row)-1]
+			row = newRow
+		}
+		if recast {
+			row = data.Sp.CastRow(row, data.Columns)
+		}
+		data.Rows[i] = row
+	}
+	data.Inferred = true
+
+	return
+}
+
 // GetDatasetFromRange returns a dataset of the provided sheet / range
 // cellRange example: `$AH$13:$AI$20` or `AH13:AI20` or `A:E`
 func (xls *Excel) GetDatasetFromRange(sheet, cellRange string) (data Dataset, err error) {
@@ -5,17 +5,21 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/flarco/g"
 	"github.com/flarco/g/json"
 	"github.com/samber/lo"
+	"github.com/spf13/cast"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
+var cellRegex = regexp.MustCompile(`^([A-Za-z]+)([0-9]*)$`)
+
 // GoogleSheet represent a Google Sheet object
 type GoogleSheet struct {
 	spreadsheet
@@ -41,49 +45,44 @@ func NewGoogleSheet(props ...string) (ggs *GoogleSheet, err error) {
 		ggs.Props[k] = v
 	}
 
-	if _, ok := ggs.Props["KEY_BODY"]; !ok {
-		return nil, g.Error("missing google credentials")
+	opts := []option.ClientOption{option.WithScopes(sheets.SpreadsheetsScope)}
+	if endpoint := ggs.Props["ENDPOINT"]; endpoint != "" {
+		// such as a local emulator
+		opts = append(opts, option.WithEndpoint(endpoint))
 	}
 
-	scope := "https://www.googleapis.com/auth/spreadsheets"
-	// scope = "https://www.googleapis.com/auth/spreadsheets.readonly"
-	config, err := google.ConfigFromJSON([]byte(ggs.Props["KEY_BODY"]), scope)
-	if err != nil {
-		err = g.Error(err, "Unable to parse client secret file to config")
-		return
-	}
-
-	// client := ggs.getGoogleClient(config)
-	// if ggs.context.Err() != nil {
-	// 	err = g.Error(ggs.context.Err(), "unable to create google client")
-	// 	return
-	// }
-
-	// srv, err := sheets.New(client)
-	// if err != nil {
-	// 	err = g.Error(err, "Unable to retrieve Sheets client")
-	// 	return
-	// }
+	keyBody := ggs.Props["KEY_BODY"]
+	keyMap := map[string]any{}
+	g.Unmarshal(keyBody, &keyMap)
 
-	// ggs.srv = srv
-
-	// https://developers.google.com/sheets/api/quickstart/js
+	switch {
+	case keyBody == "" && ggs.Props["ENDPOINT"] != "":
+		opts = append(opts, option.WithoutAuthentication())
+	case keyBody == "":
+		return nil, g.Error("missing google credentials")
+	case keyMap["type"] != nil:
+		// service account or authorized user credentials
+		creds, err := google.CredentialsFromJSON(ggs.context.Ctx, []byte(keyBody), sheets.SpreadsheetsScope)
+		if err != nil {
+			return nil, g.Error(err, "Unable to parse google credentials")
+		}
+		opts = append(opts, option.WithCredentials(creds))
+	default:
+		// oauth client secret, with the installed app flow
+		// https://developers.google.com/sheets/api/quickstart/go
+		config, err := google.ConfigFromJSON([]byte(keyBody), sheets.SpreadsheetsScope)
+		if err != nil {
+			return nil, g.Error(err, "Unable to parse client secret file to config")
+		}
 
-	token, err := ggs.getToken(config)
-	if err != nil {
-		err = g.Error(err, "Unable to obtain token")
-		return
+		token, err := ggs.getToken(config)
+		if err != nil {
+			return nil, g.Error(err, "Unable to obtain token")
+		}
+		opts = append(opts, option.WithTokenSource(config.TokenSource(ggs.context.Ctx, token)))
 	}
 
-	_ = option.WithTokenSource(config.TokenSource(ggs.context.Ctx, token))
-
-	srv, err := sheets.NewService(
-		ggs.context.Ctx,
-		// option.WithAPIKey(ggs.Props["API_KEY"]),
-		option.WithScopes(sheets.SpreadsheetsScope),
-		// option.WithScopes(sheets.SpreadsheetsReadonlyScope),
-		option.WithTokenSource(config.TokenSource(ggs.context.Ctx, token)),
-	)
+	srv, err := sheets.NewService(ggs.context.Ctx, opts...)
 	if err != nil {
 		err = g.Error(err, "Unable to retrieve Sheets client")
 		return
@@ -110,14 +109,16 @@ func NewGoogleSheetFromURL(urlStr string, props ...string) (ggs *GoogleSheet, er
 	}
 
 	pathArr := strings.Split(u.Path, "/")
-	if len(pathArr) < 4 || strings.ToLower(pathArr[1]) != "spreadsheets" {
+	if u.Scheme == "gsheets" && u.Host != "" {
+		ggs.SpreadsheetID = u.Host // gsheets://<spreadsheet_id>/<sheet>
+	} else if len(pathArr) < 4 || strings.ToLower(pathArr[1]) != "spreadsheets" {
 		err = g.Error("invalid google sheets url")
 		err = g.Error(err, "invalid google sheets url")
 		return
+	} else {
+		ggs.SpreadsheetID = pathArr[3]
 	}
 
-	ggs.SpreadsheetID = pathArr[3]
-
 	// get sheets
 	err = ggs.RefreshSheets()
 	if err != nil {
@@ -141,6 +142,8 @@ func (ggs *GoogleSheet) RefreshSheets() (err error) {
 		return g.Error("zero sheets returned")
 	}
 
+	ggs.Sheets = []string{}
+	ggs.sheetObjects = map[string]*sheets.Sheet{}
 	for _, sheet := range resp.Sheets {
 		// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/sheets#sheetproperties
 		// g.P(sheet.Data[0].MarshalJSON())
@@ -159,6 +162,9 @@ func (ggs *GoogleSheet) RefreshSheets() (err error) {
 func (ggs *GoogleSheet) getRawRows(sheet *sheets.Sheet) [][]string {
 	var blankCellCnt, trailingBlankRows int
 	rawRows := [][]string{}
+	if len(sheet.Data) == 0 {
+		return rawRows
+	}
 	for _, rowData := range sheet.Data[0].RowData {
 		blankCellCnt = 0
 		row := make([]string, len(rowData.Values))
@@ -343,6 +349,7 @@ func (ggs *GoogleSheet) createSpreadsheet() (spreadsheetID string, err error) {
 
 // WriteSheet write a datastream into a sheet
 // mode can be: `new`, `append` or `overwrite`. Default is `new`
+// the start cell can be specified with the sheet name, such as `Sheet1!B2`
 func (ggs *GoogleSheet) WriteSheet(shtName string, ds *Datastream, mode string) (err error) {
 
 	if ggs.SpreadsheetID == "" {
@@ -354,7 +361,17 @@ func (ggs *GoogleSheet) WriteSheet(shtName string, ds *Datastream, mode string)
 		}
 	}
 
-	cellRange := "A1"
+	// the start cell can be provided, such as `Sheet1!B2`
+	startCol, startRow := "A", 1
+	if parts := strings.SplitN(shtName, "!", 2); len(parts) == 2 {
+		shtName = parts[0]
+		startCol, startRow, err = splitCell(parts[1])
+		if err != nil {
+			return g.Error(err, "invalid range for sheet %s", shtName)
+		}
+	}
+
+	cellRange := g.F("%s%d", startCol, startRow)
 	if mode == "" || mode == "new" {
 		// create sheet
 		shtName, err = ggs.createSheet(shtName)
@@ -374,13 +391,10 @@ func (ggs *GoogleSheet) WriteSheet(shtName string, ds *Datastream, mode string)
 		}
 	}
 
+	// when appending to an empty sheet, the header is written
+	writeHeader := mode != "append" || len(rows) < startRow
 	if mode == "append" {
-		rowCnt := len(rows)
-		// colCnt := 0
-		// if len(rows) > 0 {
-		// 	colCnt = len(rows[0])
-		// }
-		cellRange = g.F("A%d", rowCnt+1)
+		cellRange = g.F("%s%d", startCol, max(startRow, len(rows)+1))
 	}
 
 	data, err := ds.Collect(0)
@@ -389,7 +403,7 @@ func (ggs *GoogleSheet) WriteSheet(shtName string, ds *Datastream, mode string)
 	}
 
 	outRows := data.Rows
-	if mode != "append" {
+	if writeHeader {
 		header := []interface{}{}
 		for _, field := range data.GetFields() {
 			header = append(header, field)
@@ -401,8 +415,8 @@ func (ggs *GoogleSheet) WriteSheet(shtName string, ds *Datastream, mode string)
 
 	// for overwrite, blank out the remaining rows
 	// TODO: what about the remaining columns (on the right)
-	if mode == "overwrite" && len(outRows) < len(rows)+1 {
-		for j := len(outRows); j <= len(rows); j++ {
+	if mode == "overwrite" {
+		for j := startRow - 1 + len(outRows); j < len(rows); j++ {
 			row := make([]interface{}, len(ds.Columns))
 			for i := range row {
 				row[i] = ""
@@ -450,6 +464,25 @@ func (ggs *GoogleSheet) WriteSheet(shtName string, ds *Datastream, mode string)
 	return
 }
 
+// splitCell splits a cell reference such as `B2` (or a range `B2:F`)
+// into its column letters and row number
+func splitCell(cell string) (col string, row int, err error) {
+	cell = strings.TrimSpace(strings.Split(cell, ":")[0])
+	matches := cellRegex.FindStringSubmatch(cell)
+	if len(matches) != 3 {
+		return "", 0, g.Error("invalid cell reference: %s", cell)
+	}
+
+	col, row = strings.ToUpper(matches[1]), 1
+	if matches[2] != "" {
+		row = cast.ToInt(matches[2])
+	}
+	if row < 1 {
+		return "", 0, g.Error("invalid cell reference: %s", cell)
+	}
+	return col, row, nil
+}
+
 func (ggs *GoogleSheet) getToken(config *oauth2.Config) (*oauth2.Token, error) {
 
 	tokFile := "/tmp/token.json"
@@ -40,26 +40,29 @@ type StreamProcessor struct {
 }
 
 type StreamConfig struct {
-	TrimSpace         bool                       `json:"trim_space"`
-	EmptyAsNull       bool                       `json:"empty_as_null"`
-	Header            bool                       `json:"header"`
-	Compression       string                     `json:"compression"` // AUTO | ZIP | GZIP | SNAPPY | NONE
-	NullIf            string                     `json:"null_if"`
-	NullAs            string                     `json:"null_as"`
-	DatetimeFormat    string                     `json:"datetime_format"`
-	SkipBlankLines    bool                       `json:"skip_blank_lines"`
-	Delimiter         string                     `json:"delimiter"`
-	Escape            string                     `json:"escape"`
-	FileMaxRows       int64                      `json:"file_max_rows"`
-	BatchLimit        int64                      `json:"batch_limit"`
-	MaxDecimals       int                        `json:"max_decimals"`
-	Flatten           bool                       `json:"flatten"`
-	FieldsPerRec      int                        `json:"fields_per_rec"`
-	Jmespath          string                     `json:"jmespath"`
-	BoolAsInt         bool                       `json:"-"`
-	Columns           Columns                    `json:"columns"` // list of column types. Can be partial list! likely is!
-	transforms        map[string][]TransformFunc // array of transform functions to apply
-	maxDecimalsFormat string                     `json:"-"`
+	TrimSpace         bool                         `json:"trim_space"`
+	EmptyAsNull       bool                         `json:"empty_as_null"`
+	Header            bool                         `json:"header"`
+	Compression       string                       `json:"compression"` // AUTO | ZIP | GZIP | SNAPPY | NONE
+	NullIf            string                       `json:"null_if"`
+	NullAs            string                       `json:"null_as"`
+	DatetimeFormat    string                       `json:"datetime_format"`
+	SkipBlankLines    bool                         `json:"skip_blank_lines"`
+	Delimiter         string                       `json:"delimiter"`
+	Escape            string                       `json:"escape"`
+	FileMaxRows       int64                        `json:"file_max_rows"`
+	BatchLimit        int64                        `json:"batch_limit"`
+	MaxDecimals       int                          `json:"max_decimals"`
+	Flatten           bool                         `json:"flatten"`
+	FieldsPerRec      int                          `json:"fields_per_rec"`
+	Jmespath          string                       `json:"jmespath"`
+	BoolAsInt         bool                         `json:"-"`
+	Columns           Columns                      `json:"columns"` // list of column types. Can be partial list! likely is!
+	FixedWidthColumns FixedWidthColumns            `json:"fixed_width_columns"`
+	SkipHeaderLines   int                          `json:"skip_header_lines"`
+	SkipTrailerLines  int                          `json:"skip_trailer_lines"`
+	transforms        map[string][]columnTransform // array of transform functions to apply
+	maxDecimalsFormat string                       `json:"-"`
 
 	Map map[string]string `json:"-"`
 }
@@ -216,7 +219,7 @@ func DefaultStreamConfig() *StreamConfig {
 		EmptyAsNull: true,
 		MaxDecimals: -1,
 		Columns:     Columns{},
-		transforms:  map[string][]TransformFunc{},
+		transforms:  map[string][]columnTransform{},
 	}
 }
 
@@ -300,6 +303,23 @@ func (sp *StreamProcessor) SetConfig(configMap map[string]string) {
 	if configMap["columns"] != "" {
 		g.Unmarshal(configMap["columns"], &sp.Config.Columns)
 	}
+	if configMap["fixed_width_columns"] != "" {
+		fwColumns, err := ParseFixedWidthColumns(configMap["fixed_width_columns"])
+		if err != nil {
+			g.Warn(err.Error())
+		} else {
+			sp.Config.FixedWidthColumns = fwColumns
+			if len(sp.Config.Columns) == 0 {
+				sp.Config.Columns = fwColumns.TypedColumns()
+			}
+		}
+	}
+	if configMap["skip_header_lines"] != "" {
+		sp.Config.SkipHeaderLines = cast.ToInt(configMap["skip_header_lines"])
+	}
+	if configMap["skip_trailer_lines"] != "" {
+		sp.Config.SkipTrailerLines = cast.ToInt(configMap["skip_trailer_lines"])
+	}
 	if configMap["transforms"] != "" {
 		sp.applyTransforms(configMap["transforms"])
 	}
@@ -322,20 +342,79 @@ func makeColumnTransforms(transformsPayload string) map[string][]Transform {
 
 func (sp *StreamProcessor) applyTransforms(transformsPayload string) {
 	columnTransforms := makeColumnTransforms(transformsPayload)
-	sp.Config.transforms = map[string][]TransformFunc{}
-	for key, names := range columnTransforms {
-		sp.Config.transforms[key] = []TransformFunc{}
-		for _, name := range names {
-			f, ok := Transforms[name]
-			if ok {
-				sp.Config.transforms[key] = append(sp.Config.transforms[key], f)
-			} else {
-				g.Warn("did find find transform named: '%s'", name)
+	sp.Config.transforms = map[string][]columnTransform{}
+	for key, exprs := range columnTransforms {
+		sp.Config.transforms[key] = []columnTransform{}
+		for _, expr := range exprs {
+			ct, err := newColumnTransform(string(expr))
+			if err != nil {
+				g.Warn(err.Error())
+				continue
 			}
+			sp.Config.transforms[key] = append(sp.Config.transforms[key], ct)
 		}
 	}
 }
 
+// applyColumnTransforms applies the column and wildcard transforms to the value.
+// Transforms without arguments only apply to string columns, except masking
+// transforms which apply to any column, and fail the stream on error.
+// returns the new value, and true if any transform was applied
+func (sp *StreamProcessor) applyColumnTransforms(col *Column, val any, sVal string) (newVal any, applied bool) {
+	newVal = val
+
+	key := strings.ToLower(col.Name)
+	for _, k := range []string{key, "*"} {
+		transforms, ok := sp.Config.transforms[k]
+		if !ok {
+			continue
+		}
+		for _, ct := range transforms {
+			if ct.f != nil {
+				if (!col.Type.IsString() && !ct.mask) || newVal == nil {
+					continue
+				} else if sVal, ok = newVal.(string); !ok {
+					sVal = castToString(newVal)
+				}
+			} else if newVal == nil && !ct.nullable {
+				continue // only null-aware transforms (such as coalesce) handle nulls
+			}
+
+			tVal, err := ct.apply(sp, newVal, sVal)
+			if err != nil && ct.mask {
+				// never keep the unmasked value
+				if sp.ds != nil {
+					sp.ds.Context.CaptureErr(g.Error(err, "could not apply transform '%s' on column %s", ct.Name, col.Name))
+				}
+				return nil, true
+			} else if err != nil {
+				continue // keep value
+			}
+			newVal, applied = tVal, true
+		}
+	}
+
+	return
+}
+
+// castToString casts the value as a string, marshalling slices and maps as JSON
+func castToString(val any) string {
+	if kind := reflect.TypeOf(val).Kind(); kind == reflect.Slice || kind == reflect.Map {
+		return g.Marshal(val)
+	}
+	return cast.ToString(val)
+}
+
+// transformNull applies the null-aware transforms (such as coalesce) to a null value.
+// returns nil if the value remains null
+func (sp *StreamProcessor) transformNull(col *Column) any {
+	if len(sp.Config.transforms) == 0 {
+		return nil
+	}
+	nVal, _ := sp.applyColumnTransforms(col, nil, "")
+	return nVal
+}
+
 // CastVal  casts the type of an interface based on its value
 // From html/template/content.go
 // Copyright 2011 The Go Authors. All rights reserved.
@@ -515,6 +594,10 @@ func (sp *StreamProcessor) EncodingTransform(t Transform, val string) (newVal st
 // which degrades performance by ~10%
 // go test -benchmem -run='^$ github.com/slingdata-io/sling-cli/core/dbio/iop' -bench '^BenchmarkProcessVal'
 func (sp *StreamProcessor) CastVal(i int, val interface{}, col *Column) interface{} {
+	return sp.castVal(i, val, col, true)
+}
+
+func (sp *StreamProcessor) castVal(i int, val interface{}, col *Column, transform bool) interface{} {
 	cs, ok := sp.colStats[i]
 	if !ok {
 		sp.colStats[i] = &ColumnStats{}
@@ -526,6 +609,11 @@ func (sp *StreamProcessor) CastVal(i int, val interface{}, col *Column) interfac
 	isString := false
 
 	if val == nil {
+		if transform {
+			if nVal = sp.transformNull(col); nVal != nil {
+				return sp.castVal(i, nVal, col, false)
+			}
+		}
 		cs.TotalCnt++
 		cs.NullCnt++
 		sp.rowBlankValCnt++
@@ -557,38 +645,54 @@ func (sp *StreamProcessor) CastVal(i int, val interface{}, col *Column) interfac
 		if sVal == "" {
 			sp.rowBlankValCnt++
 			if sp.Config.EmptyAsNull || !sp.ds.Columns[i].IsString() {
+				if transform {
+					if nVal = sp.transformNull(col); nVal != nil {
+						return sp.castVal(i, nVal, col, false)
+					}
+				}
 				cs.TotalCnt++
 				cs.NullCnt++
 				return nil
 			}
 		} else if sp.Config.NullIf == sVal {
+			if transform {
+				if nVal = sp.transformNull(col); nVal != nil {
+					return sp.castVal(i, nVal, col, false)
+				}
+			}
 			cs.TotalCnt++
 			cs.NullCnt++
 			return nil
 		}
 	}
 
-	switch {
-	case col.Type.IsString():
-		if sVal == "" && val != nil {
-			if reflect.TypeOf(val).Kind() == reflect.Slice || reflect.TypeOf(val).Kind() == reflect.Map {
-				sVal = g.Marshal(val)
-			} else {
-				sVal = cast.ToString(val)
+	// apply transforms
+	if transform && len(sp.Config.transforms) > 0 {
+		if tVal, applied := sp.applyColumnTransforms(col, val, sVal); applied {
+			switch tv := tVal.(type) {
+			case nil:
+				cs.TotalCnt++
+				cs.NullCnt++
+				return nil
+			case string:
+				val, sVal = tv, tv
+			default:
+				val, sVal = tv, ""
+				if col.Type.IsString() && !col.Sourced && sp.ds != nil {
+					// transform returned a typed value
+					if typ := sp.GetType(tv); !typ.IsString() {
+						sp.ds.ChangeColumn(i, typ)
+						col = &sp.ds.Columns[i]
+					}
+				}
 			}
 		}
+	}
 
-		// apply transforms
-		key := strings.ToLower(col.Name)
-		if transforms, ok := sp.Config.transforms[key]; ok {
-			for _, t := range transforms {
-				sVal, _ = t(sp, sVal)
-			}
-		}
-		if transforms, ok := sp.Config.transforms["*"]; ok {
-			for _, t := range transforms {
-				sVal, _ = t(sp, sVal)
-			}
+	switch {
+	case col.Type.IsString():
+		if sVal == "" && val != nil {
+			sVal = castToString(val)
 		}
 
 		l := len(sVal)
@@ -619,7 +723,7 @@ func (sp *StreamProcessor) CastVal(i int, val interface{}, col *Column) interfac
 			nVal = sp.ParseString(cast.ToString(val))
 			sp.ds.ChangeColumn(i, sp.GetType(nVal))
 			if !sp.ds.Columns[i].IsString() { // so we don't loop
-				return sp.CastVal(i, nVal, &sp.ds.Columns[i])
+				return sp.castVal(i, nVal, &sp.ds.Columns[i], false)
 			}
 			cs.StringCnt++
 			sp.rowChecksum[i] = uint64(len(sVal))
@@ -0,0 +1,72 @@
+"""Writes and reads ORC files with pyarrow, to check the ORC reader and writer
+against another implementation (see TestOrcPyarrow).
+
+    python3 orc_pyarrow.py write <folder>  # writes <folder>/<compression>.orc
+    python3 orc_pyarrow.py read <path>     # prints the rows as json
+"""
+import datetime
+import decimal
+import json
+import sys
+
+import pyarrow as pa
+import pyarrow.orc as orc
+
+COMPRESSIONS = {
+    "none": "uncompressed",
+    "zlib": "zlib",
+    "snappy": "snappy",
+    "zstd": "zstd",
+}
+
+
+def write(folder):
+    ts = datetime.datetime(2024, 3, 5, 10, 20, 30, 123456)
+    rows = [
+        [1, 2, "alice", True, decimal.Decimal("12.34"), 1.5, datetime.date(2001, 2, 3), ts, b"abc"],
+        [2, None, "bob", False, decimal.Decimal("-0.50"), -2.25, datetime.date(1960, 12, 31), ts - datetime.timedelta(days=60 * 365), None],
+        [3, -4, None, None, None, None, None, None, b"xyz"],
+    ]
+    schema = pa.schema([
+        ("id", pa.int64()),
+        ("small", pa.int16()),
+        ("name", pa.string()),
+        ("active", pa.bool_()),
+        ("amount", pa.decimal128(10, 2)),
+        ("rate", pa.float64()),
+        ("birth", pa.date32()),
+        ("updated", pa.timestamp("ns")),
+        ("payload", pa.binary()),
+    ])
+
+    data = [[i * 10 + row[0]] + row[1:] for i in range(100) for row in rows]
+    table = pa.Table.from_pylist([dict(zip(schema.names, row)) for row in data], schema=schema)
+    for name, compression in COMPRESSIONS.items():
+        orc.write_table(table, f"{folder}/{name}.orc", compression=compression)
+
+
+def value(val):
+    if isinstance(val, decimal.Decimal):
+        return str(val)
+    if isinstance(val, (datetime.date, datetime.datetime)):
+        return val.isoformat()
+    if isinstance(val, bytes):
+        return val.decode()
+    return val
+
+
+def read(path):
+    table = orc.ORCFile(path).read()
+    columns = []
+    for field, column in zip(table.schema, table.columns):
+        if pa.types.is_timestamp(field.type):
+            column = column.cast(pa.timestamp("us"))  # to_pylist needs microseconds
+        columns.append(column)
+    table = pa.Table.from_arrays(columns, names=table.schema.names)
+
+    rows = [{k: value(v) for k, v in row.items()} for row in table.to_pylist()]
+    print(json.dumps(rows))
+
+
+if __name__ == "__main__":
+    {"write": write, "read": read}[sys.argv[1]](sys.argv[2])
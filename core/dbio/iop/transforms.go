@@ -3,11 +3,17 @@ package iop
 import (
 	"bufio"
 	"embed"
+	"errors"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
 	"github.com/flarco/g"
+	"github.com/jmespath/go-jmespath"
 	"github.com/spf13/cast"
 )
 
@@ -16,6 +22,57 @@ var templatesFolder embed.FS
 
 var Transforms = map[Transform]TransformFunc{}
 
+var transformsMu sync.RWMutex
+
+// RegisterTransform registers a transform without arguments under the
+// provided name, overriding any transform previously registered with it
+func RegisterTransform(name Transform, f TransformFunc) error {
+	name = Transform(strings.ToLower(strings.TrimSpace(string(name))))
+	if name == "" {
+		return g.Error("transform name is blank")
+	} else if f == nil {
+		return g.Error("transform function is nil for '%s'", name)
+	}
+
+	transformsMu.Lock()
+	Transforms[name] = f
+	transformsMu.Unlock()
+
+	return nil
+}
+
+// RegisterMaskTransform registers a masking transform without arguments.
+// Masking transforms apply to the value cast as a string, whatever the
+// column type, and fail the stream on error instead of keeping the value.
+func RegisterMaskTransform(name Transform, f TransformFunc) error {
+	if err := RegisterTransform(name, f); err != nil {
+		return err
+	}
+
+	transformsMu.Lock()
+	maskTransforms[Transform(strings.ToLower(strings.TrimSpace(string(name))))] = true
+	transformsMu.Unlock()
+
+	return nil
+}
+
+// RegisterTransformArgs registers a transform accepting arguments under
+// the provided name, overriding any transform previously registered with it
+func RegisterTransformArgs(name Transform, f TransformArgsFunc) error {
+	name = Transform(strings.ToLower(strings.TrimSpace(string(name))))
+	if name == "" {
+		return g.Error("transform name is blank")
+	} else if f == nil {
+		return g.Error("transform function is nil for '%s'", name)
+	}
+
+	transformsMu.Lock()
+	TransformsArgs[name] = f
+	transformsMu.Unlock()
+
+	return nil
+}
+
 type Transform string
 
 const (
@@ -36,18 +93,62 @@ const (
 	TransformEncodeUtf16         Transform = "encode_utf16"
 	TransformEncodeWindows1250   Transform = "encode_windows1250"
 	TransformEncodeWindows1252   Transform = "encode_windows1252"
+	TransformFakeAddress         Transform = "fake_address"
+	TransformFakeEmail           Transform = "fake_email"
+	TransformFakeName            Transform = "fake_name"
+	TransformFakePhone           Transform = "fake_phone"
 	TransformHashMd5             Transform = "hash_md5"
 	TransformHashSha256          Transform = "hash_sha256"
 	TransformHashSha512          Transform = "hash_sha512"
+	TransformMaskEmail           Transform = "mask_email"
+	TransformMaskLast4           Transform = "mask_last_4"
+	TransformMaskPhone           Transform = "mask_phone"
+	TransformNullify             Transform = "nullify"
 	TransformParseBit            Transform = "parse_bit"
 	TransformParseFix            Transform = "parse_fix"
 	TransformParseUuid           Transform = "parse_uuid"
 	TransformReplace0x00         Transform = "replace_0x00"
 	TransformReplaceAccents      Transform = "replace_accents"
 	TransformReplaceNonPrintable Transform = "replace_non_printable"
+	TransformTokenize            Transform = "tokenize"
 	TransformTrimSpace           Transform = "trim_space"
 )
 
+// transforms accepting arguments, such as `replace(';', ',')`
+const (
+	TransformAbs          Transform = "abs"
+	TransformAdd          Transform = "add"
+	TransformCeil         Transform = "ceil"
+	TransformCoalesce     Transform = "coalesce"
+	TransformDateTrunc    Transform = "date_trunc"
+	TransformDivide       Transform = "divide"
+	TransformFloor        Transform = "floor"
+	TransformFormatDate   Transform = "format_date"
+	TransformJsonPath     Transform = "json_path"
+	TransformLeft         Transform = "left"
+	TransformLower        Transform = "lower"
+	TransformLpad         Transform = "lpad"
+	TransformMultiply     Transform = "multiply"
+	TransformNullIf       Transform = "null_if"
+	TransformParseDate    Transform = "parse_date"
+	TransformRegexExtract Transform = "regex_extract"
+	TransformRegexReplace Transform = "regex_replace"
+	TransformReplace      Transform = "replace"
+	TransformRight        Transform = "right"
+	TransformRound        Transform = "round"
+	TransformRpad         Transform = "rpad"
+	TransformSplitPart    Transform = "split_part"
+	TransformSubstring    Transform = "substring"
+	TransformToFloat      Transform = "to_float"
+	TransformToInt        Transform = "to_int"
+	TransformTrim         Transform = "trim"
+	TransformUpper        Transform = "upper"
+)
+
+// ErrTransformNull is returned by a transform function
+// when the value should be set to null
+var ErrTransformNull = errors.New("transform set value to null")
+
 // https://stackoverflow.com/a/46637343/2295355
 // https://web.itu.edu.tr/sgunduz/courses/mikroisl/ascii.html
 func ReplaceNonPrintable(val string) string {
@@ -182,3 +283,525 @@ func ParseFIX(message string) (fixMap map[string]any, err error) {
 
 	return
 }
+
+// TransformArgsFunc is a transform function accepting arguments.
+// It receives the typed value, and can return a typed value
+// (such as int64, float64 or time.Time) or nil.
+type TransformArgsFunc func(sp *StreamProcessor, val any, args []any) (any, error)
+
+// TransformsArgs holds the transforms accepting arguments
+var TransformsArgs = map[Transform]TransformArgsFunc{
+	// string
+	TransformReplace: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		oldS, newS, err := transformArgs2Strings(TransformReplace, args)
+		if err != nil {
+			return val, err
+		}
+		return strings.ReplaceAll(cast.ToString(val), oldS, newS), nil
+	},
+	TransformRegexReplace: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		pattern, repl, err := transformArgs2Strings(TransformRegexReplace, args)
+		if err != nil {
+			return val, err
+		}
+		re, err := transformRegex(pattern)
+		if err != nil {
+			return val, err
+		}
+		return re.ReplaceAllString(cast.ToString(val), repl), nil
+	},
+	TransformRegexExtract: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformRegexExtract, args, 1, 2); err != nil {
+			return val, err
+		}
+		re, err := transformRegex(cast.ToString(args[0]))
+		if err != nil {
+			return val, err
+		}
+
+		// first capture group by default, if any
+		group := 0
+		if re.NumSubexp() > 0 {
+			group = 1
+		}
+		if len(args) > 1 {
+			group = cast.ToInt(args[1])
+		}
+		if group > re.NumSubexp() {
+			return val, g.Error("regex_extract group %d not found in pattern", group)
+		}
+
+		match := re.FindStringSubmatch(cast.ToString(val))
+		if match == nil {
+			return nil, nil
+		}
+		return match[group], nil
+	},
+	TransformSubstring: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformSubstring, args, 1, 2); err != nil {
+			return val, err
+		}
+		runes := []rune(cast.ToString(val))
+		start := min(max(cast.ToInt(args[0]), 0), len(runes))
+		end := len(runes)
+		if len(args) > 1 {
+			end = min(start+max(cast.ToInt(args[1]), 0), len(runes))
+		}
+		return string(runes[start:end]), nil
+	},
+	TransformLeft: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformLeft, args, 1, 1); err != nil {
+			return val, err
+		}
+		runes := []rune(cast.ToString(val))
+		return string(runes[:min(max(cast.ToInt(args[0]), 0), len(runes))]), nil
+	},
+	TransformRight: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformRight, args, 1, 1); err != nil {
+			return val, err
+		}
+		runes := []rune(cast.ToString(val))
+		return string(runes[len(runes)-min(max(cast.ToInt(args[0]), 0), len(runes)):]), nil
+	},
+	TransformLower: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		return strings.ToLower(cast.ToString(val)), transformArgsCount(TransformLower, args, 0, 0)
+	},
+	TransformUpper: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		return strings.ToUpper(cast.ToString(val)), transformArgsCount(TransformUpper, args, 0, 0)
+	},
+	TransformTrim: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformTrim, args, 0, 1); err != nil {
+			return val, err
+		} else if len(args) == 1 {
+			return strings.Trim(cast.ToString(val), cast.ToString(args[0])), nil
+		}
+		return strings.TrimSpace(cast.ToString(val)), nil
+	},
+	TransformLpad: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		return transformPad(TransformLpad, val, args)
+	},
+	TransformRpad: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		return transformPad(TransformRpad, val, args)
+	},
+	TransformSplitPart: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformSplitPart, args, 2, 2); err != nil {
+			return val, err
+		}
+		parts := strings.Split(cast.ToString(val), cast.ToString(args[0]))
+		index := cast.ToInt(args[1]) // 1-based, like SQL
+		if index < 1 || index > len(parts) {
+			return nil, nil
+		}
+		return parts[index-1], nil
+	},
+	TransformCoalesce: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformCoalesce, args, 1, 1); err != nil {
+			return val, err
+		} else if val == nil || val == "" {
+			return args[0], nil
+		}
+		return val, nil
+	},
+	TransformNullIf: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformNullIf, args, 1, 1); err != nil {
+			return val, err
+		} else if cast.ToString(val) == cast.ToString(args[0]) {
+			return nil, nil
+		}
+		return val, nil
+	},
+
+	// numeric
+	TransformToInt: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformToInt, args, 0, 0); err != nil {
+			return val, err
+		}
+		fVal, err := sp.toFloat64E(val)
+		if err != nil {
+			return val, err
+		}
+		return int64(fVal), nil
+	},
+	TransformToFloat: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformToFloat, args, 0, 0); err != nil {
+			return val, err
+		}
+		return sp.toFloat64E(val)
+	},
+	TransformRound: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformRound, args, 0, 1); err != nil {
+			return val, err
+		}
+		fVal, err := sp.toFloat64E(val)
+		if err != nil {
+			return val, err
+		}
+		decimals := 0
+		if len(args) == 1 {
+			decimals = cast.ToInt(args[0])
+		}
+		pow := math.Pow10(decimals)
+		return math.Round(fVal*pow) / pow, nil
+	},
+	TransformFloor: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		return transformMath(sp, TransformFloor, val, args, math.Floor)
+	},
+	TransformCeil: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		return transformMath(sp, TransformCeil, val, args, math.Ceil)
+	},
+	TransformAbs: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		return transformMath(sp, TransformAbs, val, args, math.Abs)
+	},
+	TransformAdd: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		return transformArithmetic(sp, TransformAdd, val, args, func(a, b float64) float64 { return a + b })
+	},
+	TransformMultiply: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		return transformArithmetic(sp, TransformMultiply, val, args, func(a, b float64) float64 { return a * b })
+	},
+	TransformDivide: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if len(args) == 1 && cast.ToFloat64(args[0]) == 0 {
+			return val, g.Error("divide by zero")
+		}
+		return transformArithmetic(sp, TransformDivide, val, args, func(a, b float64) float64 { return a / b })
+	},
+
+	// date
+	TransformParseDate: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformParseDate, args, 1, 1); err != nil {
+			return val, err
+		} else if tVal, ok := val.(time.Time); ok {
+			return tVal, nil
+		}
+		return time.Parse(StrftimeToGoLayout(cast.ToString(args[0])), strings.TrimSpace(cast.ToString(val)))
+	},
+	TransformFormatDate: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformFormatDate, args, 1, 1); err != nil {
+			return val, err
+		}
+		tVal, err := sp.CastToTime(val)
+		if err != nil {
+			return val, err
+		}
+		return tVal.Format(StrftimeToGoLayout(cast.ToString(args[0]))), nil
+	},
+	TransformDateTrunc: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformDateTrunc, args, 1, 1); err != nil {
+			return val, err
+		}
+		tVal, err := sp.CastToTime(val)
+		if err != nil {
+			return val, err
+		}
+		switch strings.ToLower(cast.ToString(args[0])) {
+		case "year":
+			return time.Date(tVal.Year(), 1, 1, 0, 0, 0, 0, tVal.Location()), nil
+		case "month":
+			return time.Date(tVal.Year(), tVal.Month(), 1, 0, 0, 0, 0, tVal.Location()), nil
+		case "day":
+			return time.Date(tVal.Year(), tVal.Month(), tVal.Day(), 0, 0, 0, 0, tVal.Location()), nil
+		case "hour":
+			return tVal.Truncate(time.Hour), nil
+		case "minute":
+			return tVal.Truncate(time.Minute), nil
+		case "second":
+			return tVal.Truncate(time.Second), nil
+		}
+		return val, g.Error("invalid date_trunc unit: %s", args[0])
+	},
+
+	// json
+	TransformJsonPath: func(sp *StreamProcessor, val any, args []any) (any, error) {
+		if err := transformArgsCount(TransformJsonPath, args, 1, 1); err != nil {
+			return val, err
+		}
+
+		var payload any
+		switch v := val.(type) {
+		case string:
+			if err := g.Unmarshal(v, &payload); err != nil {
+				return val, g.Error(err, "could not parse json value")
+			}
+		default:
+			payload = v
+		}
+
+		// accept JSONPath style prefix `$.`
+		expr := strings.TrimPrefix(strings.TrimPrefix(cast.ToString(args[0]), "$"), ".")
+		result, err := jmespath.Search(expr, payload)
+		if err != nil {
+			return val, g.Error(err, "could not search json path: %s", expr)
+		}
+
+		switch result.(type) {
+		case map[string]any, []any:
+			return g.Marshal(result), nil
+		}
+		return result, nil
+	},
+}
+
+// nullTransforms are the transforms which are applied to null values
+var nullTransforms = map[Transform]bool{TransformCoalesce: true}
+
+// maskTransforms are the masking transforms, which never keep the original value.
+// The hash transforms predate them, and keep applying to string columns only.
+var maskTransforms = map[Transform]bool{
+	TransformFakeAddress: true,
+	TransformFakeEmail:   true,
+	TransformFakeName:    true,
+	TransformFakePhone:   true,
+	TransformMaskEmail:   true,
+	TransformMaskLast4:   true,
+	TransformMaskPhone:   true,
+	TransformNullify:     true,
+	TransformTokenize:    true,
+}
+
+// TransformCall is a parsed transform expression,
+// such as `trim_space` or `substring(0, 10)`
+type TransformCall struct {
+	Name Transform
+	Args []any
+}
+
+// ParseTransformCall parses a transform expression. Arguments can be
+// single or double quoted strings, numbers, booleans or null.
+func ParseTransformCall(expr string) (tc TransformCall, err error) {
+	expr = strings.TrimSpace(expr)
+	parenIndex := strings.Index(expr, "(")
+	if parenIndex == -1 {
+		tc.Name = Transform(expr)
+		return
+	} else if !strings.HasSuffix(expr, ")") {
+		return tc, g.Error("invalid transform expression, missing closing parenthesis: %s", expr)
+	}
+
+	tc.Name = Transform(strings.TrimSpace(expr[:parenIndex]))
+	tc.Args = []any{}
+
+	argsStr := []rune(expr[parenIndex+1 : len(expr)-1])
+	for i := 0; i < len(argsStr); i++ {
+		r := argsStr[i]
+		switch {
+		case unicode.IsSpace(r):
+			continue
+		case r == '\'' || r == '"':
+			// quoted string, backslash escapes the quote and the backslash
+			var arg strings.Builder
+			closed := false
+			for i++; i < len(argsStr); i++ {
+				if argsStr[i] == '\\' && i+1 < len(argsStr) && (argsStr[i+1] == r || argsStr[i+1] == '\\') {
+					i++
+				} else if argsStr[i] == r {
+					closed = true
+					break
+				}
+				arg.WriteRune(argsStr[i])
+			}
+			if !closed {
+				return tc, g.Error("invalid transform expression, unclosed quote: %s", expr)
+			}
+			tc.Args = append(tc.Args, arg.String())
+		default:
+			// unquoted literal
+			j := i
+			for j < len(argsStr) && argsStr[j] != ',' {
+				j++
+			}
+			literal := strings.TrimSpace(string(argsStr[i:j]))
+			arg, err := parseTransformLiteral(literal)
+			if err != nil {
+				return tc, g.Error(err, "invalid transform expression: %s", expr)
+			}
+			tc.Args = append(tc.Args, arg)
+			i = j - 1
+		}
+
+		// expect comma or end
+		for i++; i < len(argsStr) && unicode.IsSpace(argsStr[i]); i++ {
+		}
+		if i < len(argsStr) && argsStr[i] != ',' {
+			return tc, g.Error("invalid transform expression, expected comma: %s", expr)
+		}
+	}
+
+	return
+}
+
+func parseTransformLiteral(literal string) (any, error) {
+	switch strings.ToLower(literal) {
+	case "true", "false":
+		return literal == "true", nil
+	case "null":
+		return nil, nil
+	}
+	if iVal, err := strconv.ParseInt(literal, 10, 64); err == nil {
+		return iVal, nil
+	}
+	if fVal, err := strconv.ParseFloat(literal, 64); err == nil {
+		return fVal, nil
+	}
+	return nil, g.Error("invalid argument: %s", literal)
+}
+
+// columnTransform is a transform resolved for a column
+type columnTransform struct {
+	TransformCall
+	f        TransformFunc
+	fArgs    TransformArgsFunc
+	nullable bool
+	mask     bool
+}
+
+// newColumnTransform parses the expression and resolves its function
+func newColumnTransform(expr string) (ct columnTransform, err error) {
+	ct.TransformCall, err = ParseTransformCall(expr)
+	if err != nil {
+		return ct, err
+	}
+
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+
+	if f, ok := Transforms[ct.Name]; ok {
+		if len(ct.Args) > 0 {
+			return ct, g.Error("transform '%s' does not accept arguments", ct.Name)
+		}
+		ct.f = f
+		ct.mask = maskTransforms[ct.Name]
+	} else if f, ok := TransformsArgs[ct.Name]; ok {
+		ct.fArgs = f
+		ct.nullable = nullTransforms[ct.Name]
+	} else {
+		return ct, g.Error("did find find transform named: '%s'", ct.Name)
+	}
+
+	return ct, nil
+}
+
+// apply applies the transform to the value. sVal is the string
+// representation of the value, used for transforms without arguments.
+func (ct columnTransform) apply(sp *StreamProcessor, val any, sVal string) (newVal any, err error) {
+	if ct.fArgs != nil {
+		newVal, err = ct.fArgs(sp, val, ct.Args)
+	} else {
+		newVal, err = ct.f(sp, sVal)
+	}
+
+	if err == ErrTransformNull {
+		return nil, nil
+	}
+	return
+}
+
+func transformArgsCount(name Transform, args []any, minCnt, maxCnt int) error {
+	if len(args) < minCnt || len(args) > maxCnt {
+		if minCnt == maxCnt {
+			return g.Error("transform '%s' expects %d argument(s), got %d", name, minCnt, len(args))
+		}
+		return g.Error("transform '%s' expects %d to %d arguments, got %d", name, minCnt, maxCnt, len(args))
+	}
+	return nil
+}
+
+func transformArgs2Strings(name Transform, args []any) (string, string, error) {
+	if err := transformArgsCount(name, args, 2, 2); err != nil {
+		return "", "", err
+	}
+	return cast.ToString(args[0]), cast.ToString(args[1]), nil
+}
+
+var transformRegexCache = sync.Map{}
+
+// transformRegex compiles the pattern, and caches it
+func transformRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := transformRegexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, g.Error(err, "invalid regex pattern: %s", pattern)
+	}
+	transformRegexCache.Store(pattern, re)
+	return re, nil
+}
+
+func transformPad(name Transform, val any, args []any) (any, error) {
+	if err := transformArgsCount(name, args, 1, 2); err != nil {
+		return val, err
+	}
+
+	padChar := " "
+	if len(args) > 1 {
+		padChar = cast.ToString(args[1])
+	}
+
+	sVal := cast.ToString(val)
+	padLen := cast.ToInt(args[0]) - len([]rune(sVal))
+	if padLen <= 0 || padChar == "" {
+		return sVal, nil
+	}
+
+	padding := []rune(strings.Repeat(padChar, padLen))[:padLen]
+	if name == TransformLpad {
+		return string(padding) + sVal, nil
+	}
+	return sVal + string(padding), nil
+}
+
+func transformMath(sp *StreamProcessor, name Transform, val any, args []any, f func(float64) float64) (any, error) {
+	if err := transformArgsCount(name, args, 0, 0); err != nil {
+		return val, err
+	}
+	fVal, err := sp.toFloat64E(val)
+	if err != nil {
+		return val, err
+	}
+	return f(fVal), nil
+}
+
+func transformArithmetic(sp *StreamProcessor, name Transform, val any, args []any, f func(float64, float64) float64) (any, error) {
+	if err := transformArgsCount(name, args, 1, 1); err != nil {
+		return val, err
+	}
+	fVal, err := sp.toFloat64E(val)
+	if err != nil {
+		return val, err
+	}
+	operand, err := cast.ToFloat64E(args[0])
+	if err != nil {
+		return val, g.Error(err, "invalid argument for transform '%s'", name)
+	}
+	return f(fVal, operand), nil
+}
+
+var strftimeReplacer = strings.NewReplacer(
+	"%Y", "2006",
+	"%y", "06",
+	"%m", "01",
+	"%d", "02",
+	"%e", "_2",
+	"%b", "Jan",
+	"%h", "Jan",
+	"%B", "January",
+	"%a", "Mon",
+	"%A", "Monday",
+	"%H", "15",
+	"%I", "03",
+	"%M", "04",
+	"%S", "05",
+	"%f", "000000",
+	"%L", "000",
+	"%p", "PM",
+	"%z", "-0700",
+	"%Z", "MST",
+	"%j", "002",
+	"%F", "2006-01-02",
+	"%T", "15:04:05",
+	"%%", "%",
+)
+
+// StrftimeToGoLayout converts a strftime format (such as `%d/%m/%Y`) into a Go time layout
+func StrftimeToGoLayout(format string) string {
+	return strftimeReplacer.Replace(format)
+}
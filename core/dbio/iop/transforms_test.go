@@ -3,8 +3,10 @@ package iop
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/flarco/g"
+	"github.com/spf13/cast"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -60,3 +62,128 @@ func TestDecode(t *testing.T) {
 		g.Info("%#v, %#v, %d", string(r), r, r)
 	}
 }
+
+func TestParseTransformCall(t *testing.T) {
+	tc, err := ParseTransformCall("trim_space")
+	assert.NoError(t, err)
+	assert.Equal(t, TransformTrimSpace, tc.Name)
+	assert.Empty(t, tc.Args)
+
+	tc, err = ParseTransformCall(`replace(',', '')`)
+	assert.NoError(t, err)
+	assert.Equal(t, TransformReplace, tc.Name)
+	assert.Equal(t, []any{",", ""}, tc.Args)
+
+	tc, err = ParseTransformCall(`regex_extract('(\d+)')`)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{`(\d+)`}, tc.Args)
+
+	tc, err = ParseTransformCall(`substring(0, 10)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{int64(0), int64(10)}, tc.Args)
+
+	tc, err = ParseTransformCall(`coalesce("it's \"N/A\"")`)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{`it's "N/A"`}, tc.Args)
+
+	tc, err = ParseTransformCall(`round(2.5, true, null)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{2.5, true, nil}, tc.Args)
+
+	_, err = ParseTransformCall(`replace(',', ''`)
+	assert.Error(t, err)
+	_, err = ParseTransformCall(`replace(',' '')`)
+	assert.Error(t, err)
+	_, err = ParseTransformCall(`replace(abc)`)
+	assert.Error(t, err)
+}
+
+func TestTransformsArgs(t *testing.T) {
+	sp := NewStreamProcessor()
+
+	type testCase struct {
+		expr     string
+		input    any
+		expected any
+	}
+
+	cases := []testCase{
+		{expr: `replace(',', '')`, input: "1,234,567", expected: "1234567"},
+		{expr: `regex_replace('\s+', ' ')`, input: "a   b \t c", expected: "a b c"},
+		{expr: `regex_extract('(\d+)')`, input: "order #1234 done", expected: "1234"},
+		{expr: `regex_extract('(\d+)')`, input: "no number", expected: nil},
+		{expr: `regex_extract('([a-z]+)-(\d+)', 2)`, input: "abc-42", expected: "42"},
+		{expr: `substring(0, 10)`, input: "2024-05-01T10:00:00", expected: "2024-05-01"},
+		{expr: `substring(5)`, input: "hello world", expected: " world"},
+		{expr: `left(3)`, input: "abcdef", expected: "abc"},
+		{expr: `right(3)`, input: "abcdef", expected: "def"},
+		{expr: `upper`, input: "abc", expected: "ABC"},
+		{expr: `trim('x')`, input: "xxabcxx", expected: "abc"},
+		{expr: `lpad(5, '0')`, input: "42", expected: "00042"},
+		{expr: `rpad(4)`, input: "ab", expected: "ab  "},
+		{expr: `split_part('|', 2)`, input: "a|b|c", expected: "b"},
+		{expr: `coalesce('N/A')`, input: nil, expected: "N/A"},
+		{expr: `null_if('N/A')`, input: "N/A", expected: nil},
+		{expr: `to_int`, input: "42.7", expected: int64(42)},
+		{expr: `round(2)`, input: "3.14159", expected: 3.14},
+		{expr: `abs`, input: -2.5, expected: 2.5},
+		{expr: `multiply(100)`, input: "0.25", expected: 25.0},
+		{expr: `divide(4)`, input: int64(10), expected: 2.5},
+		{expr: `parse_date('%d/%m/%Y')`, input: "31/05/2024", expected: time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{expr: `format_date('%Y%m%d')`, input: time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), expected: "20240531"},
+		{expr: `date_trunc('month')`, input: time.Date(2024, 5, 31, 10, 0, 0, 0, time.UTC), expected: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: `json_path('$.user.name')`, input: `{"user": {"name": "bob"}}`, expected: "bob"},
+		{expr: `json_path('items[0]')`, input: `{"items": [{"id": 1}]}`, expected: `{"id":1}`},
+	}
+
+	for _, c := range cases {
+		ct, err := newColumnTransform(c.expr)
+		if !assert.NoError(t, err, c.expr) {
+			continue
+		}
+		val, err := ct.apply(sp, c.input, cast.ToString(c.input))
+		assert.NoError(t, err, c.expr)
+		assert.Equal(t, c.expected, val, c.expr)
+	}
+
+	_, err := newColumnTransform(`trim_space(1)`)
+	assert.Error(t, err)
+	_, err = newColumnTransform(`not_a_transform`)
+	assert.Error(t, err)
+}
+
+func TestTransformsCastVal(t *testing.T) {
+	columns := NewColumnsFromFields("amount", "created", "code", "note")
+	for i := range columns {
+		columns[i].Type = StringType
+	}
+	ds := NewDatastream(columns)
+	ds.SetConfig(map[string]string{
+		"transforms": g.Marshal(map[string][]string{
+			"amount":  {`replace(',', '')`, `to_float`},
+			"created": {`parse_date('%d/%m/%Y')`},
+			"code":    {`upper`, `coalesce('N/A')`},
+			"note":    {`trim`, `null_if('-')`},
+		}),
+	})
+
+	rows := [][]any{
+		{"1,234.5", "31/05/2024", "ab", " hello "},
+		{"2,000", "01/06/2024", nil, " - "},
+	}
+	for i, row := range rows {
+		rows[i] = ds.Sp.CastRow(row, ds.Columns)
+	}
+
+	assert.Equal(t, 1234.5, cast.ToFloat64(rows[0][0]))
+	assert.Equal(t, time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), rows[0][1])
+	assert.Equal(t, "AB", rows[0][2])
+	assert.Equal(t, "hello", rows[0][3])
+
+	assert.Equal(t, 2000.0, cast.ToFloat64(rows[1][0]))
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), rows[1][1])
+	assert.Equal(t, "N/A", rows[1][2])
+	assert.Nil(t, rows[1][3])
+	assert.True(t, ds.Columns[0].IsNumber())
+	assert.True(t, ds.Columns[1].IsDate())
+}
@@ -0,0 +1,146 @@
+package iop
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+)
+
+// XmlConfig is the configuration of the XML writer
+type XmlConfig struct {
+	Root       string   `json:"root,omitempty" yaml:"root,omitempty"`             // root element name, defaults to `data`
+	Row        string   `json:"row,omitempty" yaml:"row,omitempty"`               // row element name, defaults to `row`
+	Attributes []string `json:"attributes,omitempty" yaml:"attributes,omitempty"` // columns written as row attributes, `*` for all
+}
+
+// XmlWriter writes rows as XML elements
+type XmlWriter struct {
+	Writer  io.Writer
+	config  XmlConfig
+	columns Columns
+	names   []string
+	attrs   []bool
+	sp      *StreamProcessor
+	written int64
+}
+
+var (
+	xmlNameRegex        = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	xmlInvalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+)
+
+// NewXmlWriter creates a new XML writer and writes the opening root element
+func NewXmlWriter(w io.Writer, columns Columns, config XmlConfig, sp *StreamProcessor) (xw *XmlWriter, err error) {
+	config.Root = lo.Ternary(config.Root == "", "data", config.Root)
+	config.Row = lo.Ternary(config.Row == "", "row", config.Row)
+
+	if !xmlNameRegex.MatchString(config.Root) {
+		return nil, g.Error("invalid XML root element name: %s", config.Root)
+	} else if !xmlNameRegex.MatchString(config.Row) {
+		return nil, g.Error("invalid XML row element name: %s", config.Row)
+	}
+
+	if sp == nil {
+		sp = NewStreamProcessor()
+	}
+
+	xw = &XmlWriter{
+		Writer:  w,
+		config:  config,
+		columns: columns,
+		names:   make([]string, len(columns)),
+		attrs:   make([]bool, len(columns)),
+		sp:      sp,
+	}
+
+	allAttrs := g.In("*", config.Attributes...)
+	attrMap := map[string]bool{}
+	for _, name := range config.Attributes {
+		attrMap[strings.ToLower(name)] = true
+	}
+
+	for i, col := range columns {
+		xw.names[i] = xmlName(col.Name, i)
+		xw.attrs[i] = allAttrs || attrMap[strings.ToLower(col.Name)]
+	}
+
+	err = xw.write([]byte(xml.Header + "<" + config.Root + ">\n"))
+	if err != nil {
+		return nil, g.Error(err, "could not write XML header")
+	}
+
+	return xw, nil
+}
+
+// xmlName returns a valid XML element name
+func xmlName(name string, index int) string {
+	name = xmlInvalidNameChars.ReplaceAllString(name, "_")
+	if name == "" {
+		return g.F("col_%d", index+1)
+	} else if !xmlNameRegex.MatchString(name) || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return "_" + name
+	}
+	return name
+}
+
+// WriteRow writes a row element. Null values are omitted.
+func (xw *XmlWriter) WriteRow(row []any) (err error) {
+	var attrs, elems bytes.Buffer
+
+	for i, col := range xw.columns {
+		if i >= len(row) || row[i] == nil {
+			continue
+		}
+
+		val := xw.sp.CastToString(i, row[i], col.Type)
+
+		if xw.attrs[i] {
+			attrs.WriteString(" " + xw.names[i] + `="`)
+			if err = xml.EscapeText(&attrs, []byte(val)); err != nil {
+				return g.Error(err, "could not escape value for column %s", col.Name)
+			}
+			attrs.WriteString(`"`)
+			continue
+		}
+
+		elems.WriteString("<" + xw.names[i] + ">")
+		if err = xml.EscapeText(&elems, []byte(val)); err != nil {
+			return g.Error(err, "could not escape value for column %s", col.Name)
+		}
+		elems.WriteString("</" + xw.names[i] + ">")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("  <" + xw.config.Row)
+	buf.Write(attrs.Bytes())
+	if elems.Len() == 0 {
+		buf.WriteString("/>\n")
+	} else {
+		buf.WriteString(">")
+		buf.Write(elems.Bytes())
+		buf.WriteString("</" + xw.config.Row + ">\n")
+	}
+
+	return xw.write(buf.Bytes())
+}
+
+func (xw *XmlWriter) write(data []byte) error {
+	n, err := xw.Writer.Write(data)
+	xw.written += int64(n)
+	return err
+}
+
+// BytesWritten returns the number of bytes written
+func (xw *XmlWriter) BytesWritten() int64 {
+	return xw.written
+}
+
+// Close writes the closing root element
+func (xw *XmlWriter) Close() error {
+	return xw.write([]byte("</" + xw.config.Root + ">\n"))
+}
@@ -0,0 +1,64 @@
+package iop
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXmlWriter(t *testing.T) {
+	columns := NewColumns(Columns{
+		{Name: "id", Type: BigIntType},
+		{Name: "name", Type: StringType},
+		{Name: "note text", Type: StringType},
+		{Name: "1st", Type: StringType},
+	}...)
+
+	var buf bytes.Buffer
+	xw, err := NewXmlWriter(&buf, columns, XmlConfig{Root: "records", Row: "record", Attributes: []string{"ID"}}, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, xw.WriteRow([]any{int64(1), `Tom & "Jerry"`, "<b>bold</b>", nil}))
+	assert.NoError(t, xw.WriteRow([]any{int64(2), nil, nil, "x"}))
+	assert.NoError(t, xw.Close())
+
+	expected := xml.Header + `<records>
+  <record id="1"><name>Tom &amp; &#34;Jerry&#34;</name><note_text>&lt;b&gt;bold&lt;/b&gt;</note_text></record>
+  <record id="2"><_1st>x</_1st></record>
+</records>
+`
+	assert.Equal(t, expected, buf.String())
+	assert.EqualValues(t, len(expected), xw.BytesWritten())
+
+	// output is well-formed
+	type record struct {
+		ID   string `xml:"id,attr"`
+		Name string `xml:"name"`
+		Note string `xml:"note_text"`
+	}
+	var records struct {
+		Records []record `xml:"record"`
+	}
+	if assert.NoError(t, xml.Unmarshal(buf.Bytes(), &records)) && assert.Len(t, records.Records, 2) {
+		assert.Equal(t, `Tom & "Jerry"`, records.Records[0].Name)
+		assert.Equal(t, "<b>bold</b>", records.Records[0].Note)
+		assert.Equal(t, "2", records.Records[1].ID)
+	}
+
+	// all attributes, empty row
+	buf.Reset()
+	xw, err = NewXmlWriter(&buf, columns, XmlConfig{Attributes: []string{"*"}}, nil)
+	if assert.NoError(t, err) {
+		assert.NoError(t, xw.WriteRow([]any{int64(3), "a\nb", nil, nil}))
+		assert.NoError(t, xw.WriteRow([]any{nil, nil, nil, nil}))
+		assert.NoError(t, xw.Close())
+		assert.Equal(t, xml.Header+"<data>\n  <row id=\"3\" name=\"a&#xA;b\"/>\n  <row/>\n</data>\n", buf.String())
+	}
+
+	_, err = NewXmlWriter(&buf, columns, XmlConfig{Root: "my root"}, nil)
+	assert.Error(t, err)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/flarco/g"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
 )
 
 var (
@@ -222,6 +223,34 @@ func GetHomeDirConnsMap() (connsMap map[string]map[string]any, err error) {
 	return connsMap, nil
 }
 
+// GetHomeDirPoliciesMap returns the masking policies declared
+// in the env files of the home directories and in ENV_YAML
+func GetHomeDirPoliciesMap() (policiesMap map[string]map[string]any, err error) {
+	defer envMux.Unlock()
+	envMux.Lock()
+	policiesMap = map[string]map[string]any{}
+	for _, homeDir := range HomeDirs {
+		envFilePath := GetEnvFilePath(homeDir)
+		if g.PathExists(envFilePath) {
+			for k, v := range LoadEnvFile(envFilePath).Policies {
+				policiesMap[strings.ToLower(k)] = v
+			}
+		}
+	}
+
+	if content := os.Getenv("ENV_YAML"); content != "" {
+		ef := EnvFile{}
+		if err = yaml.Unmarshal([]byte(content), &ef); err != nil {
+			return policiesMap, g.Error(err, "could not parse ENV_YAML content")
+		}
+		for k, v := range ef.Policies {
+			policiesMap[strings.ToLower(k)] = v
+		}
+	}
+
+	return policiesMap, nil
+}
+
 func readConnectionsMap(env map[string]interface{}) (conns map[string]map[string]any, err error) {
 	conns = map[string]map[string]any{}
 
@@ -15,6 +15,7 @@ import (
 type EnvFile struct {
 	Connections map[string]map[string]interface{} `json:"connections,omitempty" yaml:"connections,omitempty"`
 	Variables   map[string]interface{}            `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Policies    map[string]map[string]interface{} `json:"policies,omitempty" yaml:"policies,omitempty"`
 
 	Path       string `json:"-" yaml:"-"`
 	TopComment string `json:"-" yaml:"-"`
@@ -67,6 +68,10 @@ func (ef *EnvFile) WriteEnvFile() (err error) {
 		{Key: "variables", Value: ef.Variables},
 	}
 
+	if len(ef.Policies) > 0 {
+		efMap = append(efMap, yaml.MapItem{Key: "policies", Value: ef.Policies})
+	}
+
 	envBytes, err := yaml.Marshal(efMap)
 	if err != nil {
 		return g.Error(err, "could not marshal into YAML")
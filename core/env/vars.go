@@ -24,6 +24,8 @@ var envVars = []string{
 
 	"SSH_TUNNEL", "SSH_PRIVATE_KEY", "SSH_PUBLIC_KEY",
 
+	"PGP_PUBLIC_KEY", "PGP_PRIVATE_KEY", "PGP_PASSPHRASE",
+
 	"SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "SMTP_FROM_EMAIL", "SMTP_REPLY_EMAIL",
 
 	"HTTP_USER", "HTTP_PASSWORD", "GSHEET_CLIENT_JSON_BODY",
@@ -71,6 +71,44 @@ var AllColumnCasing = []struct {
 	{SnakeColumnCasing, "SnakeColumnCasing"},
 }
 
+// AfterLoadAction is the action applied to the source files, after a successful load
+type AfterLoadAction string
+
+const (
+	AfterLoadNone   AfterLoadAction = "none"   // leaves the files as is. The default.
+	AfterLoadMove   AfterLoadAction = "move"   // moves the files into the archive path
+	AfterLoadDelete AfterLoadAction = "delete" // deletes the files
+)
+
+var AllAfterLoadAction = []struct {
+	Value  AfterLoadAction
+	TSName string
+}{
+	{AfterLoadNone, "AfterLoadNone"},
+	{AfterLoadMove, "AfterLoadMove"},
+	{AfterLoadDelete, "AfterLoadDelete"},
+}
+
+// AfterLoadOptions are the options to process the source files after a successful load
+type AfterLoadOptions struct {
+	Action      AfterLoadAction `json:"action,omitempty" yaml:"action,omitempty"`
+	ArchivePath string          `json:"archive_path,omitempty" yaml:"archive_path,omitempty"`
+}
+
+// Validate validates the after load options
+func (alo *AfterLoadOptions) Validate() error {
+	switch alo.Action {
+	case AfterLoadNone, AfterLoadDelete, "":
+	case AfterLoadMove:
+		if alo.ArchivePath == "" {
+			return g.Error("after_load action 'move' requires an archive_path")
+		}
+	default:
+		return g.Error("invalid after_load action: %s (must be move, delete or none)", alo.Action)
+	}
+	return nil
+}
+
 // NewConfig return a config object from a YAML / JSON string
 func NewConfig(cfgStr string) (cfg *Config, err error) {
 	// set default, unmarshalling will overwrite
@@ -263,6 +301,11 @@ func (cfg *Config) sourceIsFile() bool {
 	return cfg.Options.StdIn || cfg.SrcConn.Info().Type.IsFile()
 }
 
+// trackFiles means the processed source files are tracked in a file state
+func (cfg *Config) trackFiles() bool {
+	return cfg.Source.Options != nil && cast.ToBool(cfg.Source.Options.TrackFiles)
+}
+
 func (cfg *Config) DetermineType() (Type JobType, err error) {
 
 	srcFileProvided := cfg.sourceIsFile()
@@ -301,7 +344,7 @@ func (cfg *Config) DetermineType() (Type JobType, err error) {
 		} else if srcFileProvided && cfg.Source.UpdateKey == slingLoadedAtColumn {
 			// need to loaded_at column for file incremental
 			cfg.MetadataLoadedAt = g.Bool(true)
-		} else if cfg.Source.UpdateKey == "" && len(cfg.Source.PrimaryKey()) == 0 {
+		} else if cfg.Source.UpdateKey == "" && len(cfg.Source.PrimaryKey()) == 0 && !(srcFileProvided && cfg.trackFiles()) {
 			err = g.Error("must specify value for 'update_key' and/or 'primary_key' for incremental mode. See docs for more details: https://docs.slingdata.io/sling-cli/run/configuration")
 			if args := os.Getenv("SLING_CLI_ARGS"); strings.Contains(args, "-src-conn") || strings.Contains(args, "-tgt-conn") {
 				err = g.Error("must specify value for '--update-key' and/or '--primary-key' for incremental mode. See docs for more details: https://docs.slingdata.io/sling-cli/run/configuration")
@@ -589,6 +632,33 @@ func (cfg *Config) Prepare() (err error) {
 		}
 	}
 
+	// validate after load options
+	if so := cfg.Source.Options; so != nil && so.AfterLoad != nil {
+		if err = so.AfterLoad.Validate(); err != nil {
+			return g.Error(err, "invalid source options")
+		} else if so.AfterLoad.Action != AfterLoadNone && so.AfterLoad.Action != "" && !cfg.SrcConn.Type.IsFile() {
+			return g.Error("after_load is only supported for file sources")
+		}
+	}
+
+	// validate where option
+	if so := cfg.Source.Options; so != nil && so.Where != nil && !cfg.SrcConn.Type.IsFile() {
+		return g.Error("where is only supported for file sources")
+	}
+
+	// validate encryption options
+	if so := cfg.Source.Options; so != nil && so.Decrypt != nil && !cfg.SrcConn.Type.IsFile() {
+		return g.Error("decrypt is only supported for file sources")
+	}
+	if to := cfg.Target.Options; to != nil && to.Encrypt != nil && !cfg.TgtConn.Type.IsFile() {
+		return g.Error("encrypt is only supported for file targets")
+	}
+
+	// register masking policies, to be referenced in transforms
+	if err = LoadMaskPolicies(); err != nil {
+		return g.Error(err, "could not load masking policies")
+	}
+
 	// validate conn data keys
 	for key := range cfg.SrcConn.Data {
 		if strings.Contains(key, ":") {
@@ -765,7 +835,7 @@ func (cfg *Config) GetFormatMap() (m map[string]any, err error) {
 			m["stream_file_ext"] = fileNameArr[len(fileNameArr)-1]
 			if len(fileNameArr) >= 3 {
 				// in case of compression (2 extension tokens)
-				for _, suff := range []string{"gz", "zst", "snappy"} {
+				for _, suff := range []string{"gz", "zst", "snappy", "lz4", "br", "bz2", "xz"} {
 					if m["stream_file_ext"] == suff {
 						m["stream_file_ext"] = fileNameArr[len(fileNameArr)-2] + "_" + fileNameArr[len(fileNameArr)-1]
 						break
@@ -988,25 +1058,38 @@ func (t *Target) MD5() string {
 
 // SourceOptions are connection and stream processing options
 type SourceOptions struct {
-	TrimSpace      *bool               `json:"trim_space,omitempty" yaml:"trim_space,omitempty"`
-	EmptyAsNull    *bool               `json:"empty_as_null,omitempty" yaml:"empty_as_null,omitempty"`
-	Header         *bool               `json:"header,omitempty" yaml:"header,omitempty"`
-	Flatten        *bool               `json:"flatten,omitempty" yaml:"flatten,omitempty"`
-	FieldsPerRec   *int                `json:"fields_per_rec,omitempty" yaml:"fields_per_rec,omitempty"`
-	Compression    *iop.CompressorType `json:"compression,omitempty" yaml:"compression,omitempty"`
-	Format         *filesys.FileType   `json:"format,omitempty" yaml:"format,omitempty"`
-	NullIf         *string             `json:"null_if,omitempty" yaml:"null_if,omitempty"`
-	DatetimeFormat string              `json:"datetime_format,omitempty" yaml:"datetime_format,omitempty"`
-	SkipBlankLines *bool               `json:"skip_blank_lines,omitempty" yaml:"skip_blank_lines,omitempty"`
-	Delimiter      string              `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
-	Escape         string              `json:"escape,omitempty" yaml:"escape,omitempty"`
-	MaxDecimals    *int                `json:"max_decimals,omitempty" yaml:"max_decimals,omitempty"`
-	JmesPath       *string             `json:"jmespath,omitempty" yaml:"jmespath,omitempty"`
-	Sheet          *string             `json:"sheet,omitempty" yaml:"sheet,omitempty"`
-	Range          *string             `json:"range,omitempty" yaml:"range,omitempty"`
-	Limit          *int                `json:"limit,omitempty" yaml:"limit,omitempty"`
-	Columns        any                 `json:"columns,omitempty" yaml:"columns,omitempty"`
-	Transforms     any                 `json:"transforms,omitempty" yaml:"transforms,omitempty"`
+	TrimSpace         *bool               `json:"trim_space,omitempty" yaml:"trim_space,omitempty"`
+	EmptyAsNull       *bool               `json:"empty_as_null,omitempty" yaml:"empty_as_null,omitempty"`
+	Header            *bool               `json:"header,omitempty" yaml:"header,omitempty"`
+	Flatten           *bool               `json:"flatten,omitempty" yaml:"flatten,omitempty"`
+	FieldsPerRec      *int                `json:"fields_per_rec,omitempty" yaml:"fields_per_rec,omitempty"`
+	Compression       *iop.CompressorType `json:"compression,omitempty" yaml:"compression,omitempty"`
+	Format            *filesys.FileType   `json:"format,omitempty" yaml:"format,omitempty"`
+	NullIf            *string             `json:"null_if,omitempty" yaml:"null_if,omitempty"`
+	DatetimeFormat    string              `json:"datetime_format,omitempty" yaml:"datetime_format,omitempty"`
+	SkipBlankLines    *bool               `json:"skip_blank_lines,omitempty" yaml:"skip_blank_lines,omitempty"`
+	Delimiter         string              `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
+	Escape            string              `json:"escape,omitempty" yaml:"escape,omitempty"`
+	MaxDecimals       *int                `json:"max_decimals,omitempty" yaml:"max_decimals,omitempty"`
+	JmesPath          *string             `json:"jmespath,omitempty" yaml:"jmespath,omitempty"`
+	Sheet             *string             `json:"sheet,omitempty" yaml:"sheet,omitempty"`
+	Sheets            []string            `json:"sheets,omitempty" yaml:"sheets,omitempty"`
+	Range             *string             `json:"range,omitempty" yaml:"range,omitempty"`
+	AvroNativeTypes   *bool               `json:"avro_native_types,omitempty" yaml:"avro_native_types,omitempty"`
+	PartitionFilter   *string             `json:"partition_filter,omitempty" yaml:"partition_filter,omitempty"`
+	HivePartitioning  *bool               `json:"hive_partitioning,omitempty" yaml:"hive_partitioning,omitempty"`
+	Where             *string             `json:"where,omitempty" yaml:"where,omitempty"`
+	SnapshotID        *int64              `json:"snapshot_id,omitempty" yaml:"snapshot_id,omitempty"`
+	SnapshotTimestamp *string             `json:"snapshot_timestamp,omitempty" yaml:"snapshot_timestamp,omitempty"`
+	SkipHeaderLines   *int                `json:"skip_header_lines,omitempty" yaml:"skip_header_lines,omitempty"`
+	SkipTrailerLines  *int                `json:"skip_trailer_lines,omitempty" yaml:"skip_trailer_lines,omitempty"`
+	Limit             *int                `json:"limit,omitempty" yaml:"limit,omitempty"`
+	Columns           any                 `json:"columns,omitempty" yaml:"columns,omitempty"`
+	Transforms        any                 `json:"transforms,omitempty" yaml:"transforms,omitempty"`
+	AfterLoad         *AfterLoadOptions   `json:"after_load,omitempty" yaml:"after_load,omitempty"`
+	TrackFiles        *bool               `json:"track_files,omitempty" yaml:"track_files,omitempty"`
+	ReprocessChanged  *bool               `json:"reprocess_changed,omitempty" yaml:"reprocess_changed,omitempty"`
+	Decrypt           *iop.EncryptConfig  `json:"decrypt,omitempty" yaml:"decrypt,omitempty"`
 
 	extraTransforms []string `json:"-" yaml:"-"`
 }
@@ -1028,6 +1111,23 @@ type TargetOptions struct {
 	AddNewColumns    *bool               `json:"add_new_columns,omitempty" yaml:"add_new_columns,omitempty"`
 	AdjustColumnType *bool               `json:"adjust_column_type,omitempty" yaml:"adjust_column_type,omitempty"`
 	ColumnCasing     *ColumnCasing       `json:"column_casing,omitempty" yaml:"column_casing,omitempty"`
+	XmlRoot          string              `json:"xml_root,omitempty" yaml:"xml_root,omitempty"`
+	XmlRow           string              `json:"xml_row,omitempty" yaml:"xml_row,omitempty"`
+	XmlAttributes    []string            `json:"xml_attributes,omitempty" yaml:"xml_attributes,omitempty"`
+	PartitionBy      any                 `json:"partition_by,omitempty" yaml:"partition_by,omitempty"`
+	PartitionMaxOpen int                 `json:"partition_max_open,omitempty" yaml:"partition_max_open,omitempty"`
+	StripeSize       *int64              `json:"stripe_size,omitempty" yaml:"stripe_size,omitempty"`
+	Columns          any                 `json:"columns,omitempty" yaml:"columns,omitempty"`
+	Sheet            string              `json:"sheet,omitempty" yaml:"sheet,omitempty"`
+	Range            string              `json:"range,omitempty" yaml:"range,omitempty"`
+	Mode             string              `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Atomic           *bool               `json:"atomic,omitempty" yaml:"atomic,omitempty"`
+	SuccessFile      *bool               `json:"success_file,omitempty" yaml:"success_file,omitempty"`
+	Manifest         *bool               `json:"manifest,omitempty" yaml:"manifest,omitempty"`
+	Encrypt          *iop.EncryptConfig  `json:"encrypt,omitempty" yaml:"encrypt,omitempty"`
+	UploadPartSize   *int64              `json:"upload_part_size,omitempty" yaml:"upload_part_size,omitempty"`
+	UploadParallel   *int                `json:"upload_parallel,omitempty" yaml:"upload_parallel,omitempty"`
+	UploadRetries    *int                `json:"upload_retries,omitempty" yaml:"upload_retries,omitempty"`
 
 	TableKeys database.TableKeys `json:"table_keys,omitempty" yaml:"table_keys,omitempty"`
 	TableTmp  string             `json:"table_tmp,omitempty" yaml:"table_tmp,omitempty"`
@@ -1131,9 +1231,27 @@ func (o *SourceOptions) SetDefaults(sourceOptions SourceOptions) {
 	if o.Sheet == nil {
 		o.Sheet = sourceOptions.Sheet
 	}
+	if o.Sheets == nil {
+		o.Sheets = sourceOptions.Sheets
+	}
 	if o.Range == nil {
 		o.Range = sourceOptions.Range
 	}
+	if o.PartitionFilter == nil {
+		o.PartitionFilter = sourceOptions.PartitionFilter
+	}
+	if o.HivePartitioning == nil {
+		o.HivePartitioning = sourceOptions.HivePartitioning
+	}
+	if o.Where == nil {
+		o.Where = sourceOptions.Where
+	}
+	if o.SnapshotID == nil {
+		o.SnapshotID = sourceOptions.SnapshotID
+	}
+	if o.SnapshotTimestamp == nil {
+		o.SnapshotTimestamp = sourceOptions.SnapshotTimestamp
+	}
 	if o.DatetimeFormat == "" {
 		o.DatetimeFormat = sourceOptions.DatetimeFormat
 	}
@@ -1149,12 +1267,33 @@ func (o *SourceOptions) SetDefaults(sourceOptions SourceOptions) {
 	if o.MaxDecimals == nil {
 		o.MaxDecimals = sourceOptions.MaxDecimals
 	}
+	if o.SkipHeaderLines == nil {
+		o.SkipHeaderLines = sourceOptions.SkipHeaderLines
+	}
+	if o.SkipTrailerLines == nil {
+		o.SkipTrailerLines = sourceOptions.SkipTrailerLines
+	}
 	if o.Columns == nil {
 		o.Columns = sourceOptions.Columns
 	}
 	if o.Transforms == nil {
 		o.Transforms = sourceOptions.Transforms
 	}
+	if o.AfterLoad == nil {
+		o.AfterLoad = sourceOptions.AfterLoad
+	}
+	if o.Decrypt == nil {
+		o.Decrypt = sourceOptions.Decrypt
+	}
+	if o.TrackFiles == nil {
+		o.TrackFiles = sourceOptions.TrackFiles
+	}
+	if o.ReprocessChanged == nil {
+		o.ReprocessChanged = sourceOptions.ReprocessChanged
+	}
+	if o.AvroNativeTypes == nil {
+		o.AvroNativeTypes = sourceOptions.AvroNativeTypes
+	}
 
 }
 
@@ -1184,6 +1323,57 @@ func (o *TargetOptions) SetDefaults(targetOptions TargetOptions) {
 	if o.FileMaxBytes == nil {
 		o.FileMaxBytes = targetOptions.FileMaxBytes
 	}
+	if o.XmlRoot == "" {
+		o.XmlRoot = targetOptions.XmlRoot
+	}
+	if o.XmlRow == "" {
+		o.XmlRow = targetOptions.XmlRow
+	}
+	if o.XmlAttributes == nil {
+		o.XmlAttributes = targetOptions.XmlAttributes
+	}
+	if o.PartitionBy == nil {
+		o.PartitionBy = targetOptions.PartitionBy
+	}
+	if o.PartitionMaxOpen == 0 {
+		o.PartitionMaxOpen = targetOptions.PartitionMaxOpen
+	}
+	if o.StripeSize == nil {
+		o.StripeSize = targetOptions.StripeSize
+	}
+	if o.Columns == nil {
+		o.Columns = targetOptions.Columns
+	}
+	if o.Sheet == "" {
+		o.Sheet = targetOptions.Sheet
+	}
+	if o.Range == "" {
+		o.Range = targetOptions.Range
+	}
+	if o.Mode == "" {
+		o.Mode = targetOptions.Mode
+	}
+	if o.Atomic == nil {
+		o.Atomic = targetOptions.Atomic
+	}
+	if o.SuccessFile == nil {
+		o.SuccessFile = targetOptions.SuccessFile
+	}
+	if o.Manifest == nil {
+		o.Manifest = targetOptions.Manifest
+	}
+	if o.Encrypt == nil {
+		o.Encrypt = targetOptions.Encrypt
+	}
+	if o.UploadPartSize == nil {
+		o.UploadPartSize = targetOptions.UploadPartSize
+	}
+	if o.UploadParallel == nil {
+		o.UploadParallel = targetOptions.UploadParallel
+	}
+	if o.UploadRetries == nil {
+		o.UploadRetries = targetOptions.UploadRetries
+	}
 	if o.UseBulk == nil {
 		o.UseBulk = targetOptions.UseBulk
 	}
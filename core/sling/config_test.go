@@ -2,11 +2,14 @@ package sling
 
 import (
 	"math"
+	"os"
+	"path"
 	"testing"
 	"time"
 
 	"github.com/flarco/g"
 	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 	"github.com/spf13/cast"
 	"github.com/stretchr/testify/assert"
@@ -86,3 +89,195 @@ func TestColumnCasing(t *testing.T) {
 	applyColumnCasingToDf(df, dbio.TypeDbDuckDb, &snakeCasing)
 	assert.Equal(t, "dhl_original_tracking_number", df.Columns[0].Name)
 }
+
+func TestSourceOptionsSheets(t *testing.T) {
+	sheet := "Sheet1!A1:B"
+	task := &TaskExecution{Config: &Config{}}
+
+	task.Config.Source.Options = &SourceOptions{Sheet: &sheet}
+	options := task.sourceOptionsMap()
+	assert.Equal(t, sheet, options["sheet"])
+
+	// a list of sheets is read into one stream
+	task.Config.Source.Options = &SourceOptions{Sheet: &sheet, Sheets: []string{"2023_*", "summary"}}
+	options = task.sourceOptionsMap()
+	assert.Equal(t, `["2023_*","summary"]`, options["sheet"])
+	assert.NotContains(t, options, "sheets")
+}
+
+func TestAfterLoad(t *testing.T) {
+	folder := t.TempDir()
+	inFolder := path.Join(folder, "in")
+	assert.NoError(t, os.MkdirAll(path.Join(inFolder, "2024"), 0755))
+	assert.NoError(t, os.WriteFile(path.Join(inFolder, "a.csv"), []byte("id,name\n1,a\n2,b\n"), 0644))
+	assert.NoError(t, os.WriteFile(path.Join(inFolder, "2024", "b.csv"), []byte("id,name\n3,c\n"), 0644))
+
+	// invalid options
+	cfg := &Config{}
+	cfg.Source.Conn, cfg.Target.Conn = "LOCAL", "LOCAL"
+	cfg.Source.Stream = "file://" + inFolder + "/"
+	cfg.Target.Object = "file://" + path.Join(folder, "out.csv")
+	cfg.Source.Options = &SourceOptions{AfterLoad: &AfterLoadOptions{Action: AfterLoadMove}}
+	assert.ErrorContains(t, cfg.Prepare(), "requires an archive_path")
+
+	cfg.Source.Options.AfterLoad = &AfterLoadOptions{Action: "copy"}
+	assert.ErrorContains(t, cfg.Prepare(), "invalid after_load action")
+
+	// move files into archive, keeping sub-folders
+	cfg.Source.Options.AfterLoad = &AfterLoadOptions{
+		Action:      AfterLoadMove,
+		ArchivePath: "file://" + path.Join(folder, "archive"),
+	}
+	if !assert.NoError(t, cfg.Prepare()) {
+		return
+	}
+
+	task := NewTask(NewExecID(), cfg)
+	if assert.NoError(t, task.Execute()) {
+		assert.EqualValues(t, 3, task.GetCount())
+		assert.Len(t, task.ProcessedFiles, 2)
+		assert.FileExists(t, path.Join(folder, "archive", "a.csv"))
+		assert.FileExists(t, path.Join(folder, "archive", "2024", "b.csv"))
+		assert.NoFileExists(t, path.Join(inFolder, "a.csv"))
+		assert.NoFileExists(t, path.Join(inFolder, "2024", "b.csv"))
+		assert.FileExists(t, path.Join(folder, "out.csv"))
+	}
+
+	// delete files
+	cfg = &Config{}
+	cfg.Source.Conn, cfg.Target.Conn = "LOCAL", "LOCAL"
+	cfg.Source.Stream = "file://" + path.Join(folder, "archive", "a.csv")
+	cfg.Target.Object = "file://" + path.Join(folder, "out2.csv")
+	cfg.Source.Options = &SourceOptions{AfterLoad: &AfterLoadOptions{Action: AfterLoadDelete}}
+	if !assert.NoError(t, cfg.Prepare()) {
+		return
+	}
+
+	task = NewTask(NewExecID(), cfg)
+	if assert.NoError(t, task.Execute()) {
+		assert.EqualValues(t, 2, task.GetCount())
+		assert.Equal(t, []string{"file://" + path.Join(folder, "archive", "a.csv")}, task.ProcessedFiles)
+		assert.NoFileExists(t, path.Join(folder, "archive", "a.csv"))
+		assert.FileExists(t, path.Join(folder, "archive", "2024", "b.csv"))
+	}
+}
+
+func TestFileState(t *testing.T) {
+	folder := t.TempDir()
+
+	inFolder := path.Join(folder, "in")
+	assert.NoError(t, os.MkdirAll(inFolder, 0755))
+	assert.NoError(t, os.WriteFile(path.Join(inFolder, "a.csv"), []byte("id,name\n1,a\n2,b\n"), 0644))
+	assert.NoError(t, os.WriteFile(path.Join(inFolder, "b.csv"), []byte("id,name\n3,c\n"), 0644))
+
+	run := func(mode Mode, reprocessChanged bool) *TaskExecution {
+		cfg := &Config{Mode: mode}
+		cfg.Source.Conn, cfg.Target.Conn = "LOCAL", "LOCAL"
+		cfg.Source.Stream = "file://" + inFolder + "/"
+		cfg.Source.Options = &SourceOptions{TrackFiles: g.Bool(true), ReprocessChanged: g.Bool(reprocessChanged)}
+		cfg.Target.Object = "file://" + path.Join(folder, "out", "data.csv")
+
+		task := NewTask(NewExecID(), cfg)
+		assert.NoError(t, task.Execute())
+		return task
+	}
+
+	// first run loads all files, the state is saved next to the target
+	task := run(IncrementalMode, false)
+	assert.EqualValues(t, 3, task.GetCount())
+	if assert.NotNil(t, task.fileState) {
+		assert.Len(t, task.fileState.Files, 2)
+	}
+	stateFiles, _ := os.ReadDir(path.Join(folder, "out", "_sling_state"))
+	assert.Len(t, stateFiles, 1)
+
+	// no new files
+	task = run(IncrementalMode, false)
+	assert.EqualValues(t, 0, task.GetCount())
+	assert.Contains(t, task.ProgressHist[len(task.ProgressHist)-2], "no new files found")
+
+	// late file, with an older modification time
+	cPath := path.Join(inFolder, "c.csv")
+	assert.NoError(t, os.WriteFile(cPath, []byte("id,name\n4,d\n"), 0644))
+	old := time.Now().Add(-72 * time.Hour)
+	assert.NoError(t, os.Chtimes(cPath, old, old))
+
+	task = run(IncrementalMode, false)
+	assert.EqualValues(t, 1, task.GetCount())
+
+	// re-uploaded file with the same name is skipped, unless reprocessing
+	assert.NoError(t, os.WriteFile(path.Join(inFolder, "a.csv"), []byte("id,name\n1,a\n2,b\n5,e\n"), 0644))
+
+	task = run(IncrementalMode, false)
+	assert.EqualValues(t, 0, task.GetCount())
+
+	task = run(IncrementalMode, true)
+	assert.EqualValues(t, 3, task.GetCount())
+
+	task = run(IncrementalMode, true)
+	assert.EqualValues(t, 0, task.GetCount())
+
+	// a full refresh resets the state, with all the files
+	task = run(FullRefreshMode, false)
+	assert.EqualValues(t, 5, task.GetCount())
+
+	task = run(IncrementalMode, false)
+	assert.EqualValues(t, 0, task.GetCount())
+
+	// the state is opt-in
+	cfg := &Config{}
+	cfg.Source.Conn, cfg.Target.Conn = "LOCAL", "LOCAL"
+	cfg.Source.Stream = "file://" + inFolder + "/"
+	cfg.Target.Object = "file://" + path.Join(folder, "out2", "data.csv")
+	task = NewTask(NewExecID(), cfg)
+	if assert.NoError(t, task.Execute()) {
+		assert.EqualValues(t, 5, task.GetCount())
+		assert.Nil(t, task.fileState)
+		assert.NoDirExists(t, path.Join(folder, "out2", "_sling_state"))
+	}
+}
+
+func TestFileStateDatabase(t *testing.T) {
+	folder := t.TempDir()
+	inFolder := path.Join(folder, "in")
+	assert.NoError(t, os.MkdirAll(inFolder, 0755))
+	assert.NoError(t, os.WriteFile(path.Join(inFolder, "a.csv"), []byte("id,name\n1,a\n2,b\n"), 0644))
+
+	dbURL := "sqlite://" + path.Join(folder, "db.sqlite")
+
+	run := func() *TaskExecution {
+		cfg := &Config{Mode: IncrementalMode}
+		cfg.Source.Conn, cfg.Target.Conn = "LOCAL", dbURL
+		cfg.Source.Stream = "file://" + inFolder + "/"
+		cfg.Source.Options = &SourceOptions{TrackFiles: g.Bool(true)}
+		cfg.Target.Object = "main.data"
+
+		task := NewTask(NewExecID(), cfg)
+		assert.NoError(t, task.Execute())
+		return task
+	}
+
+	// the state is saved in the target database
+	task := run()
+	assert.EqualValues(t, 2, task.GetCount())
+
+	task = run()
+	assert.EqualValues(t, 0, task.GetCount())
+
+	conn, err := database.NewConn(dbURL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	data, err := conn.Query(`select count(*) from main._sling_state`)
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 1, cast.ToInt(data.Rows[0][0]))
+	}
+
+	// the state is reset once the target table is dropped
+	assert.NoError(t, conn.DropTable("main.data"))
+
+	task = run()
+	assert.EqualValues(t, 2, task.GetCount())
+}
@@ -0,0 +1,289 @@
+package sling
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// fileStateName is the table (in the schema of a database target) or the
+// folder (next to a file target) where the file state is saved
+const fileStateName = "_sling_state"
+
+// fileStateStore saves the file state of the streams in the target
+type fileStateStore interface {
+	Load(key string) (files map[string]int64, err error)
+	Save(key string, files map[string]int64) (err error) // adds the files
+	Reset(key string) (err error)
+}
+
+// usingFileState means the source files are tracked (with source option
+// `track_files`), by saving each processed file (path, size, modification
+// time, etag) in the target. Incremental runs only load new files, other
+// modes reset the file state.
+func (t *TaskExecution) usingFileState() bool {
+	return t.Config.SrcConn.Type.IsFile() && !t.Config.Options.StdIn && t.Config.trackFiles()
+}
+
+// fileStateKey returns the key of the stream in the file state
+func (t *TaskExecution) fileStateKey() string {
+	return g.MD5(
+		t.Config.SrcConn.Info().Name, t.Config.Source.Stream,
+		t.Config.TgtConn.Info().Name, t.Config.Target.Object,
+	)
+}
+
+// loadFileState loads the file state of the stream from the target. If not
+// yet saved, the state declared in the replication stream is used.
+func (t *TaskExecution) loadFileState(store fileStateStore) (err error) {
+	t.fileStateStore = store
+	t.fileState = &StreamIncrementalState{Files: map[string]int64{}}
+	key := t.fileStateKey()
+
+	if t.Config.Mode != IncrementalMode {
+		g.Debug("resetting file state (mode %s)", t.Config.Mode)
+		if err = store.Reset(key); err != nil {
+			return g.Error(err, "could not reset file state")
+		}
+		return nil
+	}
+
+	files, err := store.Load(key)
+	if err != nil {
+		return g.Error(err, "could not load file state")
+	}
+
+	if len(files) > 0 {
+		t.fileState.Files = files
+	} else if rs := t.Config.ReplicationStream; rs != nil && rs.State != nil {
+		for uri, fingerprint := range rs.State.Files {
+			t.fileState.Files[uri] = fingerprint
+		}
+	}
+
+	return nil
+}
+
+// saveFileState adds the consumed source files to the file state, and saves it.
+// Should only be called once the load succeeded.
+func (t *TaskExecution) saveFileState() (err error) {
+	if t.fileState == nil || t.fileStateStore == nil || t.srcFs == nil {
+		return nil
+	}
+
+	nodes := t.srcFs.Client().ReadNodes()
+
+	// on the first run of a stream previously loaded with a timestamp,
+	// consider the older files as processed
+	if ts := t.srcFs.Client().GetRefTs(); len(t.fileState.Files) == 0 && !ts.IsZero() {
+		t.srcFs.SetProp("SLING_FS_TIMESTAMP", "")
+		allNodes, err := t.srcFs.ListRecursive(t.df.FsURL)
+		if err != nil {
+			return g.Error(err, "could not list files for file state")
+		}
+		for _, node := range allNodes {
+			if !node.IsDir && node.Updated <= ts.Unix() {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+
+	files := map[string]int64{}
+	for _, node := range nodes {
+		if fingerprint, ok := t.fileState.Files[node.URI]; !ok || fingerprint != node.Fingerprint() {
+			files[node.URI] = node.Fingerprint()
+		}
+		t.fileState.Files[node.URI] = node.Fingerprint()
+	}
+	t.fileState.Value = t.StartTime.Unix()
+
+	if err = t.fileStateStore.Save(t.fileStateKey(), files); err != nil {
+		return g.Error(err, "could not save file state")
+	}
+
+	if t.Config.ReplicationStream != nil {
+		t.Config.ReplicationStream.State = t.fileState
+	}
+
+	g.Debug("saved file state with %d new files (%d total)", len(files), len(t.fileState.Files))
+	return nil
+}
+
+// dbFileStateStore saves the file state in the `_sling_state` table, in the
+// schema of the target table. Each processed file is a row, the latest row
+// of a file has its current fingerprint.
+type dbFileStateStore struct {
+	conn   database.Connection
+	table  string
+	target string
+}
+
+var dbFileStateColumns = iop.Columns{
+	{Name: "stream_key", Type: iop.StringType},
+	{Name: "file_uri", Type: iop.TextType},
+	{Name: "fingerprint", Type: iop.BigIntType},
+	{Name: "loaded_at", Type: iop.BigIntType},
+}
+
+func newDbFileStateStore(conn database.Connection, targetObject string) (store *dbFileStateStore, err error) {
+	target, err := database.ParseTableName(targetObject, conn.GetType())
+	if err != nil {
+		return nil, g.Error(err, "could not parse target table name: %s", targetObject)
+	}
+
+	table, err := database.ParseTableName(target.Schema+"."+fileStateName, conn.GetType())
+	if err != nil {
+		return nil, g.Error(err, "could not parse file state table name")
+	}
+
+	return &dbFileStateStore{conn: conn, table: table.FDQN(), target: target.FDQN()}, nil
+}
+
+func (s *dbFileStateStore) exists(table string) bool {
+	exists, _ := database.TableExists(s.conn, table)
+	return exists
+}
+
+// Load loads the files of the stream. The state is reset when the target
+// table does not exist (such as when it was dropped), to not drift from it.
+func (s *dbFileStateStore) Load(key string) (files map[string]int64, err error) {
+	files = map[string]int64{}
+	if !s.exists(s.table) {
+		return files, nil
+	} else if !s.exists(s.target) {
+		g.Debug("resetting file state since target table %s does not exist", s.target)
+		return files, s.Reset(key)
+	}
+
+	sql := g.F(
+		"select %s, %s from %s where %s = '%s' order by %s",
+		s.conn.Quote("file_uri"), s.conn.Quote("fingerprint"), s.table,
+		s.conn.Quote("stream_key"), key, s.conn.Quote("loaded_at"),
+	)
+	data, err := s.conn.Query(sql)
+	if err != nil {
+		return nil, g.Error(err, "could not query file state from %s", s.table)
+	}
+
+	for _, row := range data.Rows {
+		files[cast.ToString(row[0])] = cast.ToInt64(row[1])
+	}
+	return files, nil
+}
+
+func (s *dbFileStateStore) Save(key string, files map[string]int64) (err error) {
+	if len(files) == 0 {
+		return nil
+	}
+
+	if err = s.conn.CreateTable(s.table, dbFileStateColumns, ""); err != nil {
+		return g.Error(err, "could not create file state table %s", s.table)
+	}
+
+	loadedAt := time.Now().Unix()
+	data := iop.NewDataset(dbFileStateColumns)
+	for uri, fingerprint := range files {
+		data.Append([]any{key, uri, fingerprint, loadedAt})
+	}
+
+	if _, err = s.conn.InsertBatchStream(s.table, data.Stream()); err != nil {
+		return g.Error(err, "could not insert into file state table %s", s.table)
+	}
+	return nil
+}
+
+func (s *dbFileStateStore) Reset(key string) (err error) {
+	if !s.exists(s.table) {
+		return nil
+	}
+
+	sql := g.F("delete from %s where %s = '%s'", s.table, s.conn.Quote("stream_key"), key)
+	if _, err = s.conn.Exec(sql); err != nil {
+		return g.Error(err, "could not reset file state in %s", s.table)
+	}
+	return nil
+}
+
+// fsFileStateStore saves the file state in `<parent>/_sling_state/<key>.json`,
+// next to the target file or folder
+type fsFileStateStore struct {
+	fs     filesys.FileSysClient
+	folder string
+}
+
+func newFsFileStateStore(fs filesys.FileSysClient, targetURL string) *fsFileStateStore {
+	url := filesys.NormalizeURI(fs, targetURL)
+	if i := strings.Index(url, "{"); i > -1 {
+		url = url[:i] // the static part of a templated url
+	} else {
+		url = strings.TrimSuffix(url, "/")
+	}
+	return &fsFileStateStore{fs: fs, folder: url[:strings.LastIndex(url, "/")+1] + fileStateName}
+}
+
+func (s *fsFileStateStore) url(key string) string {
+	return s.folder + "/" + key + ".json"
+}
+
+func (s *fsFileStateStore) Load(key string) (files map[string]int64, err error) {
+	files = map[string]int64{}
+
+	nodes, err := s.fs.List(s.url(key))
+	if err != nil || len(nodes) == 0 {
+		return files, nil // not yet saved
+	}
+
+	reader, err := s.fs.GetReader(s.url(key))
+	if err != nil {
+		return nil, g.Error(err, "could not read file state %s", s.url(key))
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, g.Error(err, "could not read file state %s", s.url(key))
+	}
+
+	state := StreamIncrementalState{}
+	if err = g.Unmarshal(string(content), &state); err != nil {
+		return nil, g.Error(err, "could not parse file state %s", s.url(key))
+	}
+
+	for uri, fingerprint := range state.Files {
+		files[uri] = fingerprint
+	}
+	return files, nil
+}
+
+func (s *fsFileStateStore) Save(key string, files map[string]int64) (err error) {
+	if len(files) == 0 {
+		return nil
+	}
+
+	state, err := s.Load(key)
+	if err != nil {
+		return err
+	}
+	for uri, fingerprint := range files {
+		state[uri] = fingerprint
+	}
+
+	content := g.Marshal(StreamIncrementalState{Value: time.Now().Unix(), Files: state})
+	if _, err = s.fs.Write(s.url(key), strings.NewReader(content)); err != nil {
+		return g.Error(err, "could not write file state %s", s.url(key))
+	}
+	return nil
+}
+
+func (s *fsFileStateStore) Reset(key string) (err error) {
+	nodes, err := s.fs.List(s.url(key))
+	if err != nil || len(nodes) == 0 {
+		return nil
+	}
+	return filesys.DeleteStrict(s.fs, s.url(key))
+}
@@ -9,9 +9,11 @@ import (
 
 	"github.com/dustin/go-humanize"
 	"github.com/flarco/g"
+	"github.com/samber/lo"
 	"github.com/segmentio/ksuid"
 	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 	"github.com/slingdata-io/sling-cli/core/env"
 	"github.com/spf13/cast"
@@ -34,11 +36,17 @@ type TaskExecution struct {
 	Context   *g.Context `json:"-"`
 	Progress  string     `json:"progress"`
 
-	df            *iop.Dataflow `json:"-"`
-	prevRowCount  uint64
-	prevByteCount uint64
-	lastIncrement time.Time // the time of last row increment (to determine stalling)
-	Output        string    `json:"-"`
+	// ProcessedFiles are the source file URIs moved or deleted after the load
+	ProcessedFiles []string `json:"processed_files,omitempty"`
+
+	df             *iop.Dataflow `json:"-"`
+	srcFs          filesys.FileSysClient
+	fileState      *StreamIncrementalState
+	fileStateStore fileStateStore
+	prevRowCount   uint64
+	prevByteCount  uint64
+	lastIncrement  time.Time // the time of last row increment (to determine stalling)
+	Output         string    `json:"-"`
 
 	Replication    *ReplicationConfig `json:"replication"`
 	ProgressHist   []string           `json:"progress_hist"`
@@ -428,6 +436,12 @@ func (t *TaskExecution) sourceOptionsMap() (options map[string]any) {
 			g.Warn("Config.Source.Options.Columns not handled: %T", t.Config.Source.Options.Columns)
 		}
 
+		// fixed-width columns are positions, with an optional type
+		if format := t.Config.Source.Options.Format; format != nil && *format == filesys.FileTypeFixedWidth {
+			options["fixed_width_columns"] = g.Marshal(t.Config.Source.Options.Columns)
+			columns = lo.Filter(columns, func(col iop.Column, i int) bool { return col.Type != "" })
+		}
+
 		// parse length, precision, scale
 		for i := range columns {
 			columns[i].SetLengthPrecisionScale()
@@ -437,6 +451,16 @@ func (t *TaskExecution) sourceOptionsMap() (options map[string]any) {
 		options["columns"] = g.Marshal(iop.NewColumns(columns...))
 	}
 
+	// a list of sheets is passed as JSON, in place of a single sheet
+	if sheets := t.Config.Source.Options.Sheets; len(sheets) > 0 {
+		options["sheet"] = g.Marshal(sheets)
+		delete(options, "sheets")
+	}
+
+	if decrypt := t.Config.Source.Options.Decrypt; decrypt != nil {
+		options["decrypt"] = g.Marshal(decrypt)
+	}
+
 	if transforms := t.Config.Source.Options.Transforms; transforms != nil {
 		colTransforms := map[string][]string{}
 
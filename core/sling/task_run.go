@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"path"
 	"runtime"
 	"runtime/debug"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/flarco/g"
 	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
 	"github.com/slingdata-io/sling-cli/core/env"
 	"github.com/spf13/cast"
 )
@@ -370,6 +372,16 @@ func (t *TaskExecution) runFileToDB() (err error) {
 		}
 	}
 
+	if t.usingFileState() {
+		targetObject := setSchema(cast.ToString(t.Config.Target.Data["schema"]), t.Config.Target.Object)
+		store, err := newDbFileStateStore(tgtConn, targetObject)
+		if err != nil {
+			return err
+		} else if err = t.loadFileState(store); err != nil {
+			return err
+		}
+	}
+
 	if t.Config.Options.StdIn && t.Config.SrcConn.Type.IsUnknown() {
 		t.SetProgress("reading from stream (stdin)")
 	} else {
@@ -378,7 +390,9 @@ func (t *TaskExecution) runFileToDB() (err error) {
 	t.df, err = t.ReadFromFile(t.Config)
 	if err != nil {
 		if strings.Contains(err.Error(), "Provided 0 files") {
-			if t.usingCheckpoint() && t.Config.IncrementalVal != nil {
+			if t.fileState != nil && len(t.fileState.Files) > 0 {
+				t.SetProgress("no new files found (%d files already processed)", len(t.fileState.Files))
+			} else if t.usingCheckpoint() && t.Config.IncrementalVal != nil {
 				t.SetProgress("no new files found since latest timestamp (%s)", time.Unix(cast.ToInt64(t.Config.IncrementalValStr), 0))
 			} else {
 				t.SetProgress("no files found")
@@ -409,6 +423,10 @@ func (t *TaskExecution) runFileToDB() (err error) {
 
 	if err != nil {
 		err = g.Error(t.df.Err(), "error in transfer")
+	} else if err = t.df.Err(); err == nil {
+		if err = t.saveFileState(); err == nil {
+			err = t.processSourceFiles()
+		}
 	}
 	return
 }
@@ -417,6 +435,15 @@ func (t *TaskExecution) runFileToFile() (err error) {
 
 	start = time.Now()
 
+	if t.usingFileState() && !t.Config.Options.StdOut {
+		tgtFs, err := filesys.NewFileSysClientFromURLContext(t.Context.Ctx, t.Config.TgtConn.URL(), g.MapToKVArr(t.Config.TgtConn.DataS())...)
+		if err != nil {
+			return g.Error(err, "could not obtain client for: %s", t.Config.TgtConn.Type)
+		} else if err = t.loadFileState(newFsFileStateStore(tgtFs, t.Config.TgtConn.URL())); err != nil {
+			return err
+		}
+	}
+
 	if t.Config.Options.StdIn && t.Config.SrcConn.Type.IsUnknown() {
 		t.SetProgress("reading from stream (stdin)")
 	} else {
@@ -425,7 +452,9 @@ func (t *TaskExecution) runFileToFile() (err error) {
 	t.df, err = t.ReadFromFile(t.Config)
 	if err != nil {
 		if strings.Contains(err.Error(), "Provided 0 files") {
-			if t.usingCheckpoint() && t.Config.IncrementalVal != nil {
+			if t.fileState != nil && len(t.fileState.Files) > 0 {
+				t.SetProgress("no new files found (%d files already processed)", len(t.fileState.Files))
+			} else if t.usingCheckpoint() && t.Config.IncrementalVal != nil {
 				t.SetProgress("no new files found since latest timestamp (%s)", time.Unix(cast.ToInt64(t.Config.IncrementalValStr), 0))
 			} else {
 				t.SetProgress("no files found")
@@ -456,10 +485,61 @@ func (t *TaskExecution) runFileToFile() (err error) {
 
 	if t.df.Err() != nil {
 		err = g.Error(t.df.Err(), "Error in runFileToFile")
+	} else if err = t.saveFileState(); err == nil {
+		err = t.processSourceFiles()
 	}
 	return
 }
 
+// processSourceFiles applies the `after_load` action (move or delete)
+// to the consumed source files. Only called once the load succeeded.
+func (t *TaskExecution) processSourceFiles() (err error) {
+	afterLoad := t.Config.Source.Options.AfterLoad
+	if afterLoad == nil || g.In(afterLoad.Action, AfterLoadNone, "") || t.srcFs == nil {
+		return nil
+	}
+
+	nodes := t.srcFs.Client().ReadNodes()
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	// keep the sub-folders of the files, relative to the source url
+	baseURI := filesys.GetDeepestParent(filesys.NormalizeURI(t.srcFs, t.df.FsURL))
+	archiveURI := strings.TrimSuffix(filesys.NormalizeURI(t.srcFs, afterLoad.ArchivePath), "/")
+
+	t.SetProgress("processing %d source files (after_load: %s)", len(nodes), afterLoad.Action)
+	for _, node := range nodes {
+		switch afterLoad.Action {
+		case AfterLoadMove:
+			relPath := strings.TrimPrefix(node.URI, baseURI)
+			if relPath == node.URI {
+				relPath = path.Base(node.Path())
+			}
+			tgtURI := archiveURI + "/" + strings.TrimPrefix(relPath, "/")
+
+			g.Debug("moving %s to %s", node.URI, tgtURI)
+			if err = filesys.Move(t.srcFs, node.URI, tgtURI); err != nil {
+				return g.Error(err, "could not move source file %s", node.URI)
+			}
+		case AfterLoadDelete:
+			g.Debug("deleting %s", node.URI)
+			if err = filesys.DeleteStrict(t.srcFs, node.URI); err != nil {
+				return g.Error(err, "could not delete source file %s", node.URI)
+			}
+		}
+		t.ProcessedFiles = append(t.ProcessedFiles, node.URI)
+	}
+
+	if afterLoad.Action == AfterLoadMove {
+		t.SetProgress("moved %d source files to %s", len(t.ProcessedFiles), archiveURI)
+	} else {
+		t.SetProgress("deleted %d source files", len(t.ProcessedFiles))
+	}
+
+	return nil
+}
+
 func (t *TaskExecution) runDbToDb() (err error) {
 	start = time.Now()
 	if t.Config.Mode == Mode("") {
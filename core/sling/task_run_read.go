@@ -222,11 +222,20 @@ func (t *TaskExecution) ReadFromFile(cfg *Config) (df *iop.Dataflow, err error)
 	options := t.sourceOptionsMap()
 	options["METADATA"] = g.Marshal(metadata)
 
+	if t.fileState != nil {
+		// only read files which are not in the file state
+		options["SLING_FILE_STATE"] = g.Marshal(t.fileState.Files)
+		g.Debug(`file stream using file state with %d processed files`, len(t.fileState.Files))
+	}
+
 	if t.Config.IncrementalVal != nil {
 		// file stream incremental mode
 		if t.Config.Source.UpdateKey == slingLoadedAtColumn {
-			options["SLING_FS_TIMESTAMP"] = t.Config.IncrementalVal
-			g.Debug(`file stream using file_sys_timestamp=%#v and update_key=%s`, t.Config.IncrementalVal, t.Config.Source.UpdateKey)
+			// the file state has precedence over the timestamp, once populated
+			if t.fileState == nil || len(t.fileState.Files) == 0 {
+				options["SLING_FS_TIMESTAMP"] = t.Config.IncrementalVal
+				g.Debug(`file stream using file_sys_timestamp=%#v and update_key=%s`, t.Config.IncrementalVal, t.Config.Source.UpdateKey)
+			}
 		} else {
 			options["SLING_INCREMENTAL_COL"] = t.Config.Source.UpdateKey
 			options["SLING_INCREMENTAL_VAL"] = t.Config.IncrementalVal
@@ -253,6 +262,7 @@ func (t *TaskExecution) ReadFromFile(cfg *Config) (df *iop.Dataflow, err error)
 			err = g.Error(err, "Could not FileSysReadDataflow for %s", cfg.SrcConn.Type)
 			return t.df, err
 		}
+		t.srcFs = fs
 	} else {
 		stream, err = filesys.MakeDatastream(bufio.NewReader(os.Stdin), g.ToMapString(options))
 		if err != nil {
@@ -36,10 +36,21 @@ func (t *TaskExecution) WriteToFile(cfg *Config, df *iop.Dataflow) (cnt uint64,
 		// construct props by merging with options
 		options := g.M()
 		g.Unmarshal(g.Marshal(cfg.Target.Options), &options)
+		for k, v := range options {
+			switch v.(type) {
+			case map[string]any, []any:
+				options[k] = g.Marshal(v) // pass lists & maps as JSON
+			}
+		}
+		if cols, ok := options["columns"]; ok && cfg.Target.Options.Format == filesys.FileTypeFixedWidth {
+			options["fixed_width_columns"] = cols // column positions
+			delete(options, "columns")
+		}
 		props := append(
 			g.MapToKVArr(cfg.TgtConn.DataS()),
 			g.MapToKVArr(g.ToMapString(options))...,
 		)
+		props = append(props, g.F("SLING_MODE=%s", cfg.Mode), g.F("EXEC_ID=%s", t.ExecID))
 
 		fs, err := filesys.NewFileSysClientFromURLContext(t.Context.Ctx, uri, props...)
 		if err != nil {
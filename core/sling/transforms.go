@@ -40,10 +40,20 @@ var transforms = map[iop.Transform]iop.TransformFunc{
 	iop.TransformEncodeUtf8: func(sp *iop.StreamProcessor, val string) (string, error) {
 		return fmt.Sprintf("%q", val), nil
 	},
+	iop.TransformFakeAddress: func(sp *iop.StreamProcessor, val string) (string, error) {
+		return Fake(maskSeed(), "address", val), nil
+	},
+	iop.TransformFakeEmail:        func(sp *iop.StreamProcessor, val string) (string, error) { return Fake(maskSeed(), "email", val), nil },
+	iop.TransformFakeName:         func(sp *iop.StreamProcessor, val string) (string, error) { return Fake(maskSeed(), "name", val), nil },
+	iop.TransformFakePhone:        func(sp *iop.StreamProcessor, val string) (string, error) { return Fake(maskSeed(), "phone", val), nil },
 	iop.TransformDuckdbListToText: func(sp *iop.StreamProcessor, val string) (string, error) { return duckDbListAsText(val), nil },
 	iop.TransformHashMd5:          func(sp *iop.StreamProcessor, val string) (string, error) { return g.MD5(val), nil },
 	iop.TransformHashSha256:       func(sp *iop.StreamProcessor, val string) (string, error) { return SHA256(val), nil },
 	iop.TransformHashSha512:       func(sp *iop.StreamProcessor, val string) (string, error) { return SHA512(val), nil },
+	iop.TransformMaskEmail:        withMaskKey(MaskEmail),
+	iop.TransformMaskLast4:        func(sp *iop.StreamProcessor, val string) (string, error) { return Redact(val, 0, 4, "*"), nil },
+	iop.TransformMaskPhone:        withMaskKey(func(key, val string) string { return MaskPhone(key, val, 4) }),
+	iop.TransformNullify:          func(sp *iop.StreamProcessor, val string) (string, error) { return "", iop.ErrTransformNull },
 	iop.TransformParseBit:         func(sp *iop.StreamProcessor, val string) (string, error) { return ParseBit(sp, val) },
 	iop.TransformParseFix:         func(sp *iop.StreamProcessor, val string) (string, error) { return ParseFIX(sp, val) },
 	iop.TransformParseUuid:        func(sp *iop.StreamProcessor, val string) (string, error) { return ParseUUID(sp, val) },
@@ -52,14 +62,50 @@ var transforms = map[iop.Transform]iop.TransformFunc{
 		return sp.EncodingTransform(iop.TransformReplaceAccents, val)
 	},
 	iop.TransformReplaceNonPrintable: func(sp *iop.StreamProcessor, val string) (string, error) { return ReplaceNonPrint(sp, val) },
+	iop.TransformTokenize:            withMaskKey(Tokenize),
 	iop.TransformTrimSpace:           func(sp *iop.StreamProcessor, val string) (string, error) { return strings.TrimSpace(val), nil },
 }
 
+// builtInTransforms are the names of the built-in transforms, set on init
+// before any custom transform is registered. Read only afterwards.
+var builtInTransforms = map[iop.Transform]bool{}
+
 func init() {
 	// set transforms on init
 	for k, f := range transforms {
 		iop.Transforms[k] = f
 	}
+	for k := range iop.Transforms {
+		builtInTransforms[k] = true
+	}
+	for k := range iop.TransformsArgs {
+		builtInTransforms[k] = true
+	}
+}
+
+// isBuiltInTransform returns true if the name is taken by a built-in transform
+func isBuiltInTransform(name iop.Transform) bool {
+	return builtInTransforms[name]
+}
+
+// RegisterTransform registers a custom transform, which can then be
+// referenced by name in the `transforms` source option
+func RegisterTransform(name string, f iop.TransformFunc) error {
+	tName := iop.Transform(strings.ToLower(strings.TrimSpace(name)))
+	if isBuiltInTransform(tName) {
+		return g.Error("transform name '%s' conflicts with a built-in transform", name)
+	}
+	return iop.RegisterTransform(tName, f)
+}
+
+// RegisterTransformArgs registers a custom transform accepting arguments,
+// which can then be referenced as `name(arg1, arg2)` in the `transforms` source option
+func RegisterTransformArgs(name string, f iop.TransformArgsFunc) error {
+	tName := iop.Transform(strings.ToLower(strings.TrimSpace(name)))
+	if isBuiltInTransform(tName) {
+		return g.Error("transform name '%s' conflicts with a built-in transform", name)
+	}
+	return iop.RegisterTransformArgs(tName, f)
 }
 
 func Decode(sp *iop.StreamProcessor, decoder *encoding.Decoder, val string) (string, error) {
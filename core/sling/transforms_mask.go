@@ -0,0 +1,289 @@
+package sling
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/fnv"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"syreclabs.com/go/faker"
+)
+
+// MaskMethod is the masking method of a MaskPolicy
+type MaskMethod string
+
+const (
+	MaskMethodTokenize MaskMethod = "tokenize"
+	MaskMethodEmail    MaskMethod = "mask_email"
+	MaskMethodPhone    MaskMethod = "mask_phone"
+	MaskMethodRedact   MaskMethod = "redact"
+	MaskMethodFake     MaskMethod = "fake"
+	MaskMethodNullify  MaskMethod = "nullify"
+)
+
+// MaskPolicy is a named masking configuration. Once registered,
+// it can be referenced by name in the `transforms` source option
+// like any built-in transform. Policies declared under the
+// `policies` key of env.yaml are registered for every run.
+type MaskPolicy struct {
+	Name      string     `json:"name,omitempty" yaml:"name,omitempty"`
+	Method    MaskMethod `json:"method" yaml:"method"`
+	Key       string     `json:"key,omitempty" yaml:"key,omitempty"`               // secret for tokenize, mask_email & mask_phone
+	Seed      string     `json:"seed,omitempty" yaml:"seed,omitempty"`             // seed for fake
+	Kind      string     `json:"kind,omitempty" yaml:"kind,omitempty"`             // fake kind: name, first_name, last_name, email, phone, address, city, company, username
+	KeepFirst int        `json:"keep_first,omitempty" yaml:"keep_first,omitempty"` // for redact
+	KeepLast  int        `json:"keep_last,omitempty" yaml:"keep_last,omitempty"`   // for redact & mask_phone
+	Char      string     `json:"char,omitempty" yaml:"char,omitempty"`             // for redact, defaults to `*`
+	Length    int        `json:"length,omitempty" yaml:"length,omitempty"`         // for tokenize, truncates token
+}
+
+// RegisterMaskPolicy validates a masking policy and registers it
+// as a transform under its name
+func RegisterMaskPolicy(policy MaskPolicy) (err error) {
+	policy.Name = strings.ToLower(strings.TrimSpace(policy.Name))
+	if policy.Name == "" {
+		return g.Error("masking policy name is blank")
+	} else if isBuiltInTransform(iop.Transform(policy.Name)) {
+		return g.Error("masking policy name '%s' conflicts with a built-in transform", policy.Name)
+	}
+
+	switch policy.Method {
+	case MaskMethodTokenize, MaskMethodEmail, MaskMethodPhone, MaskMethodRedact, MaskMethodNullify:
+	case MaskMethodFake:
+		if !g.In(policy.Kind, "name", "first_name", "last_name", "email", "phone", "address", "city", "company", "username") {
+			return g.Error("invalid fake kind for masking policy '%s': %s", policy.Name, policy.Kind)
+		}
+	default:
+		return g.Error("invalid method for masking policy '%s': %s", policy.Name, policy.Method)
+	}
+
+	policy.Key = os.ExpandEnv(policy.Key)
+	policy.Seed = os.ExpandEnv(policy.Seed)
+	if policy.Char == "" {
+		policy.Char = "*"
+	}
+
+	return iop.RegisterMaskTransform(iop.Transform(policy.Name), policy.Transform)
+}
+
+// LoadMaskPolicies registers the masking policies declared in the env files
+func LoadMaskPolicies() (err error) {
+	policiesMap, err := env.GetHomeDirPoliciesMap()
+	if err != nil {
+		return g.Error(err, "could not read masking policies")
+	}
+
+	for name, policyMap := range policiesMap {
+		policy := MaskPolicy{}
+		if err = g.Unmarshal(g.Marshal(policyMap), &policy); err != nil {
+			return g.Error(err, "could not parse masking policy '%s'", name)
+		}
+		policy.Name = name
+
+		if err = RegisterMaskPolicy(policy); err != nil {
+			return g.Error(err, "could not register masking policy '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+// Transform applies the masking policy to a value
+func (mp MaskPolicy) Transform(sp *iop.StreamProcessor, val string) (string, error) {
+	// keyed methods
+	var key string
+	if g.In(mp.Method, MaskMethodTokenize, MaskMethodEmail, MaskMethodPhone) {
+		key = mp.Key
+		if key == "" {
+			var err error
+			if key, err = maskKey(); err != nil {
+				return "", g.Error(err, "no key for masking policy '%s'", mp.Name)
+			}
+		}
+	}
+
+	switch mp.Method {
+	case MaskMethodTokenize:
+		token := Tokenize(key, val)
+		if mp.Length > 0 && mp.Length < len(token) {
+			token = token[:mp.Length]
+		}
+		return token, nil
+	case MaskMethodEmail:
+		return MaskEmail(key, val), nil
+	case MaskMethodPhone:
+		return MaskPhone(key, val, lo.Ternary(mp.KeepLast > 0, mp.KeepLast, 4)), nil
+	case MaskMethodRedact:
+		return Redact(val, mp.KeepFirst, mp.KeepLast, mp.Char), nil
+	case MaskMethodFake:
+		return Fake(lo.Ternary(mp.Seed != "", mp.Seed, maskSeed()), mp.Kind, val), nil
+	case MaskMethodNullify:
+		return "", iop.ErrTransformNull
+	}
+
+	return val, g.Error("invalid masking method: %s", mp.Method)
+}
+
+// maskKey returns the default secret used for keyed masking.
+// Masking with a blank key would be reversible by anyone.
+func maskKey() (string, error) {
+	key := os.Getenv("SLING_MASK_KEY")
+	if key == "" {
+		return "", g.Error("SLING_MASK_KEY is not set, it is required by tokenize, mask_email and mask_phone")
+	}
+	return key, nil
+}
+
+// withMaskKey applies the keyed masking function with the default secret
+func withMaskKey(mask func(key, val string) string) iop.TransformFunc {
+	return func(sp *iop.StreamProcessor, val string) (string, error) {
+		key, err := maskKey()
+		if err != nil {
+			return "", err
+		}
+		return mask(key, val), nil
+	}
+}
+
+// maskSeed returns the default seed used for fake substitution
+func maskSeed() string {
+	return os.Getenv("SLING_MASK_SEED")
+}
+
+// Tokenize returns a deterministic token using an HMAC-SHA256 of the value
+func Tokenize(key, val string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(val))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// maskStream returns a keyed pseudo-random byte stream of length n derived from the value
+func maskStream(key, val string, n int) []byte {
+	stream := make([]byte, 0, n+sha256.Size)
+	counter := make([]byte, 8)
+	for i := uint64(0); len(stream) < n; i++ {
+		binary.BigEndian.PutUint64(counter, i)
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write([]byte(val))
+		h.Write(counter)
+		stream = h.Sum(stream)
+	}
+	return stream[:n]
+}
+
+// preserveFormat deterministically substitutes letters and digits with
+// letters and digits of the same class. Other characters are kept.
+// Characters where keep returns true are not substituted.
+func preserveFormat(key, val string, keep func(i int, r rune) bool) string {
+	runes := []rune(val)
+	stream := maskStream(key, val, len(runes))
+
+	var newVal strings.Builder
+	for i, r := range runes {
+		switch {
+		case keep != nil && keep(i, r):
+			newVal.WriteRune(r)
+		case unicode.IsDigit(r):
+			newVal.WriteRune(rune('0' + stream[i]%10))
+		case unicode.IsUpper(r):
+			newVal.WriteRune(rune('A' + stream[i]%26))
+		case unicode.IsLetter(r):
+			newVal.WriteRune(rune('a' + stream[i]%26))
+		default:
+			newVal.WriteRune(r)
+		}
+	}
+	return newVal.String()
+}
+
+// MaskEmail deterministically masks the local part of an email
+// address while preserving its length, character classes and domain
+func MaskEmail(key, val string) string {
+	at := strings.LastIndex(val, "@")
+	if at == -1 {
+		return preserveFormat(key, val, nil)
+	}
+	return preserveFormat(key, val[:at], nil) + val[at:]
+}
+
+// MaskPhone deterministically substitutes the digits of a phone number,
+// keeping the separators, a leading `+` and the last `keepLast` digits
+func MaskPhone(key, val string, keepLast int) string {
+	runes := []rune(val)
+	digitsSeen, digitsTotal := 0, 0
+	for _, r := range runes {
+		if unicode.IsDigit(r) {
+			digitsTotal++
+		}
+	}
+
+	return preserveFormat(key, val, func(i int, r rune) bool {
+		if !unicode.IsDigit(r) {
+			return true
+		}
+		digitsSeen++
+		return digitsSeen > digitsTotal-keepLast
+	})
+}
+
+// Redact replaces all characters with `char`, except for
+// the first `keepFirst` and last `keepLast` characters
+func Redact(val string, keepFirst, keepLast int, char string) string {
+	runes := []rune(val)
+	var newVal strings.Builder
+	for i, r := range runes {
+		if i < keepFirst || i >= len(runes)-keepLast {
+			newVal.WriteRune(r)
+		} else {
+			newVal.WriteString(char)
+		}
+	}
+	return newVal.String()
+}
+
+var fakeMux sync.Mutex
+
+// Fake returns a deterministic fake value of the provided kind.
+// The same seed and value always produce the same fake value.
+func Fake(seed, kind, val string) string {
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	h.Write([]byte{0})
+	h.Write([]byte(val))
+
+	// faker uses a shared random source
+	fakeMux.Lock()
+	defer fakeMux.Unlock()
+	faker.Seed(int64(h.Sum64()))
+
+	switch kind {
+	case "name":
+		return faker.Name().Name()
+	case "first_name":
+		return faker.Name().FirstName()
+	case "last_name":
+		return faker.Name().LastName()
+	case "email":
+		return faker.Internet().Email()
+	case "phone":
+		return faker.PhoneNumber().PhoneNumber()
+	case "address":
+		return faker.Address().StreetAddress()
+	case "city":
+		return faker.Address().City()
+	case "company":
+		return faker.Company().Name()
+	case "username":
+		return faker.Internet().UserName()
+	}
+	return val
+}
@@ -0,0 +1,90 @@
+package sling
+
+import (
+	"testing"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMasking(t *testing.T) {
+	// deterministic
+	assert.Equal(t, Tokenize("secret", "john"), Tokenize("secret", "john"))
+	assert.NotEqual(t, Tokenize("secret", "john"), Tokenize("other", "john"))
+	assert.Len(t, Tokenize("secret", "john"), 64)
+
+	email := MaskEmail("secret", "John.Doe42@example.com")
+	assert.Equal(t, email, MaskEmail("secret", "John.Doe42@example.com"))
+	assert.Regexp(t, `^[A-Z][a-z]{3}\.[A-Z][a-z]{2}\d\d@example\.com$`, email)
+	assert.NotEqual(t, "John.Doe42@example.com", email)
+
+	phone := MaskPhone("secret", "+1 (555) 123-4567", 4)
+	assert.Regexp(t, `^\+\d \(\d{3}\) \d{3}-4567$`, phone)
+
+	assert.Equal(t, "************1234", Redact("4111111111111234", 0, 4, "*"))
+	assert.Equal(t, "41**********1234", Redact("4111111111111234", 2, 4, "*"))
+	assert.Equal(t, "abc", Redact("abc", 0, 4, "*"))
+
+	assert.Equal(t, Fake("seed", "name", "john"), Fake("seed", "name", "john"))
+	assert.Contains(t, Fake("seed", "email", "john"), "@")
+
+	// policies
+	err := RegisterMaskPolicy(MaskPolicy{Name: "hash_md5", Method: MaskMethodNullify})
+	assert.Error(t, err)
+
+	err = RegisterMaskPolicy(MaskPolicy{Name: "pii_card", Method: MaskMethodRedact, KeepLast: 4, Char: "X"})
+	if assert.NoError(t, err) {
+		f, ok := iop.Transforms["pii_card"]
+		if assert.True(t, ok) {
+			val, err := f(nil, "4111111111111234")
+			assert.NoError(t, err)
+			assert.Equal(t, "XXXXXXXXXXXX1234", val)
+		}
+	}
+
+	err = RegisterMaskPolicy(MaskPolicy{Name: "pii_token", Method: MaskMethodTokenize, Key: "secret", Length: 16})
+	if assert.NoError(t, err) {
+		val, _ := iop.Transforms["pii_token"](nil, "john")
+		assert.Equal(t, Tokenize("secret", "john")[:16], val)
+	}
+
+	_, err = iop.Transforms[iop.TransformNullify](nil, "john")
+	assert.Equal(t, iop.ErrTransformNull, err)
+
+	// keyed masking requires a key
+	t.Setenv("SLING_MASK_KEY", "")
+	for _, name := range []iop.Transform{iop.TransformTokenize, iop.TransformMaskEmail, iop.TransformMaskPhone} {
+		_, err = iop.Transforms[name](nil, "john@example.com")
+		assert.ErrorContains(t, err, "SLING_MASK_KEY is not set", name)
+	}
+
+	err = RegisterMaskPolicy(MaskPolicy{Name: "pii_email", Method: MaskMethodEmail})
+	if assert.NoError(t, err) {
+		_, err = iop.Transforms["pii_email"](nil, "john@example.com")
+		assert.ErrorContains(t, err, "no key for masking policy 'pii_email'")
+	}
+
+	t.Setenv("SLING_MASK_KEY", "secret")
+	val, err := iop.Transforms[iop.TransformTokenize](nil, "john")
+	if assert.NoError(t, err) {
+		assert.Equal(t, Tokenize("secret", "john"), val)
+	}
+
+	// masking applies to non-string columns, and fails the stream on error
+	// hash transforms keep applying to string columns only
+	columns := iop.Columns{{Name: "card", Type: iop.BigIntType}, {Name: "email", Type: iop.StringType}, {Name: "account", Type: iop.BigIntType}}
+	ds := iop.NewDatastream(columns)
+	ds.SetConfig(map[string]string{
+		"transforms": g.Marshal(map[string][]string{"card": {"mask_last_4"}, "email": {"pii_email"}, "account": {"hash_md5"}}),
+	})
+	row := ds.Sp.CastRow([]any{int64(4111111111111234), "john@example.com", int64(42)}, ds.Columns)
+	assert.Equal(t, "************1234", row[0])
+	assert.Equal(t, MaskEmail("secret", "john@example.com"), row[1])
+	assert.EqualValues(t, 42, row[2])
+
+	t.Setenv("SLING_MASK_KEY", "")
+	row = ds.Sp.CastRow([]any{int64(4111111111111234), "john@example.com", int64(42)}, ds.Columns)
+	assert.Nil(t, row[1])
+	assert.ErrorContains(t, ds.Err(), "no key for masking policy 'pii_email'")
+}
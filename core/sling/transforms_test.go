@@ -0,0 +1,32 @@
+package sling
+
+import (
+	"testing"
+
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterTransform(t *testing.T) {
+	bang := func(sp *iop.StreamProcessor, val string) (string, error) { return val + "!", nil }
+	wrap := func(sp *iop.StreamProcessor, val any, args []any) (any, error) {
+		return cast.ToString(args[0]) + cast.ToString(val) + cast.ToString(args[0]), nil
+	}
+
+	// built-in names are reserved
+	assert.Error(t, RegisterTransform("trim_space", bang))
+	assert.Error(t, RegisterTransformArgs("replace", wrap))
+
+	// custom transforms can be registered again, to override them
+	if assert.NoError(t, RegisterTransformArgs("test_wrap", wrap)) {
+		assert.NoError(t, RegisterTransformArgs("Test_Wrap", wrap))
+		val, err := iop.TransformsArgs["test_wrap"](nil, "a", []any{"|"})
+		assert.NoError(t, err)
+		assert.Equal(t, "|a|", val)
+	}
+
+	if assert.NoError(t, RegisterTransform("test_bang", bang)) {
+		assert.NoError(t, RegisterTransform("test_bang", bang))
+	}
+}